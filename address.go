@@ -0,0 +1,122 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Address is an email address with an optional display name, e.g.
+// Address{Name: "ICAA Events", Address: "events@icaa.org"}. Use String to
+// put it in the address fields of an Email, which accept both bare and
+// formatted addresses.
+type Address struct {
+	Name    string
+	Address string
+}
+
+// String formats a as an RFC 5322 address. Display names are quoted as
+// needed, and RFC 2047 encoded if they are not ASCII. Addresses without a
+// name are returned bare.
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Address
+	}
+	return (&mail.Address{Name: a.Name, Address: a.Address}).String()
+}
+
+// ParseAddress parses a bare or formatted address, decoding an encoded
+// display name.
+func ParseAddress(s string) (Address, error) {
+	parsed, err := mail.ParseAddress(s)
+	if err != nil {
+		return Address{}, NewInvalidEmailError(fmt.Sprintf("invalid address: %s", s), err)
+	}
+	return Address{Name: parsed.Name, Address: parsed.Address}, nil
+}
+
+// AddressStrings formats addrs for the address fields of an Email.
+func AddressStrings(addrs ...Address) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return s
+}
+
+// ReturnPath formats addr as the value of a Return-Path header, <address>,
+// dropping any display name.
+func ReturnPath(addr string) string {
+	if a, err := ParseAddress(addr); err == nil {
+		return "<" + a.Address + ">"
+	}
+	return "<" + addr + ">"
+}
+
+// NormalizeAddress converts the internationalized domain of a bare or
+// formatted address to punycode, e.g. jose@münchen.de to
+// jose@xn--mnchen-3ya.de, which every provider accepts. The local part, which
+// may be UTF-8 for providers supporting SMTPUTF8, and the display name are
+// kept. Addresses with ASCII domains are returned unchanged.
+func NormalizeAddress(addr string) (string, error) {
+	a, err := ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+
+	at := strings.LastIndex(a.Address, "@")
+	local, domain := a.Address[:at], a.Address[at+1:]
+	if isASCII(domain) {
+		return addr, nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", NewInvalidEmailError(fmt.Sprintf("domain %s of %s is not a valid internationalized domain name", domain, addr), err)
+	}
+	a.Address = local + "@" + ascii
+	return a.String(), nil
+}
+
+// NormalizeAddresses returns e with every address NormalizeAddress converts,
+// From, Sender, recipients, Reply-To, bounce, read receipt and fallback,
+// converted.
+func NormalizeAddresses(e Email) (Email, error) {
+	var err error
+	for _, addr := range []*string{&e.FromAddress, &e.SenderAddress, &e.BounceAddress, &e.ReadReceiptTo, &e.FallbackFor} {
+		if *addr == "" {
+			continue
+		}
+		if *addr, err = NormalizeAddress(*addr); err != nil {
+			return e, err
+		}
+	}
+	for _, addrs := range []*[]string{&e.ToAddresses, &e.CCAddresses, &e.BCCAddresses, &e.ReplyToAddresses} {
+		if *addrs == nil {
+			continue
+		}
+		normalized := make([]string, len(*addrs))
+		for i, addr := range *addrs {
+			if normalized[i], err = NormalizeAddress(addr); err != nil {
+				return e, err
+			}
+		}
+		*addrs = normalized
+	}
+	return e, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestAddress_String(t *testing.T) {
+	tests := []struct {
+		name    string
+		address Address
+		want    string
+	}{
+		{"bare", Address{Address: "events@icaa.org"}, "events@icaa.org"},
+		{"name", Address{Name: "ICAA Events", Address: "events@icaa.org"}, `"ICAA Events" <events@icaa.org>`},
+		{"comma", Address{Name: "Doe, Jane", Address: "jane@example.com"}, `"Doe, Jane" <jane@example.com>`},
+		{"non-ASCII", Address{Name: "Zoë Ñúñez", Address: "zoe@example.com"}, "=?utf-8?q?Zo=C3=AB_=C3=91=C3=BA=C3=B1ez?= <zoe@example.com>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.address.String()
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			parsed, err := ParseAddress(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if parsed != tt.address {
+				t.Errorf("expected %+v to round-trip, got %+v", tt.address, parsed)
+			}
+		})
+	}
+}
+
+func TestParseAddress_Invalid(t *testing.T) {
+	_, err := ParseAddress("ICAA Events")
+	if !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("expected invalid email error, got %v", err)
+	}
+}
+
+func TestAddressStrings(t *testing.T) {
+	got := AddressStrings(Address{Address: "a@example.com"}, Address{Name: "Bo", Address: "bo@example.com"})
+
+	if want := []string{"a@example.com", `"Bo" <bo@example.com>`}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if AddressStrings() != nil {
+		t.Error("expected nil for no addresses")
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{name: "ascii", address: "Ada <ada@Example.com>", want: "Ada <ada@Example.com>"},
+		{name: "idn", address: "jose@münchen.de", want: "jose@xn--mnchen-3ya.de"},
+		{name: "idn with name", address: "José <jose@münchen.de>", want: "=?utf-8?q?Jos=C3=A9?= <jose@xn--mnchen-3ya.de>"},
+		{name: "utf-8 local part", address: "用户@例え.jp", want: "用户@xn--r8jz45g.jp"},
+		{name: "invalid idn", address: "jose@mün_chen.de", wantErr: true},
+		{name: "invalid address", address: "jose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAddress(tt.address)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidEmail) {
+					t.Fatalf("expected invalid email error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeAddresses(t *testing.T) {
+	e := Email{
+		FromAddress:   "events@münchen.de",
+		ToAddresses:   []string{"ada@example.com", "jose@münchen.de"},
+		BCCAddresses:  []string{"用户@例え.jp"},
+		BounceAddress: "bounces@münchen.de",
+	}
+
+	got, err := NormalizeAddresses(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.FromAddress != "events@xn--mnchen-3ya.de" || got.BounceAddress != "bounces@xn--mnchen-3ya.de" {
+		t.Errorf("unexpected From %s and bounce %s", got.FromAddress, got.BounceAddress)
+	}
+	if want := []string{"ada@example.com", "jose@xn--mnchen-3ya.de"}; !slices.Equal(got.ToAddresses, want) {
+		t.Errorf("expected To %v, got %v", want, got.ToAddresses)
+	}
+	if want := []string{"用户@xn--r8jz45g.jp"}; !slices.Equal(got.BCCAddresses, want) {
+		t.Errorf("expected BCC %v, got %v", want, got.BCCAddresses)
+	}
+	if e.ToAddresses[1] != "jose@münchen.de" {
+		t.Error("expected the original email to be unchanged")
+	}
+
+	e.CCAddresses = []string{"jose@mün_chen.de"}
+	if _, err := NormalizeAddresses(e); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("expected invalid email error, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package email
+
+import "context"
+
+// AddressField names the header an address was given in.
+type AddressField string
+
+const (
+	ADDRESS_FROM         AddressField = "From"
+	ADDRESS_SENDER       AddressField = "Sender"
+	ADDRESS_TO           AddressField = "To"
+	ADDRESS_CC           AddressField = "CC"
+	ADDRESS_BCC          AddressField = "BCC"
+	ADDRESS_REPLY_TO     AddressField = "Reply-To"
+	ADDRESS_BOUNCE       AddressField = "Return-Path"
+	ADDRESS_READ_RECEIPT AddressField = "Disposition-Notification-To"
+)
+
+// Error.Metadata keys identifying the address an AddressRule vetoed.
+const (
+	AddressFieldMetadataKey = "field"
+	AddressMetadataKey      = "address"
+)
+
+// AddressRule is an organization specific check on an address, e.g. that
+// members are on file or that role accounts are not used. It is given the
+// bare address, already checked to be well-formed, and the field it came
+// from. Returning an error vetoes the send; see providersdk.Validator.
+type AddressRule func(ctx context.Context, addr string, field AddressField) error
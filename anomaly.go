@@ -0,0 +1,306 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAnomalyWindow    = time.Hour
+	DefaultAnomalyThreshold = 0.5
+	// Fewer sends than this in the window are too few to judge.
+	DefaultAnomalyMinSamples = 20
+	// Bounds the number of distinct values tracked per field.
+	DefaultAnomalyMaxTracked = 1000
+)
+
+// Number of buckets the window slides by.
+const anomalyBuckets = 12
+
+type AnomalyPolicy string
+
+const (
+	// Report anomalies to the callback and send anyway.
+	ANOMALY_ALERT AnomalyPolicy = "ALERT"
+	// Report anomalies and refuse to send the anomalous emails.
+	ANOMALY_BLOCK AnomalyPolicy = "BLOCK"
+)
+
+// Anomaly reports a value that appeared recently and already makes up a
+// large share of the traffic, e.g. every email suddenly sent from a fallback
+// address.
+type Anomaly struct {
+	// "From" or "Subject".
+	Field string
+	Value string
+	// Share of the sends in the window that had Value.
+	Share     float64
+	FirstSeen time.Time
+}
+
+type AnomalyOption func(*AnomalyGuard)
+
+// WithAnomalyWindow sets how far back the guard looks, and how long a value
+// counts as new after it is first seen. Defaults to DefaultAnomalyWindow.
+func WithAnomalyWindow(d time.Duration) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.window = d
+	}
+}
+
+// WithAnomalyThreshold sets the share of traffic, between 0 and 1, a new
+// value may reach before it is reported. Defaults to DefaultAnomalyThreshold.
+func WithAnomalyThreshold(share float64) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.threshold = share
+	}
+}
+
+func WithAnomalyMinSamples(n int) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.minSamples = n
+	}
+}
+
+func WithAnomalyMaxTracked(n int) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.maxTracked = n
+	}
+}
+
+// WithAnomalyCallback is called for each anomaly, at most once per value and
+// window.
+func WithAnomalyCallback(fn func(Anomaly)) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.callback = fn
+	}
+}
+
+func WithAnomalyPolicy(policy AnomalyPolicy) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.policy = policy
+	}
+}
+
+// WithSubjectTracking watches subjects as well as From addresses.
+func WithSubjectTracking() AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.subjects = &distribution{}
+	}
+}
+
+func WithAnomalyClock(c Clock) AnomalyOption {
+	return func(g *AnomalyGuard) {
+		g.clock = c
+	}
+}
+
+var _ Sender = &AnomalyGuard{}
+var _ SenderV2 = &AnomalyGuard{}
+
+// AnomalyGuard decorates a SenderV2 to catch configuration regressions, such
+// as a deploy that switches every email to a default From address. It
+// reports a value that was first seen within the window and already accounts
+// for more than the threshold of the sends in it. Values that roll out
+// gradually are no longer new by the time they dominate, and are not
+// reported. Nothing is judged until the guard has watched a full window.
+type AnomalyGuard struct {
+	inner      SenderV2
+	window     time.Duration
+	threshold  float64
+	minSamples int
+	maxTracked int
+	callback   func(Anomaly)
+	policy     AnomalyPolicy
+	clock      Clock
+
+	mu       sync.Mutex
+	started  time.Time
+	from     *distribution
+	subjects *distribution
+}
+
+func NewAnomalyGuard(inner SenderV2, opts ...AnomalyOption) *AnomalyGuard {
+	g := &AnomalyGuard{
+		inner:      inner,
+		window:     DefaultAnomalyWindow,
+		threshold:  DefaultAnomalyThreshold,
+		minSamples: DefaultAnomalyMinSamples,
+		maxTracked: DefaultAnomalyMaxTracked,
+		policy:     ANOMALY_ALERT,
+		clock:      SystemClock(),
+		from:       &distribution{},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+func (g *AnomalyGuard) Unwrap() SenderV2 {
+	return g.inner
+}
+
+// Reset forgets all traffic seen so far, e.g. after an intended change of
+// From address. The guard then watches a full window again before judging.
+func (g *AnomalyGuard) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.started = time.Time{}
+	g.from = &distribution{}
+	if g.subjects != nil {
+		g.subjects = &distribution{}
+	}
+}
+
+func (g *AnomalyGuard) SendEmail(ctx context.Context, e Email) error {
+	_, err := g.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (g *AnomalyGuard) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	anomalies, report := g.observe(opts.Apply(e))
+
+	if g.callback != nil {
+		for _, a := range report {
+			g.callback(a)
+		}
+	}
+
+	if len(anomalies) > 0 && g.policy == ANOMALY_BLOCK {
+		a := anomalies[0]
+		return nil, NewMessageRejectedError(fmt.Sprintf("%s %q was first seen at %s and already makes up %.0f%% of sends",
+			a.Field, a.Value, a.FirstSeen.Format(time.RFC3339), a.Share*100), nil)
+	}
+
+	return g.inner.SendEmailV2(ctx, e, opts)
+}
+
+// observe records e and returns the anomalies it is part of, and those of
+// them that have not been reported yet.
+func (g *AnomalyGuard) observe(e Email) (anomalies, report []Anomaly) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	if g.started.IsZero() {
+		g.started = now
+	}
+	judge := now.Sub(g.started) >= g.window
+
+	check := func(field string, d *distribution, value string) {
+		d.record(now, value, g.window, g.maxTracked)
+		if !judge {
+			return
+		}
+		a, ok := d.anomaly(now, value, g.window, g.threshold, g.minSamples)
+		if !ok {
+			return
+		}
+		a.Field = field
+		anomalies = append(anomalies, a)
+
+		if s := d.seen[value]; s.alerted.IsZero() || now.Sub(s.alerted) >= g.window {
+			s.alerted = now
+			report = append(report, a)
+		}
+	}
+
+	check("From", g.from, e.FromAddress)
+	if g.subjects != nil {
+		check("Subject", g.subjects, e.Subject)
+	}
+
+	return anomalies, report
+}
+
+// distribution counts the values of one field over a sliding window.
+type distribution struct {
+	// Oldest first.
+	buckets []countBucket
+	seen    map[string]*seenValue
+}
+
+type countBucket struct {
+	start  time.Time
+	counts map[string]int
+	total  int
+}
+
+type seenValue struct {
+	first, last time.Time
+	alerted     time.Time
+}
+
+func (d *distribution) record(now time.Time, value string, window time.Duration, maxTracked int) {
+	width := window / anomalyBuckets
+	start := now.Truncate(width)
+
+	expired := 0
+	for expired < len(d.buckets) && !d.buckets[expired].start.After(now.Add(-window)) {
+		expired++
+	}
+	d.buckets = d.buckets[expired:]
+
+	if n := len(d.buckets); n == 0 || !d.buckets[n-1].start.Equal(start) {
+		d.buckets = append(d.buckets, countBucket{start: start, counts: map[string]int{}})
+	}
+	b := &d.buckets[len(d.buckets)-1]
+	b.total++
+	// Values beyond the cap still count towards the total, so shares stay
+	// correct for the values that are tracked.
+	if _, ok := b.counts[value]; ok || len(b.counts) < maxTracked {
+		b.counts[value]++
+	}
+
+	if d.seen == nil {
+		d.seen = map[string]*seenValue{}
+	}
+	if s, ok := d.seen[value]; ok {
+		s.last = now
+		return
+	}
+	if len(d.seen) >= maxTracked {
+		d.evictLeastRecent()
+	}
+	d.seen[value] = &seenValue{first: now, last: now}
+}
+
+func (d *distribution) evictLeastRecent() {
+	var oldest string
+	var oldestSeen *seenValue
+	for v, s := range d.seen {
+		if oldestSeen == nil || s.last.Before(oldestSeen.last) {
+			oldest, oldestSeen = v, s
+		}
+	}
+	delete(d.seen, oldest)
+}
+
+func (d *distribution) anomaly(now time.Time, value string, window time.Duration, threshold float64, minSamples int) (Anomaly, bool) {
+	s := d.seen[value]
+	if now.Sub(s.first) >= window {
+		return Anomaly{}, false
+	}
+
+	count, total := 0, 0
+	for _, b := range d.buckets {
+		count += b.counts[value]
+		total += b.total
+	}
+	if total < minSamples {
+		return Anomaly{}, false
+	}
+
+	share := float64(count) / float64(total)
+	if share <= threshold {
+		return Anomaly{}, false
+	}
+
+	return Anomaly{Value: value, Share: share, FirstSeen: s.first}, true
+}
@@ -0,0 +1,136 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+)
+
+type nopSender struct{}
+
+func (nopSender) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	return &email.SendResult{}, nil
+}
+
+func TestAnomalyGuard(t *testing.T) {
+	const (
+		usual    = "events@icaa.example.com"
+		fallback = "noreply@example.com"
+	)
+
+	tests := []struct {
+		name string
+		// Share of the sends from the fallback address in each of several
+		// consecutive windows of traffic.
+		shares            []float64
+		policy            email.AnomalyPolicy
+		expectedAnomalies int
+		expectedBlocked   bool
+	}{
+		{
+			name:              "regression",
+			shares:            []float64{0, 0, 1, 1},
+			policy:            email.ANOMALY_ALERT,
+			expectedAnomalies: 1,
+		},
+		{
+			name:              "regression blocked",
+			shares:            []float64{0, 0, 1, 1},
+			policy:            email.ANOMALY_BLOCK,
+			expectedAnomalies: 1,
+			expectedBlocked:   true,
+		},
+		{
+			name:   "gradual rollout",
+			shares: []float64{0, 0.1, 0.3, 0.5, 0.7, 1},
+			policy: email.ANOMALY_BLOCK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := emailtest.NewFakeClock(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+			var anomalies []email.Anomaly
+			guard := email.NewAnomalyGuard(nopSender{},
+				email.WithAnomalyWindow(time.Hour),
+				email.WithAnomalyPolicy(tt.policy),
+				email.WithAnomalyClock(clock),
+				email.WithAnomalyCallback(func(a email.Anomaly) { anomalies = append(anomalies, a) }),
+			)
+
+			blocked := false
+			for _, share := range tt.shares {
+				// 60 sends per window, one a minute.
+				for i := range 60 {
+					from := usual
+					if float64(i) < share*60 {
+						from = fallback
+					}
+					err := guard.SendEmail(context.Background(), email.Email{FromAddress: from})
+					if errors.Is(err, email.ErrMessageRejected) {
+						blocked = true
+						if from != fallback {
+							t.Fatalf("blocked a send from %s", from)
+						}
+					} else if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					clock.Advance(time.Minute)
+				}
+			}
+
+			if len(anomalies) != tt.expectedAnomalies {
+				t.Fatalf("expected %d anomalies, got %+v", tt.expectedAnomalies, anomalies)
+			}
+			for _, a := range anomalies {
+				if a.Field != "From" || a.Value != fallback || a.Share <= email.DefaultAnomalyThreshold {
+					t.Errorf("unexpected anomaly %+v", a)
+				}
+			}
+			if blocked != tt.expectedBlocked {
+				t.Errorf("expected blocked=%t, got %t", tt.expectedBlocked, blocked)
+			}
+		})
+	}
+}
+
+func TestAnomalyGuard_WarmupAndReset(t *testing.T) {
+	clock := emailtest.NewFakeClock(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	var anomalies []email.Anomaly
+	guard := email.NewAnomalyGuard(nopSender{},
+		email.WithAnomalyClock(clock),
+		email.WithSubjectTracking(),
+		email.WithAnomalyCallback(func(a email.Anomaly) { anomalies = append(anomalies, a) }),
+	)
+
+	send := func(from, subject string, n int) {
+		for range n {
+			if err := guard.SendEmail(context.Background(), email.Email{FromAddress: from, Subject: subject}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			clock.Advance(time.Minute)
+		}
+	}
+
+	// Everything is new at startup, which must not be reported.
+	send("a@example.com", "Weekly results", 90)
+	if len(anomalies) != 0 {
+		t.Fatalf("expected no anomalies while warming up, got %+v", anomalies)
+	}
+
+	send("a@example.com", "Template {{.Title}}", 40)
+	if len(anomalies) != 1 || anomalies[0].Field != "Subject" {
+		t.Fatalf("expected a subject anomaly, got %+v", anomalies)
+	}
+
+	guard.Reset()
+	anomalies = nil
+	send("b@example.com", "Weekly results", 30)
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies after a reset, got %+v", anomalies)
+	}
+}
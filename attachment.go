@@ -0,0 +1,116 @@
+package email
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxAttachmentBytes is the largest content AttachmentFromFile and
+// AttachmentFromReader read unless WithMaxAttachmentBytes is given.
+const DefaultMaxAttachmentBytes = 10 * 1024 * 1024
+
+type attachmentOptions struct {
+	maxBytes int64
+}
+
+type AttachmentOption func(*attachmentOptions)
+
+// WithMaxAttachmentBytes replaces DefaultMaxAttachmentBytes.
+func WithMaxAttachmentBytes(n int64) AttachmentOption {
+	return func(o *attachmentOptions) {
+		o.maxBytes = n
+	}
+}
+
+// AttachmentFromFile reads the file at path into an attachment named after
+// the file. See AttachmentFromReader.
+func AttachmentFromFile(path string, opts ...AttachmentOption) (Attachment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Attachment{}, NewValidationError(fmt.Sprintf("failed to open attachment %s", path), err)
+	}
+	defer f.Close()
+
+	return AttachmentFromReader(filepath.Base(path), f, opts...)
+}
+
+// AttachmentFromReader reads r into an attachment called name. The content
+// type is sniffed from the content, or taken from the extension of name
+// when sniffing only finds generic text or binary data. Content larger than
+// the maximum size is a validation error; empty content is allowed.
+func AttachmentFromReader(name string, r io.Reader, opts ...AttachmentOption) (Attachment, error) {
+	o := attachmentOptions{maxBytes: DefaultMaxAttachmentBytes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, o.maxBytes+1))
+	if err != nil {
+		return Attachment{}, NewValidationError(fmt.Sprintf("failed to read attachment %s", name), err)
+	}
+	if int64(len(content)) > o.maxBytes {
+		return Attachment{}, NewValidationError(fmt.Sprintf("attachment %s is larger than %d bytes", name, o.maxBytes), nil)
+	}
+
+	return Attachment{
+		FileName:    name,
+		Content:     content,
+		ContentType: detectContentType(name, content),
+	}, nil
+}
+
+func detectContentType(name string, content []byte) string {
+	sniffed := "application/octet-stream"
+	if len(content) > 0 {
+		sniffed = http.DetectContentType(content)
+	}
+
+	if sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain") {
+		if byExtension := mime.TypeByExtension(filepath.Ext(name)); byExtension != "" {
+			return byExtension
+		}
+	}
+	return sniffed
+}
+
+// Hash returns the hex encoded SHA-256 of a's content, e.g. to key caches of
+// attachments by content.
+func (a Attachment) Hash() string {
+	sum := sha256.Sum256(a.Content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DedupeAttachments removes attachments with the same file name and content
+// as an earlier one, keeping the first. Inline attachments with different
+// content IDs, and unresolved ones with different Refs, are kept, as they
+// are referenced separately. The attachments slice is replaced rather than
+// modified, so emails sharing it are unaffected.
+func (e *Email) DedupeAttachments() {
+	type key struct{ fileName, hash, contentID, ref string }
+
+	seen := make(map[key]bool, len(e.Attachments))
+	var kept []Attachment
+	for i, a := range e.Attachments {
+		k := key{a.FileName, a.Hash(), a.ContentID, a.Ref}
+		if !seen[k] {
+			seen[k] = true
+			if kept != nil {
+				kept = append(kept, a)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = append(make([]Attachment, 0, len(e.Attachments)-1), e.Attachments[:i]...)
+		}
+	}
+	if kept != nil {
+		e.Attachments = kept
+	}
+}
@@ -0,0 +1,176 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAttachmentFromReader(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name        string
+		fileName    string
+		content     []byte
+		contentType string
+	}{
+		{"sniffed", "logo", png, "image/png"},
+		{"sniffed over extension", "logo.pdf", png, "image/png"},
+		{"pdf", "schedule.pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"text by extension", "styles.css", []byte("p { color: red }\n"), "text/css; charset=utf-8"},
+		{"plain text", "notes", []byte("hello"), "text/plain; charset=utf-8"},
+		{"binary by extension", "photo.webp", []byte{0x00, 0x01, 0x02}, "image/webp"},
+		{"unknown binary", "data", []byte{0x00, 0x01, 0x02}, "application/octet-stream"},
+		{"empty", "empty.json", []byte{}, "application/json"},
+		{"empty unknown", "empty", []byte{}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := AttachmentFromReader(tt.fileName, bytes.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if a.FileName != tt.fileName || !bytes.Equal(a.Content, tt.content) {
+				t.Errorf("unexpected attachment %+v", a)
+			}
+			if a.ContentType != tt.contentType {
+				t.Errorf("expected content type %s, got %s", tt.contentType, a.ContentType)
+			}
+		})
+	}
+}
+
+func TestAttachmentFromReader_MaxBytes(t *testing.T) {
+	if _, err := AttachmentFromReader("a.bin", strings.NewReader("1234"), WithMaxAttachmentBytes(4)); err != nil {
+		t.Fatalf("expected content at the limit to be read, got %v", err)
+	}
+
+	_, err := AttachmentFromReader("a.bin", strings.NewReader("12345"), WithMaxAttachmentBytes(4))
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestAttachmentFromReader_ReadError(t *testing.T) {
+	_, err := AttachmentFromReader("a.bin", iotest.ErrReader(errors.New("connection reset")))
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestAttachmentFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.7\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := AttachmentFromFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.FileName != "schedule.pdf" || a.ContentType != "application/pdf" {
+		t.Errorf("unexpected attachment %+v", a)
+	}
+
+	if _, err := AttachmentFromFile(filepath.Join(t.TempDir(), "missing.pdf")); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error for a missing file, got %v", err)
+	}
+}
+
+func TestAttachment_Hash(t *testing.T) {
+	a := Attachment{FileName: "rules.pdf", Content: []byte("%PDF-1.7\n")}
+	b := Attachment{FileName: "copy.pdf", Content: []byte("%PDF-1.7\n"), ContentType: "application/pdf"}
+
+	if a.Hash() != b.Hash() {
+		t.Error("expected the same content to hash the same regardless of name")
+	}
+	if a.Hash() == (Attachment{Content: []byte("%PDF-1.6\n")}).Hash() {
+		t.Error("expected different content to hash differently")
+	}
+	if want := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"; (Attachment{}).Hash() != want {
+		t.Errorf("expected the SHA-256 of no content, got %s", (Attachment{}).Hash())
+	}
+}
+
+func TestEmail_DedupeAttachments(t *testing.T) {
+	rules := []byte("%PDF-1.7 rules")
+	schedule := []byte("%PDF-1.7 schedule")
+	logo := []byte("\x89PNG logo")
+
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		expected    []string
+	}{
+		{"none", nil, nil},
+		{"no duplicates", []Attachment{
+			{FileName: "rules.pdf", Content: rules},
+			{FileName: "schedule.pdf", Content: schedule},
+		}, []string{"rules.pdf", "schedule.pdf"}},
+		{"same content and name", []Attachment{
+			{FileName: "rules.pdf", Content: rules, Description: "first"},
+			{FileName: "schedule.pdf", Content: schedule},
+			{FileName: "rules.pdf", Content: rules, Description: "second"},
+			{FileName: "rules.pdf", Content: slices.Clone(rules), Description: "third"},
+		}, []string{"rules.pdf:first", "schedule.pdf"}},
+		{"same content, different name", []Attachment{
+			{FileName: "rules.pdf", Content: rules},
+			{FileName: "rules-2026.pdf", Content: rules},
+		}, []string{"rules.pdf", "rules-2026.pdf"}},
+		{"different content, same name", []Attachment{
+			{FileName: "rules.pdf", Content: rules},
+			{FileName: "rules.pdf", Content: schedule},
+		}, []string{"rules.pdf", "rules.pdf"}},
+		{"different content IDs", []Attachment{
+			{FileName: "logo.png", Content: logo, ContentID: "header"},
+			{FileName: "logo.png", Content: logo, ContentID: "footer"},
+			{FileName: "logo.png", Content: logo, ContentID: "header"},
+		}, []string{"logo.png", "logo.png"}},
+		{"different refs", []Attachment{
+			{FileName: "report.pdf", Ref: "s3://reports/a.pdf"},
+			{FileName: "report.pdf", Ref: "s3://reports/b.pdf"},
+			{FileName: "report.pdf", Ref: "s3://reports/a.pdf"},
+		}, []string{"report.pdf", "report.pdf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Email{Attachments: tt.attachments}
+			e.DedupeAttachments()
+
+			var got []string
+			for _, a := range e.Attachments {
+				name := a.FileName
+				if a.Description != "" {
+					name += ":" + a.Description
+				}
+				got = append(got, name)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("shared slice untouched", func(t *testing.T) {
+		shared := []Attachment{
+			{FileName: "rules.pdf", Content: rules},
+			{FileName: "rules.pdf", Content: rules},
+			{FileName: "schedule.pdf", Content: schedule},
+		}
+		a, b := Email{Attachments: shared}, Email{Attachments: shared}
+		a.DedupeAttachments()
+
+		if len(a.Attachments) != 2 || len(b.Attachments) != 3 || shared[1].FileName != "rules.pdf" || shared[2].FileName != "schedule.pdf" {
+			t.Errorf("expected the shared slice to be left alone, got %v", shared)
+		}
+	})
+}
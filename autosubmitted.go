@@ -0,0 +1,43 @@
+package email
+
+import "fmt"
+
+// AutoSubmitted marks an email as sent by a program rather than a person,
+// per RFC 3834, so well-behaved autoresponders don't reply to it. The zero
+// value sends no Auto-Submitted header.
+type AutoSubmitted string
+
+const (
+	// Sent on the program's own initiative, e.g. a registration
+	// confirmation.
+	AUTO_SUBMITTED_GENERATED AutoSubmitted = "auto-generated"
+	// Sent in automatic response to another email.
+	AUTO_SUBMITTED_REPLIED AutoSubmitted = "auto-replied"
+)
+
+const (
+	AutoSubmittedHeader = "Auto-Submitted"
+	// Exchange's equivalent of Auto-Submitted. See
+	// Email.SuppressAutoResponses.
+	AutoResponseSuppressHeader = "X-Auto-Response-Suppress"
+)
+
+func ValidateAutoSubmitted(a AutoSubmitted) error {
+	if a != "" && a != AUTO_SUBMITTED_GENERATED && a != AUTO_SUBMITTED_REPLIED {
+		return NewValidationError(fmt.Sprintf("unknown auto-submitted value %q", a), nil)
+	}
+	return nil
+}
+
+// AutoResponseHeaders returns the Auto-Submitted and
+// X-Auto-Response-Suppress headers e is sent with, if any.
+func (e Email) AutoResponseHeaders() []Header {
+	var headers []Header
+	if e.AutoSubmitted != "" {
+		headers = append(headers, Header{AutoSubmittedHeader, string(e.AutoSubmitted)})
+	}
+	if e.SuppressAutoResponses {
+		headers = append(headers, Header{AutoResponseSuppressHeader, "All"})
+	}
+	return headers
+}
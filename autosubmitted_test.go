@@ -0,0 +1,43 @@
+package email
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutoResponseHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    Email
+		expected []Header
+	}{
+		{"none", Email{}, nil},
+		{"auto-generated", Email{AutoSubmitted: AUTO_SUBMITTED_GENERATED}, []Header{{"Auto-Submitted", "auto-generated"}}},
+		{"suppressed", Email{AutoSubmitted: AUTO_SUBMITTED_REPLIED, SuppressAutoResponses: true}, []Header{
+			{"Auto-Submitted", "auto-replied"},
+			{"X-Auto-Response-Suppress", "All"},
+		}},
+		{"suppressed only", Email{SuppressAutoResponses: true}, []Header{{"X-Auto-Response-Suppress", "All"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.email.AutoResponseHeaders(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateAutoSubmitted(t *testing.T) {
+	for _, a := range []AutoSubmitted{"", AUTO_SUBMITTED_GENERATED, AUTO_SUBMITTED_REPLIED} {
+		if err := ValidateAutoSubmitted(a); err != nil {
+			t.Errorf("%q: unexpected error: %v", a, err)
+		}
+	}
+	for _, a := range []AutoSubmitted{"no", "AUTO-GENERATED", "auto-notified"} {
+		if err := ValidateAutoSubmitted(a); err == nil {
+			t.Errorf("%q: expected an error", a)
+		}
+	}
+}
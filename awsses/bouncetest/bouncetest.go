@@ -0,0 +1,236 @@
+// Package bouncetest fabricates SES bounce notifications and matching
+// delivery status notifications for testing bounce processing without
+// real bounces.
+package bouncetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+type BounceType string
+
+const (
+	// A hard bounce; SES adds the recipient to the suppression list.
+	BOUNCE_PERMANENT BounceType = "Permanent"
+	// A soft bounce, such as a full mailbox.
+	BOUNCE_TRANSIENT BounceType = "Transient"
+)
+
+const reportingMTA = "a8-70.smtp-out.amazonses.com"
+
+// Bounce describes a simulated bounce of a sent email.
+type Bounce struct {
+	Type BounceType
+	// Recipients that bounced. Defaults to every To, CC and BCC address.
+	Recipients []string
+	// SES message ID of the original send, e.g. SendResult.ProviderMessageID.
+	MessageID string
+	Timestamp time.Time
+}
+
+// Notification is the SES bounce event as delivered through SNS.
+type Notification struct {
+	NotificationType string     `json:"notificationType"`
+	Bounce           BounceInfo `json:"bounce"`
+	Mail             MailInfo   `json:"mail"`
+}
+
+type BounceInfo struct {
+	BounceType        string             `json:"bounceType"`
+	BounceSubType     string             `json:"bounceSubType"`
+	BouncedRecipients []BouncedRecipient `json:"bouncedRecipients"`
+	Timestamp         string             `json:"timestamp"`
+	FeedbackID        string             `json:"feedbackId"`
+	ReportingMTA      string             `json:"reportingMTA"`
+}
+
+type BouncedRecipient struct {
+	EmailAddress   string `json:"emailAddress"`
+	Action         string `json:"action"`
+	Status         string `json:"status"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+type MailInfo struct {
+	Timestamp        string        `json:"timestamp"`
+	Source           string        `json:"source"`
+	MessageID        string        `json:"messageId"`
+	Destination      []string      `json:"destination"`
+	HeadersTruncated bool          `json:"headersTruncated"`
+	CommonHeaders    CommonHeaders `json:"commonHeaders"`
+}
+
+type CommonHeaders struct {
+	From    []string `json:"from"`
+	To      []string `json:"to,omitempty"`
+	Subject string   `json:"subject"`
+}
+
+type recipientStatus struct {
+	subType    string
+	action     string
+	status     string
+	diagnostic string
+	// Of the DSN, and its human readable part.
+	subject     string
+	explanation string
+}
+
+var statuses = map[BounceType]recipientStatus{
+	BOUNCE_PERMANENT: {
+		subType:     "General",
+		action:      "failed",
+		status:      "5.1.1",
+		diagnostic:  "smtp; 550 5.1.1 user unknown",
+		subject:     "Delivery Status Notification (Failure)",
+		explanation: "An error occurred while trying to deliver the mail to the following recipients:",
+	},
+	BOUNCE_TRANSIENT: {
+		subType:     "MailboxFull",
+		action:      "delayed",
+		status:      "4.2.2",
+		diagnostic:  "smtp; 452 4.2.2 mailbox full",
+		subject:     "Delivery Status Notification (Delay)",
+		explanation: "Delivery to the following recipients has been delayed. Delivery will be retried:",
+	},
+}
+
+func (b Bounce) recipients(e email.Email) []string {
+	if len(b.Recipients) > 0 {
+		return b.Recipients
+	}
+
+	var all []string
+	all = append(all, e.ToAddresses...)
+	all = append(all, e.CCAddresses...)
+	all = append(all, e.BCCAddresses...)
+	return all
+}
+
+// NewNotification builds the SES bounce notification for e.
+func NewNotification(e email.Email, b Bounce) (Notification, error) {
+	status, ok := statuses[b.Type]
+	if !ok {
+		return Notification{}, fmt.Errorf("unknown bounce type %q", b.Type)
+	}
+
+	var recipients []BouncedRecipient
+	for _, addr := range b.recipients(e) {
+		recipients = append(recipients, BouncedRecipient{
+			EmailAddress:   addr,
+			Action:         status.action,
+			Status:         status.status,
+			DiagnosticCode: status.diagnostic,
+		})
+	}
+
+	var destination []string
+	destination = append(destination, e.ToAddresses...)
+	destination = append(destination, e.CCAddresses...)
+	destination = append(destination, e.BCCAddresses...)
+
+	timestamp := b.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z")
+
+	return Notification{
+		NotificationType: "Bounce",
+		Bounce: BounceInfo{
+			BounceType:        string(b.Type),
+			BounceSubType:     status.subType,
+			BouncedRecipients: recipients,
+			Timestamp:         timestamp,
+			FeedbackID:        fmt.Sprintf("%s-bounce", b.MessageID),
+			ReportingMTA:      "dsn; " + reportingMTA,
+		},
+		Mail: MailInfo{
+			Timestamp:   timestamp,
+			Source:      e.FromAddress,
+			MessageID:   b.MessageID,
+			Destination: destination,
+			CommonHeaders: CommonHeaders{
+				From:    []string{e.FromAddress},
+				To:      e.ToAddresses,
+				Subject: e.Subject,
+			},
+		},
+	}, nil
+}
+
+// NotificationJSON returns the SES bounce notification for e as JSON.
+func NotificationJSON(e email.Email, b Bounce) ([]byte, error) {
+	n, err := NewNotification(e, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(n)
+}
+
+// DSN builds the multipart/report delivery status notification (RFC 3464)
+// that the reporting MTA would return to the sender of e.
+func DSN(e email.Email, b Bounce) ([]byte, error) {
+	status, ok := statuses[b.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown bounce type %q", b.Type)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	human, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(human, "%s\r\n%s\r\n", status.explanation, strings.Join(b.recipients(e), "\r\n"))
+
+	report, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"message/delivery-status"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(report, "Reporting-MTA: dns; %s\r\n", reportingMTA)
+	fmt.Fprintf(report, "Arrival-Date: %s\r\n", b.Timestamp.Format(time.RFC1123Z))
+	for _, addr := range b.recipients(e) {
+		fmt.Fprintf(report, "\r\nFinal-Recipient: rfc822; %s\r\n", addr)
+		fmt.Fprintf(report, "Action: %s\r\n", status.action)
+		fmt.Fprintf(report, "Status: %s\r\n", status.status)
+		fmt.Fprintf(report, "Diagnostic-Code: %s\r\n", status.diagnostic)
+	}
+
+	headers, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/rfc822-headers"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(headers, "From: %s\r\n", e.FromAddress)
+	fmt.Fprintf(headers, "To: %s\r\n", strings.Join(e.ToAddresses, ", "))
+	fmt.Fprintf(headers, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Subject))
+	fmt.Fprintf(headers, "Message-ID: <%s@email.amazonses.com>\r\n", b.MessageID)
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: MAILER-DAEMON@%s\r\n", reportingMTA)
+	fmt.Fprintf(&msg, "To: %s\r\n", e.FromAddress)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", status.subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", b.Timestamp.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/report; report-type=delivery-status; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,195 @@
+package bouncetest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+var original = email.Email{
+	FromAddress:  "events@icaa.org",
+	ToAddresses:  []string{"archer@example.com"},
+	BCCAddresses: []string{"captain@example.com"},
+	Subject:      "Tournament check-in",
+	TextBody:     "See you there",
+}
+
+func TestNotificationJSON(t *testing.T) {
+	tests := []struct {
+		name            string
+		bounce          Bounce
+		expectedType    string
+		expectedSubType string
+		expectedStatus  string
+		expectedCount   int
+	}{
+		{
+			name: "hard bounce for every recipient",
+			bounce: Bounce{
+				Type:      BOUNCE_PERMANENT,
+				MessageID: "0100018c-msg",
+				Timestamp: time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC),
+			},
+			expectedType:    "Permanent",
+			expectedSubType: "General",
+			expectedStatus:  "5.1.1",
+			expectedCount:   2,
+		},
+		{
+			name: "soft bounce for one recipient",
+			bounce: Bounce{
+				Type:       BOUNCE_TRANSIENT,
+				Recipients: []string{"archer@example.com"},
+				MessageID:  "0100018c-msg",
+				Timestamp:  time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC),
+			},
+			expectedType:    "Transient",
+			expectedSubType: "MailboxFull",
+			expectedStatus:  "4.2.2",
+			expectedCount:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := NotificationJSON(original, tt.bounce)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Decode generically so the test checks the wire field names
+			// rather than round-tripping through our own struct tags.
+			var n map[string]any
+			if err := json.Unmarshal(raw, &n); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+
+			if n["notificationType"] != "Bounce" {
+				t.Errorf("expected notificationType Bounce, got %v", n["notificationType"])
+			}
+
+			bounce := n["bounce"].(map[string]any)
+			if bounce["bounceType"] != tt.expectedType || bounce["bounceSubType"] != tt.expectedSubType {
+				t.Errorf("unexpected bounce type %v/%v", bounce["bounceType"], bounce["bounceSubType"])
+			}
+			if bounce["timestamp"] != "2030-03-04T18:30:00.000Z" {
+				t.Errorf("unexpected timestamp %v", bounce["timestamp"])
+			}
+
+			recipients := bounce["bouncedRecipients"].([]any)
+			if len(recipients) != tt.expectedCount {
+				t.Fatalf("expected %d bounced recipients, got %d", tt.expectedCount, len(recipients))
+			}
+			first := recipients[0].(map[string]any)
+			if first["emailAddress"] != "archer@example.com" || first["status"] != tt.expectedStatus {
+				t.Errorf("unexpected bounced recipient %v", first)
+			}
+
+			mailInfo := n["mail"].(map[string]any)
+			if mailInfo["messageId"] != "0100018c-msg" || mailInfo["source"] != "events@icaa.org" {
+				t.Errorf("unexpected mail object %v", mailInfo)
+			}
+			if len(mailInfo["destination"].([]any)) != 2 {
+				t.Errorf("expected BCC recipients in destination, got %v", mailInfo["destination"])
+			}
+		})
+	}
+}
+
+func TestNotificationJSON_UnknownType(t *testing.T) {
+	if _, err := NotificationJSON(original, Bounce{Type: "Undetermined"}); err == nil {
+		t.Error("expected error for unknown bounce type")
+	}
+}
+
+func TestDSN(t *testing.T) {
+	subjects := map[BounceType]string{
+		BOUNCE_PERMANENT: "Delivery Status Notification (Failure)",
+		BOUNCE_TRANSIENT: "Delivery Status Notification (Delay)",
+	}
+
+	for _, bounceType := range []BounceType{BOUNCE_PERMANENT, BOUNCE_TRANSIENT} {
+		t.Run(string(bounceType), func(t *testing.T) {
+			raw, err := DSN(original, Bounce{
+				Type:      bounceType,
+				MessageID: "0100018c-msg",
+				Timestamp: time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("failed to parse DSN: %v", err)
+			}
+
+			if subject := msg.Header.Get("Subject"); subject != subjects[bounceType] {
+				t.Errorf("expected subject %q, got %q", subjects[bounceType], subject)
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("invalid Content-Type: %v", err)
+			}
+			if mediaType != "multipart/report" || params["report-type"] != "delivery-status" {
+				t.Fatalf("unexpected Content-Type %s %v", mediaType, params)
+			}
+
+			var partTypes []string
+			var report []byte
+			mr := multipart.NewReader(msg.Body, params["boundary"])
+			for {
+				part, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("invalid multipart body: %v", err)
+				}
+				partTypes = append(partTypes, part.Header.Get("Content-Type"))
+				if part.Header.Get("Content-Type") == "message/delivery-status" {
+					report, _ = io.ReadAll(part)
+				}
+			}
+
+			if len(partTypes) != 3 || partTypes[1] != "message/delivery-status" || partTypes[2] != "text/rfc822-headers" {
+				t.Fatalf("unexpected report parts %v", partTypes)
+			}
+
+			// The per-message fields and each per-recipient block are header
+			// blocks separated by blank lines.
+			tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(report)))
+			perMessage, err := tp.ReadMIMEHeader()
+			if err != nil {
+				t.Fatalf("invalid per-message fields: %v", err)
+			}
+			if perMessage.Get("Reporting-MTA") == "" {
+				t.Error("expected Reporting-MTA")
+			}
+
+			expected := statuses[bounceType]
+			for _, addr := range []string{"archer@example.com", "captain@example.com"} {
+				recipient, err := tp.ReadMIMEHeader()
+				if err != nil && err != io.EOF {
+					t.Fatalf("invalid per-recipient fields: %v", err)
+				}
+				if recipient.Get("Final-Recipient") != "rfc822; "+addr {
+					t.Errorf("expected Final-Recipient for %s, got %q", addr, recipient.Get("Final-Recipient"))
+				}
+				if recipient.Get("Status") != expected.status || recipient.Get("Action") != expected.action {
+					t.Errorf("unexpected status %q action %q", recipient.Get("Status"), recipient.Get("Action"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package awsses
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk/providertest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunSenderConformance(t, newConformanceHarness)
+}
+
+func TestConformanceV2(t *testing.T) {
+	providertest.RunSenderV2Conformance(t, newConformanceHarness)
+}
+
+func newConformanceHarness(t *testing.T) providertest.Harness {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{MessageId: aws.String("conformance-message-id")}, nil
+		},
+	}
+
+	return providertest.Harness{
+		Sender: NewAWSSESSender(client),
+		Observe: func() (providertest.Observation, bool) {
+			if input == nil {
+				return providertest.Observation{}, false
+			}
+			return observeInput(t, input), true
+		},
+		BCC:       providertest.BCC_ENVELOPE,
+		MessageID: "conformance-message-id",
+	}
+}
+
+func observeInput(t *testing.T, input *sesv2.SendEmailInput) providertest.Observation {
+	t.Helper()
+
+	if input.Content.Raw != nil {
+		// Raw messages carry no recipients; SES takes them from the
+		// destination.
+		obs := providertest.ObserveRawMessage(t, string(input.Content.Raw.Data))
+		obs.To = input.Destination.ToAddresses
+		obs.CC = input.Destination.CcAddresses
+		obs.BCC = input.Destination.BccAddresses
+		return obs
+	}
+
+	simple := input.Content.Simple
+	content, err := json.Marshal(input.Content)
+	if err != nil {
+		t.Fatalf("failed to serialize content: %v", err)
+	}
+
+	obs := providertest.Observation{
+		To:      input.Destination.ToAddresses,
+		CC:      input.Destination.CcAddresses,
+		BCC:     input.Destination.BccAddresses,
+		Subject: *simple.Subject.Data,
+		HasHTML: simple.Body.Html != nil,
+		HasText: simple.Body.Text != nil,
+		Message: string(content),
+	}
+
+	for _, h := range simple.Headers {
+		switch *h.Name {
+		case email.CampaignIDHeader:
+			obs.CampaignID = *h.Value
+		case email.SequenceStepHeader:
+			obs.SequenceStep = *h.Value
+		}
+	}
+
+	if simple.Attachments != nil {
+		obs.Attachments = make([]string, len(simple.Attachments))
+		for i, a := range simple.Attachments {
+			obs.Attachments[i] = *a.FileName
+		}
+	}
+
+	return obs
+}
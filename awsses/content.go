@@ -0,0 +1,88 @@
+package awsses
+
+import (
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// Values of SendResult.ContentMode: whether SES was given the parts of the
+// message (Simple content) or the message built by providersdk.BuildMessage
+// (Raw content).
+const (
+	ContentModeSimple = "simple"
+	ContentModeRaw    = "raw"
+)
+
+// Longest header name and value the Simple content accepts.
+const (
+	maxSimpleHeaderName  = 126
+	maxSimpleHeaderValue = 870
+)
+
+type feature struct {
+	name    string
+	present func(e email.Email) bool
+	// Whether the Simple content carries the feature the way the raw
+	// message does.
+	simple bool
+}
+
+// features lists everything an email can use beyond addresses, subject and
+// bodies. Emails using a feature Simple content can't carry are sent raw.
+var features = []feature{
+	{"attachments", func(e email.Email) bool { return len(e.Attachments) > 0 }, true},
+	{"inline attachments", func(e email.Email) bool { return hasInlineAttachments(e) && e.HTMLBody != "" }, true},
+	// The raw message attaches them as regular attachments instead.
+	{"inline attachments without an HTML body", func(e email.Email) bool { return hasInlineAttachments(e) && e.HTMLBody == "" }, false},
+	{"expiry", func(e email.Email) bool { return !e.Expires.IsZero() }, true},
+	{"campaign", func(e email.Email) bool { return e.CampaignID != "" }, true},
+	{"fallback", func(e email.Email) bool { return e.FallbackFor != "" }, true},
+	// Simple content has no Sender header.
+	{"sender", func(e email.Email) bool { return e.SenderHeaderAddress() != "" }, false},
+	{"message ID", func(e email.Email) bool { return e.MessageID != "" }, true},
+	// SES transcodes the Simple content into Content.Charset.
+	{"charset", hasCharset, true},
+	// SES stamps the Date of Simple content itself.
+	{"date", func(e email.Email) bool { return !e.Date.IsZero() }, false},
+	// Forwarded feedback goes to it either way; SES sets the envelope sender.
+	{"bounce address", func(e email.Email) bool { return e.BounceAddress != "" }, true},
+	{"custom headers", func(e email.Email) bool { return len(e.Headers) > 0 }, true},
+	{"unsubscribe", func(e email.Email) bool { return e.Unsubscribe != (email.Unsubscribe{}) }, true},
+	{"list", func(e email.Email) bool { return e.ListID != "" }, true},
+	{"priority", func(e email.Email) bool { return e.Priority != "" }, true},
+	{"auto-submitted", func(e email.Email) bool { return len(e.AutoResponseHeaders()) > 0 }, true},
+	{"tags", func(e email.Email) bool { return len(e.Tags) > 0 }, true},
+	{"long headers", hasLongHeaders, false},
+}
+
+// requiresRaw reports whether e uses a feature Simple content can't carry.
+func requiresRaw(e email.Email) bool {
+	for _, f := range features {
+		if !f.simple && f.present(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasInlineAttachments(e email.Email) bool {
+	for _, a := range e.Attachments {
+		if a.ContentID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLongHeaders(e email.Email) bool {
+	for _, h := range headersFromEmail(e) {
+		if len(*h.Name) > maxSimpleHeaderName || len(*h.Value) > maxSimpleHeaderValue {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCharset(e email.Email) bool {
+	c, err := email.LookupCharset(e.Charset)
+	return err == nil && !c.IsUTF8()
+}
@@ -0,0 +1,216 @@
+package awsses
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest/fixtures"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+// toggle turns on a feature and names the header it must produce, if any.
+type toggle struct {
+	name   string
+	apply  func(e *email.Email)
+	header string
+}
+
+var toggles = []toggle{
+	{"expiry", func(e *email.Email) { e.Expires = time.Now().Add(24 * time.Hour) }, "Expiry-Date"},
+	{"campaign", func(e *email.Email) { e.CampaignID = "spring" }, email.CampaignIDHeader},
+	{"fallback", func(e *email.Email) { e.FallbackFor = "old@example.com" }, email.DeliveryFallbackHeader},
+	{"message ID", func(e *email.Email) { e.MessageID = "<matrix@icaa.example.com>" }, "Message-ID"},
+	{"bounce address", func(e *email.Email) { e.BounceAddress = "bounces@icaa.example.com" }, ""},
+	{"custom headers", func(e *email.Email) {
+		e.Headers = append(e.Headers[:len(e.Headers):len(e.Headers)], email.Header{Name: "X-Team", Value: "north"})
+	}, "X-Team"},
+	{"unsubscribe", func(e *email.Email) { e.Unsubscribe = email.OneClickUnsubscribe("https://icaa.example.com/u/1") }, email.ListUnsubscribeHeader},
+	{"priority", func(e *email.Email) { e.Priority = email.PRIORITY_HIGH }, "X-Priority"},
+	{"tags", func(e *email.Email) { e.Tags = map[string]string{"env": "prod"} }, ""},
+	{"long headers", func(e *email.Email) {
+		e.Headers = append(e.Headers[:len(e.Headers):len(e.Headers)], email.Header{Name: "X-Long", Value: strings.Repeat("word ", 200)})
+	}, "X-Long"},
+	{"text only", func(e *email.Email) {
+		if e.TextBody != "" {
+			e.HTMLBody = ""
+		}
+	}, ""},
+}
+
+// targeted are toggles for a single feature whose content mode the
+// features list decides alone. Each is checked on its own and with all the
+// others, but not paired with every toggle.
+var targeted = []toggle{
+	{"auto-submitted", func(e *email.Email) { e.AutoSubmitted = email.AUTO_SUBMITTED_GENERATED }, email.AutoSubmittedHeader},
+	{"date", func(e *email.Email) { e.Date = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC) }, "Date"},
+	{"sender", func(e *email.Email) { e.SenderAddress = "Director <director@icaa.example.com>" }, "Sender"},
+	// Covers all of Unicode, so every fixture can be sent in it.
+	{"charset", func(e *email.Email) { e.Charset = "GB18030" }, ""},
+	{"list", func(e *email.Email) { e.ListID = "digest.icaa.example.com" }, email.ListIDHeader},
+}
+
+// handPicked are combinations of toggles worth checking beyond pairs.
+var handPicked = [][]string{
+	{"charset", "long headers", "custom headers"},
+	{"text only", "unsubscribe", "list", "priority"},
+	{"fallback", "bounce address", "tags", "message ID", "date"},
+}
+
+// combinations returns the sets of toggles to send each fixture with: none,
+// each on its own, every pair of toggles, the hand picked ones and all of
+// them, targeted ones included.
+func combinations(t *testing.T) [][]toggle {
+	sets := [][]toggle{nil}
+	for i, a := range toggles {
+		sets = append(sets, []toggle{a})
+		for _, b := range toggles[i+1:] {
+			sets = append(sets, []toggle{a, b})
+		}
+	}
+
+	for _, tg := range targeted {
+		sets = append(sets, []toggle{tg})
+	}
+
+	all := append(slices.Clone(toggles), targeted...)
+	byName := map[string]toggle{}
+	for _, tg := range all {
+		byName[tg.name] = tg
+	}
+	for _, names := range handPicked {
+		var set []toggle
+		for _, name := range names {
+			tg, ok := byName[name]
+			if !ok {
+				t.Fatalf("unknown toggle %q", name)
+			}
+			set = append(set, tg)
+		}
+		sets = append(sets, set)
+	}
+
+	return append(sets, all)
+}
+
+// TestContentMode_FeatureMatrix sends every fixture with combinations of
+// toggles and checks that the chosen content mode carries every feature the
+// email uses.
+func TestContentMode_FeatureMatrix(t *testing.T) {
+	seen := map[string]bool{}
+	sets := combinations(t)
+
+	for _, f := range fixtures.All() {
+		if f.Golden == nil {
+			// Too large to send hundreds of times.
+			continue
+		}
+
+		for _, on := range sets {
+			e := f.Email
+			for _, tg := range on {
+				tg.apply(&e)
+			}
+			for _, feat := range features {
+				if feat.present(e) {
+					seen[feat.name] = true
+				}
+			}
+
+			var input *sesv2.SendEmailInput
+			client := &mockSESClient{
+				sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+					input = params
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}
+			result, err := NewAWSSESSender(client).SendEmailV2(context.Background(), e, nil)
+			if err != nil {
+				t.Fatalf("%s with %v: unexpected error: %v", f.Name, toggleNames(on), err)
+			}
+
+			if err := checkContent(e, on, input, result); err != nil {
+				t.Errorf("%s with %v: %v", f.Name, toggleNames(on), err)
+			}
+		}
+	}
+
+	for _, feat := range features {
+		if !seen[feat.name] {
+			t.Errorf("feature %q is not exercised by any combination", feat.name)
+		}
+	}
+}
+
+func checkContent(e email.Email, on []toggle, input *sesv2.SendEmailInput, result *email.SendResult) error {
+	if e.BounceAddress != "" && aws.ToString(input.FeedbackForwardingEmailAddress) != e.BounceAddress {
+		return fmt.Errorf("bounce address not forwarded")
+	}
+	if len(e.Tags) > 0 && len(input.EmailTags) == 0 {
+		return fmt.Errorf("tags not sent")
+	}
+
+	headers := map[string]string{}
+	switch {
+	case input.Content.Raw != nil:
+		if result.ContentMode != ContentModeRaw {
+			return fmt.Errorf("sent raw content, reported %q", result.ContentMode)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+		if err != nil {
+			return fmt.Errorf("raw message does not parse: %w", err)
+		}
+		if msg.Header.Get("Bcc") != "" {
+			return fmt.Errorf("raw message reveals BCC recipients")
+		}
+		for name := range msg.Header {
+			headers[name] = msg.Header.Get(name)
+		}
+	case input.Content.Simple != nil:
+		if result.ContentMode != ContentModeSimple {
+			return fmt.Errorf("sent simple content, reported %q", result.ContentMode)
+		}
+		for _, h := range input.Content.Simple.Headers {
+			if len(*h.Value) > maxSimpleHeaderValue {
+				return fmt.Errorf("header %s is too long for simple content", *h.Name)
+			}
+			headers[textproto.CanonicalMIMEHeaderKey(*h.Name)] = *h.Value
+		}
+		if e.Charset != "" && aws.ToString(input.Content.Simple.Subject.Charset) != e.Charset {
+			return fmt.Errorf("subject sent in %s", aws.ToString(input.Content.Simple.Subject.Charset))
+		}
+		for _, a := range input.Content.Simple.Attachments {
+			if a.ContentId != nil && e.HTMLBody == "" {
+				return fmt.Errorf("inline attachment %s without an HTML body", *a.FileName)
+			}
+		}
+	default:
+		return fmt.Errorf("no content")
+	}
+
+	if raw := input.Content.Raw != nil; raw != requiresRaw(e) {
+		return fmt.Errorf("expected raw=%v", requiresRaw(e))
+	}
+	for _, tg := range on {
+		if tg.header != "" && headers[textproto.CanonicalMIMEHeaderKey(tg.header)] == "" {
+			return fmt.Errorf("missing %s header", tg.header)
+		}
+	}
+	return nil
+}
+
+func toggleNames(on []toggle) []string {
+	names := make([]string, len(on))
+	for i, tg := range on {
+		names[i] = tg.name
+	}
+	return names
+}
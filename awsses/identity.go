@@ -0,0 +1,52 @@
+package awsses
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+type SESIdentityClient interface {
+	GetEmailIdentity(ctx context.Context, params *sesv2.GetEmailIdentityInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailIdentityOutput, error)
+}
+
+var _ email.IdentityVerifier = &IdentityVerifier{}
+
+// IdentityVerifier reports whether SES may send from an address, for use
+// with email.NewIdentityGate. An address can send once either the address
+// itself or its domain is verified.
+type IdentityVerifier struct {
+	client SESIdentityClient
+}
+
+func NewIdentityVerifier(client SESIdentityClient) *IdentityVerifier {
+	return &IdentityVerifier{client: client}
+}
+
+func (v *IdentityVerifier) IsVerified(ctx context.Context, address string) (bool, error) {
+	identities := []string{address}
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		identities = append(identities, address[at+1:])
+	}
+
+	for _, identity := range identities {
+		output, err := v.client.GetEmailIdentity(ctx, &sesv2.GetEmailIdentityInput{EmailIdentity: aws.String(identity)})
+		var notFound *types.NotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return false, categorizeAWSError(err)
+		}
+		if output.VerifiedForSendingStatus {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package awsses
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+type mockIdentityClient struct {
+	// Verification status by identity; missing identities are not found.
+	identities map[string]bool
+	err        error
+	requested  []string
+}
+
+func (m *mockIdentityClient) GetEmailIdentity(ctx context.Context, params *sesv2.GetEmailIdentityInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailIdentityOutput, error) {
+	identity := aws.ToString(params.EmailIdentity)
+	m.requested = append(m.requested, identity)
+	if m.err != nil {
+		return nil, m.err
+	}
+	verified, ok := m.identities[identity]
+	if !ok {
+		return nil, &types.NotFoundException{Message: aws.String("not found")}
+	}
+	return &sesv2.GetEmailIdentityOutput{VerifiedForSendingStatus: verified}, nil
+}
+
+func TestIdentityVerifier(t *testing.T) {
+	tests := []struct {
+		name              string
+		identities        map[string]bool
+		expected          bool
+		expectedRequested []string
+	}{
+		{
+			name:              "address verified",
+			identities:        map[string]bool{"events@icaa.example.com": true},
+			expected:          true,
+			expectedRequested: []string{"events@icaa.example.com"},
+		},
+		{
+			name:              "domain verified",
+			identities:        map[string]bool{"icaa.example.com": true},
+			expected:          true,
+			expectedRequested: []string{"events@icaa.example.com", "icaa.example.com"},
+		},
+		{
+			name:              "pending",
+			identities:        map[string]bool{"events@icaa.example.com": false, "icaa.example.com": false},
+			expectedRequested: []string{"events@icaa.example.com", "icaa.example.com"},
+		},
+		{
+			name:              "unknown",
+			expectedRequested: []string{"events@icaa.example.com", "icaa.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockIdentityClient{identities: tt.identities}
+			verified, err := NewIdentityVerifier(client).IsVerified(context.Background(), "events@icaa.example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verified != tt.expected {
+				t.Errorf("expected verified=%t, got %t", tt.expected, verified)
+			}
+			if !reflect.DeepEqual(client.requested, tt.expectedRequested) {
+				t.Errorf("expected requests for %v, got %v", tt.expectedRequested, client.requested)
+			}
+		})
+	}
+}
+
+func TestIdentityVerifier_Error(t *testing.T) {
+	client := &mockIdentityClient{err: &types.TooManyRequestsException{Message: aws.String("slow down")}}
+	_, err := NewIdentityVerifier(client).IsVerified(context.Background(), "events@icaa.example.com")
+	if !errors.Is(err, email.ErrRateLimited) {
+		t.Errorf("expected a rate limited error, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package awsses
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// S3ObjectGetter reads an object from S3. It is satisfied by a small wrapper
+// around the S3 client's GetObject, so this package does not depend on the
+// S3 SDK.
+type S3ObjectGetter interface {
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// S3Resolver resolves s3://bucket/key attachment references, for use with
+// email.WithAttachmentResolver("s3", ...).
+func S3Resolver(getter S3ObjectGetter) email.AttachmentResolver {
+	return s3Resolver{getter}
+}
+
+type s3Resolver struct {
+	getter S3ObjectGetter
+}
+
+func (r s3Resolver) Open(ctx context.Context, ref *url.URL) (io.ReadCloser, error) {
+	key := strings.TrimPrefix(ref.Path, "/")
+	if ref.Host == "" || key == "" {
+		return nil, errors.New("expected a reference of the form s3://bucket/key")
+	}
+	return r.getter.GetObject(ctx, ref.Host, key)
+}
@@ -0,0 +1,56 @@
+package awsses
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type mockS3 struct {
+	bucket, key string
+}
+
+func (m *mockS3) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.bucket, m.key = bucket, key
+	return io.NopCloser(strings.NewReader("content")), nil
+}
+
+func TestS3Resolver(t *testing.T) {
+	tests := []struct {
+		name        string
+		ref         string
+		expectedErr bool
+	}{
+		{"bucket and key", "s3://rosters/2026/north.csv", false},
+		{"missing key", "s3://rosters", true},
+		{"missing bucket", "s3:///north.csv", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := url.Parse(tt.ref)
+			if err != nil {
+				t.Fatalf("invalid test reference: %v", err)
+			}
+
+			getter := &mockS3{}
+			rc, err := S3Resolver(getter).Open(context.Background(), ref)
+			if tt.expectedErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rc.Close()
+
+			if getter.bucket != "rosters" || getter.key != "2026/north.csv" {
+				t.Errorf("expected rosters/2026/north.csv, got %s/%s", getter.bucket, getter.key)
+			}
+		})
+	}
+}
@@ -4,64 +4,451 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
 )
 
+// ProviderName identifies SES in SendResult.Provider.
+const ProviderName = "ses"
+
+// MaxMessageBytes is the largest message SES accepts after encoding, for
+// comparing against email.EstimateSize. Larger messages fail with
+// REASON_MESSAGE_TOO_LARGE before calling SES.
+const MaxMessageBytes = 40 * 1024 * 1024
+
+// MaxRecipients is the most To, CC and BCC destinations SES accepts in one
+// call.
+const MaxRecipients = 50
+
 var _ email.Sender = &AWSSESSender{}
+var _ email.SenderV2 = &AWSSESSender{}
+var _ email.OptionDumper = &AWSSESSender{}
+var _ email.CapabilityReporter = &AWSSESSender{}
 
 type SESClient interface {
 	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
 }
 
 type AWSSESSender struct {
-	sesClient SESClient
+	sesClient        SESClient
+	validator        *providersdk.Validator
+	ruleCount        int
+	configurationSet string
+	endpointID       string
+	// Stamps the Date of emails without one. Nil leaves it to SES.
+	clock    email.Clock
+	defaults providersdk.Defaults
+	// Splits sends with more than MaxRecipients, see WithAutoChunking.
+	chunking bool
+	// Problems with the options, reported by New.
+	optionErrs email.OptionErrors
+}
+
+// Option configures an AWSSESSender. Invalid and conflicting options are
+// reported together by New.
+type Option func(*AWSSESSender)
+
+// WithConfigurationSet sends with the named configuration set, e.g. one that
+// archives messages with Mail Manager. Names are up to 64 ASCII letters,
+// digits, underscores and dashes.
+func WithConfigurationSet(name string) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithConfigurationSet")
+		if !validConfigurationSet(name) {
+			a.optionErrs.Add("WithConfigurationSet: invalid name %q", name)
+		}
+		a.configurationSet = name
+	}
+}
+
+func validConfigurationSet(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithEndpointID sends through the multi-region (global) endpoint with the
+// given ID.
+func WithEndpointID(id string) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithEndpointID")
+		if id == "" {
+			a.optionErrs.Add("WithEndpointID: id is required")
+		}
+		a.endpointID = id
+	}
+}
+
+// WithAddressRules checks every address against rules before sending. See
+// providersdk.Validator.
+func WithAddressRules(rules ...email.AddressRule) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithAddressRules")
+		a.validator = providersdk.NewValidator(rules...)
+		a.ruleCount = len(rules)
+	}
+}
+
+// WithClock stamps emails without a Date with the time from c instead of
+// leaving it to SES, e.g. for reproducible messages in tests. SES only takes
+// a Date in raw messages, so every email is then sent raw.
+func WithClock(c email.Clock) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithClock")
+		a.clock = c
+	}
+}
+
+// WithAutoChunking splits emails with more than MaxRecipients To, CC and BCC
+// recipients into several sends, see providersdk.SendSplit. Each send gets
+// its own Message-ID, and the send hooks run for each. It is disabled by
+// default, leaving such emails to be rejected by SES.
+func WithAutoChunking(enabled bool) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithAutoChunking")
+		a.chunking = enabled
+	}
+}
+
+// WithDefaultFrom sends emails without a FromAddress from addr.
+func WithDefaultFrom(addr string) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithDefaultFrom")
+		if _, err := email.ParseAddress(addr); err != nil {
+			a.optionErrs.Add("WithDefaultFrom: invalid address %q", addr)
+		}
+		a.defaults.From = addr
+	}
+}
+
+// WithDefaultReplyTo sets the Reply-To addresses of emails without any.
+func WithDefaultReplyTo(addrs ...string) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithDefaultReplyTo")
+		for _, addr := range addrs {
+			if _, err := email.ParseAddress(addr); err != nil {
+				a.optionErrs.Add("WithDefaultReplyTo: invalid address %q", addr)
+			}
+		}
+		a.defaults.ReplyTo = addrs
+	}
+}
+
+// WithDefaultHeaders adds headers to emails that don't set a header of the
+// same name. They are checked like email.ValidateHeaders.
+func WithDefaultHeaders(headers map[string]string) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithDefaultHeaders")
+		a.defaults.Headers = providersdk.DefaultHeaders(headers)
+		if err := email.ValidateHeaders(a.defaults.Headers); err != nil {
+			a.optionErrs.Add("WithDefaultHeaders: %v", err)
+		}
+	}
+}
+
+// WithDefaultAutoSubmitted marks emails that don't set AutoSubmitted as
+// value, e.g. email.AUTO_SUBMITTED_GENERATED for a sender of automated mail
+// only.
+func WithDefaultAutoSubmitted(value email.AutoSubmitted) Option {
+	return func(a *AWSSESSender) {
+		a.optionErrs.Once("WithDefaultAutoSubmitted")
+		if err := email.ValidateAutoSubmitted(value); err != nil {
+			a.optionErrs.Add("WithDefaultAutoSubmitted: unknown value %q", value)
+		}
+		a.defaults.AutoSubmitted = value
+	}
 }
 
-func NewAWSSESSender(client SESClient) *AWSSESSender {
-	return &AWSSESSender{
+// NewAWSSESSender returns a sender using client. It does not report invalid
+// options; when an option is given twice the last one wins. Use New to have
+// them checked.
+func NewAWSSESSender(client SESClient, opts ...Option) *AWSSESSender {
+	a := &AWSSESSender{
 		sesClient: client,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// New is NewAWSSESSender, failing with a REASON_VALIDATION_ERROR error that
+// lists every invalid or conflicting option.
+func New(client SESClient, opts ...Option) (*AWSSESSender, error) {
+	if client == nil {
+		return nil, email.NewValidationError("invalid options: an SES client is required", nil)
+	}
+	a := NewAWSSESSender(client, opts...)
+	if err := a.optionErrs.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// DumpOptions describes the configuration of a.
+func (a *AWSSESSender) DumpOptions() []email.OptionValue {
+	return []email.OptionValue{
+		{Name: "WithConfigurationSet", Value: a.configurationSet},
+		{Name: "WithEndpointID", Value: a.endpointID},
+		{Name: "WithAddressRules", Value: strconv.Itoa(a.ruleCount)},
+		{Name: "WithClock", Value: strconv.FormatBool(a.clock != nil)},
+		{Name: "WithDefaultFrom", Value: a.defaults.From},
+		{Name: "WithDefaultReplyTo", Value: strings.Join(a.defaults.ReplyTo, ", ")},
+		{Name: "WithDefaultHeaders", Value: strconv.Itoa(len(a.defaults.Headers))},
+		{Name: "WithDefaultAutoSubmitted", Value: string(a.defaults.AutoSubmitted)},
+		{Name: "WithAutoChunking", Value: strconv.FormatBool(a.chunking)},
+	}
+}
+
+// Capabilities reports the limits of a.
+func (a *AWSSESSender) Capabilities() email.Capabilities {
+	c := email.Capabilities{
+		MaxMessageSize:            MaxMessageBytes,
+		MaxRecipients:             MaxRecipients,
+		SupportsInlineAttachments: true,
+		BCC:                       email.BCC_ENVELOPE,
+		Bounces:                   email.BOUNCE_FORWARDED,
+	}
+	if a.chunking {
+		c.MaxRecipients = 0
+	}
+	return c
 }
 
 func (a *AWSSESSender) SendEmail(ctx context.Context, e email.Email) error {
-	if err := validateEmail(e); err != nil {
-		return err
+	_, err := a.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+// SendEmailWithResult sends e with default options and returns what SES
+// reported about the message, such as its ID.
+func (a *AWSSESSender) SendEmailWithResult(ctx context.Context, e email.Email) (email.SendResult, error) {
+	result, err := a.SendEmailV2(ctx, e, nil)
+	if err != nil {
+		return email.SendResult{}, err
+	}
+	return *result, nil
+}
+
+func (a *AWSSESSender) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (_ *email.SendResult, err error) {
+	defer email.RecoverSend(ctx, e, opts, &err)
+
+	e = email.EnsureMessageID(a.defaults.Apply(opts.Apply(e)))
+	if a.clock != nil && e.Date.IsZero() {
+		e.Date = a.clock.Now()
+	}
+
+	if err := a.validator.Validate(ctx, e); err != nil {
+		return nil, err
+	}
+	if err := validateTags(e.Tags); err != nil {
+		return nil, err
+	}
+	if e, err = email.NormalizeAddresses(e); err != nil {
+		return nil, err
+	}
+	if err := validateLocalParts(e); err != nil {
+		return nil, err
+	}
+	if e, err = email.ReadBodies(e); err != nil {
+		return nil, err
 	}
 
-	_, err := a.sesClient.SendEmail(ctx, &sesv2.SendEmailInput{
-		Content: &types.EmailContent{
+	limit := 0
+	if a.chunking {
+		limit = MaxRecipients
+	}
+	return providersdk.SendSplit(ctx, e, limit, func(ctx context.Context, e email.Email) (*email.SendResult, error) {
+		return a.send(ctx, e, opts)
+	})
+}
+
+// send builds and sends a validated email with its bodies read.
+func (a *AWSSESSender) send(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	input, mode, size, err := sendEmailInput(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(e, size); err != nil {
+		return nil, err
+	}
+	if a.configurationSet != "" {
+		input.ConfigurationSetName = aws.String(a.configurationSet)
+	}
+	if a.endpointID != "" {
+		input.EndpointId = aws.String(a.endpointID)
+	}
+
+	if err := opts.RunBeforeSend(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if opts.IsDryRun() {
+		result := &email.SendResult{
+			Provider:     ProviderName,
+			MessageID:    e.MessageID,
+			ContentMode:  mode,
+			DryRun:       true,
+			CampaignID:   e.CampaignID,
+			SequenceStep: e.SequenceStep,
+			MessageBytes: size,
+		}
+		opts.RunAfterSend(ctx, e, result, nil)
+		return result, nil
+	}
+
+	output, err := a.sesClient.SendEmail(ctx, input)
+	if err != nil {
+		err = categorizeAWSError(err)
+		opts.RunAfterSend(ctx, e, nil, err)
+		return nil, err
+	}
+
+	result := &email.SendResult{
+		Provider:          ProviderName,
+		ProviderMessageID: aws.ToString(output.MessageId),
+		ProviderRequestID: requestID(output.ResultMetadata),
+		MessageID:         e.MessageID,
+		ContentMode:       mode,
+		CampaignID:        e.CampaignID,
+		SequenceStep:      e.SequenceStep,
+		MessageBytes:      size,
+	}
+	opts.RunAfterSend(ctx, e, result, nil)
+	return result, nil
+}
+
+// sendEmailInput returns the request for e, with Simple content unless
+// requiresRaw, and the mode and size of the raw message if there is one.
+func sendEmailInput(e email.Email) (*sesv2.SendEmailInput, string, int, error) {
+	input := &sesv2.SendEmailInput{
+		Destination: &types.Destination{
+			ToAddresses:  formatAddresses(e.ToAddresses),
+			CcAddresses:  formatAddresses(e.CCAddresses),
+			BccAddresses: formatAddresses(e.BCCAddresses),
+		},
+		FromEmailAddress: aws.String(formatAddress(e.FromAddress)),
+		EmailTags:        tagsFromEmail(e),
+	}
+	if e.BounceAddress != "" {
+		input.FeedbackForwardingEmailAddress = aws.String(bareAddress(e.BounceAddress))
+	}
+
+	if !requiresRaw(e) {
+		input.ReplyToAddresses = formatAddresses(e.ReplyToAddresses)
+		input.Content = &types.EmailContent{
 			Simple: &types.Message{
 				Body: &types.Body{
 					Html: htmlContentFromEmail(e),
 					Text: textContentFromEmail(e),
 				},
-				Subject:     utf8Content(e.Subject),
+				Subject:     content(e, e.Subject),
 				Attachments: attachmentsToAWS(e.Attachments),
+				Headers:     headersFromEmail(e),
 			},
-		},
-		Destination: &types.Destination{
-			ToAddresses:  e.ToAddresses,
-			CcAddresses:  e.CCAddresses,
-			BccAddresses: e.BCCAddresses,
-		},
-		FromEmailAddress: aws.String(e.FromAddress),
-		ReplyToAddresses: e.ReplyToAddresses,
-	})
+		}
+		return input, ContentModeSimple, 0, nil
+	}
 
+	// BCC recipients are only in the destination, so they stay hidden.
+	raw, err := providersdk.BuildMessage(e, providersdk.BuildOptions{OmitBcc: true})
 	if err != nil {
-		return categorizeAWSError(err)
+		var emailErr *email.Error
+		if errors.As(err, &emailErr) {
+			return nil, "", 0, emailErr
+		}
+		return nil, "", 0, email.NewValidationError("failed to create message", err)
+	}
+	input.Content = &types.EmailContent{Raw: &types.RawMessage{Data: raw}}
+	return input, ContentModeRaw, len(raw), nil
+}
+
+// bareAddress strips the display name from a validated address.
+func bareAddress(addr string) string {
+	if a, err := email.ParseAddress(addr); err == nil {
+		return a.Address
+	}
+	return addr
+}
+
+// checkSize rejects messages over MaxMessageBytes: the raw message if there
+// is one, otherwise the message SES will build, estimated.
+func checkSize(e email.Email, rawSize int) error {
+	size := rawSize
+	if size == 0 {
+		size = e.EstimateSize().Total
+	}
+	if size <= MaxMessageBytes {
+		return nil
 	}
+	return email.NewMessageTooLargeError(fmt.Sprintf("message is %d bytes, over the SES limit of %d", size, MaxMessageBytes), size, MaxMessageBytes)
+}
 
+// validateLocalParts rejects addresses with UTF-8 local parts, since SES
+// does not support SMTPUTF8. Their domains have been converted to punycode
+// by email.NormalizeAddresses.
+func validateLocalParts(e email.Email) error {
+	addrs := []string{e.FromAddress, e.SenderAddress, e.BounceAddress, e.ReadReceiptTo, e.FallbackFor}
+	addrs = append(addrs, e.ToAddresses...)
+	addrs = append(addrs, e.CCAddresses...)
+	addrs = append(addrs, e.BCCAddresses...)
+	addrs = append(addrs, e.ReplyToAddresses...)
+	for _, addr := range addrs {
+		if addr == "" {
+			continue
+		}
+		bare := bareAddress(addr)
+		if strings.ContainsFunc(bare, func(r rune) bool { return r >= utf8.RuneSelf }) {
+			return email.NewInvalidEmailError(fmt.Sprintf("SES does not support non-ASCII local parts (SMTPUTF8): %s", addr), nil)
+		}
+	}
 	return nil
 }
 
+// formatAddress encodes display names, which SES requires to be ASCII. The
+// email was validated before, so addresses parse.
+func formatAddress(addr string) string {
+	if formatted, err := providersdk.FormatAddress(addr); err == nil {
+		return formatted
+	}
+	return addr
+}
+
+func formatAddresses(addrs []string) []string {
+	if addrs == nil {
+		return nil
+	}
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = formatAddress(addr)
+	}
+	return formatted
+}
+
 func attachmentsToAWS(attachments []email.Attachment) []types.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+
 	awsAttachments := make([]types.Attachment, len(attachments))
 
 	for i, a := range attachments {
@@ -72,89 +459,163 @@ func attachmentsToAWS(attachments []email.Attachment) []types.Attachment {
 }
 
 func attachmentToAWS(attachment email.Attachment) types.Attachment {
-	return types.Attachment{
+	a := types.Attachment{
 		FileName:           aws.String(attachment.FileName),
 		RawContent:         attachment.Content,
 		ContentType:        aws.String(attachment.ContentType),
 		ContentDescription: aws.String(attachment.Description),
 		ContentDisposition: types.AttachmentContentDispositionAttachment,
 	}
+	if attachment.ContentID != "" {
+		a.ContentDisposition = types.AttachmentContentDispositionInline
+		a.ContentId = aws.String(attachment.ContentID)
+	}
+	return a
 }
 
-func htmlContentFromEmail(e email.Email) *types.Content {
-	if e.HTMLBody == "" {
-		return nil
-	}
+func headersFromEmail(e email.Email) []types.MessageHeader {
+	var headers []types.MessageHeader
 
-	return utf8Content(e.HTMLBody)
-}
+	for _, h := range slices.Concat(email.ReadReceiptHeaders(e), email.FieldHeaders(e)) {
+		// SES stamps the Date and Return-Path itself, and sends tags as
+		// message tags instead.
+		if h.Name == "Date" || h.Name == "Return-Path" || strings.HasPrefix(h.Name, email.TagHeaderPrefix) {
+			continue
+		}
+		headers = append(headers, types.MessageHeader{
+			Name:  aws.String(h.Name),
+			Value: aws.String(h.Value),
+		})
+	}
 
-func textContentFromEmail(e email.Email) *types.Content {
-	if e.TextBody == "" {
-		return nil
+	for _, h := range e.Headers {
+		headers = append(headers, types.MessageHeader{
+			Name:  aws.String(h.Name),
+			Value: aws.String(h.EncodedValue()),
+		})
 	}
 
-	return utf8Content(e.TextBody)
+	return headers
 }
 
-func utf8Content(s string) *types.Content {
-	return &types.Content{
-		Data:    aws.String(s),
-		Charset: aws.String("UTF-8"),
-	}
-}
+// Names of the SES message tags carrying Email.CampaignID and
+// Email.SequenceStep, so they show up in SES event publishing.
+const (
+	campaignIDTag   = "campaign-id"
+	sequenceStepTag = "sequence-step"
+)
+
+func tagsFromEmail(e email.Email) []types.MessageTag {
+	var tags []types.MessageTag
 
-func validateEmail(e email.Email) error {
-	if e.FromAddress == "" {
-		return email.NewValidationError("from address is required", nil)
+	if e.CampaignID != "" {
+		tags = append(tags, types.MessageTag{
+			Name:  aws.String(campaignIDTag),
+			Value: aws.String(e.CampaignID),
+		})
 	}
 
-	if !isValidEmailAddress(e.FromAddress) {
-		return email.NewInvalidEmailError("invalid from address format", nil)
+	if e.SequenceStep > 0 {
+		tags = append(tags, types.MessageTag{
+			Name:  aws.String(sequenceStepTag),
+			Value: aws.String(strconv.Itoa(e.SequenceStep)),
+		})
 	}
 
-	if len(e.ToAddresses)+len(e.CCAddresses)+len(e.BCCAddresses) == 0 {
-		return email.NewValidationError("at least one recipient is required", nil)
+	for _, name := range slices.Sorted(maps.Keys(e.Tags)) {
+		tags = append(tags, types.MessageTag{
+			Name:  aws.String(name),
+			Value: aws.String(e.Tags[name]),
+		})
 	}
 
-	allAddresses := append(append(e.ToAddresses, e.CCAddresses...), e.BCCAddresses...)
-	for _, addr := range allAddresses {
-		if !isValidEmailAddress(addr) {
-			return email.NewInvalidEmailError(fmt.Sprintf("invalid recipient address: %s", addr), nil)
+	return tags
+}
+
+// validateTags rejects Email.Tags that would collide with the tags the
+// sender sets itself.
+func validateTags(tags map[string]string) error {
+	for _, name := range []string{campaignIDTag, sequenceStepTag} {
+		if _, ok := tags[name]; ok {
+			return email.NewValidationError(fmt.Sprintf("tag %q is reserved for Email.CampaignID and Email.SequenceStep", name), nil)
 		}
 	}
+	return nil
+}
 
-	if e.Subject == "" {
-		return email.NewValidationError("subject is required", nil)
+func htmlContentFromEmail(e email.Email) *types.Content {
+	if e.HTMLBody == "" {
+		return nil
 	}
 
-	if e.HTMLBody == "" && e.TextBody == "" {
-		return email.NewValidationError("email body is required (HTML or text)", nil)
+	return content(e, e.HTMLBody)
+}
+
+func textContentFromEmail(e email.Email) *types.Content {
+	if e.TextBody == "" {
+		return nil
 	}
 
-	return nil
+	return content(e, e.TextBody)
+}
+
+// content is a part of the Simple content, which SES transcodes into the
+// charset of e.
+func content(e email.Email, s string) *types.Content {
+	charset := "UTF-8"
+	if c, err := email.LookupCharset(e.Charset); err == nil && !c.IsUTF8() {
+		charset = c.Name
+	}
+	return &types.Content{
+		Data:    aws.String(s),
+		Charset: aws.String(charset),
+	}
+}
+
+func requestID(metadata middleware.Metadata) string {
+	id, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+	return id
 }
 
 func categorizeAWSError(err error) error {
 	var apiErr smithy.APIError
 	if errors.As(err, &apiErr) {
+		var emailErr *email.Error
 		switch apiErr.ErrorCode() {
 		case "TooManyRequestsException":
-			return email.NewRateLimitedError("sending rate limit exceeded", err)
+			emailErr = email.NewRateLimitedError("sending rate limit exceeded", err)
 		case "MessageRejected":
-			return email.NewMessageRejectedError("message rejected by SES", err)
+			emailErr = email.NewMessageRejectedError("message rejected by SES", err)
 		case "MailFromDomainNotVerifiedException":
-			return email.NewUnverifiedDomainError("sender domain not verified", err)
+			emailErr = email.NewUnverifiedDomainError("sender domain not verified", err)
 		case "InvalidParameterValueException":
-			return email.NewInvalidEmailError("invalid email parameter", err)
+			emailErr = email.NewInvalidEmailError("invalid email parameter", err)
 		case "ServiceUnavailableException", "InternalServiceErrorException":
-			return email.NewServiceError("AWS SES service error", err)
+			emailErr = email.NewServiceError("AWS SES service error", err)
+		case "NotFoundException":
+			emailErr = email.NewValidationError("configuration set or endpoint not found", err)
+		case "BadRequestException":
+			emailErr = email.NewValidationError("request rejected by SES", err)
+		case "SendingPausedException":
+			emailErr = email.NewServiceError("sending is paused for the account or configuration set", err)
+		case "AccountSuspendedException":
+			emailErr = email.NewMessageRejectedError("SES account is suspended", err)
+		}
+		if emailErr != nil {
+			emailErr.Metadata = map[string]string{"error_code": apiErr.ErrorCode()}
+			return emailErr
 		}
 	}
 
-	return email.NewUnknownError("failed to send email", err)
-}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return &email.Error{
+			Reason:   providersdk.MapHTTPStatus(respErr.HTTPStatusCode(), respErr.Error()),
+			Message:  fmt.Sprintf("AWS SES error (HTTP %d)", respErr.HTTPStatusCode()),
+			Cause:    err,
+			Metadata: map[string]string{"http_status": strconv.Itoa(respErr.HTTPStatusCode())},
+		}
+	}
 
-func isValidEmailAddress(email string) bool {
-	return strings.Contains(email, "@") && strings.Contains(email, ".")
+	return email.NewUnknownError("failed to send email", err)
 }
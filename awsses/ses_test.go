@@ -1,13 +1,26 @@
 package awsses
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
 // Mock SES client for testing
@@ -227,6 +240,17 @@ func TestAttachmentConversion(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "inline attachment conversion",
+			attachments: []email.Attachment{
+				{
+					FileName:    "logo.png",
+					Content:     []byte("image content"),
+					ContentType: "image/png",
+					ContentID:   "logo@example.com",
+				},
+			},
+		},
 		{
 			name:        "empty attachments",
 			attachments: []email.Attachment{},
@@ -240,6 +264,7 @@ func TestAttachmentConversion(t *testing.T) {
 				ToAddresses: []string{"recipient@example.com"},
 				Subject:     "Test Subject",
 				TextBody:    "Test body",
+				HTMLBody:    `<p>Test body</p>`,
 				Attachments: tt.attachments,
 			}
 
@@ -282,6 +307,17 @@ func TestAttachmentConversion(t *testing.T) {
 						if string(awsAttachment.RawContent) != string(expectedAttachment.Content) {
 							t.Errorf("attachment[%d] RawContent: expected %s, got %s", i, string(expectedAttachment.Content), string(awsAttachment.RawContent))
 						}
+
+						expectedDisposition := types.AttachmentContentDispositionAttachment
+						if expectedAttachment.ContentID != "" {
+							expectedDisposition = types.AttachmentContentDispositionInline
+						}
+						if awsAttachment.ContentDisposition != expectedDisposition {
+							t.Errorf("attachment[%d] ContentDisposition: expected %s, got %s", i, expectedDisposition, awsAttachment.ContentDisposition)
+						}
+						if aws.ToString(awsAttachment.ContentId) != expectedAttachment.ContentID {
+							t.Errorf("attachment[%d] ContentId: expected %s, got %s", i, expectedAttachment.ContentID, aws.ToString(awsAttachment.ContentId))
+						}
 					}
 
 					return &sesv2.SendEmailOutput{}, nil
@@ -332,6 +368,16 @@ func TestSendEmail_ValidationErrors(t *testing.T) {
 			},
 			expectedError: email.REASON_VALIDATION_ERROR,
 		},
+		{
+			name: "non-ASCII local part",
+			email: email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"用户@例え.jp"},
+				Subject:     "Test",
+				TextBody:    "Hello",
+			},
+			expectedError: email.REASON_INVALID_EMAIL,
+		},
 		{
 			name: "invalid recipient address",
 			email: email.Email{
@@ -360,6 +406,17 @@ func TestSendEmail_ValidationErrors(t *testing.T) {
 			},
 			expectedError: email.REASON_VALIDATION_ERROR,
 		},
+		{
+			name: "expiry in the past",
+			email: email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Test",
+				TextBody:    "Hello",
+				Expires:     time.Now().Add(-time.Hour),
+			},
+			expectedError: email.REASON_VALIDATION_ERROR,
+		},
 	}
 
 	for _, tt := range tests {
@@ -441,6 +498,26 @@ func TestSendEmail_AWSErrors(t *testing.T) {
 			},
 			expectedError: email.REASON_SERVICE_ERROR,
 		},
+		{
+			name:          "configuration set or endpoint not found",
+			awsError:      &types.NotFoundException{Message: aws.String("Endpoint does not exist")},
+			expectedError: email.REASON_VALIDATION_ERROR,
+		},
+		{
+			name:          "bad request",
+			awsError:      &types.BadRequestException{Message: aws.String("Bad request")},
+			expectedError: email.REASON_VALIDATION_ERROR,
+		},
+		{
+			name:          "sending paused",
+			awsError:      &types.SendingPausedException{Message: aws.String("Sending paused")},
+			expectedError: email.REASON_SERVICE_ERROR,
+		},
+		{
+			name:          "account suspended",
+			awsError:      &types.AccountSuspendedException{Message: aws.String("Account suspended")},
+			expectedError: email.REASON_MESSAGE_REJECTED,
+		},
 		{
 			name: "unknown aws error",
 			awsError: &smithy.GenericAPIError{
@@ -449,6 +526,16 @@ func TestSendEmail_AWSErrors(t *testing.T) {
 			},
 			expectedError: email.REASON_UNKNOWN,
 		},
+		{
+			name: "unmodeled http error",
+			awsError: &awshttp.ResponseError{
+				ResponseError: &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: 503}},
+					Err:      errors.New("service unavailable"),
+				},
+			},
+			expectedError: email.REASON_SERVICE_ERROR,
+		},
 		{
 			name:          "non-aws error",
 			awsError:      errors.New("network error"),
@@ -491,3 +578,859 @@ func TestSendEmail_AWSErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestSendEmail_ExpiryDateHeader(t *testing.T) {
+	expires := time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC)
+
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	sender := NewAWSSESSender(client)
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Check-in",
+		TextBody:    "Hello World",
+		MessageID:   "<check-in@example.com>",
+		Expires:     expires,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headers := input.Content.Simple.Headers
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 headers, got %d", len(headers))
+	}
+	if *headers[1].Name != "Expiry-Date" {
+		t.Errorf("expected Expiry-Date header, got %s", *headers[1].Name)
+	}
+	if *headers[1].Value != "Mon, 04 Mar 2030 18:30:00 +0000" {
+		t.Errorf("unexpected Expiry-Date value %s", *headers[1].Value)
+	}
+}
+
+func TestSendEmail_CampaignTags(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	sender := NewAWSSESSender(client)
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress:  "sender@example.com",
+		ToAddresses:  []string{"recipient@example.com"},
+		Subject:      "Payment due",
+		TextBody:     "Hello World",
+		CampaignID:   "registration-2030",
+		SequenceStep: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tags := map[string]string{}
+	for _, tag := range input.EmailTags {
+		tags[*tag.Name] = *tag.Value
+	}
+	if tags["campaign-id"] != "registration-2030" || tags["sequence-step"] != "2" || len(tags) != 2 {
+		t.Errorf("unexpected email tags %v", tags)
+	}
+}
+
+func TestSendEmail_Tags(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+	sender := NewAWSSESSender(client)
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Payment due",
+		TextBody:    "Hello World",
+		CampaignID:  "registration-2030",
+		Tags:        map[string]string{"team": "events", "env": "prod"},
+	}
+
+	if err := sender.SendEmail(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, tag := range input.EmailTags {
+		got = append(got, *tag.Name+"="+*tag.Value)
+	}
+	if want := "campaign-id=registration-2030 env=prod team=events"; strings.Join(got, " ") != want {
+		t.Errorf("expected email tags %s, got %v", want, got)
+	}
+	for _, h := range input.Content.Simple.Headers {
+		if strings.HasPrefix(aws.ToString(h.Name), email.TagHeaderPrefix) {
+			t.Errorf("expected no tag headers for SES, got %s", aws.ToString(h.Name))
+		}
+	}
+
+	t.Run("reserved", func(t *testing.T) {
+		input = nil
+		e.Tags = map[string]string{"campaign-id": "other"}
+
+		err := sender.SendEmail(context.Background(), e)
+		if !errors.Is(err, email.ErrValidation) || input != nil {
+			t.Errorf("expected a validation error before calling SES, got %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		input = nil
+		e.Tags = map[string]string{"env": "prod eu"}
+
+		err := sender.SendEmail(context.Background(), e)
+		if !errors.Is(err, email.ErrValidation) || input != nil {
+			t.Errorf("expected a validation error before calling SES, got %v", err)
+		}
+	})
+}
+
+func TestSendEmail_PanicDoesNotStopBatch(t *testing.T) {
+	sender := NewAWSSESSender(&mockSESClient{})
+	opts := &email.SendOptions{
+		Hooks: email.SendHooks{
+			BeforeSend: func(ctx context.Context, e email.Email) error {
+				if e.Subject == "broken" {
+					var template map[string]string
+					template["greeting"] = "Hi"
+				}
+				return nil
+			},
+		},
+	}
+
+	subjects := []string{"first", "broken", "last"}
+	errs := make([]error, len(subjects))
+	for i, subject := range subjects {
+		_, errs[i] = sender.SendEmailV2(context.Background(), email.Email{
+			FromAddress: "sender@example.com",
+			ToAddresses: []string{"recipient@example.com"},
+			Subject:     subject,
+			TextBody:    "Hello World",
+		}, opts)
+	}
+
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("expected the other sends to succeed, got %v and %v", errs[0], errs[2])
+	}
+	var emailErr *email.Error
+	if !errors.As(errs[1], &emailErr) || emailErr.Reason != email.REASON_UNKNOWN {
+		t.Fatalf("expected REASON_UNKNOWN for the panicking send, got %v", errs[1])
+	}
+	if emailErr.Metadata[email.PanicMetadataKey] != "assignment to entry in nil map" {
+		t.Errorf("unexpected panic value %q", emailErr.Metadata[email.PanicMetadataKey])
+	}
+}
+
+func TestSendEmail_AddressRules(t *testing.T) {
+	called := false
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			called = true
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+	noAdmin := func(ctx context.Context, addr string, field email.AddressField) error {
+		if addr == "admin@example.com" {
+			return errors.New("role accounts are not allowed")
+		}
+		return nil
+	}
+
+	sender := NewAWSSESSender(client, WithAddressRules(noAdmin))
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"admin@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	})
+
+	if !errors.Is(err, email.ErrInvalidEmail) {
+		t.Errorf("expected invalid email error, got %v", err)
+	}
+	if called {
+		t.Error("expected SES not to be called")
+	}
+}
+
+func TestSendEmail_FormatsAddresses(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	err := NewAWSSESSender(client).SendEmail(context.Background(), email.Email{
+		FromAddress: email.Address{Name: "ICAA Events", Address: "events@icaa.example.com"}.String(),
+		ToAddresses: []string{"Zoë <zoe@example.com>", "ada@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := aws.ToString(input.FromEmailAddress); got != `"ICAA Events" <events@icaa.example.com>` {
+		t.Errorf("unexpected From %s", got)
+	}
+	want := []string{"=?utf-8?q?Zo=C3=AB?= <zoe@example.com>", "ada@example.com"}
+	if got := input.Destination.ToAddresses; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected To %v, got %v", want, got)
+	}
+}
+
+func TestSendEmail_ConfigurationSetAndEndpoint(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			output := &sesv2.SendEmailOutput{MessageId: aws.String("message-id")}
+			awsmiddleware.SetRequestIDMetadata(&output.ResultMetadata, "request-id")
+			return output, nil
+		},
+	}
+
+	sender := NewAWSSESSender(client, WithConfigurationSet("archived"), WithEndpointID("abc123.xyz"))
+	result, err := sender.SendEmailV2(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if aws.ToString(input.ConfigurationSetName) != "archived" {
+		t.Errorf("unexpected configuration set %v", aws.ToString(input.ConfigurationSetName))
+	}
+	if aws.ToString(input.EndpointId) != "abc123.xyz" {
+		t.Errorf("unexpected endpoint ID %v", aws.ToString(input.EndpointId))
+	}
+	if result.ProviderMessageID != "message-id" || result.ProviderRequestID != "request-id" {
+		t.Errorf("unexpected IDs %q and %q", result.ProviderMessageID, result.ProviderRequestID)
+	}
+}
+
+func TestSendEmailWithResult(t *testing.T) {
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			return &sesv2.SendEmailOutput{MessageId: aws.String("message-id")}, nil
+		},
+	}
+
+	result, err := NewAWSSESSender(client).SendEmailWithResult(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Provider != ProviderName || result.ProviderMessageID != "message-id" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestSendEmail_GeneratesMessageID(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{MessageId: aws.String("message-id")}, nil
+		},
+	}
+
+	result, err := NewAWSSESSender(client).SendEmailV2(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headers := input.Content.Simple.Headers
+	if len(headers) == 0 || aws.ToString(headers[0].Name) != "Message-ID" {
+		t.Fatalf("expected a Message-ID header, got %v", headers)
+	}
+	if got := aws.ToString(headers[0].Value); got != result.MessageID || !strings.HasSuffix(got, "@example.com>") {
+		t.Errorf("expected Message-ID %q in the result, got %q", got, result.MessageID)
+	}
+}
+
+func TestSendEmail_BounceAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounce   string
+		expected *string
+	}{
+		{"unset", "", nil},
+		{"bare", "bounces@example.com", aws.String("bounces@example.com")},
+		{"display name", "Bounces <bounces@example.com>", aws.String("bounces@example.com")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sesv2.SendEmailInput
+			client := &mockSESClient{
+				sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+					input = params
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}
+
+			err := NewAWSSESSender(client).SendEmail(context.Background(), email.Email{
+				FromAddress:   "sender@example.com",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				TextBody:      "Hello World",
+				BounceAddress: tt.bounce,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if aws.ToString(input.FeedbackForwardingEmailAddress) != aws.ToString(tt.expected) {
+				t.Errorf("expected feedback forwarding address %v, got %v", aws.ToString(tt.expected), aws.ToString(input.FeedbackForwardingEmailAddress))
+			}
+			for _, h := range input.Content.Simple.Headers {
+				if aws.ToString(h.Name) == "Return-Path" {
+					t.Error("expected no Return-Path header for SES")
+				}
+			}
+		})
+	}
+}
+
+func TestSendEmail_PriorityAndUnsubscribeHeaders(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	err := NewAWSSESSender(client, WithDefaultAutoSubmitted(email.AUTO_SUBMITTED_GENERATED)).SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Disk almost full",
+		TextBody:    "Hello World",
+		MessageID:   "<disk@example.com>",
+		Priority:    email.PRIORITY_HIGH,
+		Unsubscribe: email.OneClickUnsubscribe("https://example.com/u/1"),
+		ListID:      "<alerts.example.com>",
+		// Auto-Submitted comes from WithDefaultAutoSubmitted.
+		SuppressAutoResponses: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, h := range input.Content.Simple.Headers {
+		got[aws.ToString(h.Name)] = aws.ToString(h.Value)
+	}
+	want := map[string]string{
+		"Message-ID":               "<disk@example.com>",
+		"X-Priority":               "1 (Highest)",
+		"X-MSMail-Priority":        "High",
+		"Importance":               "high",
+		"List-Unsubscribe":         "<https://example.com/u/1>",
+		"List-Unsubscribe-Post":    "List-Unsubscribe=One-Click",
+		"List-Id":                  "<alerts.example.com>",
+		"Auto-Submitted":           "auto-generated",
+		"X-Auto-Response-Suppress": "All",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected headers %v, got %v", want, got)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("expected %s: %s, got %q", name, value, got[name])
+		}
+	}
+}
+
+func TestSendEmail_MessageTooLarge(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachment  int
+		longHeader  bool
+		expectError bool
+	}{
+		{name: "fits", attachment: 1024},
+		{name: "simple over the limit", attachment: MaxMessageBytes * 3 / 4, expectError: true},
+		{name: "raw over the limit", attachment: MaxMessageBytes * 3 / 4, longHeader: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := false
+			client := &mockSESClient{
+				sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+					sent = true
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}
+			e := email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Photos",
+				TextBody:    "Attached.",
+				Attachments: []email.Attachment{{FileName: "photos.zip", Content: make([]byte, tt.attachment), ContentType: "application/zip"}},
+			}
+			if tt.longHeader {
+				e.Headers = []email.Header{{Name: "X-Long", Value: strings.Repeat("word ", 200)}}
+			}
+
+			err := NewAWSSESSender(client).SendEmail(context.Background(), e)
+
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var emailErr *email.Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_MESSAGE_TOO_LARGE {
+				t.Fatalf("expected message too large error, got %v", err)
+			}
+			if sent {
+				t.Error("expected the message not to be sent")
+			}
+			if emailErr.Metadata[email.LimitBytesMetadataKey] != strconv.Itoa(MaxMessageBytes) {
+				t.Errorf("unexpected limit %s", emailErr.Metadata[email.LimitBytesMetadataKey])
+			}
+			if size, _ := strconv.Atoi(emailErr.Metadata[email.MessageBytesMetadataKey]); size <= MaxMessageBytes {
+				t.Errorf("expected a size over the limit, got %d", size)
+			}
+		})
+	}
+}
+
+func TestSendEmail_ReaderBodies(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	err := NewAWSSESSender(client).SendEmail(context.Background(), email.Email{
+		FromAddress:    "sender@example.com",
+		ToAddresses:    []string{"recipient@example.com"},
+		Subject:        "Test Subject",
+		HTMLBodyReader: strings.NewReader("<p>Hello World</p>"),
+		TextBodyReader: strings.NewReader("Hello World"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := input.Content.Simple.Body
+	if got := aws.ToString(body.Html.Data); got != "<p>Hello World</p>" {
+		t.Errorf("expected the HTML reader as the HTML body, got %q", got)
+	}
+	if got := aws.ToString(body.Text.Data); got != "Hello World" {
+		t.Errorf("expected the text reader as the text body, got %q", got)
+	}
+}
+
+func TestSendEmail_ReadReceipt(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	err := NewAWSSESSender(client).SendEmail(context.Background(), email.Email{
+		FromAddress:        "Board <board@example.com>",
+		ToAddresses:        []string{"recipient@example.com"},
+		Subject:            "Minutes",
+		TextBody:           "Hello World",
+		MessageID:          "<minutes@example.com>",
+		RequestReadReceipt: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, h := range input.Content.Simple.Headers {
+		got[aws.ToString(h.Name)] = aws.ToString(h.Value)
+	}
+	for _, name := range []string{"Disposition-Notification-To", "Return-Receipt-To"} {
+		if got[name] != `"Board" <board@example.com>` {
+			t.Errorf("expected %s to default to the From address, got %q", name, got[name])
+		}
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	allowAll := func(ctx context.Context, addr string, field email.AddressField) error { return nil }
+
+	sender, err := New(&mockSESClient{}, WithConfigurationSet("archive-1"), WithEndpointID("abc123.xyz"), WithAddressRules(allowAll))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, v := range sender.DumpOptions() {
+		got = append(got, v.String())
+	}
+	if want := "WithConfigurationSet=archive-1 WithEndpointID=abc123.xyz WithAddressRules=1 WithClock=false WithDefaultFrom= WithDefaultReplyTo= WithDefaultHeaders=0 WithDefaultAutoSubmitted= WithAutoChunking=false"; strings.Join(got, " ") != want {
+		t.Errorf("expected options %s, got %v", want, got)
+	}
+
+	_, err = New(&mockSESClient{}, WithConfigurationSet("my archive"), WithEndpointID(""), WithConfigurationSet("archive"))
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_VALIDATION_ERROR {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	want := `invalid options: WithConfigurationSet: invalid name "my archive"; WithEndpointID: id is required; WithConfigurationSet given more than once`
+	if emailErr.Message != want {
+		t.Errorf("expected %q, got %q", want, emailErr.Message)
+	}
+
+	_, err = New(&mockSESClient{}, WithDefaultFrom("events"), WithDefaultReplyTo("help"), WithDefaultHeaders(map[string]string{"X-Team": "a\r\nBcc: x@example.com"}), WithDefaultAutoSubmitted("yes"))
+	for _, problem := range []string{`WithDefaultFrom: invalid address "events"`, `WithDefaultReplyTo: invalid address "help"`, "WithDefaultHeaders: ", `WithDefaultAutoSubmitted: unknown value "yes"`} {
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %v", problem, err)
+		}
+	}
+
+	// NewAWSSESSender keeps accepting them, the last one winning.
+	if lenient := NewAWSSESSender(&mockSESClient{}, WithConfigurationSet("a"), WithConfigurationSet("b")); lenient.configurationSet != "b" {
+		t.Errorf("expected the last configuration set to win, got %q", lenient.configurationSet)
+	}
+}
+
+func TestSendEmail_Date(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+	clock := emailtest.NewFakeClock(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC))
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Results",
+		TextBody:    "Hello World",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		date     time.Time
+		expected string
+	}{
+		{name: "left to SES"},
+		{name: "explicit", date: time.Date(2023, 11, 4, 18, 15, 0, 0, time.UTC), expected: "Sat, 04 Nov 2023 18:15:00 +0000"},
+		{name: "from the clock", opts: []Option{WithClock(clock)}, expected: "Fri, 01 Mar 2024 09:30:00 +0000"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			withDate := e
+			withDate.Date = tc.date
+
+			if err := NewAWSSESSender(client, tc.opts...).SendEmail(context.Background(), withDate); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expected == "" {
+				if input.Content.Simple == nil {
+					t.Fatal("expected simple content without a date")
+				}
+				return
+			}
+			if input.Content.Raw == nil {
+				t.Fatal("expected a raw message to carry the date")
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			if got := msg.Header.Get("Date"); got != tc.expected {
+				t.Errorf("expected Date %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSendEmail_InternationalizedDomains(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+
+	err := NewAWSSESSender(client).SendEmail(context.Background(), email.Email{
+		FromAddress: "events@münchen.de",
+		ToAddresses: []string{"José <jose@münchen.de>"},
+		Subject:     "Test",
+		TextBody:    "Hello",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := aws.ToString(input.FromEmailAddress); got != "events@xn--mnchen-3ya.de" {
+		t.Errorf("unexpected From %q", got)
+	}
+	if got := input.Destination.ToAddresses; len(got) != 1 || got[0] != "=?utf-8?q?Jos=C3=A9?= <jose@xn--mnchen-3ya.de>" {
+		t.Errorf("unexpected To %v", got)
+	}
+}
+
+func TestSendEmail_Defaults(t *testing.T) {
+	var input *sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			input = params
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}
+	sender, err := New(client,
+		WithDefaultFrom("events@icaa.example.com"),
+		WithDefaultReplyTo("help@icaa.example.com"),
+		WithDefaultHeaders(map[string]string{"X-Team": "events"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		email   email.Email
+		from    string
+		replyTo []string
+		xTeam   string
+	}{
+		{
+			name:    "filled in",
+			email:   email.Email{ToAddresses: []string{"recipient@example.com"}, Subject: "Results", TextBody: "Hello World"},
+			from:    "events@icaa.example.com",
+			replyTo: []string{"help@icaa.example.com"},
+			xTeam:   "events",
+		},
+		{
+			name: "explicit values win",
+			email: email.Email{
+				FromAddress:      "board@icaa.example.com",
+				ReplyToAddresses: []string{"chair@icaa.example.com"},
+				ToAddresses:      []string{"recipient@example.com"},
+				Subject:          "Results",
+				TextBody:         "Hello World",
+				Headers:          []email.Header{{Name: "x-team", Value: "board"}},
+			},
+			from:    "board@icaa.example.com",
+			replyTo: []string{"chair@icaa.example.com"},
+			xTeam:   "board",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := sender.SendEmail(context.Background(), tc.email); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := aws.ToString(input.FromEmailAddress); got != tc.from {
+				t.Errorf("expected From %q, got %q", tc.from, got)
+			}
+			if !slices.Equal(input.ReplyToAddresses, tc.replyTo) {
+				t.Errorf("expected Reply-To %v, got %v", tc.replyTo, input.ReplyToAddresses)
+			}
+			var xTeam []string
+			for _, h := range input.Content.Simple.Headers {
+				if strings.EqualFold(aws.ToString(h.Name), "X-Team") {
+					xTeam = append(xTeam, aws.ToString(h.Value))
+				}
+			}
+			if !slices.Equal(xTeam, []string{tc.xTeam}) {
+				t.Errorf("expected X-Team %q once, got %v", tc.xTeam, xTeam)
+			}
+		})
+	}
+}
+
+func TestSendEmail_AutoChunking(t *testing.T) {
+	var inputs []*sesv2.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			inputs = append(inputs, params)
+			if len(inputs) == 2 {
+				return nil, &types.TooManyRequestsException{Message: aws.String("slow down")}
+			}
+			return &sesv2.SendEmailOutput{MessageId: aws.String("id-" + strconv.Itoa(len(inputs)))}, nil
+		},
+	}
+	league := make([]string, 2*MaxRecipients+10)
+	for i := range league {
+		league[i] = "archer" + strconv.Itoa(i) + "@example.com"
+	}
+	e := email.Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  []string{"captains@example.com"},
+		BCCAddresses: league,
+		Subject:      "Season opener",
+		TextBody:     "See you there",
+	}
+
+	sender, err := New(client, WithAutoChunking(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := sender.SendEmailV2(context.Background(), e, nil)
+
+	if len(inputs) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(inputs))
+	}
+	for i, input := range inputs {
+		d := input.Destination
+		if n := len(d.ToAddresses) + len(d.CcAddresses) + len(d.BccAddresses); n > MaxRecipients {
+			t.Errorf("call %d: expected at most %d destinations, got %d", i, MaxRecipients, n)
+		}
+	}
+	if got := inputs[2].Destination.BccAddresses; len(got) != 11 {
+		t.Errorf("expected the last call to get the remaining 11 recipients, got %d", len(got))
+	}
+
+	var chunkedErr *email.ChunkedSendError
+	if !errors.As(err, &chunkedErr) || len(chunkedErr.Errors) != 1 || chunkedErr.Errors[0].Index != 1 {
+		t.Fatalf("expected the second chunk to fail, got %v", err)
+	}
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_RATE_LIMITED {
+		t.Errorf("expected the chunk's rate limit error, got %v", err)
+	}
+	if result == nil || result.ProviderMessageID != "id-1" || len(result.Chunks) != 3 || result.Chunks[2].ProviderMessageID != "id-3" {
+		t.Errorf("expected the results of the chunks sent, got %+v", result)
+	}
+	if first, last := result.Chunks[0].MessageID, result.Chunks[2].MessageID; first == "" || first == last {
+		t.Errorf("expected each chunk to have its own Message-ID, got %q and %q", first, last)
+	}
+
+	// Without the option the email is sent in one call, for SES to refuse.
+	inputs = nil
+	if _, err := NewAWSSESSender(client).SendEmailV2(context.Background(), e, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inputs) != 1 {
+		t.Errorf("expected 1 call, got %d", len(inputs))
+	}
+}
+
+func TestSendEmail_AutoChunkingDeadLetter(t *testing.T) {
+	var inputs []*sesv2.SendEmailInput
+	rejecting := true
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+			inputs = append(inputs, params)
+			if rejecting && len(inputs) == 2 {
+				return nil, &types.MessageRejected{Message: aws.String("rejected")}
+			}
+			return &sesv2.SendEmailOutput{MessageId: aws.String("id-" + strconv.Itoa(len(inputs)))}, nil
+		},
+	}
+	league := make([]string, 2*MaxRecipients+10)
+	for i := range league {
+		league[i] = "archer" + strconv.Itoa(i) + "@example.com"
+	}
+	e := email.Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  []string{"captains@example.com"},
+		BCCAddresses: league,
+		Subject:      "Season opener",
+		TextBody:     "See you there",
+	}
+
+	ses, err := New(client, WithAutoChunking(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store := email.NewFileDeadLetterStore(t.TempDir() + "/dead.jsonl")
+	result, err := email.NewDeadLetterSender(ses, store).SendEmailV2(context.Background(), e, nil)
+
+	var chunkedErr *email.ChunkedSendError
+	if !errors.As(err, &chunkedErr) {
+		t.Fatalf("expected a chunked send error, got %v", err)
+	}
+	if result == nil || len(result.Chunks) != 3 {
+		t.Errorf("expected the results of the chunks sent, got %+v", result)
+	}
+	rejected := inputs[1].Destination.BccAddresses
+
+	inputs = nil
+	rejecting = false
+	outcomes, err := email.Replay(context.Background(), store, nil, ses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Err != nil {
+		t.Fatalf("expected 1 successful replay, got %+v", outcomes)
+	}
+	if len(inputs) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(inputs))
+	}
+	d := inputs[0].Destination
+	if len(d.ToAddresses) != 0 || len(d.CcAddresses) != 0 || !slices.Equal(d.BccAddresses, rejected) {
+		t.Errorf("expected the replay to go to the rejected chunk's %d recipients only, got %d To, %d CC and %d BCC", len(rejected), len(d.ToAddresses), len(d.CcAddresses), len(d.BccAddresses))
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	sender, err := New(&mockSESClient{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := email.Capabilities{
+		MaxMessageSize:            MaxMessageBytes,
+		MaxRecipients:             MaxRecipients,
+		SupportsInlineAttachments: true,
+		BCC:                       email.BCC_ENVELOPE,
+		Bounces:                   email.BOUNCE_FORWARDED,
+	}
+	if got := sender.Capabilities(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	chunking, err := New(&mockSESClient{}, WithAutoChunking(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := chunking.Capabilities().MaxRecipients; got != 0 {
+		t.Errorf("expected no recipient limit with chunking, got %d", got)
+	}
+}
@@ -0,0 +1,39 @@
+package email
+
+import (
+	"io"
+	"strings"
+)
+
+// HasBody reports whether e has an HTML or text body, given as a string or
+// as a reader.
+func (e Email) HasBody() bool {
+	return e.HTMLBody != "" || e.TextBody != "" || e.HTMLBodyReader != nil || e.TextBodyReader != nil
+}
+
+// ReadBodies returns e with HTMLBodyReader and TextBodyReader read into
+// HTMLBody and TextBody, and the readers cleared. The readers are consumed,
+// so senders call it once, after validation. An email without readers is
+// returned unchanged.
+func ReadBodies(e Email) (Email, error) {
+	bodies := []struct {
+		name   string
+		reader *io.Reader
+		body   *string
+	}{
+		{"HTML", &e.HTMLBodyReader, &e.HTMLBody},
+		{"text", &e.TextBodyReader, &e.TextBody},
+	}
+	for _, b := range bodies {
+		if *b.reader == nil {
+			continue
+		}
+		var sb strings.Builder
+		if _, err := io.Copy(&sb, *b.reader); err != nil {
+			return e, NewValidationError("failed to read "+b.name+" body", err)
+		}
+		*b.body = sb.String()
+		*b.reader = nil
+	}
+	return e, nil
+}
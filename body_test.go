@@ -0,0 +1,25 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadBodies(t *testing.T) {
+	e, err := ReadBodies(Email{
+		HTMLBodyReader: strings.NewReader("<p>Hello</p>"),
+		TextBody:       "Hello",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.HTMLBody != "<p>Hello</p>" || e.TextBody != "Hello" {
+		t.Errorf("unexpected bodies %q and %q", e.HTMLBody, e.TextBody)
+	}
+	if e.HTMLBodyReader != nil || e.TextBodyReader != nil {
+		t.Error("expected the readers to be cleared")
+	}
+	if !e.HasBody() {
+		t.Error("expected the email to have a body")
+	}
+}
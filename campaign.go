@@ -0,0 +1,40 @@
+package email
+
+import "fmt"
+
+const (
+	// Header names carrying Email.CampaignID and Email.SequenceStep.
+	CampaignIDHeader   = "X-Campaign-ID"
+	SequenceStepHeader = "X-Sequence-Step"
+
+	maxCampaignIDLength = 256
+)
+
+// ValidateCampaign checks the campaign fields of e. Campaign IDs must be at
+// most 256 ASCII letters, digits, underscores and dashes, the most
+// restrictive format (SES message tags) among the providers.
+func ValidateCampaign(e Email) error {
+	if len(e.CampaignID) > maxCampaignIDLength {
+		return NewValidationError(fmt.Sprintf("campaign ID is longer than %d characters", maxCampaignIDLength), nil)
+	}
+
+	for _, r := range e.CampaignID {
+		if !isTagRune(r) {
+			return NewValidationError(fmt.Sprintf("campaign ID %q contains %q; only letters, digits, '_' and '-' are allowed", e.CampaignID, r), nil)
+		}
+	}
+
+	if e.SequenceStep < 0 {
+		return NewValidationError("sequence step must not be negative", nil)
+	}
+
+	if e.SequenceStep > 0 && e.CampaignID == "" {
+		return NewValidationError("sequence step requires a campaign ID", nil)
+	}
+
+	return nil
+}
+
+func isTagRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-'
+}
@@ -0,0 +1,74 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateCampaign(t *testing.T) {
+	tests := []struct {
+		name        string
+		email       Email
+		expectError bool
+	}{
+		{
+			name:  "no campaign",
+			email: Email{},
+		},
+		{
+			name:  "campaign with step",
+			email: Email{CampaignID: "Registration_2030-spring", SequenceStep: 3},
+		},
+		{
+			name:  "longest campaign ID",
+			email: Email{CampaignID: strings.Repeat("a", 256)},
+		},
+		{
+			name:        "campaign ID too long",
+			email:       Email{CampaignID: strings.Repeat("a", 257)},
+			expectError: true,
+		},
+		{
+			name:        "campaign ID with space",
+			email:       Email{CampaignID: "spring 2030"},
+			expectError: true,
+		},
+		{
+			name:        "campaign ID with non-ASCII",
+			email:       Email{CampaignID: "frühling"},
+			expectError: true,
+		},
+		{
+			name:        "negative step",
+			email:       Email{CampaignID: "spring", SequenceStep: -1},
+			expectError: true,
+		},
+		{
+			name:        "step without campaign",
+			email:       Email{SequenceStep: 1},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCampaign(tt.email)
+
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var emailErr *Error
+			if !errors.As(err, &emailErr) {
+				t.Fatalf("expected email.Error, got %v", err)
+			}
+			if emailErr.Reason != REASON_VALIDATION_ERROR {
+				t.Errorf("expected error reason %s, got %s", REASON_VALIDATION_ERROR, emailErr.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package email
+
+// BCCHandling is how a provider is told the BCC recipients of a message
+// without them being shown to the other recipients.
+type BCCHandling string
+
+const (
+	// The request lists BCC recipients in an envelope field, e.g. the SES
+	// Destination, and nowhere else.
+	BCC_ENVELOPE BCCHandling = "ENVELOPE"
+	// The raw message carries a top-level Bcc header, which the provider
+	// reads the recipients from and removes before delivery, as Gmail does.
+	BCC_STRIPPED_HEADER BCCHandling = "STRIPPED_HEADER"
+)
+
+// BounceHandling is what a provider does with Email.BounceAddress.
+type BounceHandling string
+
+const (
+	// BounceAddress is the envelope sender, so receiving servers send
+	// bounces to it.
+	BOUNCE_ENVELOPE_SENDER BounceHandling = "ENVELOPE_SENDER"
+	// The provider keeps its own envelope sender and forwards the bounces
+	// it receives to BounceAddress, as SES does.
+	BOUNCE_FORWARDED BounceHandling = "FORWARDED"
+	// BounceAddress is only written as the Return-Path header, which
+	// receiving servers usually replace with the envelope sender, as with
+	// Gmail. Bounces go to the provider's envelope sender.
+	BOUNCE_HEADER_ONLY BounceHandling = "HEADER_ONLY"
+)
+
+// Capabilities are the limits and features of a configured sender, e.g. for
+// deciding whether to attach a large file or link to it instead.
+type Capabilities struct {
+	// Largest message in bytes, as estimated by EstimateSize. Zero if
+	// unknown.
+	MaxMessageSize int
+	// Most To, CC and BCC recipients of one email. Zero if unlimited, e.g.
+	// because the sender splits larger sends.
+	MaxRecipients int
+	// Whether attachments with a ContentID are sent inline in the HTML body.
+	SupportsInlineAttachments bool
+	// Whether an AMP version of the body can be sent alongside the HTML.
+	SupportsAMP bool
+	// Whether the provider can hold an email to deliver it later.
+	SupportsScheduling bool
+	BCC                BCCHandling
+	Bounces            BounceHandling
+}
+
+// CapabilityReporter is implemented by senders that know their
+// Capabilities.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the Capabilities of s, or of the first sender in
+// its decorator chain that reports them, see Unwrapper. It returns false if
+// none does.
+func CapabilitiesOf(s Sender) (Capabilities, bool) {
+	var next any = s
+	for next != nil {
+		if r, ok := next.(CapabilityReporter); ok {
+			return r.Capabilities(), true
+		}
+
+		switch u := next.(type) {
+		case *v1Adapter:
+			next = u.sender
+		case *v2Adapter:
+			next = u.sender
+		case Unwrapper:
+			next = u.Unwrap()
+		default:
+			return Capabilities{}, false
+		}
+	}
+	return Capabilities{}, false
+}
@@ -0,0 +1,47 @@
+package email
+
+import "testing"
+
+type capableSender struct {
+	recordingSender
+	capabilities Capabilities
+}
+
+func (s *capableSender) Capabilities() Capabilities {
+	return s.capabilities
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	provider := &capableSender{capabilities: Capabilities{MaxMessageSize: 1024, MaxRecipients: 50, BCC: BCC_ENVELOPE}}
+
+	tests := []struct {
+		name     string
+		sender   Sender
+		expected bool
+	}{
+		{"reporter", provider, true},
+		{"decorator chain", NewFallbackSender(NewEventSender(AsSenderV2(provider))), true},
+		{"v1 adapter", AsSender(&v1Adapter{sender: provider}), true},
+		{"v2 adapter", AsSender(NewEventSender(AsSenderV2(provider))), true},
+		{"no reporter", &recordingSender{}, false},
+		{"no reporter in chain", NewFallbackSender(&recordingSenderV2{}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CapabilitiesOf(tt.sender)
+			if ok != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, ok)
+			}
+			if ok && got != provider.capabilities {
+				t.Errorf("expected %+v, got %+v", provider.capabilities, got)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesOf_Nil(t *testing.T) {
+	if _, ok := CapabilitiesOf(nil); ok {
+		t.Error("expected no capabilities for a nil sender")
+	}
+}
@@ -0,0 +1,22 @@
+package email
+
+import "context"
+
+// SenderFunc adapts a function to a Sender, e.g. for small middlewares and
+// test doubles.
+type SenderFunc func(ctx context.Context, e Email) error
+
+var _ Sender = SenderFunc(nil)
+
+func (f SenderFunc) SendEmail(ctx context.Context, e Email) error {
+	return f(ctx, e)
+}
+
+// Chain wraps s in middlewares, the first outermost: Chain(s, a, b) is
+// a(b(s)), so a sees every email before b does.
+func Chain(s Sender, middlewares ...func(Sender) Sender) Sender {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
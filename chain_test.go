@@ -0,0 +1,94 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tracing records its name in the trace before and after passing the email
+// on.
+func tracing(name string, trace *[]string) func(Sender) Sender {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, e Email) error {
+			*trace = append(*trace, name+" in")
+			err := next.SendEmail(ctx, e)
+			*trace = append(*trace, name+" out")
+			return err
+		})
+	}
+}
+
+// allowlist rejects emails to addresses outside domain without passing them
+// on.
+func allowlist(domain string) func(Sender) Sender {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, e Email) error {
+			for _, addr := range e.ToAddresses {
+				if !strings.HasSuffix(addr, "@"+domain) {
+					return NewValidationError("recipient not allowed: "+addr, nil)
+				}
+			}
+			return next.SendEmail(ctx, e)
+		})
+	}
+}
+
+func TestSenderFunc(t *testing.T) {
+	var got Email
+	s := SenderFunc(func(ctx context.Context, e Email) error {
+		got = e
+		return errors.New("boom")
+	})
+
+	e := Email{Subject: "Hello"}
+	if err := s.SendEmail(context.Background(), e); err == nil || err.Error() != "boom" {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+	if got.Subject != "Hello" {
+		t.Errorf("expected the function to get the email, got %+v", got)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	provider := SenderFunc(func(ctx context.Context, e Email) error {
+		trace = append(trace, "provider")
+		return nil
+	})
+
+	t.Run("outermost first", func(t *testing.T) {
+		trace = nil
+		s := Chain(provider, tracing("a", &trace), tracing("b", &trace), tracing("c", &trace))
+		if err := s.SendEmail(context.Background(), Email{ToAddresses: []string{"ada@example.com"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"a in", "b in", "c in", "provider", "c out", "b out", "a out"}
+		if !reflect.DeepEqual(trace, want) {
+			t.Errorf("expected %v, got %v", want, trace)
+		}
+	})
+
+	t.Run("short circuit", func(t *testing.T) {
+		trace = nil
+		s := Chain(provider, tracing("a", &trace), allowlist("example.com"), tracing("b", &trace))
+		err := s.SendEmail(context.Background(), Email{ToAddresses: []string{"ada@elsewhere.com"}})
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+
+		want := []string{"a in", "a out"}
+		if !reflect.DeepEqual(trace, want) {
+			t.Errorf("expected %v, got %v", want, trace)
+		}
+	})
+
+	t.Run("no middlewares", func(t *testing.T) {
+		if s := Chain(provider); reflect.ValueOf(s).Pointer() != reflect.ValueOf(provider).Pointer() {
+			t.Error("expected the sender itself")
+		}
+	})
+}
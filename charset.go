@@ -0,0 +1,99 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// DefaultCharset is the character set of emails that don't set Charset.
+const DefaultCharset = "utf-8"
+
+// Charset is a character set the subject and bodies of an email can be
+// sent in.
+type Charset struct {
+	// Canonical MIME name, e.g. "ISO-2022-JP", for the charset parameter.
+	Name string
+	enc  encoding.Encoding
+}
+
+// LookupCharset returns the character set with the IANA name or alias
+// charset, e.g. "iso-2022-jp" or "latin1". An empty name means
+// DefaultCharset. UTF-16 and UTF-32 are refused, as text parts must keep
+// line breaks ASCII.
+func LookupCharset(charset string) (Charset, error) {
+	if charset == "" || strings.EqualFold(charset, DefaultCharset) {
+		return Charset{Name: DefaultCharset}, nil
+	}
+
+	enc, err := ianaindex.MIME.Encoding(charset)
+	if err != nil || enc == nil {
+		return Charset{}, NewValidationError(fmt.Sprintf("unknown charset %q", charset), err)
+	}
+	name, err := ianaindex.MIME.Name(enc)
+	if err != nil {
+		return Charset{}, NewValidationError(fmt.Sprintf("unknown charset %q", charset), err)
+	}
+	if strings.EqualFold(name, DefaultCharset) {
+		return Charset{Name: DefaultCharset}, nil
+	}
+	if upper := strings.ToUpper(name); strings.HasPrefix(upper, "UTF-16") || strings.HasPrefix(upper, "UTF-32") {
+		return Charset{}, NewValidationError(fmt.Sprintf("charset %s can't be used for email text", name), nil)
+	}
+	return Charset{Name: name, enc: enc}, nil
+}
+
+// IsUTF8 reports whether c is DefaultCharset, which needs no transcoding.
+func (c Charset) IsUTF8() bool {
+	return c.enc == nil
+}
+
+// Encode transcodes s into c. Text with characters c can't represent is a
+// validation error, rather than being sent garbled.
+func (c Charset) Encode(s string) (string, error) {
+	if c.enc == nil {
+		return s, nil
+	}
+
+	encoded, err := c.enc.NewEncoder().String(s)
+	if err != nil {
+		return "", NewValidationError(fmt.Sprintf("text can't be encoded in %s", c.Name), err)
+	}
+	return encoded, nil
+}
+
+// ValidateCharset checks that e's Charset is known and can represent its
+// subject and string bodies. Reader bodies are checked when they are read.
+func ValidateCharset(e Email) error {
+	c, err := LookupCharset(e.Charset)
+	if err != nil || c.IsUTF8() {
+		return err
+	}
+
+	for _, part := range []struct{ name, text string }{
+		{"subject", e.Subject},
+		{"text body", e.TextBody},
+		{"HTML body", e.HTMLBody},
+	} {
+		if _, err := c.Encode(part.text); err != nil {
+			return NewValidationError(fmt.Sprintf("%s can't be encoded in %s", part.name, c.Name), err)
+		}
+	}
+	return nil
+}
+
+// Decode transcodes s from c into UTF-8, e.g. to read back a built
+// message.
+func (c Charset) Decode(s string) (string, error) {
+	if c.enc == nil {
+		return s, nil
+	}
+
+	decoded, err := c.enc.NewDecoder().String(s)
+	if err != nil {
+		return "", NewValidationError(fmt.Sprintf("text is not valid %s", c.Name), err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,64 @@
+package email
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookupCharset(t *testing.T) {
+	tests := []struct {
+		charset  string
+		expected string
+		err      bool
+	}{
+		{charset: "", expected: "utf-8"},
+		{charset: "UTF-8", expected: "utf-8"},
+		{charset: "iso-2022-jp", expected: "ISO-2022-JP"},
+		{charset: "latin1", expected: "ISO-8859-1"},
+		{charset: "shift_jis", expected: "Shift_JIS"},
+		{charset: "klingon", err: true},
+		{charset: "utf-16", err: true},
+		{charset: "UTF-32BE", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.charset, func(t *testing.T) {
+			c, err := LookupCharset(tc.charset)
+			if tc.err {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("expected a validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Name != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, c.Name)
+			}
+		})
+	}
+}
+
+func TestCharset_Encode(t *testing.T) {
+	latin1, err := LookupCharset("iso-8859-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := latin1.Encode("Grüße")
+	if err != nil || got != "Gr\xfc\xdfe" {
+		t.Errorf("expected Latin-1 bytes, got %q (%v)", got, err)
+	}
+	if back, err := latin1.Decode(got); err != nil || back != "Grüße" {
+		t.Errorf("expected the text to round-trip, got %q (%v)", back, err)
+	}
+	if _, err := latin1.Encode("大会"); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected unrepresentable text to fail, got %v", err)
+	}
+
+	utf8, _ := LookupCharset("")
+	if got, err := utf8.Encode("大会"); err != nil || got != "大会" {
+		t.Errorf("expected UTF-8 text to be kept, got %q (%v)", got, err)
+	}
+}
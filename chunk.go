@@ -0,0 +1,102 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitRecipients splits e into copies with at most maxPerMessage recipients
+// each, for providers that limit the recipients of one message. Recipients
+// are taken To, CC then BCC, and stay in their field. e is returned alone if
+// it fits or maxPerMessage is not positive.
+//
+// The copies share everything but their recipients, including body readers,
+// so read those first with ReadBodies.
+func SplitRecipients(e Email, maxPerMessage int) []Email {
+	total := len(e.ToAddresses) + len(e.CCAddresses) + len(e.BCCAddresses)
+	if maxPerMessage <= 0 || total <= maxPerMessage {
+		return []Email{e}
+	}
+
+	var chunks []Email
+	chunk := e
+	chunk.ToAddresses, chunk.CCAddresses, chunk.BCCAddresses = nil, nil, nil
+	n := 0
+	for _, field := range []struct {
+		addrs []string
+		into  func(c *Email) *[]string
+	}{
+		{e.ToAddresses, func(c *Email) *[]string { return &c.ToAddresses }},
+		{e.CCAddresses, func(c *Email) *[]string { return &c.CCAddresses }},
+		{e.BCCAddresses, func(c *Email) *[]string { return &c.BCCAddresses }},
+	} {
+		for _, addr := range field.addrs {
+			if n == maxPerMessage {
+				chunks = append(chunks, chunk)
+				chunk.ToAddresses, chunk.CCAddresses, chunk.BCCAddresses = nil, nil, nil
+				n = 0
+			}
+			into := field.into(&chunk)
+			*into = append(*into, addr)
+			n++
+		}
+	}
+	return append(chunks, chunk)
+}
+
+// chunkRecipients returns the recipients of a chunk in the order
+// SplitRecipients took them.
+func chunkRecipients(e Email) []string {
+	recipients := make([]string, 0, len(e.ToAddresses)+len(e.CCAddresses)+len(e.BCCAddresses))
+	recipients = append(recipients, e.ToAddresses...)
+	recipients = append(recipients, e.CCAddresses...)
+	return append(recipients, e.BCCAddresses...)
+}
+
+// ChunkError is the failure to send one of the emails a send was split into
+// by SplitRecipients.
+type ChunkError struct {
+	// Position of the chunk, from 0.
+	Index int
+	// To, CC and BCC recipients of the chunk, in that order.
+	Recipients []string
+	Err        error
+}
+
+// NewChunkError returns the ChunkError for sending chunk, the index-th
+// email of a split send, failing with err.
+func NewChunkError(index int, chunk Email, err error) *ChunkError {
+	return &ChunkError{Index: index, Recipients: chunkRecipients(chunk), Err: err}
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (%d recipients): %s", e.Index, len(e.Recipients), e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkedSendError is returned when sending some of the emails a send was
+// split into failed. The others were sent.
+type ChunkedSendError struct {
+	// In chunk order.
+	Errors []*ChunkError
+	Chunks int
+}
+
+func (e *ChunkedSendError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to send %d of %d chunks: %s", len(e.Errors), e.Chunks, strings.Join(messages, "; "))
+}
+
+func (e *ChunkedSendError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func addresses(prefix string, n int) []string {
+	addrs := make([]string, n)
+	for i := range addrs {
+		addrs[i] = fmt.Sprintf("%s%d@example.com", prefix, i)
+	}
+	return addrs
+}
+
+func TestSplitRecipients(t *testing.T) {
+	e := Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  addresses("to", 3),
+		CCAddresses:  addresses("cc", 2),
+		BCCAddresses: addresses("bcc", 4),
+		Subject:      "Season opener",
+		TextBody:     "See you there",
+	}
+
+	tests := []struct {
+		name     string
+		max      int
+		expected [][3][]string
+	}{
+		{name: "fits", max: 9, expected: [][3][]string{{e.ToAddresses, e.CCAddresses, e.BCCAddresses}}},
+		{name: "no limit", max: 0, expected: [][3][]string{{e.ToAddresses, e.CCAddresses, e.BCCAddresses}}},
+		{
+			name: "split across fields",
+			max:  4,
+			expected: [][3][]string{
+				{e.ToAddresses, e.CCAddresses[:1], nil},
+				{nil, e.CCAddresses[1:], e.BCCAddresses[:3]},
+				{nil, nil, e.BCCAddresses[3:]},
+			},
+		},
+		{
+			name: "one each",
+			max:  1,
+			expected: func() [][3][]string {
+				var out [][3][]string
+				for _, addr := range e.ToAddresses {
+					out = append(out, [3][]string{{addr}, nil, nil})
+				}
+				for _, addr := range e.CCAddresses {
+					out = append(out, [3][]string{nil, {addr}, nil})
+				}
+				for _, addr := range e.BCCAddresses {
+					out = append(out, [3][]string{nil, nil, {addr}})
+				}
+				return out
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitRecipients(e, tt.max)
+			if len(chunks) != len(tt.expected) {
+				t.Fatalf("expected %d chunks, got %d", len(tt.expected), len(chunks))
+			}
+			for i, chunk := range chunks {
+				got := [3][]string{chunk.ToAddresses, chunk.CCAddresses, chunk.BCCAddresses}
+				if !reflect.DeepEqual(got, tt.expected[i]) {
+					t.Errorf("chunk %d: expected recipients %v, got %v", i, tt.expected[i], got)
+				}
+				if chunk.Subject != e.Subject || chunk.FromAddress != e.FromAddress {
+					t.Errorf("chunk %d: expected the rest of the email to be kept", i)
+				}
+			}
+		})
+	}
+
+	t.Run("does not alias", func(t *testing.T) {
+		chunks := SplitRecipients(e, 2)
+		chunks[0].ToAddresses[0] = "changed@example.com"
+		chunks[0].ToAddresses = append(chunks[0].ToAddresses, "added@example.com")
+		if e.ToAddresses[0] != "to0@example.com" || chunks[1].ToAddresses[0] != "to2@example.com" {
+			t.Error("expected chunks not to share recipient slices")
+		}
+	})
+}
+
+func TestChunkedSendError(t *testing.T) {
+	rateLimited := NewRateLimitedError("slow down", nil)
+	chunk := Email{ToAddresses: []string{"a@example.com"}, BCCAddresses: []string{"b@example.com"}}
+	err := &ChunkedSendError{Chunks: 3, Errors: []*ChunkError{NewChunkError(1, chunk, rateLimited)}}
+
+	if want := "failed to send 1 of 3 chunks: chunk 1 (2 recipients): " + rateLimited.Error(); err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	if got := err.Errors[0].Recipients; !reflect.DeepEqual(got, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("expected the chunk's recipients, got %v", got)
+	}
+
+	var emailErr *Error
+	if !errors.As(err, &emailErr) || emailErr != rateLimited {
+		t.Errorf("expected the chunk's error to be unwrapped, got %v", emailErr)
+	}
+}
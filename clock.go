@@ -0,0 +1,75 @@
+package email
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Clock is the source of time for every time-dependent component in this
+// module, so tests can control them all with emailtest.FakeClock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	// Sleep waits for d to pass, returning early with the context's error if
+	// ctx is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer has
+	// already fired or been stopped.
+	Stop() bool
+}
+
+// Jitter randomizes a delay, typically between retries, and returns a
+// duration in [0, d].
+type Jitter func(d time.Duration) time.Duration
+
+// FullJitter returns a uniformly random duration in [0, d].
+func FullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// SystemClock returns the Clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (s systemTimer) C() <-chan time.Time {
+	return s.t.C
+}
+
+func (s systemTimer) Stop() bool {
+	return s.t.Stop()
+}
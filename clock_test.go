@@ -0,0 +1,32 @@
+package email
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFullJitter(t *testing.T) {
+	for range 100 {
+		if d := FullJitter(time.Second); d < 0 || d > time.Second {
+			t.Fatalf("expected jitter within [0, 1s], got %s", d)
+		}
+	}
+	if d := FullJitter(0); d != 0 {
+		t.Errorf("expected no jitter for a zero delay, got %s", d)
+	}
+}
+
+func TestSystemClock_Sleep(t *testing.T) {
+	c := SystemClock()
+
+	if err := c.Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Sleep(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package email
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of e, so the copy can be changed, e.g. its
+// recipients set per send, while e is used concurrently. The body readers
+// can only be read once and are shared, not copied.
+func (e Email) Clone() Email {
+	e.ToAddresses = slices.Clone(e.ToAddresses)
+	e.CCAddresses = slices.Clone(e.CCAddresses)
+	e.BCCAddresses = slices.Clone(e.BCCAddresses)
+	e.ReplyToAddresses = slices.Clone(e.ReplyToAddresses)
+	e.AlternateAddresses = slices.Clone(e.AlternateAddresses)
+	e.Headers = slices.Clone(e.Headers)
+	e.Tags = maps.Clone(e.Tags)
+
+	if e.Attachments != nil {
+		attachments := make([]Attachment, len(e.Attachments))
+		for i, a := range e.Attachments {
+			a.Content = bytes.Clone(a.Content)
+			attachments[i] = a
+		}
+		e.Attachments = attachments
+	}
+	return e
+}
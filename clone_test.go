@@ -0,0 +1,101 @@
+package email
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func cloneFixture() Email {
+	return Email{
+		FromAddress:        "events@icaa.org",
+		ToAddresses:        []string{"ada@example.com"},
+		CCAddresses:        []string{"coach@example.com"},
+		BCCAddresses:       []string{"records@icaa.org"},
+		ReplyToAddresses:   []string{"help@icaa.org"},
+		AlternateAddresses: []string{"ada@work.example.com"},
+		Subject:            "Results",
+		TextBody:           "Hello",
+		Attachments:        []Attachment{{FileName: "results.csv", Content: []byte("name,score")}},
+		Headers:            []Header{{Name: "X-League", Value: "Juniors"}},
+		Tags:               map[string]string{"env": "prod"},
+	}
+}
+
+func TestClone(t *testing.T) {
+	original := cloneFixture()
+	clone := original.Clone()
+
+	if !reflect.DeepEqual(clone, original) {
+		t.Fatalf("expected an equal copy, got %+v", clone)
+	}
+
+	clone.ToAddresses[0] = "grace@example.com"
+	clone.CCAddresses[0] = "grace@example.com"
+	clone.BCCAddresses[0] = "grace@example.com"
+	clone.ReplyToAddresses[0] = "grace@example.com"
+	clone.AlternateAddresses[0] = "grace@example.com"
+	clone.Attachments[0].FileName = "other.csv"
+	clone.Attachments[0].Content[0] = 'N'
+	clone.Headers[0].Value = "Seniors"
+	clone.Tags["env"] = "dev"
+
+	if !reflect.DeepEqual(original, cloneFixture()) {
+		t.Errorf("expected changes to the clone to leave the original alone, got %+v", original)
+	}
+}
+
+// Catches new slice and map fields that Clone doesn't copy.
+func TestClone_CopiesEveryReferenceField(t *testing.T) {
+	original := cloneFixture()
+	clone := original.Clone()
+
+	o, c := reflect.ValueOf(original), reflect.ValueOf(clone)
+	for i := range o.NumField() {
+		field := o.Type().Field(i)
+		switch field.Type.Kind() {
+		case reflect.Slice, reflect.Map:
+		default:
+			continue
+		}
+		if o.Field(i).Len() == 0 {
+			t.Errorf("cloneFixture leaves %s empty", field.Name)
+			continue
+		}
+		if o.Field(i).UnsafePointer() == c.Field(i).UnsafePointer() {
+			t.Errorf("expected Clone to copy %s", field.Name)
+		}
+	}
+}
+
+func TestClone_Nil(t *testing.T) {
+	clone := Email{}.Clone()
+	if !reflect.DeepEqual(clone, Email{}) {
+		t.Errorf("expected nil fields to stay nil, got %+v", clone)
+	}
+}
+
+func TestClone_ConcurrentRecipients(t *testing.T) {
+	base := cloneFixture()
+	base.ToAddresses = make([]string, 1, 10)
+
+	var wg sync.WaitGroup
+	for _, addr := range []string{"ada@example.com", "grace@example.com", "hedy@example.com"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := base.Clone()
+			e.ToAddresses[0] = addr
+			e.ToAddresses = append(e.ToAddresses, "archive@icaa.org")
+			e.Tags["recipient"] = addr
+			if e.ToAddresses[0] != addr {
+				t.Errorf("expected %s, got %s", addr, e.ToAddresses[0])
+			}
+		}()
+	}
+	wg.Wait()
+
+	if base.ToAddresses[0] != "" || len(base.Tags) != 1 {
+		t.Errorf("expected the base email to be left alone, got %v %v", base.ToAddresses, base.Tags)
+	}
+}
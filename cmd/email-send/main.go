@@ -0,0 +1,103 @@
+// Command email-send sends a one-off email through Gmail or SES, with the
+// same validation as production sends.
+//
+//	email-send -provider ses -from events@icaa.org -to ada@example.com \
+//		-subject "Range closed" -text notice.txt
+//
+// Credentials come from the environment:
+//
+//	gmail: GMAIL_CREDENTIALS_FILE (service account JSON) and GMAIL_USER
+//	ses:   AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+//	       optionally AWS_SESSION_TOKEN
+//
+// The result or error is printed as JSON; the exit codes are listed in
+// package sendcmd.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/awsses"
+	"github.com/International-Combat-Archery-Alliance/email/cmd/email-send/sendcmd"
+	"github.com/International-Combat-Archery-Alliance/email/gmail"
+)
+
+// listFlag collects a flag given several times or as a comma separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+func main() {
+	var opts sendcmd.Options
+	var to, cc, bcc, replyTo, attachments listFlag
+	provider := flag.String("provider", "", "provider to send with: gmail or ses")
+	flag.StringVar(&opts.From, "from", "", "From address")
+	flag.Var(&to, "to", "To address; repeat or separate with commas")
+	flag.Var(&cc, "cc", "CC address; repeat or separate with commas")
+	flag.Var(&bcc, "bcc", "BCC address; repeat or separate with commas")
+	flag.Var(&replyTo, "reply-to", "Reply-To address; repeat or separate with commas")
+	flag.StringVar(&opts.Subject, "subject", "", "subject")
+	flag.StringVar(&opts.TextFile, "text", "", "file with the text body, or - for stdin")
+	flag.StringVar(&opts.HTMLFile, "html", "", "file with the HTML body, or - for stdin")
+	flag.Var(&attachments, "attach", "file to attach; repeat or separate with commas")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "validate and build the email without sending it")
+	flag.Parse()
+
+	opts.To, opts.CC, opts.BCC, opts.ReplyTo, opts.Attachments = to, cc, bcc, replyTo, attachments
+
+	ctx := context.Background()
+	sender, err := newSender(ctx, *provider)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(sendcmd.EXIT_USAGE)
+	}
+
+	os.Exit(sendcmd.Run(ctx, sender, opts, os.Stdin, os.Stdout, os.Stderr))
+}
+
+func newSender(ctx context.Context, provider string) (email.SenderV2, error) {
+	switch provider {
+	case "gmail":
+		credentials, err := os.ReadFile(os.Getenv("GMAIL_CREDENTIALS_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read GMAIL_CREDENTIALS_FILE: %v", err)
+		}
+		return gmail.NewGmailSender(ctx, credentials, os.Getenv("GMAIL_USER"))
+	case "ses":
+		client := sesv2.New(sesv2.Options{
+			Region: os.Getenv("AWS_REGION"),
+			Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{
+					AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+					SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+					SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+					Source:          "environment",
+				}, nil
+			}),
+		})
+		return awsses.New(client)
+	case "smtp":
+		return nil, fmt.Errorf("the smtp provider is not available in this module")
+	default:
+		return nil, fmt.Errorf("unknown provider %q, expected gmail or ses", provider)
+	}
+}
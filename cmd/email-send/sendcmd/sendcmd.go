@@ -0,0 +1,172 @@
+// Package sendcmd is the core of the email-send command: it composes an
+// email from files and sends it with any SenderV2, reporting the outcome as
+// JSON and an exit code.
+package sendcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk"
+)
+
+// Exit codes, grouped by the class of the error's reason.
+const (
+	EXIT_OK = 0
+	// Errors without a reason and REASON_UNKNOWN.
+	EXIT_UNKNOWN = 1
+	// The command line itself is wrong.
+	EXIT_USAGE = 2
+	// REASON_VALIDATION_ERROR, REASON_INVALID_EMAIL and
+	// REASON_MESSAGE_TOO_LARGE: fix the email.
+	EXIT_INVALID = 3
+	// REASON_MESSAGE_REJECTED and REASON_UNVERIFIED_DOMAIN: the provider
+	// refused the email.
+	EXIT_REJECTED = 4
+	// Retryable reasons: try again later.
+	EXIT_RETRYABLE = 5
+)
+
+// Stdin names standard input in place of a file.
+const Stdin = "-"
+
+type Options struct {
+	From    string
+	To      []string
+	CC      []string
+	BCC     []string
+	ReplyTo []string
+	Subject string
+	// Files holding the bodies; either may be Stdin.
+	TextFile string
+	HTMLFile string
+	// Files to attach, with content types guessed from their extensions.
+	Attachments []string
+	DryRun      bool
+}
+
+// Run sends the email described by opts with sender. It prints the
+// SendResult to stdout, or the error to stderr, as JSON and returns the exit
+// code for the outcome.
+func Run(ctx context.Context, sender email.SenderV2, opts Options, stdin io.Reader, stdout, stderr io.Writer) int {
+	e, err := compose(opts, stdin)
+	if err == nil {
+		err = providersdk.Validate(e)
+	}
+
+	var result *email.SendResult
+	if err == nil {
+		result, err = sender.SendEmailV2(ctx, e, &email.SendOptions{DryRun: opts.DryRun})
+	}
+
+	if err != nil {
+		writeJSON(stderr, asEmailError(err))
+		return ExitCode(err)
+	}
+
+	writeJSON(stdout, result)
+	return EXIT_OK
+}
+
+// ExitCode maps err to the exit code for the class of its reason.
+func ExitCode(err error) int {
+	if err == nil {
+		return EXIT_OK
+	}
+
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) {
+		return EXIT_UNKNOWN
+	}
+
+	switch {
+	case emailErr.Reason == email.REASON_VALIDATION_ERROR, emailErr.Reason == email.REASON_INVALID_EMAIL,
+		emailErr.Reason == email.REASON_MESSAGE_TOO_LARGE:
+		return EXIT_INVALID
+	case emailErr.Reason == email.REASON_MESSAGE_REJECTED, emailErr.Reason == email.REASON_UNVERIFIED_DOMAIN:
+		return EXIT_REJECTED
+	case emailErr.Reason.Retryable():
+		return EXIT_RETRYABLE
+	default:
+		return EXIT_UNKNOWN
+	}
+}
+
+func compose(opts Options, stdin io.Reader) (email.Email, error) {
+	if opts.TextFile == Stdin && opts.HTMLFile == Stdin {
+		return email.Email{}, email.NewValidationError("only one body can be read from stdin", nil)
+	}
+
+	e := email.Email{
+		FromAddress:      opts.From,
+		ToAddresses:      opts.To,
+		CCAddresses:      opts.CC,
+		BCCAddresses:     opts.BCC,
+		ReplyToAddresses: opts.ReplyTo,
+		Subject:          opts.Subject,
+	}
+
+	var err error
+	if e.TextBody, err = readBody(opts.TextFile, stdin); err != nil {
+		return email.Email{}, err
+	}
+	if e.HTMLBody, err = readBody(opts.HTMLFile, stdin); err != nil {
+		return email.Email{}, err
+	}
+
+	for _, path := range opts.Attachments {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return email.Email{}, email.NewValidationError(fmt.Sprintf("failed to read attachment %s", path), err)
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		e.Attachments = append(e.Attachments, email.Attachment{
+			FileName:    filepath.Base(path),
+			Content:     content,
+			ContentType: contentType,
+		})
+	}
+
+	return e, nil
+}
+
+func readBody(path string, stdin io.Reader) (string, error) {
+	var body []byte
+	var err error
+	switch path {
+	case "":
+		return "", nil
+	case Stdin:
+		body, err = io.ReadAll(stdin)
+	default:
+		body, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", email.NewValidationError(fmt.Sprintf("failed to read body from %s", path), err)
+	}
+	return string(body), nil
+}
+
+func asEmailError(err error) *email.Error {
+	var emailErr *email.Error
+	if errors.As(err, &emailErr) {
+		return emailErr
+	}
+	return email.NewUnknownError(err.Error(), err)
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
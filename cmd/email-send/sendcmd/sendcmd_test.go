@@ -0,0 +1,121 @@
+package sendcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	htmlFile := write("notice.html", "<p>The range is closed.</p>")
+	pdf := write("map.pdf", "%PDF-1.4")
+
+	valid := func() Options {
+		return Options{
+			From:     "events@icaa.example.com",
+			To:       []string{"ada@example.com", "bo@example.com"},
+			Subject:  "Range closed",
+			TextFile: Stdin,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(o *Options)
+		sendErr  error
+		wantCode int
+		wantSent int
+	}{
+		{name: "send", modify: func(o *Options) {}, wantCode: EXIT_OK, wantSent: 1},
+		{name: "html and attachment", modify: func(o *Options) { o.HTMLFile = htmlFile; o.Attachments = []string{pdf} }, wantCode: EXIT_OK, wantSent: 1},
+		{name: "dry run", modify: func(o *Options) { o.DryRun = true }, wantCode: EXIT_OK},
+		{name: "invalid address", modify: func(o *Options) { o.To = []string{"nope"} }, wantCode: EXIT_INVALID},
+		{name: "missing subject", modify: func(o *Options) { o.Subject = "" }, wantCode: EXIT_INVALID},
+		{name: "missing attachment", modify: func(o *Options) { o.Attachments = []string{filepath.Join(dir, "nope.pdf")} }, wantCode: EXIT_INVALID},
+		{name: "both bodies from stdin", modify: func(o *Options) { o.HTMLFile = Stdin }, wantCode: EXIT_INVALID},
+		{name: "rejected", modify: func(o *Options) {}, sendErr: email.NewMessageRejectedError("spam", nil), wantCode: EXIT_REJECTED},
+		{name: "rate limited", modify: func(o *Options) {}, sendErr: email.NewRateLimitedError("slow down", nil), wantCode: EXIT_RETRYABLE},
+		{name: "too large", modify: func(o *Options) {}, sendErr: email.NewMessageTooLargeError("too large", 50, 40), wantCode: EXIT_INVALID},
+		{name: "plain error", modify: func(o *Options) {}, sendErr: errors.New("boom"), wantCode: EXIT_UNKNOWN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := valid()
+			tt.modify(&opts)
+			sender := &emailtest.CaptureSender{Err: tt.sendErr}
+			var stdout, stderr bytes.Buffer
+
+			code := Run(context.Background(), sender, opts, strings.NewReader("The range is closed."), &stdout, &stderr)
+
+			if code != tt.wantCode {
+				t.Fatalf("expected exit code %d, got %d: %s", tt.wantCode, code, stderr.String())
+			}
+			if len(sender.Sent()) != tt.wantSent {
+				t.Errorf("expected %d sends, got %d", tt.wantSent, len(sender.Sent()))
+			}
+
+			if code != EXIT_OK {
+				var emailErr email.Error
+				if err := json.Unmarshal(stderr.Bytes(), &emailErr); err != nil || emailErr.Reason == "" {
+					t.Errorf("expected a JSON error on stderr, got %q", stderr.String())
+				}
+				return
+			}
+
+			var result email.SendResult
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("expected a JSON result on stdout, got %q", stdout.String())
+			}
+			if result.DryRun != opts.DryRun {
+				t.Errorf("expected DryRun %v, got %v", opts.DryRun, result.DryRun)
+			}
+		})
+	}
+}
+
+func TestRun_ComposesEmail(t *testing.T) {
+	dir := t.TempDir()
+	htmlFile := filepath.Join(dir, "notice.html")
+	pdf := filepath.Join(dir, "map.pdf")
+	os.WriteFile(htmlFile, []byte("<p>Closed.</p>"), 0o600)
+	os.WriteFile(pdf, []byte("%PDF-1.4"), 0o600)
+	sender := &emailtest.CaptureSender{}
+
+	code := Run(context.Background(), sender, Options{
+		From:        "events@icaa.example.com",
+		To:          []string{"ada@example.com"},
+		BCC:         []string{"log@icaa.example.com"},
+		Subject:     "Range closed",
+		TextFile:    Stdin,
+		HTMLFile:    htmlFile,
+		Attachments: []string{pdf},
+	}, strings.NewReader("Closed."), &bytes.Buffer{}, &bytes.Buffer{})
+	if code != EXIT_OK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+
+	e := sender.Sent()[0]
+	if e.TextBody != "Closed." || e.HTMLBody != "<p>Closed.</p>" || e.BCCAddresses[0] != "log@icaa.example.com" {
+		t.Errorf("unexpected email %+v", e)
+	}
+	if a := e.Attachments[0]; a.FileName != "map.pdf" || a.ContentType != "application/pdf" || string(a.Content) != "%PDF-1.4" {
+		t.Errorf("unexpected attachment %+v", a)
+	}
+}
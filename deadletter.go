@@ -0,0 +1,382 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an email whose send failed permanently, kept so it can be
+// inspected and replayed with Replay once the cause is fixed.
+type DeadLetter struct {
+	ID    string
+	Email Email
+	// The error of the last attempt.
+	Err            *Error
+	Attempts       []DeadLetterAttempt
+	IdempotencyKey string
+	CampaignID     string
+	SequenceStep   int
+	CreatedAt      time.Time
+}
+
+type DeadLetterAttempt struct {
+	Time    time.Time
+	Reason  ErrorReason
+	Message string
+}
+
+// DeadLetterStore keeps dead letters. Put replaces a dead letter with the
+// same ID.
+type DeadLetterStore interface {
+	Put(ctx context.Context, dl DeadLetter) error
+	List(ctx context.Context) ([]DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+}
+
+var _ DeadLetterStore = &FileDeadLetterStore{}
+
+// FileDeadLetterStore keeps dead letters in a JSON Lines file, one record
+// per line. Put appends, so the file holds the history of each dead letter;
+// List returns the latest record of each. It is safe for concurrent use
+// within a process.
+type FileDeadLetterStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileDeadLetterStore(path string) *FileDeadLetterStore {
+	return &FileDeadLetterStore{path: path}
+}
+
+func (s *FileDeadLetterStore) Put(ctx context.Context, dl DeadLetter) error {
+	line, err := json.Marshal(dl)
+	if err != nil {
+		return NewValidationError(fmt.Sprintf("failed to encode dead letter %s", dl.ID), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return NewServiceError("failed to open dead letter file", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return NewServiceError("failed to write dead letter", err)
+	}
+	return nil
+}
+
+func (s *FileDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list()
+}
+
+func (s *FileDeadLetterStore) list() ([]DeadLetter, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewServiceError("failed to read dead letter file", err)
+	}
+
+	var all []DeadLetter
+	index := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			return nil, NewServiceError("dead letter file is corrupt", err)
+		}
+		if i, ok := index[dl.ID]; ok {
+			all[i] = dl
+			continue
+		}
+		index[dl.ID] = len(all)
+		all = append(all, dl)
+	}
+	return all, nil
+}
+
+// Delete rewrites the file without the records of the dead letter.
+func (s *FileDeadLetterStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, dl := range all {
+		if dl.ID == id {
+			continue
+		}
+		line, err := json.Marshal(dl)
+		if err != nil {
+			return NewValidationError(fmt.Sprintf("failed to encode dead letter %s", dl.ID), err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return NewServiceError("failed to rewrite dead letter file", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return NewServiceError("failed to rewrite dead letter file", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return NewServiceError("failed to rewrite dead letter file", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return NewServiceError("failed to rewrite dead letter file", err)
+	}
+	return nil
+}
+
+type DeadLetterOption func(*DeadLetterSender)
+
+// WithDeadLetterClock sets the Clock used to timestamp dead letters.
+func WithDeadLetterClock(c Clock) DeadLetterOption {
+	return func(s *DeadLetterSender) {
+		s.clock = c
+	}
+}
+
+var _ Sender = &DeadLetterSender{}
+var _ SenderV2 = &DeadLetterSender{}
+
+// DeadLetterSender decorates a SenderV2 to put emails that fail with a
+// reason that is not retryable into a DeadLetterStore. Retryable failures
+// are left to the caller. When a send split into chunks fails in part, only
+// the recipients of the failed chunks are dead-lettered, one dead letter per
+// chunk. The result and error of the send are returned either way.
+type DeadLetterSender struct {
+	inner SenderV2
+	store DeadLetterStore
+	clock Clock
+}
+
+func NewDeadLetterSender(inner SenderV2, store DeadLetterStore, opts ...DeadLetterOption) *DeadLetterSender {
+	s := &DeadLetterSender{
+		inner: inner,
+		store: store,
+		clock: SystemClock(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *DeadLetterSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *DeadLetterSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *DeadLetterSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+	// Reader bodies are consumed by the send and not serialized, so they
+	// are read first for the dead letter to keep them.
+	e, err = ReadBodies(e)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.inner.SendEmailV2(ctx, e, wrapped)
+	if err == nil {
+		return result, nil
+	}
+
+	// Only the recipients of the chunks that failed are dead-lettered, so a
+	// replay does not send the others the email again.
+	var chunked *ChunkedSendError
+	if errors.As(err, &chunked) {
+		var storeErrs []error
+		for _, chunkErr := range chunked.Errors {
+			failed := withRecipients(e, chunkErr.Recipients)
+			if chunkErr.Index > 0 {
+				// Chunks after the first were sent with a Message-ID of
+				// their own, so the replay gets a new one as well.
+				failed.MessageID = ""
+			}
+			if storeErr := s.put(ctx, failed, chunkErr.Err, wrapped); storeErr != nil {
+				storeErrs = append(storeErrs, storeErr)
+			}
+		}
+		if len(storeErrs) > 0 {
+			return result, errors.Join(append([]error{err}, storeErrs...)...)
+		}
+		return result, err
+	}
+
+	if storeErr := s.put(ctx, e, err, wrapped); storeErr != nil {
+		return result, errors.Join(err, storeErr)
+	}
+	return result, err
+}
+
+// put stores e as a dead letter if err is not retryable.
+func (s *DeadLetterSender) put(ctx context.Context, e Email, err error, opts *SendOptions) error {
+	if retryable(err) {
+		return nil
+	}
+
+	emailErr := asError(err)
+	now := s.clock.Now()
+	return s.store.Put(ctx, DeadLetter{
+		ID:             rand.Text(),
+		Email:          e,
+		Err:            emailErr,
+		Attempts:       []DeadLetterAttempt{{Time: now, Reason: emailErr.Reason, Message: emailErr.Message}},
+		IdempotencyKey: opts.IdempotencyKey,
+		CampaignID:     e.CampaignID,
+		SequenceStep:   e.SequenceStep,
+		CreatedAt:      now,
+	})
+}
+
+// withRecipients returns e with only the To, CC and BCC recipients in
+// recipients, which are in the order chunkRecipients returns them.
+func withRecipients(e Email, recipients []string) Email {
+	remaining := map[string]int{}
+	for _, r := range recipients {
+		remaining[r]++
+	}
+	keep := func(addrs []string) []string {
+		var kept []string
+		for _, addr := range addrs {
+			if remaining[addr] > 0 {
+				remaining[addr]--
+				kept = append(kept, addr)
+			}
+		}
+		return kept
+	}
+	e.ToAddresses = keep(e.ToAddresses)
+	e.CCAddresses = keep(e.CCAddresses)
+	e.BCCAddresses = keep(e.BCCAddresses)
+	return e
+}
+
+type replayOptions struct {
+	clock Clock
+}
+
+type ReplayOption func(*replayOptions)
+
+// WithReplayClock sets the Clock used to timestamp failed replay attempts.
+func WithReplayClock(c Clock) ReplayOption {
+	return func(r *replayOptions) {
+		r.clock = c
+	}
+}
+
+// ReplayOutcome is the result of replaying one dead letter.
+type ReplayOutcome struct {
+	ID     string
+	Result *SendResult
+	Err    error
+}
+
+// Replay sends the dead letters in store selected by filter, or all of them
+// if filter is nil, through sender, which validates them again. Each is sent
+// with a fresh idempotency key, so duplicate suppression keyed on the failed
+// send does not swallow it. Dead letters that are sent are deleted; those
+// that fail again get the attempt added. Only errors of the store itself are
+// returned; send failures are in the outcomes.
+func Replay(ctx context.Context, store DeadLetterStore, filter func(DeadLetter) bool, sender SenderV2, opts ...ReplayOption) ([]ReplayOutcome, error) {
+	r := replayOptions{clock: SystemClock()}
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	all, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []ReplayOutcome
+	for _, dl := range all {
+		if filter != nil && !filter(dl) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return outcomes, err
+		}
+
+		key := "replay-" + dl.ID + "-" + rand.Text()
+		result, sendErr := sender.SendEmailV2(ctx, dl.Email, &SendOptions{IdempotencyKey: key})
+		outcomes = append(outcomes, ReplayOutcome{ID: dl.ID, Result: result, Err: sendErr})
+
+		if sendErr == nil {
+			if err := store.Delete(ctx, dl.ID); err != nil {
+				return outcomes, err
+			}
+			continue
+		}
+
+		emailErr := asError(sendErr)
+		dl.Err = emailErr
+		dl.Attempts = append(dl.Attempts, DeadLetterAttempt{Time: r.clock.Now(), Reason: emailErr.Reason, Message: emailErr.Message})
+		if err := store.Put(ctx, dl); err != nil {
+			return outcomes, err
+		}
+	}
+	return outcomes, nil
+}
+
+// retryable reports whether err has a retryable reason. An error joining
+// several, e.g. with errors.Join, is retryable if any of them is, whatever
+// their order.
+func retryable(err error) bool {
+	if emailErr, ok := err.(*Error); ok {
+		return emailErr.Reason.Retryable()
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		return slices.ContainsFunc(u.Unwrap(), retryable)
+	case interface{ Unwrap() error }:
+		return retryable(u.Unwrap())
+	}
+	return false
+}
+
+// asError returns err as an *Error, wrapping errors without a reason as
+// REASON_UNKNOWN.
+func asError(err error) *Error {
+	var emailErr *Error
+	if errors.As(err, &emailErr) {
+		return emailErr
+	}
+	return NewUnknownError(err.Error(), err)
+}
@@ -0,0 +1,240 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+)
+
+// flakySenderV2 fails with err until it is cleared, recording the options of
+// every call.
+type flakySenderV2 struct {
+	err  error
+	sent []email.Email
+	keys []string
+}
+
+func (s *flakySenderV2) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	s.keys = append(s.keys, opts.IdempotencyKey)
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.sent = append(s.sent, e)
+	return &email.SendResult{Provider: "flaky"}, nil
+}
+
+func TestDeadLetterSender_ReplayAfterRecovery(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := emailtest.NewFakeClock(start)
+	store := email.NewFileDeadLetterStore(filepath.Join(t.TempDir(), "dead.jsonl"))
+	inner := &flakySenderV2{err: email.NewUnverifiedDomainError("sender domain not verified", nil)}
+	sender := email.NewDeadLetterSender(inner, store, email.WithDeadLetterClock(clock))
+
+	e := email.Email{
+		FromAddress:  "events@icaa.example.com",
+		ToAddresses:  []string{"ada@example.com"},
+		Subject:      "Spring tournament",
+		TextBody:     "Registration is open.",
+		Attachments:  []email.Attachment{{FileName: "schedule.pdf", Content: []byte("%PDF"), ContentType: "application/pdf"}},
+		CampaignID:   "spring",
+		SequenceStep: 1,
+	}
+
+	_, err := sender.SendEmailV2(ctx, e, &email.SendOptions{IdempotencyKey: "registration-42"})
+	if !errors.Is(err, email.ErrUnverifiedDomain) {
+		t.Fatalf("expected the send error, got %v", err)
+	}
+
+	letters, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	dl := letters[0]
+	if dl.ID == "" || dl.IdempotencyKey != "registration-42" || dl.CampaignID != "spring" || dl.SequenceStep != 1 || !dl.CreatedAt.Equal(start) {
+		t.Errorf("unexpected dead letter %+v", dl)
+	}
+	if dl.Err == nil || dl.Err.Reason != email.REASON_UNVERIFIED_DOMAIN || !errors.Is(dl.Err, email.ErrUnverifiedDomain) {
+		t.Errorf("unexpected error %v", dl.Err)
+	}
+	if len(dl.Attempts) != 1 || dl.Attempts[0].Reason != email.REASON_UNVERIFIED_DOMAIN || !dl.Attempts[0].Time.Equal(start) {
+		t.Errorf("unexpected attempts %+v", dl.Attempts)
+	}
+	if dl.Email.Subject != e.Subject || string(dl.Email.Attachments[0].Content) != "%PDF" {
+		t.Errorf("email did not round-trip: %+v", dl.Email)
+	}
+
+	t.Run("failing replay adds an attempt", func(t *testing.T) {
+		clock.Advance(time.Hour)
+
+		outcomes, err := email.Replay(ctx, store, nil, inner, email.WithReplayClock(clock))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(outcomes) != 1 || !errors.Is(outcomes[0].Err, email.ErrUnverifiedDomain) {
+			t.Fatalf("unexpected outcomes %+v", outcomes)
+		}
+
+		letters, _ := store.List(ctx)
+		if len(letters) != 1 || len(letters[0].Attempts) != 2 || !letters[0].Attempts[1].Time.Equal(start.Add(time.Hour)) {
+			t.Errorf("expected a second attempt, got %+v", letters)
+		}
+	})
+
+	t.Run("replay after recovery", func(t *testing.T) {
+		inner.err = nil
+
+		outcomes, err := email.Replay(ctx, store, func(dl email.DeadLetter) bool { return dl.CampaignID == "spring" }, inner)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(outcomes) != 1 || outcomes[0].Err != nil || outcomes[0].Result.Provider != "flaky" {
+			t.Fatalf("unexpected outcomes %+v", outcomes)
+		}
+		if len(inner.sent) != 1 || inner.sent[0].Subject != e.Subject {
+			t.Errorf("expected the email to be sent, got %v", inner.sent)
+		}
+
+		replayKey := inner.keys[len(inner.keys)-1]
+		if replayKey == "registration-42" || !strings.HasPrefix(replayKey, "replay-"+dl.ID) || replayKey == inner.keys[1] {
+			t.Errorf("expected a fresh idempotency key, got %q after %v", replayKey, inner.keys)
+		}
+
+		if letters, _ := store.List(ctx); len(letters) != 0 {
+			t.Errorf("expected the dead letter to be deleted, got %+v", letters)
+		}
+	})
+}
+
+func TestDeadLetterSender_RetryableFailuresAreNotKept(t *testing.T) {
+	store := email.NewFileDeadLetterStore(filepath.Join(t.TempDir(), "dead.jsonl"))
+	inner := &flakySenderV2{err: email.NewRateLimitedError("slow down", nil)}
+
+	err := email.NewDeadLetterSender(inner, store).SendEmail(context.Background(), email.Email{Subject: "Hi"})
+	if !errors.Is(err, email.ErrRateLimited) {
+		t.Fatalf("expected the send error, got %v", err)
+	}
+
+	if letters, _ := store.List(context.Background()); len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", letters)
+	}
+}
+
+func TestDeadLetterSender_JoinedErrors(t *testing.T) {
+	permanent := email.NewUnverifiedDomainError("sender domain not verified", nil)
+	retryable := email.NewRateLimitedError("slow down", nil)
+
+	tests := []struct {
+		name string
+		err  error
+		kept bool
+	}{
+		{"retryable last", errors.Join(permanent, retryable), false},
+		{"retryable first", errors.Join(retryable, permanent), false},
+		{"wrapped retryable", errors.Join(permanent, fmt.Errorf("store: %w", retryable)), false},
+		{"all permanent", errors.Join(permanent, errors.New("disk full")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := email.NewFileDeadLetterStore(filepath.Join(t.TempDir(), "dead.jsonl"))
+			inner := &flakySenderV2{err: tt.err}
+
+			err := email.NewDeadLetterSender(inner, store).SendEmail(context.Background(), email.Email{Subject: "Hi"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected the send error, got %v", err)
+			}
+
+			letters, _ := store.List(context.Background())
+			if kept := len(letters) == 1; kept != tt.kept {
+				t.Errorf("expected kept %v, got %d dead letters", tt.kept, len(letters))
+			}
+		})
+	}
+}
+
+func TestFileDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	store := email.NewFileDeadLetterStore(path)
+
+	if letters, err := store.List(ctx); err != nil || letters != nil {
+		t.Fatalf("expected an empty store, got %v, %v", letters, err)
+	}
+
+	for _, dl := range []email.DeadLetter{
+		{ID: "a", Email: email.Email{Subject: "first"}},
+		{ID: "b", Email: email.Email{Subject: "second"}},
+		{ID: "a", Email: email.Email{Subject: "first, updated"}},
+	} {
+		if err := store.Put(ctx, dl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	letters, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 2 || letters[0].Email.Subject != "first, updated" || letters[1].Email.Subject != "second" {
+		t.Errorf("expected the latest record of each, in order, got %+v", letters)
+	}
+
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), `"ID":"b"`) {
+		t.Errorf("expected only b to be left, got %s", data)
+	}
+
+	if err := os.WriteFile(path, []byte("not json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.List(ctx); !errors.Is(err, email.ErrServiceError) {
+		t.Errorf("expected a service error for a corrupt file, got %v", err)
+	}
+}
+
+func TestDeadLetterSender_ReaderBodies(t *testing.T) {
+	ctx := context.Background()
+	store := email.NewFileDeadLetterStore(filepath.Join(t.TempDir(), "dead.jsonl"))
+	inner := &flakySenderV2{err: email.NewUnverifiedDomainError("sender domain not verified", nil)}
+	sender := email.NewDeadLetterSender(inner, store)
+
+	e := email.Email{
+		FromAddress:    "events@icaa.example.com",
+		ToAddresses:    []string{"ada@example.com"},
+		Subject:        "Spring tournament",
+		HTMLBodyReader: strings.NewReader("<p>Registration is open.</p>"),
+		TextBodyReader: strings.NewReader("Registration is open."),
+	}
+	if err := sender.SendEmail(ctx, e); !errors.Is(err, email.ErrUnverifiedDomain) {
+		t.Fatalf("expected the send error, got %v", err)
+	}
+
+	letters, err := store.List(ctx)
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d (%v)", len(letters), err)
+	}
+	if got := letters[0].Email; got.HTMLBody != "<p>Registration is open.</p>" || got.TextBody != "Registration is open." {
+		t.Fatalf("expected the bodies to be kept, got %q and %q", got.HTMLBody, got.TextBody)
+	}
+
+	inner.err = nil
+	outcomes, err := email.Replay(ctx, store, nil, inner)
+	if err != nil || len(outcomes) != 1 || outcomes[0].Err != nil {
+		t.Fatalf("expected the replay to succeed, got %+v (%v)", outcomes, err)
+	}
+}
@@ -0,0 +1,68 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var defaultSender struct {
+	mu     sync.Mutex
+	sender Sender
+	// Set once the default sender has been handed out, after which it can
+	// only be swapped with ReplaceDefaultSender.
+	used bool
+}
+
+// SetDefaultSender registers the Sender used by Send. It fails once the
+// current default has been used, so a misbehaving package can't silently
+// redirect mail that other code already relies on.
+func SetDefaultSender(s Sender) error {
+	if s == nil {
+		return NewValidationError("default sender must not be nil", nil)
+	}
+
+	defaultSender.mu.Lock()
+	defer defaultSender.mu.Unlock()
+
+	if defaultSender.used {
+		return NewValidationError("default sender is already in use and cannot be changed", nil)
+	}
+
+	defaultSender.sender = s
+	return nil
+}
+
+// ReplaceDefaultSender registers s as the default Sender even if the current
+// one is already in use. It is intended for tests; passing nil unsets it.
+func ReplaceDefaultSender(s Sender) {
+	defaultSender.mu.Lock()
+	defer defaultSender.mu.Unlock()
+
+	defaultSender.sender = s
+	defaultSender.used = false
+}
+
+// DefaultSender returns the registered default Sender, or nil if none is set.
+func DefaultSender() Sender {
+	defaultSender.mu.Lock()
+	defer defaultSender.mu.Unlock()
+
+	if defaultSender.sender != nil {
+		defaultSender.used = true
+	}
+
+	return defaultSender.sender
+}
+
+var errNoDefaultSender = errors.New("email.SetDefaultSender has not been called")
+
+// Send sends e through the default Sender.
+func Send(ctx context.Context, e Email) error {
+	s := DefaultSender()
+	if s == nil {
+		return NewValidationError("no default sender is configured", errNoDefaultSender)
+	}
+
+	return s.SendEmail(ctx, e)
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type countingSender struct {
+	sent atomic.Int64
+}
+
+func (s *countingSender) SendEmail(ctx context.Context, e Email) error {
+	s.sent.Add(1)
+	return nil
+}
+
+func TestSend_Unset(t *testing.T) {
+	ReplaceDefaultSender(nil)
+	t.Cleanup(func() { ReplaceDefaultSender(nil) })
+
+	err := Send(context.Background(), Email{})
+
+	var emailErr *Error
+	if !errors.As(err, &emailErr) {
+		t.Fatalf("expected email.Error, got %v", err)
+	}
+	if emailErr.Reason != REASON_VALIDATION_ERROR {
+		t.Errorf("expected error reason %s, got %s", REASON_VALIDATION_ERROR, emailErr.Reason)
+	}
+}
+
+func TestSetDefaultSender(t *testing.T) {
+	ReplaceDefaultSender(nil)
+	t.Cleanup(func() { ReplaceDefaultSender(nil) })
+
+	first := &countingSender{}
+	second := &countingSender{}
+
+	if err := SetDefaultSender(nil); err == nil {
+		t.Error("expected error setting a nil sender")
+	}
+
+	if err := SetDefaultSender(first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetDefaultSender(second); err != nil {
+		t.Fatalf("expected to replace an unused default, got %v", err)
+	}
+
+	if err := Send(context.Background(), Email{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.sent.Load() != 1 || first.sent.Load() != 0 {
+		t.Errorf("expected the send to go to the latest sender, got first=%d second=%d", first.sent.Load(), second.sent.Load())
+	}
+
+	if err := SetDefaultSender(first); err == nil {
+		t.Error("expected error replacing a default sender that is in use")
+	}
+
+	ReplaceDefaultSender(first)
+	if DefaultSender() != first {
+		t.Error("expected ReplaceDefaultSender to override a used default")
+	}
+}
+
+func TestDefaultSender_ConcurrentSetAndSend(t *testing.T) {
+	ReplaceDefaultSender(nil)
+	t.Cleanup(func() { ReplaceDefaultSender(nil) })
+
+	senders := make([]*countingSender, 8)
+	for i := range senders {
+		senders[i] = &countingSender{}
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range senders {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = SetDefaultSender(s)
+		}()
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				_ = Send(context.Background(), Email{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	used := 0
+	for _, s := range senders {
+		if n := s.sent.Load(); n > 0 {
+			total += n
+			used++
+		}
+	}
+	if used > 1 {
+		t.Errorf("expected sends to reach at most one sender once in use, reached %d", used)
+	}
+
+	if err := SetDefaultSender(&countingSender{}); total > 0 && err == nil {
+		t.Error("expected the default sender to be locked after use")
+	}
+}
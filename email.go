@@ -1,6 +1,10 @@
 package email
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type Email struct {
 	FromAddress      string
@@ -11,8 +15,88 @@ type Email struct {
 	Subject          string
 	HTMLBody         string
 	// The email body for recipients with non-HTML email clients.
-	TextBody    string
+	TextBody string
+	// Alternatives to HTMLBody and TextBody for bodies too large to build as
+	// a string, e.g. generated reports. Each is mutually exclusive with its
+	// string field and is read once, when the email is sent. See ReadBodies.
+	// They are not kept in dead letters.
+	HTMLBodyReader io.Reader `json:"-"`
+	TextBodyReader io.Reader `json:"-"`
+	// Character set the subject and bodies are sent in, e.g. "iso-2022-jp"
+	// for legacy Japanese systems. They are transcoded from UTF-8 when
+	// sent. Leave empty for DefaultCharset. See LookupCharset.
+	Charset string
+	// A nil or empty slice both mean the email has no attachments.
 	Attachments []Attachment
+	// The mailbox actually sending the email when it differs from the
+	// author in From, e.g. a tournament director sending on behalf of the
+	// association. Sent as the Sender header, which is left out when empty
+	// or the same as From. See SenderHeaderAddress.
+	SenderAddress string
+	// Where bounces go instead of the From address, e.g. a VERP mailbox.
+	// Gmail writes it as the Return-Path header, which receiving servers
+	// usually replace with the envelope sender Gmail chooses. SES forwards
+	// bounce and complaint notifications to it but keeps its own envelope
+	// sender. Leave empty to use the From address.
+	BounceAddress string
+	// Asks the recipient's client to send a read receipt to ReadReceiptTo,
+	// or to the From address when ReadReceiptTo is empty. Clients may ignore
+	// the request. See ReadReceiptHeaders.
+	RequestReadReceipt bool
+	// Where read receipts go. Setting it requests a receipt on its own.
+	ReadReceiptTo string
+	// Identifies the message, e.g. for threading and bounce correlation.
+	// Senders generate one with EnsureMessageID when it is empty. See
+	// GenerateMessageID.
+	MessageID string
+	// When the message was written, sent as the Date header, e.g. the
+	// original date of an archived message being re-sent. Leave zero to let
+	// the provider stamp it, or the sender's clock when it was given one.
+	Date time.Time
+	// When the message stops being relevant, sent as the Expiry-Date header.
+	// Leave zero for messages that never expire.
+	Expires time.Time
+	// Allows sending with an Expires date that has already passed.
+	AllowPastExpiry bool
+	// Ties together the emails of one multi-email flow, e.g. a registration
+	// confirmation and its payment reminder. See ValidateCampaign.
+	CampaignID string
+	// Position of this email within its campaign, starting at 1. Zero means
+	// unset.
+	SequenceStep int
+	// Other addresses of the single To recipient, tried in order by
+	// FallbackSender when the provider rejects the address.
+	AlternateAddresses []string
+	// Custom headers, written after the ones the library sets. See
+	// ValidateHeaders.
+	Headers []Header
+	// How recipients unsubscribe from bulk mail. See OneClickUnsubscribe.
+	Unsubscribe Unsubscribe
+	// Identifies the mailing list the email belongs to, e.g.
+	// "digest.icaa.example.com", so clients can group and filter it. Sent
+	// as the List-Id header, with angle brackets added if missing. See
+	// ValidateList.
+	ListID string
+	// Where past emails of the list can be read, an http or https URL. Only
+	// sent with a ListID.
+	ListArchiveURL string
+	// An address, or a mailto: URI, that posts to the list. Only sent with
+	// a ListID.
+	ListPostAddress string
+	// Labels for analytics, e.g. {"env": "prod"}. SES sends them as
+	// message tags, Gmail as X-Tag-<name> headers. See ValidateTags.
+	Tags map[string]string
+	// How urgent the email is. Leave empty to send no priority headers.
+	Priority Priority
+	// Marks the email as automated so autoresponders don't reply to it.
+	// Leave empty to send no Auto-Submitted header.
+	AutoSubmitted AutoSubmitted
+	// Sends X-Auto-Response-Suppress: All, which stops Exchange sending
+	// out-of-office replies, but also delivery reports, to the sender.
+	SuppressAutoResponses bool
+	// Set by FallbackSender to the rejected address this email was rerouted
+	// from. Sent as the X-Delivery-Fallback header.
+	FallbackFor string
 }
 
 type Attachment struct {
@@ -21,6 +105,14 @@ type Attachment struct {
 	Description string
 	// MimeType of the content
 	ContentType string
+	// Makes the attachment an inline part of the HTML body, referenced from
+	// it as cid:<ContentID>. Leave empty for a regular attachment.
+	ContentID string
+	// Where to fetch Content from at send time instead of carrying it, e.g.
+	// s3://bucket/key or an https URL. See ResolvingSender.
+	Ref string
+	// Hex encoded SHA-256 of the content fetched from Ref. Optional.
+	SHA256 string
 }
 
 type Sender interface {
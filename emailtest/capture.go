@@ -0,0 +1,61 @@
+package emailtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+var _ email.Sender = &CaptureSender{}
+var _ email.SenderV2 = &CaptureSender{}
+
+// CaptureSender records the emails it is asked to send instead of sending
+// them. It applies the Override and hooks of SendOptions like a provider
+// would, and records nothing for dry runs.
+type CaptureSender struct {
+	// Returned from every send when set.
+	Err error
+
+	mu   sync.Mutex
+	sent []email.Email
+}
+
+func (s *CaptureSender) SendEmail(ctx context.Context, e email.Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *CaptureSender) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	e = opts.Apply(e)
+
+	if err := opts.RunBeforeSend(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if opts.IsDryRun() {
+		result := &email.SendResult{Provider: "capture", DryRun: true, CampaignID: e.CampaignID, SequenceStep: e.SequenceStep}
+		opts.RunAfterSend(ctx, e, result, nil)
+		return result, nil
+	}
+
+	if s.Err != nil {
+		opts.RunAfterSend(ctx, e, nil, s.Err)
+		return nil, s.Err
+	}
+
+	s.mu.Lock()
+	s.sent = append(s.sent, e)
+	s.mu.Unlock()
+
+	result := &email.SendResult{Provider: "capture", CampaignID: e.CampaignID, SequenceStep: e.SequenceStep}
+	opts.RunAfterSend(ctx, e, result, nil)
+	return result, nil
+}
+
+// Sent returns the emails sent so far, oldest first.
+func (s *CaptureSender) Sent() []email.Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]email.Email(nil), s.sent...)
+}
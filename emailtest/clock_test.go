@@ -0,0 +1,105 @@
+package emailtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+var start = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFakeClock_Timers(t *testing.T) {
+	c := NewFakeClock(start)
+
+	late := c.NewTimer(2 * time.Minute)
+	early := c.NewTimer(time.Minute)
+	stopped := c.NewTimer(time.Minute)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("expected Stop to report whether the timer was pending")
+	}
+
+	c.Advance(90 * time.Second)
+
+	select {
+	case at := <-early.C():
+		if !at.Equal(start.Add(time.Minute)) {
+			t.Errorf("expected the timer to fire at its deadline, got %s", at)
+		}
+	default:
+		t.Error("expected the due timer to fire")
+	}
+
+	select {
+	case <-late.C():
+		t.Error("expected the later timer not to fire yet")
+	case <-stopped.C():
+		t.Error("expected the stopped timer never to fire")
+	default:
+	}
+
+	if !c.Now().Equal(start.Add(90 * time.Second)) {
+		t.Errorf("expected the clock to advance, got %s", c.Now())
+	}
+
+	c.Advance(time.Minute)
+	if _, ok := <-late.C(); !ok || late.Stop() {
+		t.Error("expected the later timer to have fired")
+	}
+}
+
+func TestFakeClock_Sleep(t *testing.T) {
+	c := NewFakeClock(start)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Sleep(context.Background(), time.Hour)
+	}()
+
+	c.BlockUntilTimers(1)
+	c.Advance(time.Hour)
+
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Sleep(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+func TestFakeClock_DrivesEventSender(t *testing.T) {
+	c := NewFakeClock(start)
+
+	var events []email.Event
+	sender := email.NewEventSender(email.AsSenderV2(nopSender{}),
+		email.WithEventClock(c),
+		email.WithEventCallback(func(ev email.Event) { events = append(events, ev) }),
+	)
+
+	if err := sender.SendEmail(context.Background(), email.Email{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, ev := range events {
+		if !ev.Time.Equal(start) {
+			t.Errorf("expected %s event at the fake time, got %s", ev.Kind, ev.Time)
+		}
+	}
+}
+
+func TestFixedJitter(t *testing.T) {
+	if got := FixedJitter(0.5)(time.Second); got != 500*time.Millisecond {
+		t.Errorf("expected 500ms, got %s", got)
+	}
+}
+
+type nopSender struct{}
+
+func (nopSender) SendEmail(ctx context.Context, e email.Email) error {
+	return nil
+}
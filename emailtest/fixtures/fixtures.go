@@ -0,0 +1,195 @@
+// Package fixtures is a corpus of canonical emails for tests, each with the
+// golden message providersdk.BuildMessage renders for it with default
+// options.
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// NearSizeLimit is the rendered size the "near-size-limit" fixture stays
+// just under: Gmail's 25 MiB, the smallest limit among the providers.
+const NearSizeLimit = 25 * 1024 * 1024
+
+//go:embed golden
+var golden embed.FS
+
+type Fixture struct {
+	Name  string
+	Email email.Email
+	// The rendered message. Nil for fixtures too large to keep in the
+	// repository, which only keep GoldenSHA256.
+	Golden []byte
+	// Hex encoded SHA-256 of the rendered message.
+	GoldenSHA256 string
+
+	large bool
+}
+
+// GoldenPath is where the golden rendering of f is stored, relative to this
+// package: the message itself, or only its digest for large fixtures.
+func (f Fixture) GoldenPath() string {
+	if f.large {
+		return "golden/" + f.Name + ".eml.sha256"
+	}
+	return "golden/" + f.Name + ".eml"
+}
+
+var load = sync.OnceValue(func() []Fixture {
+	var all []Fixture
+	for _, c := range corpus() {
+		f := Fixture{Name: c.name, Email: c.email, large: c.large}
+		if f.large {
+			if digest, err := golden.ReadFile(f.GoldenPath()); err == nil {
+				f.GoldenSHA256 = strings.TrimSpace(string(digest))
+			}
+		} else if eml, err := golden.ReadFile(f.GoldenPath()); err == nil {
+			sum := sha256.Sum256(eml)
+			f.Golden = eml
+			f.GoldenSHA256 = hex.EncodeToString(sum[:])
+		}
+		all = append(all, f)
+	}
+	return all
+})
+
+// All returns every fixture. The fixtures are shared, so they must not be
+// modified.
+func All() []Fixture {
+	return load()
+}
+
+func ByName(name string) (Fixture, bool) {
+	for _, f := range load() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Fixture{}, false
+}
+
+type entry struct {
+	name  string
+	email email.Email
+	large bool
+}
+
+func corpus() []entry {
+	base := func() email.Email {
+		return email.Email{
+			FromAddress: "ICAA Events <events@icaa.example.com>",
+			ToAddresses: []string{"ada@example.com"},
+			Subject:     "Spring tournament",
+		}
+	}
+
+	simpleText := base()
+	simpleText.TextBody = "Hi Ada,\n\nRegistration for the spring tournament is open.\n\nICAA"
+
+	htmlAndText := base()
+	htmlAndText.TextBody = simpleText.TextBody
+	htmlAndText.HTMLBody = "<p>Hi Ada,</p><p>Registration for the <b>spring tournament</b> is open.</p><p>ICAA</p>"
+	htmlAndText.ReplyToAddresses = []string{"help@icaa.example.com"}
+
+	unicode := email.Email{
+		FromAddress:  "Zoë Ñúñez <zoe@icaa.example.com>",
+		ToAddresses:  []string{"Jürgen Groß <juergen@example.de>", "李雷 <lilei@example.cn>"},
+		CCAddresses:  []string{"Ólafur <olafur@example.is>"},
+		Subject:      "Résultats du tournoi 🏹 — 第一名",
+		TextBody:     "Félicitations ! 恭喜！ Поздравляем! 🎯",
+		HTMLBody:     "<p>Félicitations ! 恭喜！ Поздравляем! 🎯</p>",
+		Attachments:  []email.Attachment{{FileName: "résultats-第一.csv", Content: []byte("nom,score\nZoë,98\n"), ContentType: "text/csv"}},
+		CampaignID:   "spring-results",
+		SequenceStep: 2,
+	}
+
+	attachments := base()
+	attachments.TextBody = "The documents for the tournament are attached."
+	attachments.HTMLBody = "<p>The documents for the tournament are attached.</p>"
+	attachments.Attachments = []email.Attachment{
+		{FileName: "schedule.pdf", Content: []byte("%PDF-1.4\n% schedule\n"), ContentType: "application/pdf", Description: "Schedule"},
+		{FileName: "roster.csv", Content: []byte("name,club\nAda,North\nBo,South\n"), ContentType: "text/csv"},
+		{FileName: "map.png", Content: pattern(300), ContentType: "image/png"},
+	}
+
+	inlineImages := base()
+	inlineImages.TextBody = "Our new logo is here."
+	inlineImages.HTMLBody = `<p><img src="cid:logo@icaa.example.com" alt="ICAA"></p><p>Our new logo is here.</p><p><img src="cid:banner@icaa.example.com" alt=""></p>`
+	inlineImages.Attachments = []email.Attachment{
+		{FileName: "logo.png", Content: pattern(120), ContentType: "image/png", ContentID: "logo@icaa.example.com"},
+		{FileName: "banner.jpg", Content: pattern(200), ContentType: "image/jpeg", ContentID: "banner@icaa.example.com"},
+		{FileName: "rules.pdf", Content: []byte("%PDF-1.4\n% rules\n"), ContentType: "application/pdf"},
+	}
+
+	invite := base()
+	invite.Subject = "Invitation: Spring tournament"
+	invite.TextBody = "You are invited to the spring tournament on 2 May 2026."
+	invite.Attachments = []email.Attachment{{
+		FileName:    "invite.ics",
+		ContentType: "text/calendar; method=REQUEST; charset=utf-8",
+		Content: []byte(strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//ICAA//Events//EN",
+			"METHOD:REQUEST",
+			"BEGIN:VEVENT",
+			"UID:spring-2026@icaa.example.com",
+			"DTSTAMP:20260401T120000Z",
+			"DTSTART:20260502T090000Z",
+			"DTEND:20260502T170000Z",
+			"SUMMARY:Spring tournament",
+			"ORGANIZER:mailto:events@icaa.example.com",
+			"ATTENDEE;RSVP=TRUE:mailto:ada@example.com",
+			"END:VEVENT",
+			"END:VCALENDAR",
+			"",
+		}, "\r\n")),
+	}}
+
+	manyRecipients := base()
+	manyRecipients.ToAddresses = nil
+	for i := range 100 {
+		manyRecipients.ToAddresses = append(manyRecipients.ToAddresses, fmt.Sprintf("member%03d@example.com", i+1))
+	}
+	manyRecipients.TextBody = "The club newsletter for May."
+
+	nearSizeLimit := base()
+	nearSizeLimit.Subject = "Tournament photos"
+	nearSizeLimit.TextBody = "All photos of the tournament are attached."
+	// Base64 with line breaks grows content by 4/3 * 78/76; this leaves
+	// about 2% of NearSizeLimit for the rest of the message.
+	nearSizeLimit.Attachments = []email.Attachment{{
+		FileName:    "photos.zip",
+		Content:     pattern(NearSizeLimit * 98 / 100 * 3 / 4 * 76 / 78),
+		ContentType: "application/zip",
+	}}
+
+	return []entry{
+		{name: "simple-text", email: simpleText},
+		{name: "html-and-text", email: htmlAndText},
+		{name: "unicode", email: unicode},
+		{name: "attachments", email: attachments},
+		{name: "inline-images", email: inlineImages},
+		{name: "calendar-invite", email: invite},
+		{name: "100-recipients", email: manyRecipients},
+		{name: "near-size-limit", email: nearSizeLimit, large: true},
+	}
+}
+
+// pattern returns n deterministic bytes covering every byte value.
+func pattern(n int) []byte {
+	var b bytes.Buffer
+	b.Grow(n)
+	for i := range n {
+		b.WriteByte(byte(i*31 + i/256))
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,79 @@
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the current MIME builder")
+
+// TestGolden doubles as the regression suite of providersdk.BuildMessage:
+// any change to its output shows up as a golden diff.
+func TestGolden(t *testing.T) {
+	for _, f := range All() {
+		t.Run(f.Name, func(t *testing.T) {
+			raw, err := providersdk.BuildMessage(f.Email, providersdk.BuildOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := providersdk.VerifyMessage(raw, f.Email); err != nil {
+				t.Fatalf("message does not verify: %v", err)
+			}
+
+			sum := sha256.Sum256(raw)
+			digest := hex.EncodeToString(sum[:])
+
+			if *update {
+				content := raw
+				if f.large {
+					content = []byte(digest + "\n")
+				}
+				if err := os.WriteFile(f.GoldenPath(), content, 0o644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+				return
+			}
+
+			if f.GoldenSHA256 == "" {
+				t.Fatalf("missing golden file %s, run go test -update", f.GoldenPath())
+			}
+			if f.Golden != nil && !bytes.Equal(raw, f.Golden) {
+				t.Fatalf("rendering differs from %s, run go test -update if the change is intended\ngot:\n%s", f.GoldenPath(), raw)
+			}
+			if digest != f.GoldenSHA256 {
+				t.Fatalf("rendering differs from %s, run go test -update if the change is intended", f.GoldenPath())
+			}
+		})
+	}
+}
+
+func TestNearSizeLimit(t *testing.T) {
+	f, ok := ByName("near-size-limit")
+	if !ok {
+		t.Fatal("fixture not found")
+	}
+
+	size := email.EstimateSize(f.Email).Total
+	if size > NearSizeLimit || size < NearSizeLimit*95/100 {
+		t.Errorf("expected a size just under %d bytes, got %d", NearSizeLimit, size)
+	}
+}
+
+func TestByName(t *testing.T) {
+	if _, ok := ByName("unicode"); !ok {
+		t.Error("expected the unicode fixture")
+	}
+	if _, ok := ByName("missing"); ok {
+		t.Error("expected no fixture")
+	}
+	if len(All()) != 8 {
+		t.Errorf("expected 8 fixtures, got %d", len(All()))
+	}
+}
@@ -0,0 +1,255 @@
+package emailtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/awsses/bouncetest"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk"
+)
+
+var _ email.Sender = &SimulatedProvider{}
+var _ email.SenderV2 = &SimulatedProvider{}
+
+type SimulationOption func(*SimulatedProvider)
+
+// WithLatency makes every send take a duration drawn uniformly from
+// [min, max].
+func WithLatency(min, max time.Duration) SimulationOption {
+	return func(p *SimulatedProvider) {
+		p.minLatency, p.maxLatency = min, max
+	}
+}
+
+// WithRateLimitBursts makes each send start a burst of burst rate limited
+// sends with probability rate. The errors ask to retry after retryAfter.
+func WithRateLimitBursts(rate float64, burst int, retryAfter time.Duration) SimulationOption {
+	return func(p *SimulatedProvider) {
+		p.rateLimitRate, p.rateLimitBurst, p.retryAfter = rate, max(burst, 1), retryAfter
+	}
+}
+
+// WithServiceErrorStreaks makes each send start a streak of streak 503
+// service errors with probability rate.
+func WithServiceErrorStreaks(rate float64, streak int) SimulationOption {
+	return func(p *SimulatedProvider) {
+		p.serviceErrorRate, p.serviceErrorStreak = rate, max(streak, 1)
+	}
+}
+
+// WithHardBounces makes delivered recipients matching any of patterns
+// bounce permanently with probability rate. Addresses are matched
+// lowercased.
+func WithHardBounces(rate float64, patterns ...*regexp.Regexp) SimulationOption {
+	return func(p *SimulatedProvider) {
+		p.bounceRate, p.bouncePatterns = rate, patterns
+	}
+}
+
+// WithSimulationClock replaces the system clock used to wait out latency and
+// timestamp bounces.
+func WithSimulationClock(c email.Clock) SimulationOption {
+	return func(p *SimulatedProvider) {
+		p.clock = c
+	}
+}
+
+// SimulationStats counts what a SimulatedProvider did.
+type SimulationStats struct {
+	Sends         int
+	Delivered     int
+	RateLimited   int
+	ServiceErrors int
+	// Recipients that hard bounced after delivery.
+	Bounces int
+	// Summed latency of all sends.
+	Latency time.Duration
+}
+
+// SimulatedBounce is a hard bounce of a delivered email.
+type SimulatedBounce struct {
+	Email  email.Email
+	Bounce bouncetest.Bounce
+}
+
+// Notification returns the SES bounce notification for b.
+func (b SimulatedBounce) Notification() ([]byte, error) {
+	return bouncetest.NotificationJSON(b.Email, b.Bounce)
+}
+
+// DSN returns the delivery status notification for b.
+func (b SimulatedBounce) DSN() ([]byte, error) {
+	return bouncetest.DSN(b.Email, b.Bounce)
+}
+
+// SimulatedProvider is a fake provider for load and resilience tests. Its
+// latency, rate limiting, service outages and hard bounces follow the
+// configured distributions, drawn from a generator seeded with seed, so a
+// run made one send at a time is reproducible. Hard bounces don't fail the
+// send; like with a real provider they are reported afterwards, see
+// Bounces.
+type SimulatedProvider struct {
+	minLatency         time.Duration
+	maxLatency         time.Duration
+	rateLimitRate      float64
+	rateLimitBurst     int
+	retryAfter         time.Duration
+	serviceErrorRate   float64
+	serviceErrorStreak int
+	bounceRate         float64
+	bouncePatterns     []*regexp.Regexp
+	clock              email.Clock
+
+	mu sync.Mutex
+	// Guarded by mu.
+	rng               *rand.Rand
+	rateLimitLeft     int
+	serviceErrorsLeft int
+	stats             SimulationStats
+	bounces           []SimulatedBounce
+}
+
+func NewSimulatedProvider(seed uint64, opts ...SimulationOption) *SimulatedProvider {
+	p := &SimulatedProvider{
+		clock: email.SystemClock(),
+		rng:   rand.New(rand.NewPCG(seed, seed)),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *SimulatedProvider) SendEmail(ctx context.Context, e email.Email) error {
+	_, err := p.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (p *SimulatedProvider) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	e = opts.Apply(e)
+
+	if err := providersdk.Validate(e); err != nil {
+		return nil, err
+	}
+
+	if err := opts.RunBeforeSend(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if opts.IsDryRun() {
+		result := &email.SendResult{Provider: "simulated", DryRun: true, CampaignID: e.CampaignID, SequenceStep: e.SequenceStep}
+		opts.RunAfterSend(ctx, e, result, nil)
+		return result, nil
+	}
+
+	latency, err := p.draw()
+	if err == nil {
+		err = p.clock.Sleep(ctx, latency)
+	}
+	if err != nil {
+		opts.RunAfterSend(ctx, e, nil, err)
+		return nil, err
+	}
+
+	result := &email.SendResult{Provider: "simulated", CampaignID: e.CampaignID, SequenceStep: e.SequenceStep}
+	p.deliver(e, result)
+	opts.RunAfterSend(ctx, e, result, nil)
+	return result, nil
+}
+
+// draw picks the latency of the next send and whether it fails.
+func (p *SimulatedProvider) draw() (time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Sends++
+	latency := p.minLatency
+	if p.maxLatency > p.minLatency {
+		latency += time.Duration(p.rng.Int64N(int64(p.maxLatency - p.minLatency + 1)))
+	}
+	p.stats.Latency += latency
+
+	if p.rateLimitLeft == 0 && p.rateLimitRate > 0 && p.rng.Float64() < p.rateLimitRate {
+		p.rateLimitLeft = p.rateLimitBurst
+	}
+	if p.rateLimitLeft > 0 {
+		p.rateLimitLeft--
+		p.stats.RateLimited++
+		err := email.NewRateLimitedError("simulated rate limit", nil)
+		err.RetryAfter = p.retryAfter
+		return latency, err
+	}
+
+	if p.serviceErrorsLeft == 0 && p.serviceErrorRate > 0 && p.rng.Float64() < p.serviceErrorRate {
+		p.serviceErrorsLeft = p.serviceErrorStreak
+	}
+	if p.serviceErrorsLeft > 0 {
+		p.serviceErrorsLeft--
+		p.stats.ServiceErrors++
+		err := email.NewServiceError("simulated service unavailable", nil)
+		err.Metadata = map[string]string{"http_status": "503"}
+		return latency, err
+	}
+
+	return latency, nil
+}
+
+func (p *SimulatedProvider) deliver(e email.Email, result *email.SendResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Delivered++
+	result.ProviderMessageID = fmt.Sprintf("simulated-%d", p.stats.Delivered)
+
+	var bounced []string
+	for _, addrs := range [][]string{e.ToAddresses, e.CCAddresses, e.BCCAddresses} {
+		for _, addr := range addrs {
+			if p.shouldBounce(addr) {
+				bounced = append(bounced, addr)
+			}
+		}
+	}
+	if len(bounced) == 0 {
+		return
+	}
+
+	p.stats.Bounces += len(bounced)
+	p.bounces = append(p.bounces, SimulatedBounce{
+		Email: e,
+		Bounce: bouncetest.Bounce{
+			Type:       bouncetest.BOUNCE_PERMANENT,
+			Recipients: bounced,
+			MessageID:  result.ProviderMessageID,
+			Timestamp:  p.clock.Now(),
+		},
+	})
+}
+
+func (p *SimulatedProvider) shouldBounce(addr string) bool {
+	addr = strings.ToLower(addr)
+	for _, pattern := range p.bouncePatterns {
+		if pattern.MatchString(addr) {
+			return p.rng.Float64() < p.bounceRate
+		}
+	}
+	return false
+}
+
+func (p *SimulatedProvider) Stats() SimulationStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Bounces returns the hard bounces so far, oldest first.
+func (p *SimulatedProvider) Bounces() []SimulatedBounce {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SimulatedBounce(nil), p.bounces...)
+}
@@ -0,0 +1,227 @@
+package emailtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/awsses/bouncetest"
+)
+
+// instantClock skips ahead instead of waiting, so simulated latency costs
+// nothing.
+type instantClock struct {
+	*FakeClock
+}
+
+func (c instantClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.Advance(d)
+	return ctx.Err()
+}
+
+func simulatedEmail(to ...string) email.Email {
+	return email.Email{
+		FromAddress: "events@icaa.org",
+		ToAddresses: to,
+		Subject:     "Tournament update",
+		TextBody:    "Hello",
+	}
+}
+
+// runSimulation sends n emails one at a time and returns each outcome: the
+// error reason, or "" for a delivery.
+func runSimulation(t *testing.T, p *SimulatedProvider, n int, to ...string) []email.ErrorReason {
+	t.Helper()
+
+	outcomes := make([]email.ErrorReason, n)
+	for i := range outcomes {
+		_, err := p.SendEmailV2(context.Background(), simulatedEmail(to...), nil)
+		var emailErr *email.Error
+		switch {
+		case err == nil:
+		case errors.As(err, &emailErr):
+			outcomes[i] = emailErr.Reason
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return outcomes
+}
+
+func within(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("expected %s of %.3f±%.3f, got %.3f", name, want, tolerance, got)
+	}
+}
+
+func TestSimulatedProvider_Failures(t *testing.T) {
+	const n = 20000
+
+	tests := []struct {
+		name   string
+		opt    SimulationOption
+		reason email.ErrorReason
+		rate   float64
+		run    int
+	}{
+		{
+			name:   "rate limit bursts",
+			opt:    WithRateLimitBursts(0.05, 3, 2*time.Second),
+			reason: email.REASON_RATE_LIMITED,
+			rate:   0.05,
+			run:    3,
+		},
+		{
+			name:   "service error streaks",
+			opt:    WithServiceErrorStreaks(0.01, 5),
+			reason: email.REASON_SERVICE_ERROR,
+			rate:   0.01,
+			run:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSimulatedProvider(42, tt.opt, WithSimulationClock(instantClock{NewFakeClock(start)}))
+			outcomes := runSimulation(t, p, n, "archer@example.com")
+
+			failed := 0
+			for i := 0; i < len(outcomes); {
+				if outcomes[i] == "" {
+					i++
+					continue
+				}
+				if outcomes[i] != tt.reason {
+					t.Fatalf("expected %s errors, got %s", tt.reason, outcomes[i])
+				}
+				j := i
+				for j < len(outcomes) && outcomes[j] == tt.reason {
+					j++
+				}
+				// Back to back runs merge, but never split.
+				if (j-i)%tt.run != 0 && j != len(outcomes) {
+					t.Fatalf("expected runs of %d failures, got %d at send %d", tt.run, j-i, i)
+				}
+				failed += j - i
+				i = j
+			}
+
+			// Each send outside a run starts one with probability rate.
+			r, b := tt.rate, float64(tt.run)
+			want := b * r / (b*r + 1 - r)
+			within(t, "failure rate", float64(failed)/n, want, 0.02)
+
+			stats := p.Stats()
+			if stats.Sends != n || stats.Delivered != n-failed {
+				t.Errorf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestSimulatedProvider_RetryAfter(t *testing.T) {
+	p := NewSimulatedProvider(1, WithRateLimitBursts(1, 1, 3*time.Second), WithSimulationClock(instantClock{NewFakeClock(start)}))
+
+	_, err := p.SendEmailV2(context.Background(), simulatedEmail("archer@example.com"), nil)
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_RATE_LIMITED || emailErr.RetryAfter != 3*time.Second {
+		t.Fatalf("expected a rate limit asking to retry after 3s, got %v", err)
+	}
+}
+
+func TestSimulatedProvider_Latency(t *testing.T) {
+	const n = 5000
+
+	clock := NewFakeClock(start)
+	p := NewSimulatedProvider(7, WithLatency(100*time.Millisecond, 300*time.Millisecond), WithSimulationClock(instantClock{clock}))
+	runSimulation(t, p, n, "archer@example.com")
+
+	stats := p.Stats()
+	mean := stats.Latency / n
+	within(t, "mean latency in ms", float64(mean)/float64(time.Millisecond), 200, 5)
+	if elapsed := clock.Now().Sub(start); elapsed != stats.Latency {
+		t.Errorf("expected the sends to wait %s, waited %s", stats.Latency, elapsed)
+	}
+}
+
+func TestSimulatedProvider_HardBounces(t *testing.T) {
+	const n = 5000
+
+	p := NewSimulatedProvider(3,
+		WithHardBounces(0.3, regexp.MustCompile(`@bounce\.example\.com$`)),
+		WithSimulationClock(instantClock{NewFakeClock(start)}),
+	)
+	outcomes := runSimulation(t, p, n, "archer@example.com", "Coach@Bounce.Example.com")
+	for _, reason := range outcomes {
+		if reason != "" {
+			t.Fatalf("expected bounces not to fail the send, got %s", reason)
+		}
+	}
+
+	bounces := p.Bounces()
+	for _, b := range bounces {
+		if len(b.Bounce.Recipients) != 1 || b.Bounce.Recipients[0] != "Coach@Bounce.Example.com" {
+			t.Fatalf("expected only the matching recipient to bounce, got %v", b.Bounce.Recipients)
+		}
+	}
+	within(t, "bounce rate", float64(len(bounces))/n, 0.3, 0.03)
+	if p.Stats().Bounces != len(bounces) {
+		t.Errorf("expected %d bounces in the stats, got %d", len(bounces), p.Stats().Bounces)
+	}
+
+	raw, err := bounces[0].Notification()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var notification bouncetest.Notification
+	if err := json.Unmarshal(raw, &notification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notification.Bounce.BounceType != string(bouncetest.BOUNCE_PERMANENT) || !strings.HasPrefix(notification.Mail.MessageID, "simulated-") {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+}
+
+func TestSimulatedProvider_Deterministic(t *testing.T) {
+	run := func(seed uint64) ([]email.ErrorReason, SimulationStats) {
+		p := NewSimulatedProvider(seed,
+			WithLatency(0, time.Second),
+			WithRateLimitBursts(0.05, 2, time.Second),
+			WithServiceErrorStreaks(0.02, 3),
+			WithHardBounces(0.5, regexp.MustCompile(`^coach@`)),
+			WithSimulationClock(instantClock{NewFakeClock(start)}),
+		)
+		return runSimulation(t, p, 500, "archer@example.com", "coach@example.com"), p.Stats()
+	}
+
+	first, firstStats := run(99)
+	second, secondStats := run(99)
+	if !slices.Equal(first, second) || firstStats != secondStats {
+		t.Error("expected runs with the same seed to match")
+	}
+
+	_, otherStats := run(100)
+	if otherStats == firstStats {
+		t.Error("expected runs with different seeds to differ")
+	}
+}
+
+func TestSimulatedProvider_DryRun(t *testing.T) {
+	p := NewSimulatedProvider(1, WithServiceErrorStreaks(1, 1))
+
+	result, err := p.SendEmailV2(context.Background(), simulatedEmail("archer@example.com"), &email.SendOptions{DryRun: true})
+	if err != nil || !result.DryRun {
+		t.Fatalf("expected a dry run result, got %+v, %v", result, err)
+	}
+	if p.Stats().Sends != 0 {
+		t.Error("expected dry runs not to count as sends")
+	}
+}
@@ -0,0 +1,78 @@
+package email
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// UnreferencedImageError is returned by EmbedImage when the HTML body does
+// not reference the embedded image, which most clients then show as a
+// regular attachment, if at all.
+type UnreferencedImageError struct {
+	ContentID string
+}
+
+func (e *UnreferencedImageError) Error() string {
+	return fmt.Sprintf("HTML body does not reference inline image cid:%s", e.ContentID)
+}
+
+// InlineImageFromFile reads the image at path into an inline attachment
+// with Content-ID cid. See InlineImageFromBytes.
+func InlineImageFromFile(path, cid string, opts ...AttachmentOption) (Attachment, error) {
+	a, err := AttachmentFromFile(path, opts...)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return inlineImage(a, cid)
+}
+
+// InlineImageFromBytes returns data as an inline attachment called name, for
+// the HTML body to show as <img src="cid:...">. The content type is detected
+// like AttachmentFromReader's and must be an image. cid may be given with or
+// without angle brackets or a cid: prefix; if empty, one is derived from
+// the content.
+func InlineImageFromBytes(name string, data []byte, cid string) (Attachment, error) {
+	return inlineImage(Attachment{
+		FileName:    name,
+		Content:     data,
+		ContentType: detectContentType(name, data),
+	}, cid)
+}
+
+func inlineImage(a Attachment, cid string) (Attachment, error) {
+	mediaType, _, err := mime.ParseMediaType(a.ContentType)
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return Attachment{}, NewValidationError(fmt.Sprintf("%s is not an image: %s", a.FileName, a.ContentType), err)
+	}
+
+	cid = strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(cid), "cid:"), ">"), "<")
+	if cid == "" {
+		sum := sha256.Sum256(a.Content)
+		cid = hex.EncodeToString(sum[:8]) + "@inline"
+	}
+	if strings.ContainsAny(cid, "<>\"() \t\r\n") {
+		return Attachment{}, NewValidationError(fmt.Sprintf("invalid content ID %q", cid), nil)
+	}
+
+	a.ContentID = cid
+	return a, nil
+}
+
+// EmbedImage adds the inline attachment a to e. If the HTML body does not
+// reference it, a is still added, and an *UnreferencedImageError returned.
+// Bodies set as readers can't be checked without consuming them, so they
+// are assumed to reference it.
+func (e *Email) EmbedImage(a Attachment) error {
+	if a.ContentID == "" {
+		return NewValidationError(fmt.Sprintf("attachment %s has no content ID", a.FileName), nil)
+	}
+
+	e.Attachments = append(e.Attachments, a)
+	if e.HTMLBodyReader == nil && !strings.Contains(e.HTMLBody, "cid:"+a.ContentID) {
+		return &UnreferencedImageError{ContentID: a.ContentID}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package email
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInlineImageFromBytes(t *testing.T) {
+	logo := encodePNG(t, noiseImage(4, 4, true))
+
+	tests := []struct {
+		name      string
+		cid       string
+		contentID string
+	}{
+		{"bare", "logo@icaa.example.com", "logo@icaa.example.com"},
+		{"brackets", "<logo@icaa.example.com>", "logo@icaa.example.com"},
+		{"cid prefix", "cid:logo@icaa.example.com", "logo@icaa.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := InlineImageFromBytes("logo.png", logo, tt.cid)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a.ContentID != tt.contentID || a.ContentType != "image/png" || a.FileName != "logo.png" {
+				t.Errorf("unexpected attachment %s %s %s", a.FileName, a.ContentType, a.ContentID)
+			}
+		})
+	}
+
+	t.Run("derived content ID", func(t *testing.T) {
+		a, err := InlineImageFromBytes("logo.png", logo, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, _ := InlineImageFromBytes("copy.png", logo, "")
+		if a.ContentID == "" || a.ContentID != b.ContentID {
+			t.Errorf("expected the same content to get the same content ID, got %q and %q", a.ContentID, b.ContentID)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for name, call := range map[string]func() error{
+			"not an image": func() error {
+				_, err := InlineImageFromBytes("logo.png", []byte("%PDF-1.7\n"), "logo")
+				return err
+			},
+			"invalid content ID": func() error {
+				_, err := InlineImageFromBytes("logo.png", logo, "my logo")
+				return err
+			},
+		} {
+			if err := call(); !errors.Is(err, ErrValidation) {
+				t.Errorf("%s: expected a validation error, got %v", name, err)
+			}
+		}
+	})
+}
+
+func TestInlineImageFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(path, encodePNG(t, noiseImage(4, 4, true)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := InlineImageFromFile(path, "logo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.FileName != "logo.png" || a.ContentType != "image/png" || a.ContentID != "logo" {
+		t.Errorf("unexpected attachment %s %s %s", a.FileName, a.ContentType, a.ContentID)
+	}
+
+	if _, err := InlineImageFromFile(path, "logo", WithMaxAttachmentBytes(10)); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected the size limit to apply, got %v", err)
+	}
+
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("notes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InlineImageFromFile(text, "notes"); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error for a text file, got %v", err)
+	}
+}
+
+func TestEmail_EmbedImage(t *testing.T) {
+	logo, err := InlineImageFromBytes("logo.png", encodePNG(t, noiseImage(4, 4, true)), "logo@icaa.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("referenced", func(t *testing.T) {
+		e := Email{HTMLBody: `<p><img src="cid:logo@icaa.example.com"></p>`}
+		if err := e.EmbedImage(logo); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(e.Attachments) != 1 {
+			t.Errorf("expected the image to be attached, got %d attachments", len(e.Attachments))
+		}
+	})
+
+	t.Run("unreferenced", func(t *testing.T) {
+		e := Email{HTMLBody: `<p><img src="cid:banner@icaa.example.com"></p>`}
+		err := e.EmbedImage(logo)
+
+		var unreferenced *UnreferencedImageError
+		if !errors.As(err, &unreferenced) || unreferenced.ContentID != "logo@icaa.example.com" {
+			t.Errorf("expected an UnreferencedImageError, got %v", err)
+		}
+		if len(e.Attachments) != 1 {
+			t.Errorf("expected the image to be attached anyway, got %d attachments", len(e.Attachments))
+		}
+	})
+
+	t.Run("not inline", func(t *testing.T) {
+		e := Email{HTMLBody: `<p>Hello</p>`}
+		if err := e.EmbedImage(Attachment{FileName: "a.pdf"}); !errors.Is(err, ErrValidation) {
+			t.Errorf("expected a validation error, got %v", err)
+		}
+		if len(e.Attachments) != 0 {
+			t.Error("expected the attachment not to be added")
+		}
+	})
+}
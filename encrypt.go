@@ -0,0 +1,94 @@
+package email
+
+import "github.com/International-Combat-Archery-Alliance/email/internal/aeszip"
+
+// DefaultEncryptedArchiveName is the file name of the archive produced by
+// EncryptAttachments unless WithArchiveName is given.
+const DefaultEncryptedArchiveName = "attachments.zip"
+
+// PasswordResolver supplies the password for the archive protecting the
+// attachments of e. It is called once per EncryptAttachments call, so it can
+// hand out a fresh password for every send.
+type PasswordResolver func(e Email) (string, error)
+
+type encryptConfig struct {
+	selectAttachment func(a Attachment) bool
+	archiveName      string
+	notice           string
+}
+
+type EncryptOption func(*encryptConfig)
+
+// WithAttachmentSelector limits encryption to the attachments for which
+// selectAttachment returns true. The others are sent as they are.
+func WithAttachmentSelector(selectAttachment func(a Attachment) bool) EncryptOption {
+	return func(c *encryptConfig) {
+		c.selectAttachment = selectAttachment
+	}
+}
+
+func WithArchiveName(name string) EncryptOption {
+	return func(c *encryptConfig) {
+		c.archiveName = name
+	}
+}
+
+// WithPasswordNotice appends notice to the bodies of the email, typically to
+// tell recipients how the password reaches them.
+func WithPasswordNotice(notice string) EncryptOption {
+	return func(c *encryptConfig) {
+		c.notice = notice
+	}
+}
+
+// EncryptAttachments returns a copy of e with its attachments replaced by a
+// single AES-256 encrypted zip archive, protected with the password returned
+// by password. Emails without matching attachments are returned unchanged.
+func EncryptAttachments(e Email, password PasswordResolver, opts ...EncryptOption) (Email, error) {
+	cfg := encryptConfig{
+		selectAttachment: func(Attachment) bool { return true },
+		archiveName:      DefaultEncryptedArchiveName,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var files []aeszip.File
+	var kept []Attachment
+	for _, a := range e.Attachments {
+		if cfg.selectAttachment(a) {
+			files = append(files, aeszip.File{Name: a.FileName, Content: a.Content})
+		} else {
+			kept = append(kept, a)
+		}
+	}
+
+	if len(files) == 0 {
+		return e, nil
+	}
+
+	pw, err := password(e)
+	if err != nil {
+		return Email{}, NewValidationError("failed to resolve attachment password", err)
+	}
+	if pw == "" {
+		return Email{}, NewValidationError("attachment password must not be empty", nil)
+	}
+
+	archive, err := aeszip.Encrypt(files, pw)
+	if err != nil {
+		return Email{}, NewUnknownError("failed to encrypt attachments", err)
+	}
+
+	e.Attachments = append(kept, Attachment{
+		FileName:    cfg.archiveName,
+		Content:     archive,
+		ContentType: "application/zip",
+	})
+
+	if cfg.notice != "" {
+		e = appendNotice(e, cfg.notice)
+	}
+
+	return e, nil
+}
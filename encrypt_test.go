@@ -0,0 +1,116 @@
+package email
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email/internal/aeszip"
+)
+
+func TestEncryptAttachments(t *testing.T) {
+	roster := Attachment{FileName: "roster.csv", Content: []byte("name,club\nAda,North\n"), ContentType: "text/csv"}
+	waiver := Attachment{FileName: "waivers.csv", Content: []byte("name,signed\nAda,yes\n"), ContentType: "text/csv"}
+	flyer := Attachment{FileName: "flyer.pdf", Content: []byte("%PDF-1.4"), ContentType: "application/pdf"}
+
+	e := Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Rosters",
+		TextBody:    "Rosters attached.",
+		HTMLBody:    "<p>Rosters attached.</p>",
+		Attachments: []Attachment{roster, flyer, waiver},
+	}
+
+	calls := 0
+	password := func(Email) (string, error) {
+		calls++
+		return "s3cret", nil
+	}
+
+	got, err := EncryptAttachments(e, password,
+		WithAttachmentSelector(func(a Attachment) bool { return strings.HasSuffix(a.FileName, ".csv") }),
+		WithArchiveName("rosters.zip"),
+		WithPasswordNotice("The password will be sent by text message."),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the password to be resolved once, got %d", calls)
+	}
+
+	if len(got.Attachments) != 2 || !reflect.DeepEqual(got.Attachments[0], flyer) {
+		t.Fatalf("expected the flyer to be kept and one archive added, got %v", got.Attachments)
+	}
+
+	archive := got.Attachments[1]
+	if archive.FileName != "rosters.zip" || archive.ContentType != "application/zip" {
+		t.Errorf("unexpected archive attachment %s (%s)", archive.FileName, archive.ContentType)
+	}
+
+	files, err := aeszip.Decrypt(archive.Content, "s3cret")
+	if err != nil {
+		t.Fatalf("failed to decrypt archive: %v", err)
+	}
+	expected := []aeszip.File{
+		{Name: roster.FileName, Content: roster.Content},
+		{Name: waiver.FileName, Content: waiver.Content},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected archive to hold %v, got %v", expected, files)
+	}
+
+	if !strings.HasSuffix(got.TextBody, "\n\nThe password will be sent by text message.") {
+		t.Errorf("expected notice in text body, got %q", got.TextBody)
+	}
+	if !strings.HasSuffix(got.HTMLBody, "<p>The password will be sent by text message.</p>") {
+		t.Errorf("expected notice in HTML body, got %q", got.HTMLBody)
+	}
+
+	if len(e.Attachments) != 3 {
+		t.Error("expected the original email to be left untouched")
+	}
+}
+
+func TestEncryptAttachments_Errors(t *testing.T) {
+	e := Email{Attachments: []Attachment{{FileName: "roster.csv", Content: []byte("x")}}}
+
+	tests := []struct {
+		name     string
+		password PasswordResolver
+	}{
+		{"empty password", func(Email) (string, error) { return "", nil }},
+		{"resolver failure", func(Email) (string, error) { return "", errors.New("vault unavailable") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncryptAttachments(e, tt.password)
+
+			var emailErr *Error
+			if !errors.As(err, &emailErr) {
+				t.Fatalf("expected email.Error, got %v", err)
+			}
+			if emailErr.Reason != REASON_VALIDATION_ERROR {
+				t.Errorf("expected error reason %s, got %s", REASON_VALIDATION_ERROR, emailErr.Reason)
+			}
+		})
+	}
+}
+
+func TestEncryptAttachments_NoAttachments(t *testing.T) {
+	e := Email{TextBody: "Hello"}
+
+	got, err := EncryptAttachments(e, func(Email) (string, error) {
+		t.Error("expected the password not to be resolved")
+		return "", nil
+	}, WithPasswordNotice("notice"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("expected email to be unchanged, got %+v", got)
+	}
+}
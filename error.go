@@ -1,6 +1,13 @@
 package email
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type ErrorReason string
 
@@ -12,14 +19,101 @@ const (
 	REASON_MESSAGE_REJECTED  ErrorReason = "MESSAGE_REJECTED"
 	REASON_SERVICE_ERROR     ErrorReason = "SERVICE_ERROR"
 	REASON_VALIDATION_ERROR  ErrorReason = "VALIDATION_ERROR"
+	// The message is over the provider's size limit.
+	REASON_MESSAGE_TOO_LARGE ErrorReason = "MESSAGE_TOO_LARGE"
+)
+
+// Error.Metadata keys set on REASON_MESSAGE_TOO_LARGE errors.
+const (
+	MessageBytesMetadataKey = "message_bytes"
+	LimitBytesMetadataKey   = "limit_bytes"
 )
 
+// Separates the provider from the name in provider specific reasons.
+const providerReasonSeparator = ":"
+
+// NewProviderReason returns a reason specific to one provider, such as
+// POSTMARK:INACTIVE_RECIPIENT, for failures the core reasons do not cover.
+// The namespace keeps it from colliding with core reasons and with other
+// providers. Both parts are upper-cased and anything but letters, digits and
+// underscores is replaced by an underscore.
+func NewProviderReason(provider, name string) ErrorReason {
+	return ErrorReason(sanitizeReasonPart(provider) + providerReasonSeparator + sanitizeReasonPart(name))
+}
+
+// IsProviderReason reports whether r was made by NewProviderReason.
+func IsProviderReason(r ErrorReason) bool {
+	return strings.Contains(string(r), providerReasonSeparator)
+}
+
+// SplitReason returns the provider and name of a provider specific reason.
+// Core reasons have an empty provider.
+func SplitReason(r ErrorReason) (provider, name string) {
+	provider, name, ok := strings.Cut(string(r), providerReasonSeparator)
+	if !ok {
+		return "", string(r)
+	}
+	return provider, name
+}
+
+// Retryable reports whether a send that failed for reason r may succeed
+// when tried again later. Provider specific reasons are not retryable, as
+// the core cannot know what they mean.
+func (r ErrorReason) Retryable() bool {
+	return r == REASON_RATE_LIMITED || r == REASON_SERVICE_ERROR
+}
+
+func sanitizeReasonPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+	if s == "" {
+		return "UNKNOWN"
+	}
+	return s
+}
+
 var _ error = &Error{}
 
 type Error struct {
 	Message string
 	Reason  ErrorReason
 	Cause   error
+	// How long the provider asked to wait before retrying, if it said.
+	RetryAfter time.Duration
+	// Provider specific details, such as the HTTP status or error code.
+	Metadata map[string]string
+}
+
+// Sentinels for matching errors by reason with errors.Is, e.g.
+// errors.Is(err, email.ErrRateLimited).
+var (
+	ErrUnknown          = &Error{Reason: REASON_UNKNOWN}
+	ErrRateLimited      = &Error{Reason: REASON_RATE_LIMITED}
+	ErrInvalidEmail     = &Error{Reason: REASON_INVALID_EMAIL}
+	ErrUnverifiedDomain = &Error{Reason: REASON_UNVERIFIED_DOMAIN}
+	ErrMessageRejected  = &Error{Reason: REASON_MESSAGE_REJECTED}
+	ErrServiceError     = &Error{Reason: REASON_SERVICE_ERROR}
+	ErrValidation       = &Error{Reason: REASON_VALIDATION_ERROR}
+	ErrMessageTooLarge  = &Error{Reason: REASON_MESSAGE_TOO_LARGE}
+)
+
+var sentinels = map[ErrorReason]*Error{
+	REASON_UNKNOWN:           ErrUnknown,
+	REASON_RATE_LIMITED:      ErrRateLimited,
+	REASON_INVALID_EMAIL:     ErrInvalidEmail,
+	REASON_UNVERIFIED_DOMAIN: ErrUnverifiedDomain,
+	REASON_MESSAGE_REJECTED:  ErrMessageRejected,
+	REASON_SERVICE_ERROR:     ErrServiceError,
+	REASON_VALIDATION_ERROR:  ErrValidation,
+	REASON_MESSAGE_TOO_LARGE: ErrMessageTooLarge,
 }
 
 func (e *Error) Error() string {
@@ -34,6 +128,74 @@ func (e *Error) Unwrap() error {
 	return e.Cause
 }
 
+// Is matches the sentinel error for the reason of e.
+func (e *Error) Is(target error) bool {
+	sentinel, ok := sentinels[e.Reason]
+	return ok && target == sentinel
+}
+
+type errorJSON struct {
+	Reason     ErrorReason       `json:"reason"`
+	Message    string            `json:"message"`
+	RetryAfter string            `json:"retryAfter,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	// Causes can't be reconstructed, so only their text is kept.
+	Cause string `json:"cause,omitempty"`
+}
+
+// MarshalJSON encodes e for storage, e.g. in a job queue. The cause is
+// flattened to its message.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	j := errorJSON{
+		Reason:   e.Reason,
+		Message:  e.Message,
+		Metadata: e.Metadata,
+	}
+	if e.RetryAfter != 0 {
+		j.RetryAfter = e.RetryAfter.String()
+	}
+	if e.Cause != nil {
+		j.Cause = e.Cause.Error()
+	}
+	return json.Marshal(j)
+}
+
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var j errorJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+	if j.RetryAfter != "" {
+		d, err := time.ParseDuration(j.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("invalid retryAfter: %w", err)
+		}
+		retryAfter = d
+	}
+
+	*e = Error{
+		Message:    j.Message,
+		Reason:     j.Reason,
+		RetryAfter: retryAfter,
+		Metadata:   j.Metadata,
+	}
+	if j.Cause != "" {
+		e.Cause = errors.New(j.Cause)
+	}
+	return nil
+}
+
+// FromJSON reconstructs an Error stored with json.Marshal.
+func FromJSON(data []byte) (*Error, error) {
+	e := &Error{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 func newError(reason ErrorReason, message string, cause error) *Error {
 	return &Error{
 		Message: message,
@@ -69,3 +231,14 @@ func NewServiceError(message string, cause error) *Error {
 func NewValidationError(message string, cause error) *Error {
 	return newError(REASON_VALIDATION_ERROR, message, cause)
 }
+
+// NewMessageTooLargeError reports a message of size bytes over a limit of
+// limit bytes, both also set in the metadata.
+func NewMessageTooLargeError(message string, size, limit int) *Error {
+	err := newError(REASON_MESSAGE_TOO_LARGE, message, nil)
+	err.Metadata = map[string]string{
+		MessageBytesMetadataKey: strconv.Itoa(size),
+		LimitBytesMetadataKey:   strconv.Itoa(limit),
+	}
+	return err
+}
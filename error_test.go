@@ -0,0 +1,181 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestError_JSONRoundTrip(t *testing.T) {
+	reasons := []struct {
+		reason   ErrorReason
+		sentinel *Error
+	}{
+		{REASON_UNKNOWN, ErrUnknown},
+		{REASON_RATE_LIMITED, ErrRateLimited},
+		{REASON_INVALID_EMAIL, ErrInvalidEmail},
+		{REASON_UNVERIFIED_DOMAIN, ErrUnverifiedDomain},
+		{REASON_MESSAGE_REJECTED, ErrMessageRejected},
+		{REASON_SERVICE_ERROR, ErrServiceError},
+		{REASON_VALIDATION_ERROR, ErrValidation},
+		{REASON_MESSAGE_TOO_LARGE, ErrMessageTooLarge},
+	}
+
+	for _, r := range reasons {
+		t.Run(string(r.reason), func(t *testing.T) {
+			original := &Error{
+				Message:    "something went wrong",
+				Reason:     r.reason,
+				Cause:      errors.New("upstream said no"),
+				RetryAfter: 90 * time.Second,
+				Metadata:   map[string]string{"http_status": "429", "error_code": "Throttling"},
+			}
+
+			data, err := json.Marshal(original)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := FromJSON(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Message != original.Message || got.Reason != original.Reason || got.RetryAfter != original.RetryAfter {
+				t.Errorf("expected %+v, got %+v", original, got)
+			}
+			if !reflect.DeepEqual(got.Metadata, original.Metadata) {
+				t.Errorf("expected metadata %v, got %v", original.Metadata, got.Metadata)
+			}
+			if got.Cause == nil || got.Cause.Error() != "upstream said no" {
+				t.Errorf("expected the flattened cause, got %v", got.Cause)
+			}
+			if got.Error() != original.Error() {
+				t.Errorf("expected message %q, got %q", original.Error(), got.Error())
+			}
+
+			if !errors.Is(got, r.sentinel) {
+				t.Errorf("expected errors.Is to match %s", r.reason)
+			}
+			for _, other := range reasons {
+				if other.reason != r.reason && errors.Is(got, other.sentinel) {
+					t.Errorf("expected errors.Is not to match %s", other.reason)
+				}
+			}
+		})
+	}
+}
+
+func TestError_JSONMinimal(t *testing.T) {
+	data, err := json.Marshal(NewValidationError("subject is required", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != `{"reason":"VALIDATION_ERROR","message":"subject is required"}` {
+		t.Errorf("unexpected encoding %s", data)
+	}
+
+	got, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Cause != nil || got.RetryAfter != 0 || got.Metadata != nil {
+		t.Errorf("expected no optional fields, got %+v", got)
+	}
+
+	if _, err := FromJSON([]byte(`{"reason":"RATE_LIMITED","retryAfter":"soon"}`)); err == nil {
+		t.Error("expected error for an invalid retryAfter")
+	}
+}
+
+func TestError_IsWrapped(t *testing.T) {
+	err := errors.Join(errors.New("batch failed"), NewRateLimitedError("slow down", nil))
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("expected a wrapped error to match its sentinel")
+	}
+	if errors.Is(err, ErrServiceError) {
+		t.Error("expected a wrapped error not to match another sentinel")
+	}
+}
+
+func TestProviderReason(t *testing.T) {
+	tests := []struct {
+		provider, name   string
+		expected         ErrorReason
+		expectedProvider string
+		expectedName     string
+	}{
+		{"postmark", "inactive recipient", "POSTMARK:INACTIVE_RECIPIENT", "POSTMARK", "INACTIVE_RECIPIENT"},
+		{"Send-Grid", "RATE_LIMITED", "SEND_GRID:RATE_LIMITED", "SEND_GRID", "RATE_LIMITED"},
+		{"a:b", "c", "A_B:C", "A_B", "C"},
+		{"", "", "UNKNOWN:UNKNOWN", "UNKNOWN", "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.expected), func(t *testing.T) {
+			r := NewProviderReason(tt.provider, tt.name)
+			if r != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, r)
+			}
+			if !IsProviderReason(r) {
+				t.Error("expected a provider reason")
+			}
+			if _, ok := sentinels[r]; ok {
+				t.Errorf("%q collides with a core reason", r)
+			}
+			if provider, name := SplitReason(r); provider != tt.expectedProvider || name != tt.expectedName {
+				t.Errorf("expected %s and %s, got %s and %s", tt.expectedProvider, tt.expectedName, provider, name)
+			}
+			if r.Retryable() {
+				t.Error("expected provider reasons not to be retryable")
+			}
+		})
+	}
+
+	if IsProviderReason(REASON_RATE_LIMITED) {
+		t.Error("expected core reasons not to be provider reasons")
+	}
+	if provider, name := SplitReason(REASON_RATE_LIMITED); provider != "" || name != string(REASON_RATE_LIMITED) {
+		t.Errorf("unexpected split of a core reason: %q, %q", provider, name)
+	}
+}
+
+func TestErrorReason_Retryable(t *testing.T) {
+	retryable := map[ErrorReason]bool{
+		REASON_UNKNOWN:           false,
+		REASON_RATE_LIMITED:      true,
+		REASON_INVALID_EMAIL:     false,
+		REASON_UNVERIFIED_DOMAIN: false,
+		REASON_MESSAGE_REJECTED:  false,
+		REASON_SERVICE_ERROR:     true,
+		REASON_VALIDATION_ERROR:  false,
+		REASON_MESSAGE_TOO_LARGE: false,
+	}
+	for reason := range sentinels {
+		if reason.Retryable() != retryable[reason] {
+			t.Errorf("expected %s retryable=%t", reason, retryable[reason])
+		}
+	}
+}
+
+func TestError_ProviderReasonJSON(t *testing.T) {
+	reason := NewProviderReason("postmark", "inactive recipient")
+	data, err := json.Marshal(&Error{Reason: reason, Message: "inactive"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Reason != reason {
+		t.Errorf("expected %q, got %q", reason, got.Reason)
+	}
+	if errors.Is(got, ErrUnknown) {
+		t.Error("expected provider reasons not to match core sentinels")
+	}
+}
@@ -0,0 +1,218 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+type EventKind string
+
+const (
+	// The send was handed to the EventSender.
+	EVENT_QUEUED EventKind = "QUEUED"
+	// The email passed validation and is about to reach the provider.
+	EVENT_ATTEMPTING EventKind = "ATTEMPTING"
+	EVENT_SENT       EventKind = "SENT"
+	EVENT_FAILED     EventKind = "FAILED"
+)
+
+// Event describes a step in the lifecycle of a single send.
+type Event struct {
+	Kind           EventKind
+	Time           time.Time
+	IdempotencyKey string
+	CampaignID     string
+	SequenceStep   int
+	// Set on EVENT_SENT.
+	ProviderMessageID string
+	DryRun            bool
+	// Set on EVENT_FAILED.
+	Reason ErrorReason
+	Err    error
+}
+
+type EventDeliveryPolicy string
+
+const (
+	// Publishing waits for the channel to have room, slowing sends down to
+	// the pace of the consumer. An event is dropped instead if the send's
+	// context is done first.
+	EVENT_DELIVERY_BLOCK EventDeliveryPolicy = "BLOCK"
+	// Publishing discards the oldest buffered event when the channel is full,
+	// so a slow consumer never holds up sends. On an unbuffered channel,
+	// events nobody is waiting for are dropped.
+	EVENT_DELIVERY_DROP_OLDEST EventDeliveryPolicy = "DROP_OLDEST"
+)
+
+var _ Sender = &EventSender{}
+var _ SenderV2 = &EventSender{}
+
+// EventSender decorates a SenderV2 to publish an Event for every step of each
+// send, e.g. to show live progress of a campaign.
+type EventSender struct {
+	inner    SenderV2
+	callback func(Event)
+	events   chan Event
+	policy   EventDeliveryPolicy
+	dropped  atomic.Int64
+	clock    Clock
+}
+
+type EventOption func(*EventSender)
+
+// WithEventChannel publishes events to ch according to policy. The channel is
+// bidirectional so EVENT_DELIVERY_DROP_OLDEST can take events back out of it.
+func WithEventChannel(ch chan Event, policy EventDeliveryPolicy) EventOption {
+	return func(s *EventSender) {
+		s.events = ch
+		s.policy = policy
+	}
+}
+
+// WithEventCallback calls fn with every event, on the sending goroutine.
+func WithEventCallback(fn func(Event)) EventOption {
+	return func(s *EventSender) {
+		s.callback = fn
+	}
+}
+
+// WithEventClock sets the Clock used to timestamp events.
+func WithEventClock(c Clock) EventOption {
+	return func(s *EventSender) {
+		s.clock = c
+	}
+}
+
+func NewEventSender(inner SenderV2, opts ...EventOption) *EventSender {
+	s := &EventSender{
+		inner:  inner,
+		policy: EVENT_DELIVERY_BLOCK,
+		clock:  SystemClock(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *EventSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+// Dropped returns how many events were not delivered to the channel, either
+// discarded by EVENT_DELIVERY_DROP_OLDEST or abandoned when a send's context
+// was done.
+func (s *EventSender) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *EventSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *EventSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	var wrapped SendOptions
+	if opts != nil {
+		wrapped = *opts
+	}
+
+	newEvent := func(kind EventKind, e Email) Event {
+		return Event{
+			Kind:           kind,
+			Time:           s.clock.Now(),
+			IdempotencyKey: wrapped.IdempotencyKey,
+			CampaignID:     e.CampaignID,
+			SequenceStep:   e.SequenceStep,
+		}
+	}
+
+	final := opts.Apply(e)
+	s.publish(ctx, newEvent(EVENT_QUEUED, final))
+
+	beforeSend := wrapped.Hooks.BeforeSend
+	wrapped.Hooks.BeforeSend = func(ctx context.Context, e Email) error {
+		s.publish(ctx, newEvent(EVENT_ATTEMPTING, e))
+		if beforeSend != nil {
+			return beforeSend(ctx, e)
+		}
+		return nil
+	}
+
+	result, err := s.inner.SendEmailV2(ctx, e, &wrapped)
+	if err != nil {
+		ev := newEvent(EVENT_FAILED, final)
+		ev.Reason = REASON_UNKNOWN
+		var emailErr *Error
+		if errors.As(err, &emailErr) {
+			ev.Reason = emailErr.Reason
+		}
+		ev.Err = err
+		s.publish(ctx, ev)
+		return result, err
+	}
+
+	ev := newEvent(EVENT_SENT, final)
+	if result != nil {
+		ev.ProviderMessageID = result.ProviderMessageID
+		ev.DryRun = result.DryRun
+	}
+	s.publish(ctx, ev)
+
+	return result, nil
+}
+
+func (s *EventSender) publish(ctx context.Context, ev Event) {
+	if s.callback != nil {
+		s.callback(ev)
+	}
+
+	if s.events == nil {
+		return
+	}
+
+	// Delivered right away if there is room, even if ctx is already done,
+	// e.g. for the EVENT_FAILED of a cancelled send.
+	select {
+	case s.events <- ev:
+		return
+	default:
+	}
+
+	if s.policy != EVENT_DELIVERY_DROP_OLDEST {
+		select {
+		case s.events <- ev:
+		case <-ctx.Done():
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	// There is no oldest event to discard without a buffer, and waiting
+	// for a reader would hold up the send.
+	if cap(s.events) == 0 {
+		s.dropped.Add(1)
+		return
+	}
+
+	for {
+		select {
+		case s.events <- ev:
+			return
+		default:
+		}
+
+		// The channel is full: discard the oldest event to make room. Another
+		// reader may have drained it in the meantime, in which case the next
+		// attempt succeeds without dropping anything.
+		select {
+		case <-s.events:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventSender_Lifecycle(t *testing.T) {
+	tests := []struct {
+		name          string
+		inner         *recordingSender
+		beforeSend    func(ctx context.Context, e Email) error
+		expectedKinds []EventKind
+		expectedErr   ErrorReason
+	}{
+		{
+			name:          "sent",
+			inner:         &recordingSender{},
+			expectedKinds: []EventKind{EVENT_QUEUED, EVENT_ATTEMPTING, EVENT_SENT},
+		},
+		{
+			name:          "provider failure",
+			inner:         &recordingSender{err: NewRateLimitedError("slow down", nil)},
+			expectedKinds: []EventKind{EVENT_QUEUED, EVENT_ATTEMPTING, EVENT_FAILED},
+			expectedErr:   REASON_RATE_LIMITED,
+		},
+		{
+			name:          "rejected before the attempt",
+			inner:         &recordingSender{},
+			beforeSend:    func(ctx context.Context, e Email) error { return errors.New("blocked") },
+			expectedKinds: []EventKind{EVENT_QUEUED, EVENT_ATTEMPTING, EVENT_FAILED},
+			expectedErr:   REASON_UNKNOWN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Event
+			sender := NewEventSender(AsSenderV2(tt.inner), WithEventCallback(func(ev Event) {
+				events = append(events, ev)
+			}))
+
+			_, _ = sender.SendEmailV2(context.Background(), Email{CampaignID: "draft"}, &SendOptions{
+				IdempotencyKey: "key-1",
+				Override:       func(e *Email) { e.CampaignID = "spring"; e.SequenceStep = 2 },
+				Hooks:          SendHooks{BeforeSend: tt.beforeSend},
+			})
+
+			var kinds []EventKind
+			for _, ev := range events {
+				kinds = append(kinds, ev.Kind)
+				if ev.IdempotencyKey != "key-1" || ev.CampaignID != "spring" || ev.SequenceStep != 2 {
+					t.Errorf("expected identifiers on %s event, got %+v", ev.Kind, ev)
+				}
+				if ev.Time.IsZero() {
+					t.Errorf("expected a timestamp on %s event", ev.Kind)
+				}
+			}
+			if !reflect.DeepEqual(kinds, tt.expectedKinds) {
+				t.Fatalf("expected events %v, got %v", tt.expectedKinds, kinds)
+			}
+
+			last := events[len(events)-1]
+			if last.Reason != tt.expectedErr {
+				t.Errorf("expected reason %q, got %q", tt.expectedErr, last.Reason)
+			}
+			if (tt.expectedErr != "") != (last.Err != nil) {
+				t.Errorf("expected Err to be set only on failure, got %v", last.Err)
+			}
+		})
+	}
+}
+
+func TestEventSender_DropOldest(t *testing.T) {
+	ch := make(chan Event, 2)
+	sender := NewEventSender(AsSenderV2(&recordingSender{}), WithEventChannel(ch, EVENT_DELIVERY_DROP_OLDEST))
+
+	// Nobody reads ch, so all but the last two events must be dropped
+	// without blocking the sends.
+	for range 3 {
+		if err := sender.SendEmail(context.Background(), Email{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if sender.Dropped() != 7 {
+		t.Errorf("expected 7 dropped events, got %d", sender.Dropped())
+	}
+
+	close(ch)
+	var kinds []EventKind
+	for ev := range ch {
+		kinds = append(kinds, ev.Kind)
+	}
+	if expected := []EventKind{EVENT_ATTEMPTING, EVENT_SENT}; !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected the newest events %v, got %v", expected, kinds)
+	}
+}
+
+func TestEventSender_Block(t *testing.T) {
+	ch := make(chan Event)
+	sender := NewEventSender(AsSenderV2(&recordingSender{}), WithEventChannel(ch, EVENT_DELIVERY_BLOCK))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.SendEmail(context.Background(), Email{})
+		close(ch)
+	}()
+
+	var kinds []EventKind
+	for ev := range ch {
+		kinds = append(kinds, ev.Kind)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []EventKind{EVENT_QUEUED, EVENT_ATTEMPTING, EVENT_SENT}; !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected every event in order %v, got %v", expected, kinds)
+	}
+	if sender.Dropped() != 0 {
+		t.Errorf("expected no dropped events, got %d", sender.Dropped())
+	}
+}
+
+func TestEventSender_DropOldestUnbuffered(t *testing.T) {
+	ch := make(chan Event)
+	sender := NewEventSender(AsSenderV2(&recordingSender{}), WithEventChannel(ch, EVENT_DELIVERY_DROP_OLDEST))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.SendEmail(context.Background(), Email{})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the send not to wait for a reader")
+	}
+	if sender.Dropped() != 3 {
+		t.Errorf("expected 3 dropped events, got %d", sender.Dropped())
+	}
+}
+
+func TestEventSender_BlockCancelled(t *testing.T) {
+	ch := make(chan Event)
+	sender := NewEventSender(AsSenderV2(&recordingSender{}), WithEventChannel(ch, EVENT_DELIVERY_BLOCK))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.SendEmail(ctx, Email{})
+	}()
+
+	// Nobody reads ch, so the send waits on its first event until cancelled.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the send to stop waiting once its context was cancelled")
+	}
+	if sender.Dropped() == 0 {
+		t.Error("expected the undelivered events to be counted as dropped")
+	}
+}
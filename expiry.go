@@ -0,0 +1,43 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// ExpiryNoticeResolver renders the human-readable expiry notice for the
+// recipients of an email, typically in their locale and time zone.
+type ExpiryNoticeResolver func(e Email, expires time.Time) string
+
+// EnglishExpiryNotice returns an ExpiryNoticeResolver that renders the expiry
+// in English in the given time zone.
+func EnglishExpiryNotice(loc *time.Location) ExpiryNoticeResolver {
+	return func(e Email, expires time.Time) string {
+		return fmt.Sprintf("This message expires on %s.", expires.In(loc).Format("Monday, January 2, 2006 at 3:04 PM MST"))
+	}
+}
+
+// WithExpiryNotice returns a copy of e with the notice produced by resolve
+// appended to its text and HTML bodies. Emails without an Expires date are
+// returned unchanged.
+func WithExpiryNotice(e Email, resolve ExpiryNoticeResolver) Email {
+	if e.Expires.IsZero() {
+		return e
+	}
+
+	return appendNotice(e, resolve(e, e.Expires))
+}
+
+// appendNotice adds notice as a final paragraph to whichever bodies e has.
+func appendNotice(e Email, notice string) Email {
+	if e.TextBody != "" {
+		e.TextBody += "\n\n" + notice
+	}
+
+	if e.HTMLBody != "" {
+		e.HTMLBody += "<p>" + html.EscapeString(notice) + "</p>"
+	}
+
+	return e
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithExpiryNotice(t *testing.T) {
+	expires := time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name         string
+		email        Email
+		expectedText string
+		expectedHTML string
+	}{
+		{
+			name: "appends notice to both bodies",
+			email: Email{
+				TextBody: "Check in here",
+				HTMLBody: "<p>Check in here</p>",
+				Expires:  expires,
+			},
+			expectedText: "Check in here\n\nThis message expires on Tuesday, March 5, 2030 at 3:30 AM JST.",
+			expectedHTML: "<p>Check in here</p><p>This message expires on Tuesday, March 5, 2030 at 3:30 AM JST.</p>",
+		},
+		{
+			name: "leaves empty bodies empty",
+			email: Email{
+				TextBody: "Check in here",
+				Expires:  expires,
+			},
+			expectedText: "Check in here\n\nThis message expires on Tuesday, March 5, 2030 at 3:30 AM JST.",
+		},
+		{
+			name: "no expiry leaves email unchanged",
+			email: Email{
+				TextBody: "Check in here",
+				HTMLBody: "<p>Check in here</p>",
+			},
+			expectedText: "Check in here",
+			expectedHTML: "<p>Check in here</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WithExpiryNotice(tt.email, EnglishExpiryNotice(tokyo))
+
+			if got.TextBody != tt.expectedText {
+				t.Errorf("expected text body %q, got %q", tt.expectedText, got.TextBody)
+			}
+			if got.HTMLBody != tt.expectedHTML {
+				t.Errorf("expected HTML body %q, got %q", tt.expectedHTML, got.HTMLBody)
+			}
+		})
+	}
+}
+
+func TestWithExpiryNotice_EscapesHTML(t *testing.T) {
+	e := Email{
+		HTMLBody: "<p>Hi</p>",
+		Expires:  time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC),
+	}
+
+	got := WithExpiryNotice(e, func(e Email, expires time.Time) string {
+		return "Expires <soon> & forever"
+	})
+
+	if !strings.HasSuffix(got.HTMLBody, "<p>Expires &lt;soon&gt; &amp; forever</p>") {
+		t.Errorf("expected escaped notice, got %q", got.HTMLBody)
+	}
+}
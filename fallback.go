@@ -0,0 +1,82 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DeliveryFallbackHeader carries Email.FallbackFor.
+const DeliveryFallbackHeader = "X-Delivery-Fallback"
+
+var _ Sender = &FallbackSender{}
+var _ SenderV2 = &FallbackSender{}
+
+// FallbackSender decorates a SenderV2 to resend an email to the recipient's
+// AlternateAddresses, in order, when the provider rejects the address with
+// REASON_INVALID_EMAIL. Each address is tried at most once. Only emails with
+// a single To recipient and no CC or BCC are rerouted, since a rejection does
+// not say which of several recipients failed.
+type FallbackSender struct {
+	inner SenderV2
+}
+
+func NewFallbackSender(inner SenderV2) *FallbackSender {
+	return &FallbackSender{inner: inner}
+}
+
+func (s *FallbackSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *FallbackSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *FallbackSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	result, err := s.inner.SendEmailV2(ctx, e, wrapped)
+	// An email that already is a fallback is never rerouted again, so
+	// stacked FallbackSenders cannot loop.
+	if err == nil || !errors.Is(err, ErrInvalidEmail) || e.FallbackFor != "" ||
+		len(e.ToAddresses) != 1 || len(e.CCAddresses)+len(e.BCCAddresses) > 0 {
+		return result, err
+	}
+
+	primary := e.ToAddresses[0]
+	tried := []string{strings.ToLower(primary)}
+	for _, alt := range e.AlternateAddresses {
+		if slices.Contains(tried, strings.ToLower(alt)) {
+			continue
+		}
+		tried = append(tried, strings.ToLower(alt))
+
+		fallback := e
+		fallback.ToAddresses = []string{alt}
+		fallback.AlternateAddresses = nil
+		fallback.FallbackFor = primary
+
+		result, err = s.inner.SendEmailV2(ctx, fallback, wrapped)
+		if err == nil {
+			if result == nil {
+				result = &SendResult{}
+			}
+			result.Fallbacks = append(result.Fallbacks, AddressCorrection{Original: primary, Corrected: alt})
+			return result, nil
+		}
+		if !errors.Is(err, ErrInvalidEmail) {
+			return result, err
+		}
+	}
+
+	if len(tried) == 1 {
+		return result, err
+	}
+	return nil, NewInvalidEmailError(fmt.Sprintf("%s and its alternate addresses were all rejected", primary), err)
+}
@@ -0,0 +1,117 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// rejectingSender fails with REASON_INVALID_EMAIL for the rejected addresses.
+type rejectingSender struct {
+	rejected []string
+	sent     []Email
+}
+
+func (s *rejectingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	s.sent = append(s.sent, e)
+	if slices.Contains(s.rejected, e.ToAddresses[0]) {
+		return nil, NewInvalidEmailError("address rejected", nil)
+	}
+	return &SendResult{Provider: "rejecting"}, nil
+}
+
+func TestFallbackSender(t *testing.T) {
+	tests := []struct {
+		name              string
+		alternates        []string
+		rejected          []string
+		expectedTried     []string
+		expectedFallbacks []AddressCorrection
+		expectedErr       bool
+	}{
+		{
+			name:          "primary succeeds",
+			alternates:    []string{"ada@work.example.com"},
+			expectedTried: []string{"ada@example.com"},
+		},
+		{
+			name:              "fallback succeeds",
+			alternates:        []string{"ada@old.example.com", "ada@work.example.com"},
+			rejected:          []string{"ada@example.com", "ada@old.example.com"},
+			expectedTried:     []string{"ada@example.com", "ada@old.example.com", "ada@work.example.com"},
+			expectedFallbacks: []AddressCorrection{{Original: "ada@example.com", Corrected: "ada@work.example.com"}},
+		},
+		{
+			name:          "all addresses fail",
+			alternates:    []string{"ADA@example.com", "ada@old.example.com", "ada@old.example.com"},
+			rejected:      []string{"ada@example.com", "ada@old.example.com"},
+			expectedTried: []string{"ada@example.com", "ada@old.example.com"},
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &rejectingSender{rejected: tt.rejected}
+			sender := NewFallbackSender(inner)
+
+			result, err := sender.SendEmailV2(context.Background(), Email{
+				ToAddresses:        []string{"ada@example.com"},
+				AlternateAddresses: tt.alternates,
+			}, nil)
+
+			var tried []string
+			for i, e := range inner.sent {
+				tried = append(tried, e.ToAddresses[0])
+				if i > 0 && e.FallbackFor != "ada@example.com" {
+					t.Errorf("expected fallback %d to name the primary address, got %q", i, e.FallbackFor)
+				}
+			}
+			if !reflect.DeepEqual(tried, tt.expectedTried) {
+				t.Errorf("expected addresses %v to be tried, got %v", tt.expectedTried, tried)
+			}
+
+			if tt.expectedErr {
+				if !errors.Is(err, ErrInvalidEmail) {
+					t.Fatalf("expected an invalid email error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result.Fallbacks, tt.expectedFallbacks) {
+				t.Errorf("expected fallbacks %v, got %v", tt.expectedFallbacks, result.Fallbacks)
+			}
+		})
+	}
+}
+
+func TestFallbackSender_NotRerouted(t *testing.T) {
+	tests := []struct {
+		name  string
+		email Email
+	}{
+		{"several recipients", Email{ToAddresses: []string{"ada@example.com", "bo@example.com"}}},
+		{"cc", Email{ToAddresses: []string{"ada@example.com"}, CCAddresses: []string{"bo@example.com"}}},
+		{"already a fallback", Email{ToAddresses: []string{"ada@example.com"}, FallbackFor: "ada@old.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &rejectingSender{rejected: []string{"ada@example.com"}}
+			e := tt.email
+			e.AlternateAddresses = []string{"ada@work.example.com"}
+
+			_, err := NewFallbackSender(inner).SendEmailV2(context.Background(), e, nil)
+			if !errors.Is(err, ErrInvalidEmail) {
+				t.Fatalf("expected the original rejection, got %v", err)
+			}
+			if len(inner.sent) != 1 {
+				t.Errorf("expected a single attempt, got %d", len(inner.sent))
+			}
+		})
+	}
+}
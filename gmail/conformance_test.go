@@ -0,0 +1,44 @@
+package gmail
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email/providersdk/providertest"
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunSenderConformance(t, newConformanceHarness)
+}
+
+func TestConformanceV2(t *testing.T) {
+	providertest.RunSenderV2Conformance(t, newConformanceHarness)
+}
+
+func newConformanceHarness(t *testing.T) providertest.Harness {
+	var raw string
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			decoded, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			raw = string(decoded)
+			return &gmail.Message{Id: "conformance-message-id"}, nil
+		},
+	}
+
+	return providertest.Harness{
+		Sender: newTestGmailSender(mockService),
+		Observe: func() (providertest.Observation, bool) {
+			if raw == "" {
+				return providertest.Observation{}, false
+			}
+			return providertest.ObserveRawMessage(t, raw), true
+		},
+		BCC:       providertest.BCC_STRIPPED_HEADER,
+		MessageID: "conformance-message-id",
+	}
+}
@@ -3,10 +3,12 @@ package gmail
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"mime"
-	"net/mail"
+	neturl "net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
@@ -14,263 +16,445 @@ import (
 	"google.golang.org/api/option"
 
 	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/providersdk"
 )
 
-type GmailSender struct {
-	service *gmail.Service
-	userID  string
+// ProviderName identifies Gmail in SendResult.Provider.
+const ProviderName = "gmail"
+
+// MaxMessageBytes is the largest send request Gmail accepts. The limit
+// applies to the message after base64url encoding and wrapping in the JSON
+// request body; see EncodedSize.
+const MaxMessageBytes = 25 * 1024 * 1024
+
+// DefaultSizeSafetyMargin is kept free below MaxMessageBytes unless
+// WithSizeSafetyMargin is given, for request overhead not accounted for by
+// EncodedSize.
+const DefaultSizeSafetyMargin = 64 * 1024
+
+// MaxRecipients is the most To, CC and BCC recipients Gmail accepts in one
+// message.
+const MaxRecipients = 500
+
+// The JSON request body around the encoded message: {"raw":"..."}.
+const requestEnvelopeBytes = len(`{"raw":""}`)
+
+// Error.Metadata keys set when a message is too large for Gmail, next to
+// email.MessageBytesMetadataKey and email.LimitBytesMetadataKey.
+const (
+	MessageBytesMetadataKey = email.MessageBytesMetadataKey
+	EncodedBytesMetadataKey = "encoded_bytes"
+	LimitBytesMetadataKey   = email.LimitBytesMetadataKey
+)
+
+// EncodedSize is the size of the send request for a raw message of
+// messageBytes bytes, for comparing against MaxMessageBytes, e.g. with the
+// total of email.EstimateSize.
+func EncodedSize(messageBytes int) int {
+	return base64.URLEncoding.EncodedLen(messageBytes) + requestEnvelopeBytes
 }
 
-func NewGmailSender(ctx context.Context, credentialsJSON []byte, userEmail string) (*GmailSender, error) {
-	config, err := google.JWTConfigFromJSON(credentialsJSON, gmail.GmailSendScope)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse service account file: %v", err)
-	}
+var _ email.Sender = &GmailSender{}
+var _ email.SenderV2 = &GmailSender{}
+var _ email.OptionDumper = &GmailSender{}
+var _ email.CapabilityReporter = &GmailSender{}
 
-	config.Subject = userEmail
+// gmailService is the subset of the Gmail API used by GmailSender.
+type gmailService interface {
+	sendMessage(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error)
+}
 
-	service, err := gmail.NewService(ctx, option.WithHTTPClient(config.Client(ctx)))
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve Gmail client: %v", err)
-	}
+type apiService struct {
+	service *gmail.Service
+}
 
-	return &GmailSender{
-		service: service,
-		userID:  "me",
-	}, nil
+func (s *apiService) sendMessage(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+	return s.service.Users.Messages.Send(userID, message).Context(ctx).Do()
 }
 
-func (g *GmailSender) SendEmail(ctx context.Context, e email.Email) error {
-	if err := g.validateEmail(e); err != nil {
-		return err
-	}
+// GmailSender sends emails as raw messages through the Gmail API. Gmail
+// learns the BCC recipients from the message's Bcc header and removes it
+// before delivery, so it is the one provider-bound message that keeps it.
+type GmailSender struct {
+	service        gmailService
+	userID         string
+	validateOutput bool
+	sizeMargin     int
+	validator      *providersdk.Validator
+	ruleCount      int
+	build          providersdk.BuildOptions
+	baseURL        string
+	clientOptions  []option.ClientOption
+	// Skips the service account credentials, see WithoutAuthentication.
+	unauthenticated bool
+	// Stamps the Date of emails without one. Nil leaves it to Gmail.
+	clock    email.Clock
+	defaults providersdk.Defaults
+	// Splits sends with more than MaxRecipients, see WithAutoChunking.
+	chunking bool
+	// Problems with the options, reported by NewGmailSender.
+	optionErrs email.OptionErrors
+	// Lets tests tamper with the generated message before it is validated.
+	rawHook func(raw []byte) []byte
+}
 
-	message, err := g.createMessage(e)
-	if err != nil {
-		return email.NewValidationError("Failed to create message", err)
+// Option configures a GmailSender. Invalid and conflicting options are
+// reported together by NewGmailSender.
+type Option func(*GmailSender)
+
+// WithOutputValidation controls whether every generated message is re-parsed
+// and checked against the email it was built from before it is sent. It is
+// enabled by default; disable it to skip the cost in production.
+func WithOutputValidation(enabled bool) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithOutputValidation")
+		g.validateOutput = enabled
 	}
+}
 
-	_, err = g.service.Users.Messages.Send(g.userID, message).Context(ctx).Do()
-	if err != nil {
-		return g.mapGmailError(err)
+// WithAddressRules checks every address against rules before sending. See
+// providersdk.Validator.
+func WithAddressRules(rules ...email.AddressRule) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithAddressRules")
+		g.validator = providersdk.NewValidator(rules...)
+		g.ruleCount = len(rules)
 	}
+}
 
-	return nil
+// WithSizeSafetyMargin replaces DefaultSizeSafetyMargin. It must not be
+// negative.
+func WithSizeSafetyMargin(bytes int) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithSizeSafetyMargin")
+		if bytes < 0 {
+			g.optionErrs.Add("WithSizeSafetyMargin: margin must not be negative, got %d", bytes)
+		}
+		g.sizeMargin = bytes
+	}
 }
 
-func (g *GmailSender) createMessage(e email.Email) (*gmail.Message, error) {
-	headers := []string{
-		fmt.Sprintf("From: %s", e.FromAddress),
-		fmt.Sprintf("To: %s", strings.Join(e.ToAddresses, ", ")),
-		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("utf-8", e.Subject)),
-		"MIME-Version: 1.0",
+// WithBaseURL sends API requests to url instead of the Gmail API, e.g. to a
+// fake server speaking its REST shape in integration tests.
+func WithBaseURL(url string) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithBaseURL")
+		if u, err := neturl.Parse(url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			g.optionErrs.Add("WithBaseURL: %q is not an http or https URL", url)
+		}
+		if !strings.HasSuffix(url, "/") {
+			url += "/"
+		}
+		g.baseURL = url
+		g.clientOptions = append(g.clientOptions, option.WithEndpoint(url))
 	}
+}
 
-	if len(e.CCAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Cc: %s", strings.Join(e.CCAddresses, ", ")))
+// WithoutAuthentication sends API requests without credentials, ignoring
+// the credentials given to NewGmailSender. Only fake servers accept them,
+// so it requires WithBaseURL.
+func WithoutAuthentication() Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithoutAuthentication")
+		g.unauthenticated = true
 	}
+}
 
-	if len(e.BCCAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Bcc: %s", strings.Join(e.BCCAddresses, ", ")))
+// WithClock stamps emails without a Date with the time from c instead of
+// leaving it to Gmail, e.g. for reproducible messages in tests.
+func WithClock(c email.Clock) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithClock")
+		g.clock = c
 	}
+}
 
-	if len(e.ReplyToAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Reply-To: %s", strings.Join(e.ReplyToAddresses, ", ")))
+// WithAutoChunking splits emails with more than MaxRecipients To, CC and BCC
+// recipients into several sends, see providersdk.SendSplit. Each send gets
+// its own Message-ID, and the send hooks run for each. It is disabled by
+// default, leaving such emails to be rejected by Gmail.
+func WithAutoChunking(enabled bool) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithAutoChunking")
+		g.chunking = enabled
 	}
+}
 
-	var body string
-	if e.HTMLBody != "" && e.TextBody != "" {
-		boundary := "boundary123456789"
-		headers = append(headers, fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s", boundary))
+// WithDefaultFrom sends emails without a FromAddress from addr.
+func WithDefaultFrom(addr string) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithDefaultFrom")
+		if _, err := email.ParseAddress(addr); err != nil {
+			g.optionErrs.Add("WithDefaultFrom: invalid address %q", addr)
+		}
+		g.defaults.From = addr
+	}
+}
 
-		body = fmt.Sprintf(`
---%s
-Content-Type: text/plain; charset=utf-8
-Content-Transfer-Encoding: 8bit
+// WithDefaultReplyTo sets the Reply-To addresses of emails without any.
+func WithDefaultReplyTo(addrs ...string) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithDefaultReplyTo")
+		for _, addr := range addrs {
+			if _, err := email.ParseAddress(addr); err != nil {
+				g.optionErrs.Add("WithDefaultReplyTo: invalid address %q", addr)
+			}
+		}
+		g.defaults.ReplyTo = addrs
+	}
+}
 
-%s
+// WithDefaultHeaders adds headers to emails that don't set a header of the
+// same name. They are checked like email.ValidateHeaders.
+func WithDefaultHeaders(headers map[string]string) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithDefaultHeaders")
+		g.defaults.Headers = providersdk.DefaultHeaders(headers)
+		if err := email.ValidateHeaders(g.defaults.Headers); err != nil {
+			g.optionErrs.Add("WithDefaultHeaders: %v", err)
+		}
+	}
+}
 
---%s
-Content-Type: text/html; charset=utf-8
-Content-Transfer-Encoding: 8bit
+// WithDefaultAutoSubmitted marks emails that don't set AutoSubmitted as
+// value, e.g. email.AUTO_SUBMITTED_GENERATED for a sender of automated mail
+// only.
+func WithDefaultAutoSubmitted(value email.AutoSubmitted) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithDefaultAutoSubmitted")
+		if err := email.ValidateAutoSubmitted(value); err != nil {
+			g.optionErrs.Add("WithDefaultAutoSubmitted: unknown value %q", value)
+		}
+		g.defaults.AutoSubmitted = value
+	}
+}
 
-%s
+func NewGmailSender(ctx context.Context, credentialsJSON []byte, userEmail string, opts ...Option) (*GmailSender, error) {
+	g := &GmailSender{
+		userID:         "me",
+		validateOutput: true,
+		sizeMargin:     DefaultSizeSafetyMargin,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.unauthenticated && g.baseURL == "" {
+		g.optionErrs.Add("WithoutAuthentication requires WithBaseURL")
+	}
+	if err := g.optionErrs.Err(); err != nil {
+		return nil, err
+	}
 
---%s--`, boundary, e.TextBody, boundary, e.HTMLBody, boundary)
-	} else if e.HTMLBody != "" {
-		headers = append(headers, "Content-Type: text/html; charset=utf-8")
-		headers = append(headers, "Content-Transfer-Encoding: 8bit")
-		body = e.HTMLBody
+	clientOptions := g.clientOptions
+	if g.unauthenticated {
+		clientOptions = append(clientOptions, option.WithoutAuthentication())
 	} else {
-		headers = append(headers, "Content-Type: text/plain; charset=utf-8")
-		headers = append(headers, "Content-Transfer-Encoding: 8bit")
-		body = e.TextBody
-	}
+		config, err := google.JWTConfigFromJSON(credentialsJSON, gmail.GmailSendScope)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse service account file: %v", err)
+		}
 
-	if len(e.Attachments) > 0 {
-		return g.createMessageWithAttachments(headers, body, e.Attachments)
+		config.Subject = userEmail
+		clientOptions = append(clientOptions, option.WithHTTPClient(config.Client(ctx)))
 	}
 
-	raw := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+	service, err := gmail.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve Gmail client: %v", err)
+	}
+	g.service = &apiService{service: service}
 
-	return &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(raw)),
-	}, nil
+	return g, nil
 }
 
-func (g *GmailSender) createMessageWithAttachments(headers []string, body string, attachments []email.Attachment) (*gmail.Message, error) {
-	boundary := "mixed_boundary_123456789"
-
-	for i, header := range headers {
-		if strings.HasPrefix(header, "Content-Type:") {
-			headers[i] = fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s", boundary)
-			break
+// DumpOptions describes the configuration of g. Credentials are left out.
+func (g *GmailSender) DumpOptions() []email.OptionValue {
+	force7Bit := "false"
+	if g.build.Force7Bit {
+		force7Bit = string(g.build.Unrepresentable)
+		if force7Bit == "" {
+			force7Bit = string(UNREPRESENTABLE_REJECT)
 		}
 	}
-	if !containsContentType(headers) {
-		headers = append(headers, fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s", boundary))
+	return []email.OptionValue{
+		{Name: "WithOutputValidation", Value: strconv.FormatBool(g.validateOutput)},
+		{Name: "WithAddressRules", Value: strconv.Itoa(g.ruleCount)},
+		{Name: "WithSizeSafetyMargin", Value: strconv.Itoa(g.sizeMargin)},
+		{Name: "WithBaseURL", Value: g.baseURL},
+		{Name: "WithoutAuthentication", Value: strconv.FormatBool(g.unauthenticated)},
+		{Name: "WithForce7Bit", Value: force7Bit},
+		{Name: "WithClock", Value: strconv.FormatBool(g.clock != nil)},
+		{Name: "WithDefaultFrom", Value: g.defaults.From},
+		{Name: "WithDefaultReplyTo", Value: strings.Join(g.defaults.ReplyTo, ", ")},
+		{Name: "WithDefaultHeaders", Value: strconv.Itoa(len(g.defaults.Headers))},
+		{Name: "WithDefaultAutoSubmitted", Value: string(g.defaults.AutoSubmitted)},
+		{Name: "WithAutoChunking", Value: strconv.FormatBool(g.chunking)},
 	}
+}
 
-	var parts []string
-
-	textPart := fmt.Sprintf(`--%s
-Content-Type: text/plain; charset=utf-8
-Content-Transfer-Encoding: 8bit
-
-%s`, boundary, body)
-	parts = append(parts, textPart)
-
-	for _, attachment := range attachments {
-		encodedContent := base64.StdEncoding.EncodeToString(attachment.Content)
+// Capabilities reports the limits of g. MaxMessageSize is the largest raw
+// message whose EncodedSize fits below MaxMessageBytes and the size safety
+// margin.
+func (g *GmailSender) Capabilities() email.Capabilities {
+	c := email.Capabilities{
+		MaxMessageSize:            base64.URLEncoding.DecodedLen(MaxMessageBytes - g.sizeMargin - requestEnvelopeBytes),
+		MaxRecipients:             MaxRecipients,
+		SupportsInlineAttachments: true,
+		BCC:                       email.BCC_STRIPPED_HEADER,
+		Bounces:                   email.BOUNCE_HEADER_ONLY,
+	}
+	if g.chunking {
+		c.MaxRecipients = 0
+	}
+	return c
+}
 
-		attachmentPart := fmt.Sprintf(`--%s
-Content-Type: %s; name="%s"
-Content-Disposition: attachment; filename="%s"
-Content-Transfer-Encoding: base64
+func (g *GmailSender) SendEmail(ctx context.Context, e email.Email) error {
+	_, err := g.SendEmailV2(ctx, e, nil)
+	return err
+}
 
-%s`, boundary, attachment.ContentType, attachment.FileName, attachment.FileName, encodedContent)
-		parts = append(parts, attachmentPart)
+// SendEmailWithResult sends e with default options and returns what Gmail
+// reported about the message, such as its ID and thread.
+func (g *GmailSender) SendEmailWithResult(ctx context.Context, e email.Email) (email.SendResult, error) {
+	result, err := g.SendEmailV2(ctx, e, nil)
+	if err != nil {
+		return email.SendResult{}, err
 	}
+	return *result, nil
+}
 
-	parts = append(parts, fmt.Sprintf("--%s--", boundary))
-
-	raw := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.Join(parts, "\r\n")
+func (g *GmailSender) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (_ *email.SendResult, err error) {
+	defer email.RecoverSend(ctx, e, opts, &err)
 
-	return &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(raw)),
-	}, nil
-}
+	e = email.EnsureMessageID(g.defaults.Apply(opts.Apply(e)))
+	if g.clock != nil && e.Date.IsZero() {
+		e.Date = g.clock.Now()
+	}
 
-func containsContentType(headers []string) bool {
-	for _, header := range headers {
-		if strings.HasPrefix(header, "Content-Type:") {
-			return true
-		}
+	if err := g.validator.Validate(ctx, e); err != nil {
+		return nil, err
+	}
+	if e, err = email.NormalizeAddresses(e); err != nil {
+		return nil, err
+	}
+	if e, err = email.ReadBodies(e); err != nil {
+		return nil, err
 	}
-	return false
-}
 
-func (g *GmailSender) validateEmail(e email.Email) error {
-	if e.FromAddress == "" {
-		return email.NewValidationError("From address is required", nil)
+	limit := 0
+	if g.chunking {
+		limit = MaxRecipients
 	}
+	return providersdk.SendSplit(ctx, e, limit, func(ctx context.Context, e email.Email) (*email.SendResult, error) {
+		return g.send(ctx, e, opts)
+	})
+}
 
-	if _, err := mail.ParseAddress(e.FromAddress); err != nil {
-		return email.NewInvalidEmailError("Invalid from address format", err)
+// send builds and sends a validated email with its bodies read.
+func (g *GmailSender) send(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	message, size, err := g.createMessage(e)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(e.ToAddresses) == 0 && len(e.CCAddresses) == 0 && len(e.BCCAddresses) == 0 {
-		return email.NewValidationError("At least one recipient is required", nil)
+	if err := opts.RunBeforeSend(ctx, e); err != nil {
+		return nil, err
 	}
 
-	allRecipients := append(e.ToAddresses, e.CCAddresses...)
-	allRecipients = append(allRecipients, e.BCCAddresses...)
-	for _, addr := range allRecipients {
-		if _, err := mail.ParseAddress(addr); err != nil {
-			return email.NewInvalidEmailError(fmt.Sprintf("Invalid recipient address format: %s", addr), err)
+	if opts.IsDryRun() {
+		result := &email.SendResult{
+			Provider:     ProviderName,
+			MessageID:    e.MessageID,
+			DryRun:       true,
+			CampaignID:   e.CampaignID,
+			SequenceStep: e.SequenceStep,
+			MessageBytes: size,
+			EncodedBytes: EncodedSize(size),
 		}
+		opts.RunAfterSend(ctx, e, result, nil)
+		return result, nil
 	}
 
-	if e.Subject == "" {
-		return email.NewValidationError("Subject is required", nil)
+	sent, err := g.service.sendMessage(ctx, g.userID, message)
+	if err != nil {
+		err = g.mapGmailError(err)
+		opts.RunAfterSend(ctx, e, nil, err)
+		return nil, err
 	}
 
-	if e.HTMLBody == "" && e.TextBody == "" {
-		return email.NewValidationError("Email body is required", nil)
+	result := &email.SendResult{
+		Provider:          ProviderName,
+		ProviderMessageID: sent.Id,
+		ThreadID:          sent.ThreadId,
+		MessageID:         e.MessageID,
+		CampaignID:        e.CampaignID,
+		SequenceStep:      e.SequenceStep,
+		MessageBytes:      size,
+		EncodedBytes:      EncodedSize(size),
 	}
-
-	return nil
+	opts.RunAfterSend(ctx, e, result, nil)
+	return result, nil
 }
 
-func (g *GmailSender) mapGmailError(err error) error {
-	if apiErr, ok := err.(*googleapi.Error); ok {
-		switch apiErr.Code {
-		case 400:
-			if strings.Contains(strings.ToLower(apiErr.Message), "invalid") {
-				if strings.Contains(strings.ToLower(apiErr.Message), "recipient") ||
-					strings.Contains(strings.ToLower(apiErr.Message), "email") ||
-					strings.Contains(strings.ToLower(apiErr.Message), "address") {
-					return email.NewInvalidEmailError("Invalid email address", err)
-				}
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "malformed") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "encoding") {
-				return email.NewValidationError("Invalid message format", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "too large") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "size") {
-				return email.NewValidationError("Message too large", err)
-			}
-			return email.NewValidationError("Invalid request parameters", err)
+// createMessage returns the Gmail message for e and the size of the raw
+// message in it.
+func (g *GmailSender) createMessage(e email.Email) (*gmail.Message, int, error) {
+	raw, err := providersdk.BuildMessage(e, g.build)
+	if err != nil {
+		var emailErr *email.Error
+		if errors.As(err, &emailErr) {
+			return nil, 0, emailErr
+		}
+		return nil, 0, email.NewValidationError("Failed to create message", err)
+	}
 
-		case 401:
-			return email.NewValidationError("Authentication failed - check service account credentials", err)
+	if g.rawHook != nil {
+		raw = g.rawHook(raw)
+	}
 
-		case 403:
-			if strings.Contains(strings.ToLower(apiErr.Message), "scope") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "permission") {
-				return email.NewUnverifiedDomainError("Insufficient permissions to send email", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "domain") {
-				return email.NewUnverifiedDomainError("Domain policy prevents sending", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "blocked") {
-				return email.NewMessageRejectedError("Sender blocked by recipient", err)
-			}
-			return email.NewUnverifiedDomainError("Permission denied", err)
+	if g.validateOutput {
+		if err := providersdk.VerifyMessage(raw, e); err != nil {
+			return nil, 0, email.NewValidationError(fmt.Sprintf("Generated message is malformed: %s", err), err)
+		}
+	}
 
-		case 429:
-			if strings.Contains(strings.ToLower(apiErr.Message), "quota") {
-				return email.NewRateLimitedError("Gmail API quota exceeded", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "rate") {
-				return email.NewRateLimitedError("Gmail API rate limit exceeded", err)
-			}
-			return email.NewRateLimitedError("Too many requests", err)
+	if err := g.checkSize(len(raw)); err != nil {
+		return nil, 0, err
+	}
 
-		case 500:
-			return email.NewServiceError("Internal Gmail server error", err)
+	return &gmail.Message{
+		Raw: base64.URLEncoding.EncodeToString(raw),
+	}, len(raw), nil
+}
 
-		case 503:
-			return email.NewServiceError("Gmail service temporarily unavailable", err)
+func (g *GmailSender) checkSize(messageBytes int) error {
+	encoded := EncodedSize(messageBytes)
+	limit := MaxMessageBytes - g.sizeMargin
+	if encoded <= limit {
+		return nil
+	}
 
-		case 504:
-			return email.NewServiceError("Gmail API request timeout", err)
+	err := email.NewMessageTooLargeError(fmt.Sprintf("Message is %d bytes, %d bytes once encoded for Gmail, over the limit of %d", messageBytes, encoded, limit), messageBytes, limit)
+	err.Metadata[EncodedBytesMetadataKey] = strconv.Itoa(encoded)
+	return err
+}
 
-		default:
-			return email.NewServiceError(fmt.Sprintf("Gmail API error (HTTP %d)", apiErr.Code), err)
+func (g *GmailSender) mapGmailError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return &email.Error{
+			Reason:     providersdk.MapHTTPStatus(apiErr.Code, apiErr.Message),
+			Message:    fmt.Sprintf("Gmail API error (HTTP %d)", apiErr.Code),
+			Cause:      err,
+			RetryAfter: providersdk.ParseRetryAfter(apiErr.Header.Get("Retry-After"), time.Now()),
+			Metadata:   map[string]string{"http_status": strconv.Itoa(apiErr.Code)},
 		}
 	}
 
-	if strings.Contains(strings.ToLower(err.Error()), "context") &&
-		strings.Contains(strings.ToLower(err.Error()), "deadline") {
-		return email.NewServiceError("Request timeout", err)
+	return &email.Error{
+		Reason:  providersdk.MapTransportError(err),
+		Message: "Gmail API request failed",
+		Cause:   err,
 	}
-
-	if strings.Contains(strings.ToLower(err.Error()), "connection") ||
-		strings.Contains(strings.ToLower(err.Error()), "network") {
-		return email.NewServiceError("Network error", err)
-	}
-
-	return email.NewUnknownError("Gmail API error", err)
 }
@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
-	"fmt"
-	"mime"
+	"io"
+	"net/http"
 	"net/mail"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+	"golang.org/x/text/encoding/japanese"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/googleapi"
 )
@@ -27,244 +31,12 @@ func (m *mockGmailService) sendMessage(ctx context.Context, userID string, messa
 	return &gmail.Message{Id: "mock-message-id"}, nil
 }
 
-// Test version of GmailSender that uses our mock service
-type testGmailSender struct {
-	mockService *mockGmailService
-	userID      string
-}
-
-func newTestGmailSender(mockService *mockGmailService) *testGmailSender {
-	return &testGmailSender{
-		mockService: mockService,
-		userID:      "me",
-	}
-}
-
-func (g *testGmailSender) SendEmail(ctx context.Context, e email.Email) error {
-	if err := g.validateEmail(e); err != nil {
-		return err
-	}
-
-	message, err := g.createMessage(e)
-	if err != nil {
-		return email.NewValidationError("Failed to create message", err)
-	}
-
-	_, err = g.mockService.sendMessage(ctx, g.userID, message)
-	if err != nil {
-		return g.mapGmailError(err)
-	}
-
-	return nil
-}
-
-// Copy validation, message creation, and error mapping methods from original implementation
-func (g *testGmailSender) validateEmail(e email.Email) error {
-	if e.FromAddress == "" {
-		return email.NewValidationError("From address is required", nil)
-	}
-
-	if _, err := mail.ParseAddress(e.FromAddress); err != nil {
-		return email.NewInvalidEmailError("Invalid from address format", err)
-	}
-
-	if len(e.ToAddresses) == 0 && len(e.CCAddresses) == 0 && len(e.BCCAddresses) == 0 {
-		return email.NewValidationError("At least one recipient is required", nil)
-	}
-
-	allRecipients := append(e.ToAddresses, e.CCAddresses...)
-	allRecipients = append(allRecipients, e.BCCAddresses...)
-	for _, addr := range allRecipients {
-		if _, err := mail.ParseAddress(addr); err != nil {
-			return email.NewInvalidEmailError(fmt.Sprintf("Invalid recipient address format: %s", addr), err)
-		}
-	}
-
-	if e.Subject == "" {
-		return email.NewValidationError("Subject is required", nil)
-	}
-
-	if e.HTMLBody == "" && e.TextBody == "" {
-		return email.NewValidationError("Email body is required", nil)
-	}
-
-	return nil
-}
-
-func (g *testGmailSender) createMessage(e email.Email) (*gmail.Message, error) {
-	headers := []string{
-		fmt.Sprintf("From: %s", e.FromAddress),
-		fmt.Sprintf("To: %s", strings.Join(e.ToAddresses, ", ")),
-		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("utf-8", e.Subject)),
-		"MIME-Version: 1.0",
-	}
-
-	if len(e.CCAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Cc: %s", strings.Join(e.CCAddresses, ", ")))
-	}
-
-	if len(e.BCCAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Bcc: %s", strings.Join(e.BCCAddresses, ", ")))
-	}
-
-	if len(e.ReplyToAddresses) > 0 {
-		headers = append(headers, fmt.Sprintf("Reply-To: %s", strings.Join(e.ReplyToAddresses, ", ")))
-	}
-
-	var body string
-	if e.HTMLBody != "" && e.TextBody != "" {
-		boundary := "boundary123456789"
-		headers = append(headers, fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s", boundary))
-
-		body = fmt.Sprintf(`
---%s
-Content-Type: text/plain; charset=utf-8
-Content-Transfer-Encoding: 8bit
-
-%s
-
---%s
-Content-Type: text/html; charset=utf-8
-Content-Transfer-Encoding: 8bit
-
-%s
-
---%s--`, boundary, e.TextBody, boundary, e.HTMLBody, boundary)
-	} else if e.HTMLBody != "" {
-		headers = append(headers, "Content-Type: text/html; charset=utf-8")
-		headers = append(headers, "Content-Transfer-Encoding: 8bit")
-		body = e.HTMLBody
-	} else {
-		headers = append(headers, "Content-Type: text/plain; charset=utf-8")
-		headers = append(headers, "Content-Transfer-Encoding: 8bit")
-		body = e.TextBody
-	}
-
-	if len(e.Attachments) > 0 {
-		return g.createMessageWithAttachments(headers, body, e.Attachments)
-	}
-
-	raw := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
-
-	return &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(raw)),
-	}, nil
-}
-
-func (g *testGmailSender) createMessageWithAttachments(headers []string, body string, attachments []email.Attachment) (*gmail.Message, error) {
-	boundary := "mixed_boundary_123456789"
-
-	for i, header := range headers {
-		if strings.HasPrefix(header, "Content-Type:") {
-			headers[i] = fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s", boundary)
-			break
-		}
-	}
-	if !containsContentType(headers) {
-		headers = append(headers, fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s", boundary))
-	}
-
-	var parts []string
-
-	textPart := fmt.Sprintf(`--%s
-Content-Type: text/plain; charset=utf-8
-Content-Transfer-Encoding: 8bit
-
-%s`, boundary, body)
-	parts = append(parts, textPart)
-
-	for _, attachment := range attachments {
-		encodedContent := base64.StdEncoding.EncodeToString(attachment.Content)
-
-		attachmentPart := fmt.Sprintf(`--%s
-Content-Type: %s; name="%s"
-Content-Disposition: attachment; filename="%s"
-Content-Transfer-Encoding: base64
-
-%s`, boundary, attachment.ContentType, attachment.FileName, attachment.FileName, encodedContent)
-		parts = append(parts, attachmentPart)
-	}
-
-	parts = append(parts, fmt.Sprintf("--%s--", boundary))
-
-	raw := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.Join(parts, "\r\n")
-
-	return &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(raw)),
-	}, nil
-}
-
-func (g *testGmailSender) mapGmailError(err error) error {
-	if apiErr, ok := err.(*googleapi.Error); ok {
-		switch apiErr.Code {
-		case 400:
-			if strings.Contains(strings.ToLower(apiErr.Message), "invalid") {
-				if strings.Contains(strings.ToLower(apiErr.Message), "recipient") ||
-					strings.Contains(strings.ToLower(apiErr.Message), "email") ||
-					strings.Contains(strings.ToLower(apiErr.Message), "address") {
-					return email.NewInvalidEmailError("Invalid email address", err)
-				}
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "malformed") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "encoding") {
-				return email.NewValidationError("Invalid message format", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "too large") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "size") {
-				return email.NewValidationError("Message too large", err)
-			}
-			return email.NewValidationError("Invalid request parameters", err)
-
-		case 401:
-			return email.NewValidationError("Authentication failed - check service account credentials", err)
-
-		case 403:
-			if strings.Contains(strings.ToLower(apiErr.Message), "scope") ||
-				strings.Contains(strings.ToLower(apiErr.Message), "permission") {
-				return email.NewUnverifiedDomainError("Insufficient permissions to send email", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "domain") {
-				return email.NewUnverifiedDomainError("Domain policy prevents sending", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "blocked") {
-				return email.NewMessageRejectedError("Sender blocked by recipient", err)
-			}
-			return email.NewUnverifiedDomainError("Permission denied", err)
-
-		case 429:
-			if strings.Contains(strings.ToLower(apiErr.Message), "quota") {
-				return email.NewRateLimitedError("Gmail API quota exceeded", err)
-			}
-			if strings.Contains(strings.ToLower(apiErr.Message), "rate") {
-				return email.NewRateLimitedError("Gmail API rate limit exceeded", err)
-			}
-			return email.NewRateLimitedError("Too many requests", err)
-
-		case 500:
-			return email.NewServiceError("Internal Gmail server error", err)
-
-		case 503:
-			return email.NewServiceError("Gmail service temporarily unavailable", err)
-
-		case 504:
-			return email.NewServiceError("Gmail API request timeout", err)
-
-		default:
-			return email.NewServiceError(fmt.Sprintf("Gmail API error (HTTP %d)", apiErr.Code), err)
-		}
-	}
-
-	if strings.Contains(strings.ToLower(err.Error()), "context") &&
-		strings.Contains(strings.ToLower(err.Error()), "deadline") {
-		return email.NewServiceError("Request timeout", err)
+func newTestGmailSender(mockService *mockGmailService) *GmailSender {
+	return &GmailSender{
+		service:        mockService,
+		userID:         "me",
+		validateOutput: true,
 	}
-
-	if strings.Contains(strings.ToLower(err.Error()), "connection") ||
-		strings.Contains(strings.ToLower(err.Error()), "network") {
-		return email.NewServiceError("Network error", err)
-	}
-
-	return email.NewUnknownError("Gmail API error", err)
 }
 
 func TestSendEmail_Success(t *testing.T) {
@@ -450,6 +222,17 @@ func TestSendEmail_ValidationErrors(t *testing.T) {
 			},
 			expectedError: email.REASON_VALIDATION_ERROR,
 		},
+		{
+			name: "expiry in the past",
+			email: email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Test",
+				TextBody:    "Hello",
+				Expires:     time.Now().Add(-time.Hour),
+			},
+			expectedError: email.REASON_VALIDATION_ERROR,
+		},
 	}
 
 	for _, tt := range tests {
@@ -693,3 +476,939 @@ func TestMessageCreation(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageCreation_ExpiryDate(t *testing.T) {
+	expires := time.Date(2030, time.March, 4, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		expires        time.Time
+		allowPast      bool
+		expectedHeader string
+	}{
+		{
+			name:           "future expiry",
+			expires:        expires,
+			expectedHeader: "Mon, 04 Mar 2030 18:30:00 +0000",
+		},
+		{
+			name:           "past expiry when allowed",
+			expires:        time.Date(2001, time.January, 2, 3, 4, 5, 0, time.FixedZone("", -5*60*60)),
+			allowPast:      true,
+			expectedHeader: "Tue, 02 Jan 2001 03:04:05 -0500",
+		},
+		{
+			name: "no expiry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header string
+			mockService := &mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					raw, err := base64.URLEncoding.DecodeString(message.Raw)
+					if err != nil {
+						t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+					}
+					msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+					if err != nil {
+						t.Fatalf("failed to parse raw message: %v", err)
+					}
+					header = msg.Header.Get("Expiry-Date")
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			}
+
+			sender := newTestGmailSender(mockService)
+			err := sender.SendEmail(context.Background(), email.Email{
+				FromAddress:     "sender@example.com",
+				ToAddresses:     []string{"recipient@example.com"},
+				Subject:         "Check-in",
+				TextBody:        "Hello World",
+				Expires:         tt.expires,
+				AllowPastExpiry: tt.allowPast,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if header != tt.expectedHeader {
+				t.Errorf("expected Expiry-Date %q, got %q", tt.expectedHeader, header)
+			}
+			if header != "" {
+				parsed, err := mail.ParseDate(header)
+				if err != nil {
+					t.Fatalf("Expiry-Date is not an RFC 5322 date: %v", err)
+				}
+				if !parsed.Equal(tt.expires) {
+					t.Errorf("expected Expiry-Date to round-trip to %v, got %v", tt.expires, parsed)
+				}
+			}
+		})
+	}
+}
+
+func TestSendEmail_GmailAPIErrorMetadata(t *testing.T) {
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			return nil, &googleapi.Error{
+				Code:    429,
+				Message: "Rate limit exceeded",
+				Header:  http.Header{"Retry-After": []string{"30"}},
+			}
+		},
+	}
+
+	err := newTestGmailSender(mockService).SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	})
+
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) {
+		t.Fatalf("expected email.Error, got %v", err)
+	}
+	if emailErr.RetryAfter != 30*time.Second {
+		t.Errorf("expected RetryAfter 30s, got %s", emailErr.RetryAfter)
+	}
+	if emailErr.Metadata["http_status"] != "429" {
+		t.Errorf("expected the HTTP status in metadata, got %v", emailErr.Metadata)
+	}
+	if !errors.Is(err, email.ErrRateLimited) {
+		t.Error("expected the error to match email.ErrRateLimited")
+	}
+}
+
+func TestSendEmail_EncodedSizeLimit(t *testing.T) {
+	limit := MaxMessageBytes - DefaultSizeSafetyMargin
+	// The largest raw message whose encoded request still fits.
+	maxRaw := (limit - requestEnvelopeBytes) / 4 * 3
+
+	base := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Photos",
+		TextBody:    "Attached.",
+	}
+
+	tests := []struct {
+		name string
+		// Replaces the generated message when set.
+		rawSize     int
+		attachment  int
+		expectError bool
+	}{
+		{name: "largest message that fits", rawSize: maxRaw},
+		{name: "one byte over once encoded", rawSize: maxRaw + 1, expectError: true},
+		// About 24.5 MB decoded, which the decoded size alone would allow.
+		{name: "24.5 MB message", attachment: 24_500_000 * 3 / 4 * 76 / 78, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := false
+			sender := newTestGmailSender(&mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					sent = true
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			})
+			sender.sizeMargin = DefaultSizeSafetyMargin
+
+			e := base
+			if tt.rawSize > 0 {
+				sender.validateOutput = false
+				sender.rawHook = func(raw []byte) []byte {
+					return make([]byte, tt.rawSize)
+				}
+			}
+			if tt.attachment > 0 {
+				e.Attachments = []email.Attachment{{FileName: "photos.zip", Content: make([]byte, tt.attachment), ContentType: "application/zip"}}
+			}
+
+			result, err := sender.SendEmailV2(context.Background(), e, nil)
+
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result.MessageBytes != tt.rawSize || result.EncodedBytes != EncodedSize(tt.rawSize) {
+					t.Errorf("expected sizes %d and %d, got %d and %d", tt.rawSize, EncodedSize(tt.rawSize), result.MessageBytes, result.EncodedBytes)
+				}
+				if result.EncodedBytes > limit {
+					t.Errorf("encoded size %d is over the limit %d", result.EncodedBytes, limit)
+				}
+				return
+			}
+
+			var emailErr *email.Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_MESSAGE_TOO_LARGE {
+				t.Fatalf("expected message too large error, got %v", err)
+			}
+			if sent {
+				t.Error("expected the message not to be sent")
+			}
+
+			decoded, _ := strconv.Atoi(emailErr.Metadata[MessageBytesMetadataKey])
+			encoded, _ := strconv.Atoi(emailErr.Metadata[EncodedBytesMetadataKey])
+			if decoded >= MaxMessageBytes {
+				t.Errorf("expected a message that passes a decoded size check, got %d bytes", decoded)
+			}
+			if encoded != EncodedSize(decoded) || encoded <= limit {
+				t.Errorf("expected encoded size over %d, got %d for %d bytes", limit, encoded, decoded)
+			}
+			if emailErr.Metadata[LimitBytesMetadataKey] != strconv.Itoa(limit) {
+				t.Errorf("unexpected limit %s", emailErr.Metadata[LimitBytesMetadataKey])
+			}
+		})
+	}
+}
+
+func TestSendEmail_SizesInResult(t *testing.T) {
+	sender := newTestGmailSender(&mockGmailService{})
+
+	result, err := sender.SendEmailV2(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	}, &email.SendOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.MessageBytes == 0 || result.EncodedBytes != EncodedSize(result.MessageBytes) {
+		t.Errorf("unexpected sizes %d and %d", result.MessageBytes, result.EncodedBytes)
+	}
+}
+
+func TestSendEmailWithResult(t *testing.T) {
+	sender := newTestGmailSender(&mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			return &gmail.Message{Id: "message-id", ThreadId: "thread-id"}, nil
+		},
+	})
+
+	result, err := sender.SendEmailWithResult(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Provider != ProviderName || result.ProviderMessageID != "message-id" || result.ThreadID != "thread-id" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestMessageCreation_Priority(t *testing.T) {
+	tests := []struct {
+		priority   email.Priority
+		xPriority  string
+		msMail     string
+		importance string
+	}{
+		{priority: email.PRIORITY_HIGH, xPriority: "1 (Highest)", msMail: "High", importance: "high"},
+		{priority: email.PRIORITY_NORMAL, xPriority: "3 (Normal)", msMail: "Normal", importance: "normal"},
+		{priority: email.PRIORITY_LOW, xPriority: "5 (Lowest)", msMail: "Low", importance: "low"},
+		{priority: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.priority), func(t *testing.T) {
+			var header mail.Header
+			sender := newTestGmailSender(&mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					raw, err := base64.URLEncoding.DecodeString(message.Raw)
+					if err != nil {
+						t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+					}
+					msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+					if err != nil {
+						t.Fatalf("failed to parse raw message: %v", err)
+					}
+					header = msg.Header
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			})
+
+			err := sender.SendEmail(context.Background(), email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Disk almost full",
+				TextBody:    "Hello World",
+				Priority:    tt.priority,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := header.Get("X-Priority"); got != tt.xPriority {
+				t.Errorf("expected X-Priority %q, got %q", tt.xPriority, got)
+			}
+			if got := header.Get("X-MSMail-Priority"); got != tt.msMail {
+				t.Errorf("expected X-MSMail-Priority %q, got %q", tt.msMail, got)
+			}
+			if got := header.Get("Importance"); got != tt.importance {
+				t.Errorf("expected Importance %q, got %q", tt.importance, got)
+			}
+		})
+	}
+}
+
+func TestMessageCreation_MessageID(t *testing.T) {
+	tests := []struct {
+		name      string
+		messageID string
+	}{
+		{"given", "<registration-42@icaa.example.com>"},
+		{"generated", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header mail.Header
+			sender := newTestGmailSender(&mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					raw, err := base64.URLEncoding.DecodeString(message.Raw)
+					if err != nil {
+						t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+					}
+					msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+					if err != nil {
+						t.Fatalf("failed to parse raw message: %v", err)
+					}
+					header = msg.Header
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			})
+
+			result, err := sender.SendEmailV2(context.Background(), email.Email{
+				FromAddress: "Events <events@icaa.example.com>",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Registration",
+				TextBody:    "Hello World",
+				MessageID:   tt.messageID,
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := header.Get("Message-ID")
+			if got != result.MessageID {
+				t.Errorf("expected result MessageID %q to match the header, got %q", got, result.MessageID)
+			}
+			if tt.messageID != "" && got != tt.messageID {
+				t.Errorf("expected Message-ID %q, got %q", tt.messageID, got)
+			}
+			if !strings.HasSuffix(got, "@icaa.example.com>") {
+				t.Errorf("expected a Message-ID for the From domain, got %q", got)
+			}
+		})
+	}
+}
+
+func TestMessageCreation_ReturnPath(t *testing.T) {
+	var header mail.Header
+	sender := newTestGmailSender(&mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	})
+
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress:   "Events <events@icaa.example.com>",
+		ToAddresses:   []string{"recipient@example.com"},
+		Subject:       "Registration",
+		TextBody:      "Hello World",
+		BounceAddress: "Bounces <bounces+ada=example.com@icaa.example.com>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := header.Get("Return-Path"); got != "<bounces+ada=example.com@icaa.example.com>" {
+		t.Errorf("unexpected Return-Path %q", got)
+	}
+}
+
+func TestMessageCreation_Tags(t *testing.T) {
+	var header mail.Header
+	sender := newTestGmailSender(&mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	})
+
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Registration",
+		TextBody:    "Hello World",
+		Tags:        map[string]string{"env": "prod", "team": "events"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if header.Get("X-Tag-env") != "prod" || header.Get("X-Tag-team") != "events" {
+		t.Errorf("expected tag headers, got %v", header)
+	}
+}
+
+func TestNewGmailSender_Options(t *testing.T) {
+	ctx := context.Background()
+	allowAll := func(ctx context.Context, addr string, field email.AddressField) error { return nil }
+
+	t.Run("every option", func(t *testing.T) {
+		sender, err := NewGmailSender(ctx, nil, "events@icaa.example.com",
+			WithBaseURL("http://localhost:8080"),
+			WithoutAuthentication(),
+			WithOutputValidation(false),
+			WithAddressRules(allowAll, allowAll),
+			WithSizeSafetyMargin(1024),
+			WithForce7Bit(UNREPRESENTABLE_TRANSLITERATE),
+			WithClock(email.SystemClock()),
+			WithDefaultFrom("events@icaa.example.com"),
+			WithDefaultReplyTo("help@icaa.example.com", "board@icaa.example.com"),
+			WithDefaultHeaders(map[string]string{"X-Team": "events"}),
+			WithAutoChunking(true),
+			WithDefaultAutoSubmitted(email.AUTO_SUBMITTED_GENERATED),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, v := range sender.DumpOptions() {
+			got = append(got, v.String())
+		}
+		want := []string{
+			"WithOutputValidation=false",
+			"WithAddressRules=2",
+			"WithSizeSafetyMargin=1024",
+			"WithBaseURL=http://localhost:8080/",
+			"WithoutAuthentication=true",
+			"WithForce7Bit=TRANSLITERATE",
+			"WithClock=true",
+			"WithDefaultFrom=events@icaa.example.com",
+			"WithDefaultReplyTo=help@icaa.example.com, board@icaa.example.com",
+			"WithDefaultHeaders=1",
+			"WithDefaultAutoSubmitted=auto-generated",
+			"WithAutoChunking=true",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("expected options %v, got %v", want, got)
+		}
+	})
+
+	t.Run("problems are reported together", func(t *testing.T) {
+		_, err := NewGmailSender(ctx, nil, "events@icaa.example.com",
+			WithoutAuthentication(),
+			WithSizeSafetyMargin(-1),
+			WithForce7Bit("ASCII"),
+			WithOutputValidation(true),
+			WithOutputValidation(false),
+			WithDefaultFrom("events"),
+			WithDefaultReplyTo("help@icaa.example.com", "help"),
+			WithDefaultHeaders(map[string]string{"Bad Name": "x"}),
+		)
+
+		var emailErr *email.Error
+		if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_VALIDATION_ERROR {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		for _, problem := range []string{
+			"WithSizeSafetyMargin: margin must not be negative",
+			`WithForce7Bit: unknown policy "ASCII"`,
+			"WithOutputValidation given more than once",
+			"WithoutAuthentication requires WithBaseURL",
+			`WithDefaultFrom: invalid address "events"`,
+			`WithDefaultReplyTo: invalid address "help"`,
+			"WithDefaultHeaders: ",
+		} {
+			if !strings.Contains(emailErr.Message, problem) {
+				t.Errorf("expected %q in %q", problem, emailErr.Message)
+			}
+		}
+	})
+
+	t.Run("invalid base URL", func(t *testing.T) {
+		_, err := NewGmailSender(ctx, nil, "events@icaa.example.com", WithBaseURL("localhost:8080"), WithoutAuthentication())
+		if !errors.Is(err, email.ErrValidation) {
+			t.Errorf("expected a validation error, got %v", err)
+		}
+	})
+}
+
+func TestMessageCreation_Date(t *testing.T) {
+	var headers []mail.Header
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			headers = append(headers, msg.Header)
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+	archived := time.Date(2023, 11, 4, 18, 15, 0, 0, time.FixedZone("", -5*60*60))
+	now := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Results",
+		TextBody:    "Hello World",
+	}
+
+	tests := []struct {
+		name     string
+		clock    email.Clock
+		date     time.Time
+		expected string
+	}{
+		{name: "left to Gmail", expected: ""},
+		{name: "explicit", date: archived, expected: "Sat, 04 Nov 2023 18:15:00 -0500"},
+		{name: "from the clock", clock: emailtest.NewFakeClock(now), expected: "Fri, 01 Mar 2024 09:30:00 +0000"},
+		{name: "explicit wins over the clock", clock: emailtest.NewFakeClock(now), date: archived, expected: "Sat, 04 Nov 2023 18:15:00 -0500"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			headers = nil
+			sender := newTestGmailSender(mockService)
+			sender.clock = tc.clock
+			withDate := e
+			withDate.Date = tc.date
+
+			if err := sender.SendEmail(context.Background(), withDate); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := headers[0].Get("Date"); got != tc.expected {
+				t.Errorf("expected Date %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMessageCreation_InternationalizedAddresses(t *testing.T) {
+	var header mail.Header
+	sender := newTestGmailSender(&mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	})
+
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress: "events@münchen.de",
+		ToAddresses: []string{"用户@例え.jp"},
+		Subject:     "Test",
+		TextBody:    "Hello",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Gmail supports SMTPUTF8, so the UTF-8 local part is kept.
+	if got := header.Get("From"); got != "events@xn--mnchen-3ya.de" {
+		t.Errorf("unexpected From %q", got)
+	}
+	if got := header.Get("To"); got != "用户@xn--r8jz45g.jp" {
+		t.Errorf("unexpected To %q", got)
+	}
+}
+
+func TestMessageCreation_Sender(t *testing.T) {
+	var header mail.Header
+	sender := newTestGmailSender(&mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	})
+
+	tests := []struct {
+		name     string
+		sender   string
+		expected string
+	}{
+		{name: "unset", expected: ""},
+		{name: "director", sender: "Director <director@icaa.example.com>", expected: `"Director" <director@icaa.example.com>`},
+		{name: "same as From", sender: "events@icaa.example.com", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sender.SendEmail(context.Background(), email.Email{
+				FromAddress:   "ICAA <events@icaa.example.com>",
+				SenderAddress: tt.sender,
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Draw",
+				TextBody:      "Hello World",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := header.Get("Sender"); got != tt.expected {
+				t.Errorf("expected Sender %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMessageCreation_Charset(t *testing.T) {
+	var contentType string
+	var body []byte
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			contentType = msg.Header.Get("Content-Type")
+			body, _ = io.ReadAll(msg.Body)
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+	sender := newTestGmailSender(mockService)
+
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.jp"},
+		Subject:     "Results",
+		TextBody:    "来週の大会",
+		Charset:     "iso-2022-jp",
+	}
+	if err := sender.SendEmail(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "text/plain; charset=ISO-2022-JP" {
+		t.Errorf("unexpected Content-Type %q", contentType)
+	}
+	if decoded, err := japanese.ISO2022JP.NewDecoder().Bytes(body); err != nil || string(decoded) != e.TextBody {
+		t.Errorf("expected the body in ISO-2022-JP, got %q", body)
+	}
+
+	e.Charset = "klingon"
+	if err := sender.SendEmail(context.Background(), e); !errors.Is(err, email.ErrValidation) {
+		t.Errorf("expected an unknown charset to fail validation, got %v", err)
+	}
+}
+
+func TestMessageCreation_Defaults(t *testing.T) {
+	var header mail.Header
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+	sender := newTestGmailSender(mockService)
+	for _, opt := range []Option{
+		WithDefaultFrom("events@icaa.example.com"),
+		WithDefaultReplyTo("help@icaa.example.com"),
+		WithDefaultHeaders(map[string]string{"X-Team": "events", "X-Build": "42"}),
+	} {
+		opt(sender)
+	}
+
+	tests := []struct {
+		name     string
+		email    email.Email
+		expected map[string]string
+	}{
+		{
+			name: "filled in",
+			email: email.Email{
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Results",
+				TextBody:    "Hello World",
+			},
+			expected: map[string]string{
+				"From":     "events@icaa.example.com",
+				"Reply-To": "help@icaa.example.com",
+				"X-Team":   "events",
+				"X-Build":  "42",
+			},
+		},
+		{
+			name: "explicit values win",
+			email: email.Email{
+				FromAddress:      "board@icaa.example.com",
+				ReplyToAddresses: []string{"chair@icaa.example.com"},
+				ToAddresses:      []string{"recipient@example.com"},
+				Subject:          "Results",
+				TextBody:         "Hello World",
+				Headers:          []email.Header{{Name: "x-team", Value: "board"}},
+			},
+			expected: map[string]string{
+				"From":     "board@icaa.example.com",
+				"Reply-To": "chair@icaa.example.com",
+				"X-Team":   "board",
+				"X-Build":  "42",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := sender.SendEmail(context.Background(), tc.email); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for name, want := range tc.expected {
+				if got := header.Get(name); got != want {
+					t.Errorf("expected %s %q, got %q", name, want, got)
+				}
+			}
+			if !strings.HasSuffix(header.Get("Message-ID"), "@icaa.example.com>") {
+				t.Errorf("expected a Message-ID from the sending domain, got %q", header.Get("Message-ID"))
+			}
+		})
+	}
+}
+
+func TestSendEmail_AutoChunking(t *testing.T) {
+	var headers []mail.Header
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			headers = append(headers, msg.Header)
+			return &gmail.Message{Id: "id-" + strconv.Itoa(len(headers))}, nil
+		},
+	}
+	league := make([]string, MaxRecipients+1)
+	for i := range league {
+		league[i] = "archer" + strconv.Itoa(i) + "@example.com"
+	}
+	e := email.Email{
+		FromAddress:  "league@example.com",
+		CCAddresses:  []string{"board@example.com"},
+		BCCAddresses: league,
+		Subject:      "Season opener",
+		TextBody:     "See you there",
+	}
+
+	sender := newTestGmailSender(mockService)
+	WithAutoChunking(true)(sender)
+	result, err := sender.SendEmailV2(context.Background(), e, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(headers))
+	}
+	for i, h := range headers {
+		cc, _ := h.AddressList("Cc")
+		bcc, _ := h.AddressList("Bcc")
+		if n := len(cc) + len(bcc); n > MaxRecipients {
+			t.Errorf("message %d: expected at most %d recipients, got %d", i, MaxRecipients, n)
+		}
+	}
+	if cc := headers[1].Get("Cc"); cc != "" {
+		t.Errorf("expected only the first message to carry the CC, got %q", cc)
+	}
+	if first, second := headers[0].Get("Message-ID"), headers[1].Get("Message-ID"); first == "" || first == second {
+		t.Errorf("expected each message to have its own Message-ID, got %q and %q", first, second)
+	}
+	if result.ProviderMessageID != "id-1" || len(result.Chunks) != 2 || result.Chunks[1].ProviderMessageID != "id-2" {
+		t.Errorf("expected the results of both messages, got %+v", result)
+	}
+	for i, chunk := range result.Chunks {
+		if chunk.MessageID != headers[i].Get("Message-ID") {
+			t.Errorf("chunk %d: expected Message-ID %q, got %q", i, headers[i].Get("Message-ID"), chunk.MessageID)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	sender := newTestGmailSender(&mockGmailService{})
+	sender.sizeMargin = DefaultSizeSafetyMargin
+
+	c := sender.Capabilities()
+	if err := sender.checkSize(c.MaxMessageSize); err != nil {
+		t.Errorf("expected a message of MaxMessageSize to fit, got %v", err)
+	}
+	if err := sender.checkSize(c.MaxMessageSize + 3); err == nil {
+		t.Error("expected MaxMessageSize to be the largest message that fits")
+	}
+	if c.MaxRecipients != MaxRecipients || c.BCC != email.BCC_STRIPPED_HEADER || c.Bounces != email.BOUNCE_HEADER_ONLY || !c.SupportsInlineAttachments {
+		t.Errorf("unexpected capabilities %+v", c)
+	}
+
+	WithAutoChunking(true)(sender)
+	if got := sender.Capabilities().MaxRecipients; got != 0 {
+		t.Errorf("expected no recipient limit with chunking, got %d", got)
+	}
+
+	if got, ok := email.CapabilitiesOf(email.NewEventSender(sender)); !ok || got != sender.Capabilities() {
+		t.Errorf("expected the capabilities through decorators, got %+v", got)
+	}
+}
+
+func TestMessageCreation_AutoSubmitted(t *testing.T) {
+	var header mail.Header
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Registration confirmed",
+		TextBody:    "Hello World",
+	}
+
+	tests := []struct {
+		name          string
+		byDefault     email.AutoSubmitted
+		autoSubmitted email.AutoSubmitted
+		suppress      bool
+		expected      string
+		suppressed    string
+	}{
+		{name: "unset"},
+		{name: "explicit", autoSubmitted: email.AUTO_SUBMITTED_REPLIED, expected: "auto-replied"},
+		{name: "from the default", byDefault: email.AUTO_SUBMITTED_GENERATED, expected: "auto-generated"},
+		{name: "explicit wins over the default", byDefault: email.AUTO_SUBMITTED_GENERATED, autoSubmitted: email.AUTO_SUBMITTED_REPLIED, expected: "auto-replied"},
+		{name: "suppressed", autoSubmitted: email.AUTO_SUBMITTED_GENERATED, suppress: true, expected: "auto-generated", suppressed: "All"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newTestGmailSender(mockService)
+			WithDefaultAutoSubmitted(tt.byDefault)(sender)
+			sent := e
+			sent.AutoSubmitted = tt.autoSubmitted
+			sent.SuppressAutoResponses = tt.suppress
+
+			if err := sender.SendEmail(context.Background(), sent); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := header.Get("Auto-Submitted"); got != tt.expected {
+				t.Errorf("expected Auto-Submitted %q, got %q", tt.expected, got)
+			}
+			if got := header.Get("X-Auto-Response-Suppress"); got != tt.suppressed {
+				t.Errorf("expected X-Auto-Response-Suppress %q, got %q", tt.suppressed, got)
+			}
+		})
+	}
+}
+
+func TestMessageCreation_ListHeaders(t *testing.T) {
+	var header mail.Header
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, err := base64.URLEncoding.DecodeString(message.Raw)
+			if err != nil {
+				t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			header = msg.Header
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+
+	err := newTestGmailSender(mockService).SendEmail(context.Background(), email.Email{
+		FromAddress:     "sender@example.com",
+		ToAddresses:     []string{"recipient@example.com"},
+		Subject:         "Weekly digest",
+		TextBody:        "Hello World",
+		ListID:          "digest.icaa.example.com",
+		ListArchiveURL:  "https://icaa.example.com/digest",
+		ListPostAddress: "digest@icaa.example.com",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"List-Id":      "<digest.icaa.example.com>",
+		"List-Archive": "<https://icaa.example.com/digest>",
+		"List-Post":    "<mailto:digest@icaa.example.com>",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("expected %s %q, got %q", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// TestNewGmailSender_FakeServer sends through the real Gmail client against
+// a fake server speaking the REST shape of messages.send.
+func TestNewGmailSender_FakeServer(t *testing.T) {
+	var raw []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/gmail/v1/users/me/messages/send" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no credentials, got %q", auth)
+		}
+
+		var body struct {
+			Raw string `json:"raw"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		var err error
+		if raw, err = base64.URLEncoding.DecodeString(body.Raw); err != nil {
+			t.Errorf("invalid base64 encoding in Raw message: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": "fake-message-id", "threadId": "fake-thread-id"}`)
+	}))
+	defer server.Close()
+
+	sender, err := NewGmailSender(context.Background(), nil, "events@icaa.example.com", WithBaseURL(server.URL), WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schedule := []byte("%PDF-1.4\n% schedule\n")
+	result, err := sender.SendEmailV2(context.Background(), email.Email{
+		FromAddress: "ICAA Events <events@icaa.example.com>",
+		ToAddresses: []string{"ada@example.com"},
+		Subject:     "Spring tournament",
+		TextBody:    "The schedule is attached.",
+		Attachments: []email.Attachment{{FileName: "schedule.pdf", Content: schedule, ContentType: "application/pdf"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.ProviderMessageID != "fake-message-id" || result.ThreadID != "fake-thread-id" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse raw message: %v", err)
+	}
+	if msg.Header.Get("Subject") != "Spring tournament" || msg.Header.Get("To") != "ada@example.com" {
+		t.Errorf("unexpected headers %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected multipart/mixed, got %q", msg.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	if _, err := mr.NextPart(); err != nil {
+		t.Fatalf("missing body part: %v", err)
+	}
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment part: %v", err)
+	}
+	if part.FileName() != "schedule.pdf" {
+		t.Errorf("expected schedule.pdf, got %q", part.FileName())
+	}
+	encoded, _ := io.ReadAll(part)
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || !bytes.Equal(content, schedule) {
+		t.Errorf("attachment content does not round-trip: %q, %v", content, err)
+	}
+}
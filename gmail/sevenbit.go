@@ -0,0 +1,23 @@
+package gmail
+
+import "github.com/International-Combat-Archery-Alliance/email/providersdk"
+
+type UnrepresentablePolicy = providersdk.UnrepresentablePolicy
+
+const (
+	UNREPRESENTABLE_REJECT        = providersdk.UNREPRESENTABLE_REJECT
+	UNREPRESENTABLE_TRANSLITERATE = providersdk.UNREPRESENTABLE_TRANSLITERATE
+)
+
+// WithForce7Bit guarantees the generated message only contains 7-bit bytes,
+// for relays that corrupt 8-bit content. See providersdk.BuildOptions.
+func WithForce7Bit(policy UnrepresentablePolicy) Option {
+	return func(g *GmailSender) {
+		g.optionErrs.Once("WithForce7Bit")
+		if policy != "" && policy != UNREPRESENTABLE_REJECT && policy != UNREPRESENTABLE_TRANSLITERATE {
+			g.optionErrs.Add("WithForce7Bit: unknown policy %q", policy)
+		}
+		g.build.Force7Bit = true
+		g.build.Unrepresentable = policy
+	}
+}
@@ -0,0 +1,266 @@
+package gmail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"google.golang.org/api/gmail/v1"
+)
+
+func unicodeEmail(text, html string, attachments []email.Attachment) email.Email {
+	return email.Email{
+		FromAddress:      "Zoë Bogenschütze <events@münchen.de>",
+		ToAddresses:      []string{"José Núñez <archer@bücher.example>", "plain@example.com"},
+		CCAddresses:      []string{"Renée <cc@example.com>"},
+		ReplyToAddresses: []string{"Ærø <reply@ærø.example>"},
+		Subject:          "Turnier-Anmeldung – Größe 🎯",
+		TextBody:         text,
+		HTMLBody:         html,
+		Attachments:      attachments,
+	}
+}
+
+func TestForce7Bit_OutputIs7BitClean(t *testing.T) {
+	attachments := []email.Attachment{
+		{
+			FileName:    "Ergebnisse-Größe.pdf",
+			Content:     []byte{0x00, 0xff, 0x80, 0x7f, 'p', 'd', 'f'},
+			ContentType: "application/pdf",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		email email.Email
+	}{
+		{
+			name:  "text body",
+			email: unicodeEmail("Grüße aus München — bis bald!", "", nil),
+		},
+		{
+			name:  "html body",
+			email: unicodeEmail("", "<p>Grüße aus <b>München</b></p>", nil),
+		},
+		{
+			name:  "text and html bodies",
+			email: unicodeEmail("Grüße aus München", "<p>Grüße aus München</p>", nil),
+		},
+		{
+			name:  "text body with attachment",
+			email: unicodeEmail("Grüße aus München", "", attachments),
+		},
+		{
+			name:  "long text body",
+			email: unicodeEmail(strings.Repeat("Ümlaut ", 200), "", nil),
+		},
+		{
+			name: "library headers",
+			email: func() email.Email {
+				e := unicodeEmail("Grüße aus München", "", nil)
+				e.BounceAddress = "Rückläufer <bounces@münchen.de>"
+				e.FallbackFor = "Zoë <old@bücher.example>"
+				e.ListID = "turnier.xn--mnchen-3ya.de"
+				e.Unsubscribe = email.OneClickUnsubscribe("https://xn--mnchen-3ya.de/abmelden")
+				return e
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw []byte
+			mockService := &mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					var err error
+					raw, err = base64.URLEncoding.DecodeString(message.Raw)
+					if err != nil {
+						t.Fatalf("invalid base64 encoding in Raw message: %v", err)
+					}
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			}
+
+			sender := newTestGmailSender(mockService)
+			WithForce7Bit(UNREPRESENTABLE_REJECT)(sender)
+
+			if err := sender.SendEmail(context.Background(), tt.email); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i, b := range raw {
+				if b > 127 {
+					t.Fatalf("byte %d is 0x%x, message is not 7-bit clean:\n%s", i, b, raw)
+				}
+			}
+			for i, line := range strings.Split(string(raw), "\n") {
+				if len(line) > 998 {
+					t.Errorf("line %d is %d characters long", i, len(line))
+				}
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("failed to parse raw message: %v", err)
+			}
+			from, err := mail.ParseAddress(msg.Header.Get("From"))
+			if err != nil {
+				t.Fatalf("invalid From header: %v", err)
+			}
+			if from.Name != "Zoë Bogenschütze" || from.Address != "events@xn--mnchen-3ya.de" {
+				t.Errorf("unexpected From %q <%s>", from.Name, from.Address)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != tt.email.Subject {
+				t.Errorf("expected subject %q, got %q (%v)", tt.email.Subject, subject, err)
+			}
+		})
+	}
+}
+
+func TestForce7Bit_UnrepresentableHeaders(t *testing.T) {
+	e := unicodeEmail("Grüße aus München", "", nil)
+	e.ListID = "turnier.xn--mnchen-3ya.de"
+	e.ListArchiveURL = "https://münchen.de/archiv"
+
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			t.Fatal("expected nothing to be sent")
+			return nil, nil
+		},
+	}
+	sender := newTestGmailSender(mockService)
+	WithForce7Bit(UNREPRESENTABLE_REJECT)(sender)
+
+	err := sender.SendEmail(context.Background(), e)
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_VALIDATION_ERROR || !strings.Contains(err.Error(), email.ListArchiveHeader) {
+		t.Fatalf("expected a validation error for the List-Archive header, got %v", err)
+	}
+}
+
+func TestForce7Bit_BodyRoundTrips(t *testing.T) {
+	var raw []byte
+	mockService := &mockGmailService{
+		sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+			raw, _ = base64.URLEncoding.DecodeString(message.Raw)
+			return &gmail.Message{Id: "test-id"}, nil
+		},
+	}
+
+	sender := newTestGmailSender(mockService)
+	WithForce7Bit(UNREPRESENTABLE_REJECT)(sender)
+
+	e := unicodeEmail("Grüße aus München", "<p>Grüße aus München</p>", nil)
+	if err := sender.SendEmail(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("failed to parse raw message: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("invalid Content-Type: %v", err)
+	}
+
+	var bodies []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid multipart body: %v", err)
+		}
+		b, _ := io.ReadAll(part)
+		bodies = append(bodies, strings.TrimSpace(string(b)))
+	}
+
+	if len(bodies) != 2 || bodies[0] != e.TextBody || bodies[1] != e.HTMLBody {
+		t.Errorf("bodies did not round-trip: %q", bodies)
+	}
+}
+
+func TestForce7Bit_UnrepresentableAddresses(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        UnrepresentablePolicy
+		to            string
+		expectedTo    string
+		expectedError email.ErrorReason
+	}{
+		{
+			name:          "reject non-ASCII local part",
+			policy:        UNREPRESENTABLE_REJECT,
+			to:            "josé@example.com",
+			expectedError: email.REASON_INVALID_EMAIL,
+		},
+		{
+			name:       "transliterate diacritics",
+			policy:     UNREPRESENTABLE_TRANSLITERATE,
+			to:         "josé@example.com",
+			expectedTo: "<jose@example.com>",
+		},
+		{
+			name:          "reject what cannot be transliterated",
+			policy:        UNREPRESENTABLE_TRANSLITERATE,
+			to:            "用户@example.com",
+			expectedError: email.REASON_INVALID_EMAIL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var to string
+			mockService := &mockGmailService{
+				sendMessageFunc: func(ctx context.Context, userID string, message *gmail.Message) (*gmail.Message, error) {
+					raw, _ := base64.URLEncoding.DecodeString(message.Raw)
+					msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+					if err != nil {
+						t.Fatalf("failed to parse raw message: %v", err)
+					}
+					to = msg.Header.Get("To")
+					return &gmail.Message{Id: "test-id"}, nil
+				},
+			}
+
+			sender := newTestGmailSender(mockService)
+			WithForce7Bit(tt.policy)(sender)
+
+			err := sender.SendEmail(context.Background(), email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{tt.to},
+				Subject:     "Test",
+				TextBody:    "Hello",
+			})
+
+			if tt.expectedError != "" {
+				var emailErr *email.Error
+				if !errors.As(err, &emailErr) {
+					t.Fatalf("expected email.Error, got %v", err)
+				}
+				if emailErr.Reason != tt.expectedError {
+					t.Errorf("expected error reason %s, got %s", tt.expectedError, emailErr.Reason)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if to != tt.expectedTo {
+				t.Errorf("expected To %q, got %q", tt.expectedTo, to)
+			}
+		})
+	}
+}
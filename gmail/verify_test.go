@@ -0,0 +1,147 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+var boundaryParam = regexp.MustCompile(`boundary="?[^";\r\n]+"?`)
+
+func TestOutputValidation_CatchesCorruption(t *testing.T) {
+	withAttachment := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+		Attachments: []email.Attachment{
+			{
+				FileName:    "document.pdf",
+				Content:     []byte("fake pdf content"),
+				ContentType: "application/pdf",
+			},
+		},
+	}
+	alternative := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+		HTMLBody:    "<p>Hello World</p>",
+	}
+
+	tests := []struct {
+		name          string
+		email         email.Email
+		hook          func(raw []byte) []byte
+		expectedInMsg string
+	}{
+		{
+			name:  "unparseable From header",
+			email: alternative,
+			hook: func(raw []byte) []byte {
+				return bytes.Replace(raw, []byte("From: sender@example.com"), []byte("From: <sender@"), 1)
+			},
+			expectedInMsg: "invalid From header",
+		},
+		{
+			name:  "mismatched boundary",
+			email: alternative,
+			hook: func(raw []byte) []byte {
+				loc := boundaryParam.FindIndex(raw)
+				return slices.Concat(raw[:loc[0]], []byte("boundary=other"), raw[loc[1]:])
+			},
+			expectedInMsg: "multipart/alternative",
+		},
+		{
+			name:  "wrong alternative nesting",
+			email: alternative,
+			hook: func(raw []byte) []byte {
+				return bytes.Replace(raw, []byte("multipart/alternative"), []byte("multipart/mixed"), 1)
+			},
+			expectedInMsg: "expected multipart/alternative",
+		},
+		{
+			name: "alternative relabelled inside mixed",
+			email: email.Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Test Subject",
+				TextBody:    "Hello World",
+				HTMLBody:    "<p>Hello World</p>",
+				Attachments: withAttachment.Attachments,
+			},
+			hook: func(raw []byte) []byte {
+				return bytes.Replace(raw, []byte("multipart/alternative"), []byte("text/plain"), 1)
+			},
+			expectedInMsg: "expected multipart/alternative",
+		},
+		{
+			name:  "corrupted attachment encoding",
+			email: withAttachment,
+			hook: func(raw []byte) []byte {
+				return bytes.Replace(raw, []byte("ZmFrZSBwZGYgY29udGVudA=="), []byte("ZmFrZSBwZGYgY29udGVudA=!"), 1)
+			},
+			expectedInMsg: "does not decode",
+		},
+		{
+			name:  "dropped attachment",
+			email: withAttachment,
+			hook: func(raw []byte) []byte {
+				return bytes.Replace(raw, []byte("Content-Disposition: attachment"), []byte("Content-Disposition: inline"), 1)
+			},
+			expectedInMsg: "expected 1 attachments, found 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newTestGmailSender(&mockGmailService{})
+			sender.rawHook = func(raw []byte) []byte {
+				corrupted := tt.hook(raw)
+				if bytes.Equal(corrupted, raw) {
+					t.Fatal("hook did not change the message")
+				}
+				return corrupted
+			}
+
+			err := sender.SendEmail(context.Background(), tt.email)
+
+			var emailErr *email.Error
+			if !errors.As(err, &emailErr) {
+				t.Fatalf("expected email.Error, got %v", err)
+			}
+			if emailErr.Reason != email.REASON_VALIDATION_ERROR {
+				t.Errorf("expected error reason %s, got %s", email.REASON_VALIDATION_ERROR, emailErr.Reason)
+			}
+			if !strings.Contains(emailErr.Message, tt.expectedInMsg) {
+				t.Errorf("expected message to name the defect %q, got %q", tt.expectedInMsg, emailErr.Message)
+			}
+		})
+	}
+}
+
+func TestOutputValidation_Disabled(t *testing.T) {
+	sender := newTestGmailSender(&mockGmailService{})
+	WithOutputValidation(false)(sender)
+	sender.rawHook = func(raw []byte) []byte {
+		return bytes.Replace(raw, []byte("multipart/alternative"), []byte("multipart/mixed"), 1)
+	}
+
+	err := sender.SendEmail(context.Background(), email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Test Subject",
+		TextBody:    "Hello World",
+		HTMLBody:    "<p>Hello World</p>",
+	})
+	if err != nil {
+		t.Errorf("expected corrupted message to be sent with validation disabled, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package email
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HashField names a part of an Email covered by CanonicalHash.
+type HashField string
+
+const (
+	HASH_FROM     HashField = "From"
+	HASH_TO       HashField = "To"
+	HASH_CC       HashField = "CC"
+	HASH_BCC      HashField = "BCC"
+	HASH_REPLY_TO HashField = "ReplyTo"
+	HASH_SUBJECT  HashField = "Subject"
+	HASH_HTML     HashField = "HTMLBody"
+	HASH_TEXT     HashField = "TextBody"
+	// File names, content types, descriptions, content IDs and content.
+	HASH_ATTACHMENTS HashField = "Attachments"
+	// Expires and AllowPastExpiry.
+	HASH_EXPIRES             HashField = "Expires"
+	HASH_ALTERNATE_ADDRESSES HashField = "AlternateAddresses"
+	HASH_HEADERS             HashField = "Headers"
+	HASH_UNSUBSCRIBE         HashField = "Unsubscribe"
+	HASH_PRIORITY            HashField = "Priority"
+	// CampaignID and SequenceStep. Excluded by default.
+	HASH_CAMPAIGN HashField = "Campaign"
+	// Set per send by FallbackSender. Excluded by default.
+	HASH_FALLBACK_FOR HashField = "FallbackFor"
+	// Unique per message. Excluded by default.
+	HASH_MESSAGE_ID HashField = "MessageID"
+	HASH_BOUNCE     HashField = "BounceAddress"
+	// Analytics labels. Excluded by default.
+	HASH_TAGS HashField = "Tags"
+	// RequestReadReceipt and ReadReceiptTo, as the address receipts go to.
+	HASH_READ_RECEIPT HashField = "ReadReceipt"
+	// Excluded by default.
+	HASH_DATE HashField = "Date"
+
+	HASH_SENDER HashField = "Sender"
+	// The canonical name, so aliases of a charset hash the same.
+	HASH_CHARSET HashField = "Charset"
+	// AutoSubmitted and SuppressAutoResponses.
+	HASH_AUTO_SUBMITTED HashField = "AutoSubmitted"
+	// ListID, ListArchiveURL and ListPostAddress.
+	HASH_LIST HashField = "List"
+)
+
+// The fields in the order they are hashed. New fields are appended, so
+// hashes of emails that don't use them stay the same.
+var hashFields = []HashField{
+	HASH_FROM, HASH_TO, HASH_CC, HASH_BCC, HASH_REPLY_TO, HASH_SUBJECT, HASH_HTML, HASH_TEXT,
+	HASH_ATTACHMENTS, HASH_EXPIRES, HASH_ALTERNATE_ADDRESSES, HASH_HEADERS, HASH_UNSUBSCRIBE,
+	HASH_PRIORITY, HASH_CAMPAIGN, HASH_FALLBACK_FOR, HASH_MESSAGE_ID,
+	HASH_BOUNCE, HASH_TAGS, HASH_READ_RECEIPT, HASH_DATE,
+	HASH_SENDER, HASH_CHARSET, HASH_AUTO_SUBMITTED, HASH_LIST,
+}
+
+// Fields that vary between sends of the same email.
+var volatileHashFields = []HashField{HASH_CAMPAIGN, HASH_FALLBACK_FOR, HASH_MESSAGE_ID, HASH_TAGS, HASH_DATE}
+
+type HashOption func(map[HashField]bool)
+
+// HashIncluding adds fields, such as the volatile ones excluded by default.
+func HashIncluding(fields ...HashField) HashOption {
+	return func(included map[HashField]bool) {
+		for _, f := range fields {
+			included[f] = true
+		}
+	}
+}
+
+func HashExcluding(fields ...HashField) HashOption {
+	return func(included map[HashField]bool) {
+		for _, f := range fields {
+			delete(included, f)
+		}
+	}
+}
+
+// CanonicalHash returns a SHA-256 of e that stays the same for emails that
+// are sent the same way. Addresses are compared by their parsed form with a
+// lower-case domain, recipient lists and headers are sorted, and
+// attachments are represented by a hash of their content. By default every
+// field except the volatile HASH_CAMPAIGN, HASH_FALLBACK_FOR,
+// HASH_MESSAGE_ID, HASH_TAGS and HASH_DATE is included. Reader bodies are not
+// consumed, so only whether they are set counts; hash the result of
+// ReadBodies to cover their content.
+func CanonicalHash(e Email, opts ...HashOption) [32]byte {
+	included := map[HashField]bool{}
+	for _, f := range hashFields {
+		included[f] = true
+	}
+	for _, f := range volatileHashFields {
+		delete(included, f)
+	}
+	for _, opt := range opts {
+		opt(included)
+	}
+
+	h := sha256.New()
+	for _, f := range hashFields {
+		if !included[f] {
+			continue
+		}
+		writeHashString(h, string(f))
+		values := hashValues(e, f)
+		// Without the count, a value equal to the next field's name would
+		// let lists regroup across fields with the same bytes.
+		writeHashCount(h, len(values))
+		for _, v := range values {
+			writeHashString(h, v)
+		}
+	}
+
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// hashValues returns the canonical form of field f of e.
+func hashValues(e Email, f HashField) []string {
+	switch f {
+	case HASH_FROM:
+		return []string{canonicalAddress(e.FromAddress)}
+	case HASH_TO:
+		return canonicalAddresses(e.ToAddresses)
+	case HASH_CC:
+		return canonicalAddresses(e.CCAddresses)
+	case HASH_BCC:
+		return canonicalAddresses(e.BCCAddresses)
+	case HASH_REPLY_TO:
+		return canonicalAddresses(e.ReplyToAddresses)
+	case HASH_SUBJECT:
+		return []string{e.Subject}
+	case HASH_HTML:
+		return bodyHashValues(e.HTMLBody, e.HTMLBodyReader)
+	case HASH_TEXT:
+		return bodyHashValues(e.TextBody, e.TextBodyReader)
+	case HASH_ATTACHMENTS:
+		var values []string
+		for _, a := range e.Attachments {
+			content := sha256.Sum256(a.Content)
+			values = append(values, a.FileName, a.ContentType, a.Description, a.ContentID, a.Ref, strings.ToLower(a.SHA256), string(content[:]))
+		}
+		return values
+	case HASH_EXPIRES:
+		expires := ""
+		if !e.Expires.IsZero() {
+			expires = e.Expires.UTC().Format(time.RFC3339Nano)
+		}
+		return []string{expires, strconv.FormatBool(e.AllowPastExpiry)}
+	case HASH_ALTERNATE_ADDRESSES:
+		// Tried in order, so the order matters.
+		values := make([]string, len(e.AlternateAddresses))
+		for i, addr := range e.AlternateAddresses {
+			values[i] = canonicalAddress(addr)
+		}
+		return values
+	case HASH_HEADERS:
+		headers := slices.Clone(e.Headers)
+		slices.SortStableFunc(headers, func(a, b Header) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		var values []string
+		for _, h := range headers {
+			values = append(values, strings.ToLower(h.Name), h.Value)
+		}
+		return values
+	case HASH_UNSUBSCRIBE:
+		return []string{e.Unsubscribe.URL, e.Unsubscribe.Mailto, strconv.FormatBool(e.Unsubscribe.OneClick)}
+	case HASH_PRIORITY:
+		return []string{string(e.Priority)}
+	case HASH_LIST:
+		if e.ListID == "" && e.ListArchiveURL == "" && e.ListPostAddress == "" {
+			return nil
+		}
+		return []string{strings.ToLower(bareListID(e.ListID)), e.ListArchiveURL, canonicalAddress(e.ListPostAddress)}
+	case HASH_AUTO_SUBMITTED:
+		if e.AutoSubmitted == "" && !e.SuppressAutoResponses {
+			return nil
+		}
+		return []string{string(e.AutoSubmitted), strconv.FormatBool(e.SuppressAutoResponses)}
+	case HASH_CAMPAIGN:
+		return []string{e.CampaignID, strconv.Itoa(e.SequenceStep)}
+	case HASH_FALLBACK_FOR:
+		return []string{canonicalAddress(e.FallbackFor)}
+	case HASH_MESSAGE_ID:
+		return []string{e.MessageID}
+	case HASH_BOUNCE:
+		return []string{canonicalAddress(e.BounceAddress)}
+	case HASH_READ_RECEIPT:
+		return []string{canonicalAddress(e.ReadReceiptAddress())}
+	case HASH_SENDER:
+		return []string{canonicalAddress(e.SenderHeaderAddress())}
+	case HASH_CHARSET:
+		c, err := LookupCharset(e.Charset)
+		if err != nil {
+			return []string{strings.ToLower(e.Charset)}
+		}
+		if c.IsUTF8() {
+			return nil
+		}
+		return []string{c.Name}
+	case HASH_DATE:
+		if e.Date.IsZero() {
+			return nil
+		}
+		return []string{e.Date.UTC().Format(time.RFC3339Nano)}
+	case HASH_TAGS:
+		var values []string
+		for _, name := range slices.Sorted(maps.Keys(e.Tags)) {
+			values = append(values, name, e.Tags[name])
+		}
+		return values
+	}
+	return nil
+}
+
+func canonicalAddress(addr string) string {
+	a, err := ParseAddress(addr)
+	if err != nil {
+		return strings.TrimSpace(addr)
+	}
+	if at := strings.LastIndex(a.Address, "@"); at >= 0 {
+		a.Address = a.Address[:at] + strings.ToLower(a.Address[at:])
+	}
+	return a.String()
+}
+
+func canonicalAddresses(addrs []string) []string {
+	values := make([]string, len(addrs))
+	for i, addr := range addrs {
+		values[i] = canonicalAddress(addr)
+	}
+	slices.Sort(values)
+	return values
+}
+
+// writeHashString length-prefixes s so that field boundaries can't shift.
+func writeHashString(h hash.Hash, s string) {
+	writeHashCount(h, len(s))
+	h.Write([]byte(s))
+}
+
+func writeHashCount(h hash.Hash, n int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(n))
+	h.Write(b[:])
+}
+
+// bodyHashValues marks a reader body without reading it. Emails with string
+// bodies hash as they did before readers existed.
+func bodyHashValues(body string, r io.Reader) []string {
+	if r == nil {
+		return []string{body}
+	}
+	return []string{body, "reader"}
+}
@@ -0,0 +1,170 @@
+package email
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hashEmail() Email {
+	return Email{
+		FromAddress:        "Events <events@icaa.example.com>",
+		ToAddresses:        []string{"ada@example.com", "bo@example.com"},
+		CCAddresses:        []string{"cc@example.com"},
+		BCCAddresses:       []string{"bcc@example.com"},
+		ReplyToAddresses:   []string{"help@icaa.example.com"},
+		Subject:            "Spring tournament",
+		BounceAddress:      "bounces@icaa.example.com",
+		MessageID:          "<abc@icaa.example.com>",
+		HTMLBody:           "<p>See you there</p>",
+		TextBody:           "See you there",
+		Attachments:        []Attachment{{FileName: "schedule.pdf", Content: []byte("%PDF"), ContentType: "application/pdf"}},
+		Expires:            time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		CampaignID:         "spring",
+		SequenceStep:       2,
+		AlternateAddresses: []string{"ada@work.example.com"},
+		Headers:            []Header{{Name: "X-Team", Value: "north"}, {Name: "X-Build", Value: "42"}},
+		Unsubscribe:        OneClickUnsubscribe("https://icaa.example.com/u/1"),
+		ListID:             "digest.icaa.example.com",
+		ListArchiveURL:     "https://icaa.example.com/digest",
+		ListPostAddress:    "digest@icaa.example.com",
+		Priority:           PRIORITY_HIGH,
+		AutoSubmitted:      AUTO_SUBMITTED_GENERATED,
+		Tags:               map[string]string{"env": "prod", "team": "events"},
+		FallbackFor:        "old@example.com",
+	}
+}
+
+// hashMutations changes each field of Email, keyed by the HashField covering
+// it. Every field must be listed so new fields get a decision.
+var hashMutations = map[string]struct {
+	field  HashField
+	mutate func(e *Email)
+}{
+	"FromAddress":        {HASH_FROM, func(e *Email) { e.FromAddress = "News <events@icaa.example.com>" }},
+	"ToAddresses":        {HASH_TO, func(e *Email) { e.ToAddresses = e.ToAddresses[:1] }},
+	"CCAddresses":        {HASH_CC, func(e *Email) { e.CCAddresses = nil }},
+	"BCCAddresses":       {HASH_BCC, func(e *Email) { e.BCCAddresses = []string{"other@example.com"} }},
+	"ReplyToAddresses":   {HASH_REPLY_TO, func(e *Email) { e.ReplyToAddresses = nil }},
+	"Subject":            {HASH_SUBJECT, func(e *Email) { e.Subject += "!" }},
+	"SenderAddress":      {HASH_SENDER, func(e *Email) { e.SenderAddress = "Director <director@icaa.example.com>" }},
+	"Charset":            {HASH_CHARSET, func(e *Email) { e.Charset = "iso-2022-jp" }},
+	"BounceAddress":      {HASH_BOUNCE, func(e *Email) { e.BounceAddress = "" }},
+	"RequestReadReceipt": {HASH_READ_RECEIPT, func(e *Email) { e.RequestReadReceipt = true }},
+	"ReadReceiptTo":      {HASH_READ_RECEIPT, func(e *Email) { e.ReadReceiptTo = "board@icaa.example.com" }},
+	"MessageID":          {HASH_MESSAGE_ID, func(e *Email) { e.MessageID = "<def@icaa.example.com>" }},
+	"Date":               {HASH_DATE, func(e *Email) { e.Date = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC) }},
+	"HTMLBody":           {HASH_HTML, func(e *Email) { e.HTMLBody = "<p>See you</p>" }},
+	"TextBody":           {HASH_TEXT, func(e *Email) { e.TextBody = "See you" }},
+	// Reading would consume them, so only whether one is set is hashed.
+	"HTMLBodyReader": {HASH_HTML, func(e *Email) { e.HTMLBodyReader = strings.NewReader("<p>See you there</p>") }},
+	"TextBodyReader": {HASH_TEXT, func(e *Email) { e.TextBodyReader = strings.NewReader("See you there") }},
+	"Attachments": {HASH_ATTACHMENTS, func(e *Email) {
+		e.Attachments = []Attachment{{FileName: "schedule.pdf", Content: []byte("%PDF-1.7"), ContentType: "application/pdf"}}
+	}},
+	"Expires":               {HASH_EXPIRES, func(e *Email) { e.Expires = e.Expires.Add(time.Second) }},
+	"AllowPastExpiry":       {HASH_EXPIRES, func(e *Email) { e.AllowPastExpiry = true }},
+	"CampaignID":            {HASH_CAMPAIGN, func(e *Email) { e.CampaignID = "autumn" }},
+	"SequenceStep":          {HASH_CAMPAIGN, func(e *Email) { e.SequenceStep = 3 }},
+	"AlternateAddresses":    {HASH_ALTERNATE_ADDRESSES, func(e *Email) { e.AlternateAddresses = nil }},
+	"Headers":               {HASH_HEADERS, func(e *Email) { e.Headers[0].Value = "south" }},
+	"Unsubscribe":           {HASH_UNSUBSCRIBE, func(e *Email) { e.Unsubscribe.OneClick = false }},
+	"ListID":                {HASH_LIST, func(e *Email) { e.ListID = "news.icaa.example.com" }},
+	"ListArchiveURL":        {HASH_LIST, func(e *Email) { e.ListArchiveURL = "" }},
+	"ListPostAddress":       {HASH_LIST, func(e *Email) { e.ListPostAddress = "board@icaa.example.com" }},
+	"Tags":                  {HASH_TAGS, func(e *Email) { e.Tags = map[string]string{"env": "prod"} }},
+	"Priority":              {HASH_PRIORITY, func(e *Email) { e.Priority = PRIORITY_LOW }},
+	"AutoSubmitted":         {HASH_AUTO_SUBMITTED, func(e *Email) { e.AutoSubmitted = AUTO_SUBMITTED_REPLIED }},
+	"SuppressAutoResponses": {HASH_AUTO_SUBMITTED, func(e *Email) { e.SuppressAutoResponses = true }},
+	"FallbackFor":           {HASH_FALLBACK_FOR, func(e *Email) { e.FallbackFor = "" }},
+}
+
+func TestCanonicalHash_EveryFieldIsCovered(t *testing.T) {
+	for _, f := range reflect.VisibleFields(reflect.TypeFor[Email]()) {
+		if _, ok := hashMutations[f.Name]; !ok {
+			t.Errorf("Email.%s has no hash mutation; decide which HashField covers it", f.Name)
+		}
+	}
+}
+
+func TestCanonicalHash_Sensitivity(t *testing.T) {
+	all := HashIncluding(hashFields...)
+
+	for name, m := range hashMutations {
+		t.Run(name, func(t *testing.T) {
+			changed := hashEmail()
+			m.mutate(&changed)
+
+			if CanonicalHash(hashEmail(), all) == CanonicalHash(changed, all) {
+				t.Errorf("changing %s did not change the hash", name)
+			}
+			if CanonicalHash(hashEmail(), all, HashExcluding(m.field)) != CanonicalHash(changed, all, HashExcluding(m.field)) {
+				t.Errorf("changing %s changed the hash with %s excluded", name, m.field)
+			}
+		})
+	}
+}
+
+func TestCanonicalHash_VolatileFieldsExcludedByDefault(t *testing.T) {
+	e := hashEmail()
+	e.CampaignID = "autumn"
+	e.SequenceStep = 9
+	e.FallbackFor = ""
+
+	if CanonicalHash(hashEmail()) != CanonicalHash(e) {
+		t.Error("expected campaign and fallback fields to be excluded by default")
+	}
+	if CanonicalHash(hashEmail(), HashIncluding(HASH_CAMPAIGN)) == CanonicalHash(e, HashIncluding(HASH_CAMPAIGN)) {
+		t.Error("expected HashIncluding to include the campaign")
+	}
+}
+
+func TestCanonicalHash_Stability(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(e *Email)
+	}{
+		{"unchanged", func(e *Email) {}},
+		{"domain case", func(e *Email) { e.FromAddress = "Events <events@ICAA.Example.com>" }},
+		{"surrounding space", func(e *Email) { e.CCAddresses = []string{" cc@example.com "} }},
+		{"recipient order", func(e *Email) { e.ToAddresses = []string{"bo@example.com", "ada@example.com"} }},
+		{"header order", func(e *Email) { e.Headers = []Header{e.Headers[1], e.Headers[0]} }},
+		{"header name case", func(e *Email) { e.Headers[0].Name = "x-team" }},
+		{"expiry zone", func(e *Email) { e.Expires = e.Expires.In(time.FixedZone("CEST", 2*60*60)) }},
+		{"default charset spelled out", func(e *Email) { e.Charset = "UTF-8" }},
+		{"list ID brackets and case", func(e *Email) { e.ListID = "<Digest.ICAA.example.com>" }},
+	}
+
+	want := CanonicalHash(hashEmail())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := hashEmail()
+			tt.modify(&e)
+
+			if got := CanonicalHash(e); got != want {
+				t.Errorf("expected %x, got %x", want, got)
+			}
+		})
+	}
+}
+
+func TestCanonicalHash_FieldBoundaries(t *testing.T) {
+	a := Email{Subject: "ab", TextBody: "c"}
+	b := Email{Subject: "a", TextBody: "bc"}
+
+	if CanonicalHash(a) == CanonicalHash(b) {
+		t.Error("expected moving bytes between fields to change the hash")
+	}
+}
+
+func TestCanonicalHash_ListBoundaries(t *testing.T) {
+	// Invalid addresses hash as written, so a recipient can spell the name
+	// of the next field.
+	a := Email{ToAddresses: []string{"CC"}}
+	b := Email{CCAddresses: []string{"CC"}}
+
+	if CanonicalHash(a) == CanonicalHash(b) {
+		t.Error("expected moving recipients between lists to change the hash")
+	}
+}
@@ -0,0 +1,233 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is a message header. Headers are written in the order given.
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Longest header line RFC 5322 recommends, without the CRLF.
+const maxHeaderLine = 78
+
+// String renders h as it appears in a message: a value that is not ASCII is
+// RFC 2047 encoded, and lines longer than 78 characters are folded at
+// spaces where possible.
+func (h Header) String() string {
+	value := h.EncodedValue()
+
+	var b strings.Builder
+	line := len(h.Name) + 1
+	b.WriteString(h.Name + ":")
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && line+1+len(word) > maxHeaderLine {
+			b.WriteString("\r\n")
+			line = 0
+		}
+		b.WriteString(" " + word)
+		line += 1 + len(word)
+	}
+	return b.String()
+}
+
+// EncodedValue is the value of h, RFC 2047 encoded if it is not ASCII.
+func (h Header) EncodedValue() string {
+	for _, r := range h.Value {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", h.Value)
+		}
+	}
+	return h.Value
+}
+
+// FieldHeaders returns the headers the library derives from the fields of e
+// other than its addresses, subject and bodies, in the order
+// providersdk.BuildMessage writes them after the address headers. Values
+// are as set on e, not yet RFC 2047 encoded.
+func FieldHeaders(e Email) []Header {
+	var headers []Header
+	if !e.Date.IsZero() {
+		headers = append(headers, Header{"Date", e.Date.Format(time.RFC1123Z)})
+	}
+	if e.MessageID != "" {
+		headers = append(headers, Header{"Message-ID", e.MessageID})
+	}
+	if e.BounceAddress != "" {
+		headers = append(headers, Header{"Return-Path", ReturnPath(e.BounceAddress)})
+	}
+	if !e.Expires.IsZero() {
+		headers = append(headers, Header{"Expiry-Date", e.Expires.Format(time.RFC1123Z)})
+	}
+	if e.CampaignID != "" {
+		headers = append(headers, Header{CampaignIDHeader, e.CampaignID})
+	}
+	if e.SequenceStep > 0 {
+		headers = append(headers, Header{SequenceStepHeader, strconv.Itoa(e.SequenceStep)})
+	}
+	if e.FallbackFor != "" {
+		headers = append(headers, Header{DeliveryFallbackHeader, e.FallbackFor})
+	}
+	headers = append(headers, e.Unsubscribe.Headers()...)
+	headers = append(headers, e.ListHeaders()...)
+	headers = append(headers, e.Priority.Headers()...)
+	headers = append(headers, e.AutoResponseHeaders()...)
+	return append(headers, TagHeaders(e.Tags)...)
+}
+
+// Headers the library writes itself, which Email.Headers may not set.
+var reservedHeaders = map[string]bool{
+	"from": true, "to": true, "cc": true, "bcc": true, "reply-to": true, "sender": true,
+	"subject": true, "date": true, "message-id": true, "mime-version": true, "return-path": true,
+	"content-type": true, "content-transfer-encoding": true, "content-disposition": true, "content-id": true,
+	"expiry-date": true, "importance": true, "x-priority": true, "x-msmail-priority": true,
+	strings.ToLower(AutoSubmittedHeader): true, strings.ToLower(AutoResponseSuppressHeader): true,
+	strings.ToLower(ListIDHeader): true, strings.ToLower(ListArchiveHeader): true, strings.ToLower(ListPostHeader): true,
+	strings.ToLower(ListUnsubscribeHeader): true, strings.ToLower(ListUnsubscribePostHeader): true,
+	strings.ToLower(CampaignIDHeader): true, strings.ToLower(SequenceStepHeader): true,
+	strings.ToLower(DeliveryFallbackHeader):          true,
+	strings.ToLower(DispositionNotificationToHeader): true, strings.ToLower(ReturnReceiptToHeader): true,
+}
+
+// ValidateHeaders checks custom headers: names must be printable ASCII
+// without colons and not be one the library sets, values must not contain
+// line breaks, and no name may repeat.
+func ValidateHeaders(headers []Header) error {
+	seen := map[string]bool{}
+	for _, h := range headers {
+		if h.Name == "" {
+			return NewValidationError("header name is required", nil)
+		}
+		for _, r := range h.Name {
+			if r <= ' ' || r > '~' || r == ':' {
+				return NewValidationError(fmt.Sprintf("header name %q contains %q", h.Name, r), nil)
+			}
+		}
+		key := strings.ToLower(h.Name)
+		if reservedHeaders[key] {
+			return NewValidationError(fmt.Sprintf("header %s is set by the library and cannot be set directly", h.Name), nil)
+		}
+		if seen[key] {
+			return NewValidationError(fmt.Sprintf("header %s is set more than once", h.Name), nil)
+		}
+		seen[key] = true
+		if strings.ContainsAny(h.Value, "\r\n") {
+			return NewValidationError(fmt.Sprintf("header %s contains a line break", h.Name), nil)
+		}
+	}
+	return nil
+}
+
+// HeaderInjector returns headers to add to an email, evaluated for every
+// send so values can carry timestamps or request IDs.
+type HeaderInjector func(ctx context.Context, e Email) ([]Header, error)
+
+// HeaderCollisionPolicy decides what HeaderInjectingSender does with an
+// injected header the email already has.
+type HeaderCollisionPolicy string
+
+const (
+	// Fail the send.
+	HEADER_COLLISION_REJECT HeaderCollisionPolicy = "REJECT"
+	// Replace the email's header with the injected one.
+	HEADER_COLLISION_OVERWRITE HeaderCollisionPolicy = "OVERWRITE"
+	// Keep the email's header and drop the injected one.
+	HEADER_COLLISION_SKIP HeaderCollisionPolicy = "SKIP"
+)
+
+type HeaderInjectorOption func(*HeaderInjectingSender)
+
+// WithHeaderCollisionPolicy replaces the default, HEADER_COLLISION_REJECT.
+func WithHeaderCollisionPolicy(policy HeaderCollisionPolicy) HeaderInjectorOption {
+	return func(s *HeaderInjectingSender) {
+		s.policy = policy
+	}
+}
+
+var _ Sender = &HeaderInjectingSender{}
+var _ SenderV2 = &HeaderInjectingSender{}
+
+// HeaderInjectingSender decorates a SenderV2 to add organization-wide
+// headers, such as a classification, to every email after its own headers.
+// Injected headers are validated like the email's own.
+type HeaderInjectingSender struct {
+	inner  SenderV2
+	inject HeaderInjector
+	policy HeaderCollisionPolicy
+}
+
+func NewHeaderInjectingSender(inner SenderV2, inject HeaderInjector, opts ...HeaderInjectorOption) *HeaderInjectingSender {
+	s := &HeaderInjectingSender{
+		inner:  inner,
+		inject: inject,
+		policy: HEADER_COLLISION_REJECT,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *HeaderInjectingSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *HeaderInjectingSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *HeaderInjectingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	injected, err := s.inject(ctx, e)
+	if err != nil {
+		return nil, NewServiceError("failed to compute injected headers", err)
+	}
+	if err := ValidateHeaders(injected); err != nil {
+		return nil, err
+	}
+
+	e.Headers, err = s.merge(e.Headers, injected)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
+
+func (s *HeaderInjectingSender) merge(own, injected []Header) ([]Header, error) {
+	merged := make([]Header, len(own), len(own)+len(injected))
+	copy(merged, own)
+
+	index := map[string]int{}
+	for i, h := range own {
+		index[strings.ToLower(h.Name)] = i
+	}
+
+	for _, h := range injected {
+		i, ok := index[strings.ToLower(h.Name)]
+		if !ok {
+			merged = append(merged, h)
+			continue
+		}
+
+		switch s.policy {
+		case HEADER_COLLISION_OVERWRITE:
+			merged[i] = h
+		case HEADER_COLLISION_SKIP:
+		default:
+			return nil, NewValidationError(fmt.Sprintf("injected header %s is already set on the email", h.Name), nil)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,200 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/mail"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeader_String(t *testing.T) {
+	long := strings.Repeat("fingerprint ", 12)
+
+	tests := []struct {
+		name   string
+		header Header
+		want   string
+	}{
+		{"short", Header{"X-Org-Classification", "internal"}, "X-Org-Classification: internal"},
+		{"non-ASCII", Header{"X-Team", "Zoë"}, "X-Team: =?utf-8?q?Zo=C3=AB?="},
+		{"folded", Header{"X-Build", long}, "X-Build: fingerprint fingerprint fingerprint fingerprint fingerprint\r\n fingerprint fingerprint fingerprint fingerprint fingerprint fingerprint\r\n fingerprint "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.header.String()
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			for _, line := range strings.Split(got, "\r\n") {
+				if len(line) > maxHeaderLine {
+					t.Errorf("line of %d characters: %q", len(line), line)
+				}
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(got + "\r\n\r\n"))
+			if err != nil {
+				t.Fatalf("header does not parse: %v", err)
+			}
+			decoded, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get(tt.header.Name))
+			if err != nil || strings.TrimSpace(decoded) != strings.TrimSpace(tt.header.Value) {
+				t.Errorf("expected value %q to round-trip, got %q (%v)", tt.header.Value, decoded, err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []Header
+		wantErr bool
+	}{
+		{"valid", []Header{{"X-Org-Classification", "internal"}, {"X-Build", "abc123"}}, false},
+		{"empty name", []Header{{"", "x"}}, true},
+		{"colon in name", []Header{{"X-Org:Class", "x"}}, true},
+		{"space in name", []Header{{"X Org", "x"}}, true},
+		{"reserved", []Header{{"bcc", "x@example.com"}}, true},
+		{"library header", []Header{{"X-Priority", "1"}}, true},
+		{"auto-submitted", []Header{{"auto-submitted", "no"}}, true},
+		{"list", []Header{{"List-Id", "<digest.example.com>"}}, true},
+		{"duplicate", []Header{{"X-Build", "a"}, {"x-build", "b"}}, true},
+		{"line break", []Header{{"X-Build", "a\r\nBcc: x@example.com"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHeaders(tt.headers)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHeaderInjectingSender(t *testing.T) {
+	now := time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+	inject := func(ctx context.Context, e Email) ([]Header, error) {
+		return []Header{
+			{"X-Org-Classification", "internal"},
+			{"X-Sent-At", now.Format(time.RFC3339)},
+		}, nil
+	}
+	userHeaders := []Header{{"X-Org-Classification", "public"}, {"X-Team", "north"}}
+
+	tests := []struct {
+		name    string
+		policy  HeaderCollisionPolicy
+		headers []Header
+		want    []Header
+		wantErr bool
+	}{
+		{
+			name: "no collision",
+			want: []Header{{"X-Org-Classification", "internal"}, {"X-Sent-At", "2026-05-02T09:00:00Z"}},
+		},
+		{
+			name:    "reject",
+			policy:  HEADER_COLLISION_REJECT,
+			headers: userHeaders,
+			wantErr: true,
+		},
+		{
+			name:    "overwrite",
+			policy:  HEADER_COLLISION_OVERWRITE,
+			headers: userHeaders,
+			want:    []Header{{"X-Org-Classification", "internal"}, {"X-Team", "north"}, {"X-Sent-At", "2026-05-02T09:00:00Z"}},
+		},
+		{
+			name:    "skip",
+			policy:  HEADER_COLLISION_SKIP,
+			headers: []Header{{"x-org-classification", "public"}},
+			want:    []Header{{"x-org-classification", "public"}, {"X-Sent-At", "2026-05-02T09:00:00Z"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []HeaderInjectorOption
+			if tt.policy != "" {
+				opts = append(opts, WithHeaderCollisionPolicy(tt.policy))
+			}
+			inner := &recordingSender{}
+			original := slices.Clone(tt.headers)
+
+			err := NewHeaderInjectingSender(AsSenderV2(inner), inject, opts...).SendEmail(context.Background(), Email{Headers: tt.headers})
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) || len(inner.sent) != 0 {
+					t.Errorf("expected validation error and no send, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := inner.sent[0].Headers; !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if !slices.Equal(tt.headers, original) {
+				t.Errorf("the caller's headers were modified: %v", tt.headers)
+			}
+		})
+	}
+}
+
+func TestHeaderInjectingSender_InvalidInjection(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject HeaderInjector
+		want   error
+	}{
+		{
+			name:   "reserved header",
+			inject: func(ctx context.Context, e Email) ([]Header, error) { return []Header{{"From", "x@example.com"}}, nil },
+			want:   ErrValidation,
+		},
+		{
+			name:   "injector error",
+			inject: func(ctx context.Context, e Email) ([]Header, error) { return nil, errors.New("no build info") },
+			want:   ErrServiceError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingSender{}
+			err := NewHeaderInjectingSender(AsSenderV2(inner), tt.inject).SendEmail(context.Background(), Email{})
+			if !errors.Is(err, tt.want) || len(inner.sent) != 0 {
+				t.Errorf("expected %v and no send, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFieldHeaders(t *testing.T) {
+	e := Email{
+		Date:         time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		MessageID:    "<abc@example.com>",
+		CampaignID:   "spring",
+		SequenceStep: 2,
+		Tags:         map[string]string{"env": "prod"},
+	}
+
+	var names []string
+	for _, h := range FieldHeaders(e) {
+		names = append(names, h.Name)
+	}
+	expected := []string{"Date", "Message-ID", CampaignIDHeader, SequenceStepHeader, TagHeaderPrefix + "env"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if headers := FieldHeaders(Email{TextBody: "Hello"}); headers != nil {
+		t.Errorf("expected no headers for a plain email, got %v", headers)
+	}
+}
@@ -0,0 +1,279 @@
+package email
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TextWrapWidth is the line length GenerateTextFromHTML wraps at.
+const TextWrapWidth = 78
+
+// GenerateTextFromHTML renders an HTML body as plain text for TextBody.
+// Paragraphs, headings and line breaks become newlines, list items are
+// bulleted or numbered, table rows become lines, links are written as
+// "text (url)" and lines are wrapped at TextWrapWidth. The content of
+// <head>, <style> and <script> is dropped.
+func GenerateTextFromHTML(body string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", NewValidationError("failed to parse HTML body", err)
+	}
+
+	w := &textWriter{}
+	w.node(doc)
+	w.flush()
+	return w.out.String(), nil
+}
+
+// textWriter accumulates the words of the current line and writes finished
+// lines, wrapped, to out.
+type textWriter struct {
+	out strings.Builder
+	// Words of the line being built.
+	words []string
+	// Whether the next text continues the last word without a space.
+	glued bool
+	// Newlines to write before the next line.
+	breaks int
+	// Indentation of the lines of the current list item, and the bullet
+	// that starts its first line.
+	indent string
+	bullet string
+	pre    bool
+}
+
+func (w *textWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.Data {
+	case "head", "style", "script", "noscript", "template":
+	case "br":
+		if len(w.words) == 0 {
+			w.breaks = max(w.breaks, 1) + 1
+		}
+		w.flush()
+	case "p", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote":
+		w.block(2)
+		w.children(n)
+		w.block(2)
+	case "pre":
+		w.block(2)
+		w.pre = true
+		w.children(n)
+		if len(w.words) > 0 {
+			w.flushRaw()
+		}
+		w.pre = false
+		w.block(2)
+	case "hr":
+		w.block(2)
+		w.words = []string{strings.Repeat("-", TextWrapWidth)}
+		w.block(2)
+	case "ul", "ol":
+		w.list(n)
+	case "a":
+		w.link(n)
+	case "img":
+		if alt := attribute(n, "alt"); alt != "" {
+			w.text(" " + alt + " ")
+		}
+	case "td", "th":
+		w.text(" ")
+		w.children(n)
+		w.text(" ")
+	case "div", "table", "tr", "li", "section", "article", "header", "footer", "center", "dl", "dt", "dd", "title":
+		w.block(1)
+		w.children(n)
+		w.block(1)
+	default:
+		w.children(n)
+	}
+}
+
+func (w *textWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+// block ends the current line and asks for at least breaks newlines before
+// the next one.
+func (w *textWriter) block(breaks int) {
+	w.flush()
+	w.breaks = max(w.breaks, breaks)
+}
+
+func (w *textWriter) list(n *html.Node) {
+	w.block(1)
+	outer := w.indent
+	number := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			w.node(c)
+			continue
+		}
+		number++
+		bullet := "* "
+		if n.Data == "ol" {
+			bullet = strconv.Itoa(number) + ". "
+		}
+
+		w.block(1)
+		w.indent, w.bullet = outer+strings.Repeat(" ", len(bullet)), outer+bullet
+		w.children(c)
+		w.block(1)
+		w.indent, w.bullet = outer, ""
+	}
+	w.block(1)
+}
+
+func (w *textWriter) link(n *html.Node) {
+	start := len(w.words)
+	w.children(n)
+
+	href := strings.TrimSpace(attribute(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") {
+		return
+	}
+	label := strings.Join(w.words[min(start, len(w.words)):], " ")
+	target := strings.TrimPrefix(href, "mailto:")
+	switch {
+	case label == "":
+		w.text(" " + href + " ")
+	case label != target && label != href:
+		w.text(" (" + href + ") ")
+	}
+}
+
+func (w *textWriter) text(s string) {
+	if w.pre {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				w.flushRaw()
+			}
+			w.words = append(w.words, line)
+		}
+		return
+	}
+
+	if s == "" {
+		return
+	}
+	if isHTMLSpace(s[0]) {
+		w.glued = false
+	}
+	for i, word := range strings.Fields(s) {
+		if i == 0 && w.glued && len(w.words) > 0 {
+			w.words[len(w.words)-1] += word
+			continue
+		}
+		w.words = append(w.words, word)
+	}
+	w.glued = !isHTMLSpace(s[len(s)-1])
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// newline writes the newlines requested before the next line.
+func (w *textWriter) newline() {
+	if w.out.Len() > 0 {
+		w.out.WriteString(strings.Repeat("\n", max(w.breaks, 1)))
+	}
+	w.breaks = 0
+}
+
+// flush writes the current line wrapped at TextWrapWidth. Words longer than
+// a line, e.g. URLs, are kept whole.
+func (w *textWriter) flush() {
+	w.glued = false
+	if len(w.words) == 0 {
+		return
+	}
+
+	w.newline()
+	prefix := w.indent
+	if w.bullet != "" {
+		prefix, w.bullet = w.bullet, ""
+	}
+	line := prefix
+	for i, word := range w.words {
+		if i > 0 && len(line)+1+len(word) > TextWrapWidth {
+			w.out.WriteString(line + "\n")
+			line = w.indent + word
+			continue
+		}
+		if i > 0 {
+			line += " "
+		}
+		line += word
+	}
+	w.out.WriteString(line)
+	w.words = nil
+}
+
+// flushRaw writes the current line of preformatted text unwrapped.
+func (w *textWriter) flushRaw() {
+	w.newline()
+	w.out.WriteString(strings.Join(w.words, ""))
+	w.words = nil
+	w.breaks = 1
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+var _ Sender = &TextGeneratingSender{}
+var _ SenderV2 = &TextGeneratingSender{}
+
+// TextGeneratingSender decorates a SenderV2 to fill in the TextBody of
+// HTML-only emails with GenerateTextFromHTML.
+type TextGeneratingSender struct {
+	inner SenderV2
+}
+
+func NewTextGeneratingSender(inner SenderV2) *TextGeneratingSender {
+	return &TextGeneratingSender{inner: inner}
+}
+
+func (s *TextGeneratingSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *TextGeneratingSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *TextGeneratingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	if e.TextBody == "" && e.TextBodyReader == nil && e.HTMLBody != "" {
+		if e.TextBody, err = GenerateTextFromHTML(e.HTMLBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
@@ -0,0 +1,74 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTextFromHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"paragraphs", `<p>Hello <b>Ada</b>,</p><p>See you<br>there.</p>`, "Hello Ada,\n\nSee you\nthere."},
+		{"entities", `<p>Spring &amp; Summer &lt;2026&gt; &eacute;t&eacute;</p>`, "Spring & Summer <2026> été"},
+		{"whitespace", "<p>  Lots\n\tof   space </p>", "Lots of space"},
+		{"double break", `one<br><br>two`, "one\n\ntwo"},
+		{"links", `<a href="https://icaa.example.com/s">the schedule</a><br><a href="https://icaa.example.com">https://icaa.example.com</a><br><a href="mailto:help@icaa.example.com">help@icaa.example.com</a> <a href="#top">top</a>`,
+			"the schedule (https://icaa.example.com/s)\nhttps://icaa.example.com\nhelp@icaa.example.com top"},
+		{"image link", `<a href="https://icaa.example.com"><img src="logo.png"></a>`, "https://icaa.example.com"},
+		{"image alt", `<p>Logo: <img src="logo.png" alt="ICAA"></p>`, "Logo: ICAA"},
+		{"style and script", `<html><head><title>T</title><style>p { color: red }</style></head><body><script>alert(1)</script><p>Body</p><style>.x{}</style></body></html>`, "Body"},
+		{"nested lists", `<ul><li>One</li><li>Two<ol><li>Two a</li><li>Two b</li></ol></li><li>Three</li></ul>`,
+			"* One\n* Two\n  1. Two a\n  2. Two b\n* Three"},
+		{"table", `<table><tr><th>Team</th><th>Score</th></tr><tr><td>North</td><td>3</td></tr></table>`, "Team Score\nNorth 3"},
+		{"layout table", `<table><tr><td><p>Intro</p></td></tr><tr><td><p>Details</p></td></tr></table>`, "Intro\n\nDetails"},
+		{"preformatted", "<pre>a  b\n  c</pre><p>end</p>", "a  b\n  c\n\nend"},
+		{"wrapping", `<p>` + strings.Repeat("word ", 20) + `</p>`,
+			strings.TrimSpace(strings.Repeat("word ", 15)) + "\n" + strings.TrimSpace(strings.Repeat("word ", 5))},
+		{"long url", `<p>Go to ` + "https://icaa.example.com/" + strings.Repeat("x", 80) + ` now</p>`,
+			"Go to\nhttps://icaa.example.com/" + strings.Repeat("x", 80) + "\nnow"},
+		{"wrapped list item", `<ul><li>` + strings.Repeat("word ", 20) + `</li></ul>`,
+			"* " + strings.TrimSpace(strings.Repeat("word ", 15)) + "\n  " + strings.TrimSpace(strings.Repeat("word ", 5))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateTextFromHTML(tt.html)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, got)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if len(line) > TextWrapWidth && strings.Contains(line, " ") {
+					t.Errorf("line longer than %d characters: %q", TextWrapWidth, line)
+				}
+			}
+		})
+	}
+}
+
+func TestTextGeneratingSender(t *testing.T) {
+	inner := &rejectingSender{}
+	sender := NewTextGeneratingSender(inner)
+
+	for _, e := range []Email{
+		{ToAddresses: []string{"ada@example.com"}, HTMLBody: "<p>Hello</p>"},
+		{ToAddresses: []string{"ada@example.com"}, HTMLBody: "<p>Hello</p>", TextBody: "Hi"},
+	} {
+		if err := sender.SendEmail(context.Background(), e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if got := inner.sent[0].TextBody; got != "Hello" {
+		t.Errorf("expected the text body to be generated, got %q", got)
+	}
+	if got := inner.sent[1].TextBody; got != "Hi" {
+		t.Errorf("expected the text body to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultIdentityPollInterval    = 30 * time.Second
+	DefaultIdentityMaxPollInterval = 10 * time.Minute
+	DefaultIdentityWaitLimit       = 24 * time.Hour
+)
+
+// Error.Metadata keys set when IdentityGate refuses a send.
+const (
+	IdentityMetadataKey     = "identity"
+	VerificationMetadataKey = "verification"
+)
+
+// IdentityVerifier reports whether a provider allows sending from an
+// address, e.g. whether SES has verified it or its domain.
+type IdentityVerifier interface {
+	IsVerified(ctx context.Context, address string) (bool, error)
+}
+
+type IdentityGatePolicy string
+
+const (
+	// Fail sends from unverified addresses immediately.
+	IDENTITY_REJECT IdentityGatePolicy = "REJECT"
+	// Hold sends from unverified addresses until verification completes or
+	// the wait limit is reached.
+	IDENTITY_WAIT IdentityGatePolicy = "WAIT"
+)
+
+type IdentityOption func(*IdentityGate)
+
+func WithIdentityPolicy(policy IdentityGatePolicy) IdentityOption {
+	return func(g *IdentityGate) {
+		g.policy = policy
+	}
+}
+
+// WithIdentityPolling sets how often IDENTITY_WAIT checks the verification
+// again. The interval starts at initial and doubles up to max.
+func WithIdentityPolling(initial, max time.Duration) IdentityOption {
+	return func(g *IdentityGate) {
+		g.pollInterval = initial
+		g.maxPollInterval = max
+	}
+}
+
+// WithIdentityWaitLimit sets how long IDENTITY_WAIT holds a send before
+// giving up. Defaults to DefaultIdentityWaitLimit.
+func WithIdentityWaitLimit(d time.Duration) IdentityOption {
+	return func(g *IdentityGate) {
+		g.waitLimit = d
+	}
+}
+
+func WithIdentityClock(c Clock) IdentityOption {
+	return func(g *IdentityGate) {
+		g.clock = c
+	}
+}
+
+var _ Sender = &IdentityGate{}
+var _ SenderV2 = &IdentityGate{}
+
+// IdentityGate decorates a SenderV2 to hold back sends from From addresses
+// the provider has not verified yet, instead of letting them fail with a
+// confusing provider error. Unverified sends fail with
+// REASON_UNVERIFIED_DOMAIN. Addresses are only checked until they are seen
+// verified.
+type IdentityGate struct {
+	inner           SenderV2
+	verifier        IdentityVerifier
+	policy          IdentityGatePolicy
+	pollInterval    time.Duration
+	maxPollInterval time.Duration
+	waitLimit       time.Duration
+	clock           Clock
+
+	mu       sync.Mutex
+	verified map[string]bool
+}
+
+func NewIdentityGate(inner SenderV2, verifier IdentityVerifier, opts ...IdentityOption) *IdentityGate {
+	g := &IdentityGate{
+		inner:           inner,
+		verifier:        verifier,
+		policy:          IDENTITY_REJECT,
+		pollInterval:    DefaultIdentityPollInterval,
+		maxPollInterval: DefaultIdentityMaxPollInterval,
+		waitLimit:       DefaultIdentityWaitLimit,
+		clock:           SystemClock(),
+		verified:        map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+func (g *IdentityGate) Unwrap() SenderV2 {
+	return g.inner
+}
+
+func (g *IdentityGate) SendEmail(ctx context.Context, e Email) error {
+	_, err := g.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (g *IdentityGate) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	final := opts.Apply(e)
+
+	// Malformed addresses are left for the provider's validation to report.
+	if from, err := mail.ParseAddress(final.FromAddress); err == nil {
+		if err := g.await(ctx, strings.ToLower(from.Address)); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.inner.SendEmailV2(ctx, e, opts)
+}
+
+func (g *IdentityGate) await(ctx context.Context, address string) error {
+	start := g.clock.Now()
+	interval := g.pollInterval
+
+	for {
+		ok, err := g.isVerified(ctx, address)
+		if err != nil {
+			return NewServiceError(fmt.Sprintf("failed to check verification of %s", address), err)
+		}
+		if ok {
+			return nil
+		}
+
+		if g.policy != IDENTITY_WAIT {
+			return pendingVerificationError(fmt.Sprintf("from address %s is pending verification", address), address, nil)
+		}
+
+		waited := g.clock.Now().Sub(start)
+		if waited >= g.waitLimit {
+			return pendingVerificationError(fmt.Sprintf("from address %s was still not verified after %s", address, waited), address, nil)
+		}
+
+		if err := g.clock.Sleep(ctx, min(interval, g.waitLimit-waited)); err != nil {
+			return pendingVerificationError(fmt.Sprintf("gave up waiting for verification of %s", address), address, err)
+		}
+		interval = min(interval*2, g.maxPollInterval)
+	}
+}
+
+func (g *IdentityGate) isVerified(ctx context.Context, address string) (bool, error) {
+	g.mu.Lock()
+	ok := g.verified[address]
+	g.mu.Unlock()
+	if ok {
+		return true, nil
+	}
+
+	ok, err := g.verifier.IsVerified(ctx, address)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	g.mu.Lock()
+	g.verified[address] = true
+	g.mu.Unlock()
+	return true, nil
+}
+
+func pendingVerificationError(message, address string, cause error) *Error {
+	err := NewUnverifiedDomainError(message, cause)
+	err.Metadata = map[string]string{
+		IdentityMetadataKey:     address,
+		VerificationMetadataKey: "PENDING",
+	}
+	return err
+}
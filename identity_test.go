@@ -0,0 +1,156 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+)
+
+// countingSender counts the emails that reach it.
+type countingSender struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (s *countingSender) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	return &email.SendResult{}, nil
+}
+
+// flippingVerifier reports an address as verified from the given check on.
+type flippingVerifier struct {
+	clock      email.Clock
+	verifiedAt int
+
+	mu     sync.Mutex
+	checks []time.Time
+}
+
+func (v *flippingVerifier) IsVerified(ctx context.Context, address string) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.checks = append(v.checks, v.clock.Now())
+	return v.verifiedAt > 0 && len(v.checks) >= v.verifiedAt, nil
+}
+
+func TestIdentityGate(t *testing.T) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		policy     email.IdentityGatePolicy
+		verifiedAt int
+		// Clock advances the test makes while the send waits.
+		sleeps         []time.Duration
+		expectedChecks []time.Duration
+		expectedSent   bool
+	}{
+		{
+			name:           "verified",
+			policy:         email.IDENTITY_REJECT,
+			verifiedAt:     1,
+			expectedChecks: []time.Duration{0},
+			expectedSent:   true,
+		},
+		{
+			name:           "rejected while pending",
+			policy:         email.IDENTITY_REJECT,
+			expectedChecks: []time.Duration{0},
+		},
+		{
+			name:           "verified while waiting",
+			policy:         email.IDENTITY_WAIT,
+			verifiedAt:     3,
+			sleeps:         []time.Duration{time.Second, 2 * time.Second},
+			expectedChecks: []time.Duration{0, time.Second, 3 * time.Second},
+			expectedSent:   true,
+		},
+		{
+			name:   "gives up waiting",
+			policy: email.IDENTITY_WAIT,
+			// Backs off 1s, 2s, 4s, then is capped by the 4s maximum and
+			// the 10s wait limit.
+			sleeps:         []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 3 * time.Second},
+			expectedChecks: []time.Duration{0, time.Second, 3 * time.Second, 7 * time.Second, 10 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := emailtest.NewFakeClock(start)
+			verifier := &flippingVerifier{clock: clock, verifiedAt: tt.verifiedAt}
+			inner := &countingSender{}
+			gate := email.NewIdentityGate(inner, verifier,
+				email.WithIdentityPolicy(tt.policy),
+				email.WithIdentityPolling(time.Second, 4*time.Second),
+				email.WithIdentityWaitLimit(10*time.Second),
+				email.WithIdentityClock(clock),
+			)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- gate.SendEmail(context.Background(), email.Email{FromAddress: "New Organizer <Organizer@club.example.com>"})
+			}()
+			for _, d := range tt.sleeps {
+				clock.BlockUntilTimers(1)
+				clock.Advance(d)
+			}
+			err := <-done
+
+			var checks []time.Duration
+			for _, c := range verifier.checks {
+				checks = append(checks, c.Sub(start))
+			}
+			if !reflect.DeepEqual(checks, tt.expectedChecks) {
+				t.Errorf("expected checks at %v, got %v", tt.expectedChecks, checks)
+			}
+
+			if tt.expectedSent {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if inner.count != 1 {
+					t.Errorf("expected the email to be sent, got %d sends", inner.count)
+				}
+				return
+			}
+
+			var emailErr *email.Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_UNVERIFIED_DOMAIN {
+				t.Fatalf("expected an unverified domain error, got %v", err)
+			}
+			if emailErr.Metadata[email.IdentityMetadataKey] != "organizer@club.example.com" ||
+				emailErr.Metadata[email.VerificationMetadataKey] != "PENDING" {
+				t.Errorf("unexpected metadata %v", emailErr.Metadata)
+			}
+			if inner.count != 0 {
+				t.Errorf("expected nothing to be sent, got %d sends", inner.count)
+			}
+		})
+	}
+}
+
+func TestIdentityGate_RemembersVerified(t *testing.T) {
+	clock := emailtest.NewFakeClock(time.Now())
+	verifier := &flippingVerifier{clock: clock, verifiedAt: 1}
+	gate := email.NewIdentityGate(&countingSender{}, verifier)
+
+	for range 3 {
+		if err := gate.SendEmail(context.Background(), email.Email{FromAddress: "organizer@club.example.com"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(verifier.checks) != 1 {
+		t.Errorf("expected a single verification check, got %d", len(verifier.checks))
+	}
+}
@@ -0,0 +1,174 @@
+package email
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ImageDimensions is the size in pixels an image is displayed at.
+type ImageDimensions struct {
+	Width  int
+	Height int
+}
+
+type ImageHintOption func(*ImageAnnotator)
+
+// WithDefaultAltText sets the alt text given to images without one. Defaults
+// to empty, which marks them as decorative for screen readers.
+func WithDefaultAltText(alt string) ImageHintOption {
+	return func(a *ImageAnnotator) {
+		a.defaultAlt = alt
+	}
+}
+
+// WithRemoteImageDimensions gives the dimensions of remote images, keyed by
+// their src URL. Remote images not listed get no dimensions.
+func WithRemoteImageDimensions(dims map[string]ImageDimensions) ImageHintOption {
+	return func(a *ImageAnnotator) {
+		a.remote = dims
+	}
+}
+
+// ImageAnnotator adds the attributes email clients need to lay out and read
+// out the images of HTML bodies: an alt attribute, and width and height so
+// Outlook reserves space for the image. It is safe for concurrent use.
+type ImageAnnotator struct {
+	defaultAlt string
+	remote     map[string]ImageDimensions
+}
+
+func NewImageAnnotator(opts ...ImageHintOption) *ImageAnnotator {
+	a := &ImageAnnotator{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Annotate returns a copy of e whose <img> tags all have an alt attribute,
+// and width and height attributes when their dimensions are known: from the
+// inline attachment a cid: URL references, or from WithRemoteImageDimensions.
+// Attributes already present are kept; when only one of width and height is
+// given, the other follows the image's aspect ratio. Annotating twice gives
+// the same body.
+func (a *ImageAnnotator) Annotate(e Email) (Email, error) {
+	if e.HTMLBody == "" {
+		return e, nil
+	}
+
+	var sb strings.Builder
+	changed := false
+	z := html.NewTokenizer(strings.NewReader(e.HTMLBody))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return Email{}, NewValidationError("failed to parse HTML body", z.Err())
+			}
+			break
+		}
+
+		// Token lowercases the raw tag name in place, so copy it first.
+		raw := string(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			sb.WriteString(raw)
+			continue
+		}
+
+		tok := z.Token()
+		if tok.Data != "img" || !a.annotate(&tok, e.Attachments) {
+			sb.WriteString(raw)
+			continue
+		}
+		changed = true
+		sb.WriteString(tok.String())
+	}
+
+	if !changed {
+		return e, nil
+	}
+	e.HTMLBody = sb.String()
+	return e, nil
+}
+
+// annotate adds the missing attributes to an <img> token and reports whether
+// it added any.
+func (a *ImageAnnotator) annotate(tok *html.Token, attachments []Attachment) bool {
+	attr := func(key string) (string, bool) {
+		i := slices.IndexFunc(tok.Attr, func(at html.Attribute) bool { return at.Key == key })
+		if i < 0 {
+			return "", false
+		}
+		return tok.Attr[i].Val, true
+	}
+
+	changed := false
+	if _, ok := attr("alt"); !ok {
+		tok.Attr = append(tok.Attr, html.Attribute{Key: "alt", Val: a.defaultAlt})
+		changed = true
+	}
+
+	width, hasWidth := attr("width")
+	height, hasHeight := attr("height")
+	if hasWidth && hasHeight {
+		return changed
+	}
+
+	src, _ := attr("src")
+	dims, ok := a.dimensions(src, attachments)
+	if !ok {
+		return changed
+	}
+
+	switch {
+	case hasWidth:
+		w, err := strconv.Atoi(width)
+		if err != nil {
+			return changed
+		}
+		dims = ImageDimensions{Width: w, Height: w * dims.Height / dims.Width}
+	case hasHeight:
+		h, err := strconv.Atoi(height)
+		if err != nil {
+			return changed
+		}
+		dims = ImageDimensions{Width: h * dims.Width / dims.Height, Height: h}
+	}
+
+	if !hasWidth {
+		tok.Attr = append(tok.Attr, html.Attribute{Key: "width", Val: strconv.Itoa(dims.Width)})
+	}
+	if !hasHeight {
+		tok.Attr = append(tok.Attr, html.Attribute{Key: "height", Val: strconv.Itoa(dims.Height)})
+	}
+	return true
+}
+
+// dimensions looks up the size of the image at src. Inline images are
+// decoded, so only formats registered with the image package are known.
+func (a *ImageAnnotator) dimensions(src string, attachments []Attachment) (ImageDimensions, bool) {
+	contentID, ok := strings.CutPrefix(src, "cid:")
+	if !ok {
+		dims, ok := a.remote[src]
+		return dims, ok && dims.Width > 0 && dims.Height > 0
+	}
+
+	i := slices.IndexFunc(attachments, func(at Attachment) bool { return at.ContentID == contentID })
+	if i < 0 {
+		return ImageDimensions{}, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(attachments[i].Content))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return ImageDimensions{}, false
+	}
+	return ImageDimensions{Width: cfg.Width, Height: cfg.Height}, true
+}
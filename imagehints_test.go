@@ -0,0 +1,81 @@
+package email
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodedImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageAnnotator_Annotate(t *testing.T) {
+	pngImage := encodedImage(t, func(b *bytes.Buffer, m image.Image) error { return png.Encode(b, m) }, 120, 40)
+	jpegImage := encodedImage(t, func(b *bytes.Buffer, m image.Image) error { return jpeg.Encode(b, m, nil) }, 300, 200)
+	attachments := []Attachment{
+		{FileName: "logo.png", Content: pngImage, ContentType: "image/png", ContentID: "logo@example.com"},
+		{FileName: "photo.jpg", Content: jpegImage, ContentType: "image/jpeg", ContentID: "photo@example.com"},
+		{FileName: "broken.png", Content: []byte("not a png"), ContentType: "image/png", ContentID: "broken@example.com"},
+	}
+	annotator := NewImageAnnotator(
+		WithDefaultAltText("Image"),
+		WithRemoteImageDimensions(map[string]ImageDimensions{"https://cdn.example.com/banner.png": {Width: 600, Height: 100}}),
+	)
+
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"png", `<img src="cid:logo@example.com">`, `<img src="cid:logo@example.com" alt="Image" width="120" height="40">`},
+		{"jpeg", `<IMG SRC="cid:photo@example.com" alt="Team photo"/>`, `<img src="cid:photo@example.com" alt="Team photo" width="300" height="200"/>`},
+		{"width given", `<img src="cid:photo@example.com" alt="" width="150">`, `<img src="cid:photo@example.com" alt="" width="150" height="100">`},
+		{"height given", `<img src="cid:logo@example.com" alt="" height="20">`, `<img src="cid:logo@example.com" alt="" height="20" width="60">`},
+		{"annotated", `<img src="cid:logo@example.com" alt="Logo" width="60" height="20">`, `<img src="cid:logo@example.com" alt="Logo" width="60" height="20">`},
+		{"remote with hint", `<img src="https://cdn.example.com/banner.png" alt="Banner">`, `<img src="https://cdn.example.com/banner.png" alt="Banner" width="600" height="100">`},
+		{"remote without hint", `<img src="https://cdn.example.com/other.png" alt="Other">`, `<img src="https://cdn.example.com/other.png" alt="Other">`},
+		{"undecodable", `<img src="cid:broken@example.com" alt="Broken">`, `<img src="cid:broken@example.com" alt="Broken">`},
+		{"unknown content id", `<img src="cid:missing@example.com">`, `<img src="cid:missing@example.com" alt="Image">`},
+		{"other tags", `<p class="x">Hi <b>there</b></p>`, `<p class="x">Hi <b>there</b></p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Email{HTMLBody: tt.html, Attachments: attachments}
+
+			got, err := annotator.Annotate(e)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.HTMLBody != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got.HTMLBody)
+			}
+
+			again, err := annotator.Annotate(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if again.HTMLBody != got.HTMLBody {
+				t.Errorf("annotating twice changed %s to %s", got.HTMLBody, again.HTMLBody)
+			}
+		})
+	}
+}
+
+func TestImageAnnotator_DefaultAltIsEmpty(t *testing.T) {
+	got, err := NewImageAnnotator().Annotate(Email{HTMLBody: `<img src="https://cdn.example.com/x.png">`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `<img src="https://cdn.example.com/x.png" alt="">`; got.HTMLBody != want {
+		t.Errorf("expected %s, got %s", want, got.HTMLBody)
+	}
+}
@@ -0,0 +1,215 @@
+package email
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultMaxInlineImageBytes is the largest image ImageInliner embeds unless
+// WithMaxImageBytes is given.
+const DefaultMaxInlineImageBytes = 1024 * 1024
+
+type FetchFailurePolicy string
+
+const (
+	// Fail the whole transformation when an image cannot be inlined.
+	FETCH_FAILURE_FAIL FetchFailurePolicy = "FAIL"
+	// Leave the remote URL in place when an image cannot be inlined.
+	FETCH_FAILURE_KEEP_URL FetchFailurePolicy = "KEEP_URL"
+)
+
+type InlineOption func(*ImageInliner)
+
+func WithImageHTTPClient(client *http.Client) InlineOption {
+	return func(i *ImageInliner) {
+		i.client = client
+	}
+}
+
+// WithMaxImageBytes replaces DefaultMaxInlineImageBytes.
+func WithMaxImageBytes(n int64) InlineOption {
+	return func(i *ImageInliner) {
+		i.maxBytes = n
+	}
+}
+
+// WithFetchFailurePolicy decides what happens to images that cannot be
+// fetched, are too large or are not images. Defaults to FETCH_FAILURE_FAIL.
+func WithFetchFailurePolicy(policy FetchFailurePolicy) InlineOption {
+	return func(i *ImageInliner) {
+		i.onFailure = policy
+	}
+}
+
+// ImageInliner embeds the remote images of HTML bodies as inline
+// attachments, so they show for recipients who block remote content. Fetched
+// images are cached, so reuse one ImageInliner across a bulk send. It is safe
+// for concurrent use.
+type ImageInliner struct {
+	allowedHosts []string
+	client       *http.Client
+	maxBytes     int64
+	onFailure    FetchFailurePolicy
+
+	mu    sync.Mutex
+	cache map[string]Attachment
+}
+
+// NewImageInliner returns an ImageInliner that only fetches images served by
+// allowedHosts. Images from other hosts are left as they are.
+func NewImageInliner(allowedHosts []string, opts ...InlineOption) *ImageInliner {
+	i := &ImageInliner{
+		client:    http.DefaultClient,
+		maxBytes:  DefaultMaxInlineImageBytes,
+		onFailure: FETCH_FAILURE_FAIL,
+		cache:     map[string]Attachment{},
+	}
+	for _, h := range allowedHosts {
+		i.allowedHosts = append(i.allowedHosts, strings.ToLower(h))
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Inline returns a copy of e whose <img> tags pointing at allowed hosts
+// reference inline attachments through cid: URLs instead.
+func (i *ImageInliner) Inline(ctx context.Context, e Email) (Email, error) {
+	if e.HTMLBody == "" {
+		return e, nil
+	}
+
+	var sb strings.Builder
+	var inlined []Attachment
+	z := html.NewTokenizer(strings.NewReader(e.HTMLBody))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return Email{}, NewValidationError("failed to parse HTML body", z.Err())
+			}
+			break
+		}
+
+		// Token lowercases the raw tag name in place, so copy it first.
+		raw := string(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			sb.WriteString(raw)
+			continue
+		}
+
+		tok := z.Token()
+		src := slices.IndexFunc(tok.Attr, func(a html.Attribute) bool { return a.Key == "src" })
+		if tok.Data != "img" || src < 0 || !i.allowed(tok.Attr[src].Val) {
+			sb.WriteString(raw)
+			continue
+		}
+
+		a, err := i.fetch(ctx, tok.Attr[src].Val)
+		if err != nil {
+			if i.onFailure == FETCH_FAILURE_KEEP_URL {
+				sb.WriteString(raw)
+				continue
+			}
+			return Email{}, err
+		}
+
+		if !slices.ContainsFunc(inlined, func(b Attachment) bool { return b.ContentID == a.ContentID }) {
+			inlined = append(inlined, a)
+		}
+		tok.Attr[src].Val = "cid:" + a.ContentID
+		sb.WriteString(tok.String())
+	}
+
+	if len(inlined) == 0 {
+		return e, nil
+	}
+
+	e.HTMLBody = sb.String()
+	e.Attachments = append(slices.Clip(e.Attachments), inlined...)
+	return e, nil
+}
+
+func (i *ImageInliner) allowed(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return slices.Contains(i.allowedHosts, strings.ToLower(u.Hostname()))
+}
+
+func (i *ImageInliner) fetch(ctx context.Context, src string) (Attachment, error) {
+	i.mu.Lock()
+	a, ok := i.cache[src]
+	i.mu.Unlock()
+	if ok {
+		return a, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return Attachment{}, NewValidationError(fmt.Sprintf("invalid image URL %s", src), err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return Attachment{}, NewServiceError(fmt.Sprintf("failed to fetch image %s", src), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Attachment{}, NewServiceError(fmt.Sprintf("failed to fetch image %s: HTTP %d", src, resp.StatusCode), nil)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return Attachment{}, NewValidationError(fmt.Sprintf("%s is not an image: %s", src, resp.Header.Get("Content-Type")), err)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, i.maxBytes+1))
+	if err != nil {
+		return Attachment{}, NewServiceError(fmt.Sprintf("failed to fetch image %s", src), err)
+	}
+	if int64(len(content)) > i.maxBytes {
+		return Attachment{}, NewValidationError(fmt.Sprintf("image %s is larger than %d bytes", src, i.maxBytes), nil)
+	}
+
+	sum := sha256.Sum256([]byte(src))
+	a = Attachment{
+		FileName:    imageFileName(src),
+		Content:     content,
+		ContentType: mediaType,
+		ContentID:   hex.EncodeToString(sum[:8]) + "@inline",
+	}
+
+	i.mu.Lock()
+	i.cache[src] = a
+	i.mu.Unlock()
+
+	return a, nil
+}
+
+func imageFileName(src string) string {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "image"
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return "image"
+	}
+	return name
+}
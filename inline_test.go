@@ -0,0 +1,138 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func newAssetServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png bytes"))
+	})
+	mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(bytes.Repeat([]byte{0xff}, 2048))
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestImageInliner_Inline(t *testing.T) {
+	srv, requests := newAssetServer(t)
+	u, _ := url.Parse(srv.URL)
+	host := u.Hostname()
+	inliner := NewImageInliner([]string{host}, WithImageHTTPClient(srv.Client()))
+
+	e := Email{
+		TextBody: "Hello",
+		HTMLBody: fmt.Sprintf(`<p>Hello</p><IMG SRC="%[1]s/logo.png" alt="Logo"><img src="%[1]s/logo.png"/><img src="https://elsewhere.example.com/x.png">`, srv.URL),
+	}
+
+	got, err := inliner.Inline(context.Background(), e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got.Attachments) != 1 {
+		t.Fatalf("expected one inline attachment, got %d", len(got.Attachments))
+	}
+	a := got.Attachments[0]
+	if a.FileName != "logo.png" || a.ContentType != "image/png" || string(a.Content) != "png bytes" || a.ContentID == "" {
+		t.Errorf("unexpected attachment %+v", a)
+	}
+
+	want := fmt.Sprintf(`<p>Hello</p><img src="cid:%[1]s" alt="Logo"><img src="cid:%[1]s"/><img src="https://elsewhere.example.com/x.png">`, a.ContentID)
+	if got.HTMLBody != want {
+		t.Errorf("unexpected HTML body\n got: %s\nwant: %s", got.HTMLBody, want)
+	}
+	if e.Attachments != nil {
+		t.Error("original email was modified")
+	}
+
+	if _, err := inliner.Inline(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the image to be fetched once across sends, got %d requests", n)
+	}
+}
+
+func TestImageInliner_Failures(t *testing.T) {
+	srv, _ := newAssetServer(t)
+	u, _ := url.Parse(srv.URL)
+	host := u.Hostname()
+
+	tests := []struct {
+		name   string
+		path   string
+		reason ErrorReason
+	}{
+		{"too large", "/large.png", REASON_VALIDATION_ERROR},
+		{"not an image", "/page.html", REASON_VALIDATION_ERROR},
+		{"not found", "/missing.png", REASON_SERVICE_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Email{HTMLBody: fmt.Sprintf(`<img src="%s%s">`, srv.URL, tt.path)}
+
+			inliner := NewImageInliner([]string{host}, WithImageHTTPClient(srv.Client()), WithMaxImageBytes(1024))
+			_, err := inliner.Inline(context.Background(), e)
+			var emailErr *Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != tt.reason {
+				t.Fatalf("expected %s, got %v", tt.reason, err)
+			}
+
+			inliner = NewImageInliner([]string{host},
+				WithImageHTTPClient(srv.Client()),
+				WithMaxImageBytes(1024),
+				WithFetchFailurePolicy(FETCH_FAILURE_KEEP_URL),
+			)
+			got, err := inliner.Inline(context.Background(), e)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.HTMLBody != e.HTMLBody || len(got.Attachments) != 0 {
+				t.Errorf("expected the email to be unchanged, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestImageInliner_ContextCancelled(t *testing.T) {
+	srv, requests := newAssetServer(t)
+	u, _ := url.Parse(srv.URL)
+	inliner := NewImageInliner([]string{u.Hostname()}, WithImageHTTPClient(srv.Client()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := inliner.Inline(ctx, Email{HTMLBody: fmt.Sprintf(`<img src="%s/logo.png">`, srv.URL)})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
@@ -0,0 +1,217 @@
+// Package aeszip reads and writes zip archives encrypted with WinZip AE-2
+// (AES-256), the password-protected zip format understood by 7-Zip, WinZip
+// and macOS Archive Utility.
+package aeszip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	methodAES      = 99
+	extraAES       = 0x9901
+	versionAE2     = 2
+	strengthAES256 = 3
+
+	keyLen      = 32
+	saltLen     = 16
+	verifierLen = 2
+	authLen     = 10
+	iterations  = 1000
+)
+
+var ErrWrongPassword = errors.New("aeszip: wrong password")
+
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Encrypt builds an archive holding files, each deflated and encrypted with
+// password.
+func Encrypt(files []File, password string) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("aeszip: empty password")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		var compressed bytes.Buffer
+		fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(f.Content); err != nil {
+			return nil, err
+		}
+		if err := fw.Close(); err != nil {
+			return nil, err
+		}
+
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+
+		encKey, authKey, verifier, err := deriveKeys(password, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		data := compressed.Bytes()
+		if err := xorKeyStream(encKey, data); err != nil {
+			return nil, err
+		}
+
+		mac := hmac.New(sha1.New, authKey)
+		mac.Write(data)
+
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               f.Name,
+			Method:             methodAES,
+			Flags:              0x1,
+			CompressedSize64:   uint64(saltLen + verifierLen + len(data) + authLen),
+			UncompressedSize64: uint64(len(f.Content)),
+			// AE-2 leaves the CRC empty; the authentication code replaces it.
+			CRC32: 0,
+			Extra: aesExtra(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range [][]byte{salt, verifier, data, mac.Sum(nil)[:authLen]} {
+			if _, err := w.Write(chunk); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decrypt opens an archive written by Encrypt and returns its files.
+func Decrypt(archive []byte, password string) ([]File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, zf := range zr.File {
+		if zf.Method != methodAES {
+			return nil, fmt.Errorf("aeszip: %s is not AES encrypted", zf.Name)
+		}
+
+		r, err := zf.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) < saltLen+verifierLen+authLen {
+			return nil, fmt.Errorf("aeszip: %s is truncated", zf.Name)
+		}
+
+		salt := raw[:saltLen]
+		verifier := raw[saltLen : saltLen+verifierLen]
+		data := raw[saltLen+verifierLen : len(raw)-authLen]
+		authCode := raw[len(raw)-authLen:]
+
+		encKey, authKey, expected, err := deriveKeys(password, salt)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(verifier, expected) {
+			return nil, ErrWrongPassword
+		}
+
+		mac := hmac.New(sha1.New, authKey)
+		mac.Write(data)
+		if !hmac.Equal(mac.Sum(nil)[:authLen], authCode) {
+			return nil, fmt.Errorf("aeszip: %s failed authentication", zf.Name)
+		}
+
+		data = bytes.Clone(data)
+		if err := xorKeyStream(encKey, data); err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, File{Name: zf.Name, Content: content})
+	}
+
+	return files, nil
+}
+
+func deriveKeys(password string, salt []byte) (encKey, authKey, verifier []byte, err error) {
+	key, err := pbkdf2.Key(sha1.New, password, salt, iterations, 2*keyLen+verifierLen)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return key[:keyLen], key[keyLen : 2*keyLen], key[2*keyLen:], nil
+}
+
+// xorKeyStream applies AES-CTR in place. WinZip increments the counter as a
+// little-endian integer starting at 1, unlike cipher.NewCTR.
+func xorKeyStream(key, data []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	var counter, stream [aes.BlockSize]byte
+	for i := 0; i < len(data); i += aes.BlockSize {
+		incrementLE(counter[:])
+		block.Encrypt(stream[:], counter[:])
+		end := min(i+aes.BlockSize, len(data))
+		for j := i; j < end; j++ {
+			data[j] ^= stream[j-i]
+		}
+	}
+
+	return nil
+}
+
+func incrementLE(counter []byte) {
+	for i := range counter {
+		counter[i]++
+		if counter[i] != 0 {
+			return
+		}
+	}
+}
+
+func aesExtra() []byte {
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra[0:], extraAES)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], versionAE2)
+	copy(extra[6:], "AE")
+	extra[8] = strengthAES256
+	binary.LittleEndian.PutUint16(extra[9:], zip.Deflate)
+	return extra
+}
@@ -0,0 +1,54 @@
+package aeszip
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncryptDecrypt_RoundTrip(t *testing.T) {
+	files := []File{
+		{Name: "roster.csv", Content: []byte("name,club\nAda,North\nGrace,South\n")},
+		{Name: "empty.txt", Content: []byte{}},
+		{Name: "large.bin", Content: bytes.Repeat([]byte("0123456789abcdef!"), 1000)},
+	}
+
+	archive, err := Encrypt(files, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bytes.Contains(archive, []byte("Ada,North")) {
+		t.Error("expected file content to be encrypted")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("archive does not parse as zip: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Method != methodAES || f.Flags&0x1 == 0 {
+			t.Errorf("expected %s to be marked AES encrypted, got method %d flags %#x", f.Name, f.Method, f.Flags)
+		}
+	}
+
+	got, err := Decrypt(archive, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("round trip mismatch: got %v", got)
+	}
+
+	if _, err := Decrypt(archive, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+}
+
+func TestEncrypt_EmptyPassword(t *testing.T) {
+	if _, err := Encrypt([]File{{Name: "a.txt"}}, ""); err == nil {
+		t.Error("expected error for empty password")
+	}
+}
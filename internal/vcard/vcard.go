@@ -0,0 +1,127 @@
+// Package vcard reads and writes vCard 3.0 (RFC 2426) contact cards, the
+// .vcf files address books import.
+package vcard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lines are folded to at most this many octets, excluding the line break.
+const maxLineOctets = 75
+
+// Property is one content line, e.g. EMAIL;TYPE=INTERNET:ada@example.com.
+type Property struct {
+	Name string
+	// Parameters as written, e.g. "TYPE=INTERNET".
+	Params []string
+	// The components of a structured value such as N, joined with
+	// semicolons. Most properties have a single one.
+	Values []string
+}
+
+// Encode returns the vCard with props between its BEGIN, VERSION and END
+// lines, escaped and folded, with CRLF line breaks.
+func Encode(props []Property) []byte {
+	var b bytes.Buffer
+	writeLine(&b, "BEGIN:VCARD")
+	writeLine(&b, "VERSION:3.0")
+	for _, p := range props {
+		escaped := make([]string, len(p.Values))
+		for i, v := range p.Values {
+			escaped[i] = Escape(v)
+		}
+		name := strings.Join(append([]string{p.Name}, p.Params...), ";")
+		writeLine(&b, name+":"+strings.Join(escaped, ";"))
+	}
+	writeLine(&b, "END:VCARD")
+	return b.Bytes()
+}
+
+// writeLine writes line folded after every maxLineOctets octets, without
+// splitting a UTF-8 sequence.
+func writeLine(b *bytes.Buffer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts towards it.
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// Escape escapes backslashes, semicolons, commas and line breaks in a text
+// value.
+func Escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// Decode parses a single vCard, unfolding and unescaping its content lines.
+// The BEGIN, VERSION and END lines are checked and left out.
+func Decode(data []byte) ([]Property, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.NewReplacer("\n ", "", "\n\t", "").Replace(text)
+
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) < 3 || !strings.EqualFold(lines[0], "BEGIN:VCARD") || !strings.EqualFold(lines[len(lines)-1], "END:VCARD") {
+		return nil, errors.New("not a single vCard")
+	}
+
+	var props []Property
+	version := ""
+	for _, line := range lines[1 : len(lines)-1] {
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("content line %q has no value", line)
+		}
+		name, params, _ := strings.Cut(head, ";")
+		p := Property{Name: strings.ToUpper(name), Values: splitValue(value)}
+		if params != "" {
+			p.Params = strings.Split(params, ";")
+		}
+		if p.Name == "VERSION" {
+			version = value
+			continue
+		}
+		props = append(props, p)
+	}
+	if version != "3.0" {
+		return nil, fmt.Errorf("unsupported vCard version %q", version)
+	}
+	return props, nil
+}
+
+// splitValue splits value at unescaped semicolons and unescapes the
+// components.
+func splitValue(value string) []string {
+	var values []string
+	var current strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' && i+1 < len(value):
+			i++
+			if value[i] == 'n' || value[i] == 'N' {
+				current.WriteByte('\n')
+			} else {
+				current.WriteByte(value[i])
+			}
+		case c == ';':
+			values = append(values, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(values, current.String())
+}
@@ -0,0 +1,87 @@
+package vcard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"North Club", "North Club"},
+		{"Arrows; Bows, and more", `Arrows\; Bows\, and more`},
+		{`C:\archery`, `C:\\archery`},
+		{"line one\nline two\r\nline three", `line one\nline two\nline three`},
+	}
+
+	for _, tt := range tests {
+		if got := Escape(tt.in); got != tt.expected {
+			t.Errorf("Escape(%q): expected %q, got %q", tt.in, tt.expected, got)
+		}
+	}
+}
+
+func TestEncode_Folding(t *testing.T) {
+	long := strings.Repeat("Grüße aus München, ", 20)
+	data := Encode([]Property{{Name: "NOTE", Values: []string{long}}})
+
+	if !strings.HasPrefix(string(data), "BEGIN:VCARD\r\nVERSION:3.0\r\n") || !strings.HasSuffix(string(data), "\r\nEND:VCARD\r\n") {
+		t.Errorf("expected the card to be framed by BEGIN, VERSION and END, got %q", data)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 sequence: %q", line)
+		}
+	}
+
+	props, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := props[0].Values[0]; got != long {
+		t.Errorf("expected %q after unfolding, got %q", long, got)
+	}
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	props := []Property{
+		{Name: "FN", Values: []string{"Ada Lovelace"}},
+		{Name: "N", Values: []string{"Lovelace", "Ada", "", "", ""}},
+		{Name: "ORG", Values: []string{`Arrows; Bows, \ Co.`}},
+		{Name: "NOTE", Values: []string{"line one\nline two"}},
+		{Name: "EMAIL", Params: []string{"TYPE=INTERNET"}, Values: []string{"ada@example.com"}},
+	}
+
+	got, err := Decode(Encode(props))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, props) {
+		t.Errorf("expected %+v, got %+v", props, got)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"not a vcard": "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"no end":      "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Ada\r\n",
+		"no value":    "BEGIN:VCARD\r\nVERSION:3.0\r\nFN\r\nEND:VCARD\r\n",
+		"version 4.0": "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Ada\r\nEND:VCARD\r\n",
+		"no version":  "BEGIN:VCARD\r\nFN:Ada\r\nEND:VCARD\r\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode([]byte(data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
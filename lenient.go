@@ -0,0 +1,92 @@
+package email
+
+import "strings"
+
+// Characters people leave at the end of a pasted address.
+const trailingPunctuation = ".,;:"
+
+// Pairs of characters addresses get wrapped in when copied from a client or
+// document.
+var wrappers = []struct{ open, close string }{
+	{`"`, `"`}, {`'`, `'`}, {"(", ")"}, {"[", "]"}, {"<", ">"},
+}
+
+// ParseLenient repairs common mistakes in addresses typed or pasted into
+// forms: a mailto: prefix, trailing punctuation, wrapping quotes or
+// brackets, a name without angle brackets around the address ("Jane Doe
+// jane@example.com"), an unclosed angle bracket and a display name that
+// needs quoting. It returns the address formatted for an Email, whether it
+// could be parsed, and a note on what was repaired, empty if nothing was.
+//
+// Inputs that are ambiguous, such as several addresses, are not repaired.
+// The repaired address is still checked strictly when sent.
+func ParseLenient(s string) (normalized string, ok bool, note string) {
+	s = strings.TrimSpace(s)
+	if a, err := ParseAddress(s); err == nil {
+		return a.String(), true, ""
+	}
+
+	var fixes []string
+	if len(s) >= len("mailto:") && strings.EqualFold(s[:len("mailto:")], "mailto:") {
+		s = s[len("mailto:"):]
+		if i := strings.IndexByte(s, '?'); i >= 0 {
+			s = s[:i]
+		}
+		fixes = append(fixes, "removed mailto: prefix")
+	}
+
+	if trimmed := strings.TrimRight(s, trailingPunctuation); trimmed != s {
+		s = strings.TrimSpace(trimmed)
+		fixes = append(fixes, "removed trailing punctuation")
+	}
+
+	for _, w := range wrappers {
+		inner, ok := strings.CutPrefix(s, w.open)
+		if !ok {
+			continue
+		}
+		inner, ok = strings.CutSuffix(inner, w.close)
+		if !ok || strings.Contains(inner, w.open) || strings.Contains(inner, w.close) {
+			continue
+		}
+		// "<jane@example.com>" already parses, so only wrapped names and
+		// addresses get here.
+		s = strings.TrimSpace(inner)
+		fixes = append(fixes, "removed surrounding "+w.open+w.close)
+		break
+	}
+
+	if strings.Contains(s, "<") && !strings.Contains(s, ">") {
+		s += ">"
+		fixes = append(fixes, "closed the angle bracket")
+	}
+
+	if a, err := ParseAddress(s); err == nil {
+		return a.String(), true, strings.Join(fixes, "; ")
+	}
+
+	var a Address
+	if name, addr, ok := strings.Cut(s, "<"); ok {
+		addr, rest, _ := strings.Cut(addr, ">")
+		if strings.TrimSpace(rest) != "" {
+			return "", false, ""
+		}
+		a = Address{Name: strings.Trim(strings.TrimSpace(name), `"`), Address: strings.TrimSpace(addr)}
+		fixes = append(fixes, "quoted the display name")
+	} else {
+		fields := strings.Fields(s)
+		last := len(fields) - 1
+		if last < 1 || strings.Count(s, "@") != 1 || !strings.Contains(fields[last], "@") {
+			return "", false, ""
+		}
+		a = Address{Name: strings.Join(fields[:last], " "), Address: fields[last]}
+		fixes = append(fixes, "added angle brackets around the address")
+	}
+
+	parsed, err := ParseAddress("<" + a.Address + ">")
+	if err != nil || a.Name == "" {
+		return "", false, ""
+	}
+	a.Address = parsed.Address
+	return a.String(), true, strings.Join(fixes, "; ")
+}
@@ -0,0 +1,69 @@
+package email
+
+import "testing"
+
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		input      string
+		normalized string
+		note       string
+	}{
+		{"jane@example.com", "jane@example.com", ""},
+		{"Jane Doe <jane@example.com>", `"Jane Doe" <jane@example.com>`, ""},
+		{"  <jane@example.com>  ", "jane@example.com", ""},
+		{"John Smith john@example.com", `"John Smith" <john@example.com>`, "added angle brackets around the address"},
+		{"mailto:jane@example.com", "jane@example.com", "removed mailto: prefix"},
+		{"MAILTO:jane@example.com?subject=Hi", "jane@example.com", "removed mailto: prefix"},
+		{"jane@example.com.", "jane@example.com", "removed trailing punctuation"},
+		{"jane@example.com;", "jane@example.com", "removed trailing punctuation"},
+		{`"jane@example.com"`, "jane@example.com", `removed surrounding ""`},
+		{"'Jane Doe jane@example.com'", `"Jane Doe" <jane@example.com>`, "removed surrounding ''; added angle brackets around the address"},
+		{"(jane@example.com)", "jane@example.com", "removed surrounding ()"},
+		{"<Jane Doe jane@example.com>", `"Jane Doe" <jane@example.com>`, "removed surrounding <>; added angle brackets around the address"},
+		{"<jane@example.com", "jane@example.com", "closed the angle bracket"},
+		{"Jane Doe <jane@example.com", `"Jane Doe" <jane@example.com>`, "closed the angle bracket"},
+		{"Doe, Jane <jane@example.com>", `"Doe, Jane" <jane@example.com>`, "quoted the display name"},
+		{"mailto:Jane Doe jane@example.com,", `"Jane Doe" <jane@example.com>`, "removed mailto: prefix; removed trailing punctuation; added angle brackets around the address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			normalized, ok, note := ParseLenient(tt.input)
+			if !ok {
+				t.Fatalf("expected %q to be repaired", tt.input)
+			}
+			if normalized != tt.normalized {
+				t.Errorf("expected %q, got %q", tt.normalized, normalized)
+			}
+			if note != tt.note {
+				t.Errorf("expected note %q, got %q", tt.note, note)
+			}
+			if _, err := ParseAddress(normalized); err != nil {
+				t.Errorf("normalized address does not parse strictly: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseLenient_NotRepaired(t *testing.T) {
+	inputs := []string{
+		"",
+		"jane",
+		"jane at example dot com",
+		"jane@",
+		"@example.com",
+		"jane@@example.com",
+		"jane@example.com, john@example.com",
+		"jane@example.com john@example.com",
+		"Jane Doe <jane@example.com> extra",
+		"Jane <jane>",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if normalized, ok, _ := ParseLenient(input); ok {
+				t.Errorf("expected %q not to be repaired, got %q", input, normalized)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package email
+
+import (
+	"context"
+	"errors"
+)
+
+// Shutdowner is implemented by components that own background work, such as
+// goroutines that can outlive a send. Shutdown waits for that work to finish
+// until ctx is done, then returns an error describing what was abandoned.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Unwrapper is implemented by decorators to return the sender they wrap, so
+// Shutdown reaches every component of a chain.
+type Unwrapper interface {
+	Unwrap() SenderV2
+}
+
+// Shutdown shuts down every component of the decorator chain starting at s
+// that implements Shutdowner, outermost first, so no decorator hands work to
+// a component that has already shut down. Components are still shut down
+// after ctx is done, giving them a chance to report what they abandoned, and
+// every error is returned joined.
+func Shutdown(ctx context.Context, s SenderV2) error {
+	var errs []error
+	for s != nil {
+		if sd, ok := s.(Shutdowner); ok {
+			if err := sd.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		u, ok := s.(Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	return errors.Join(errs...)
+}
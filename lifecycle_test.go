@@ -0,0 +1,135 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	_ Unwrapper = &AnomalyGuard{}
+	_ Unwrapper = &AutoCorrectSender{}
+	_ Unwrapper = &BudgetedSender{}
+	_ Unwrapper = &DeadLetterSender{}
+	_ Unwrapper = &EventSender{}
+	_ Unwrapper = &FallbackSender{}
+	_ Unwrapper = &HeaderInjectingSender{}
+	_ Unwrapper = &IdentityGate{}
+	_ Unwrapper = &IndexingSender{}
+	_ Unwrapper = &ModerationSender{}
+	_ Unwrapper = &ResolvingSender{}
+	_ Unwrapper = &RosterSender{}
+	_ Unwrapper = &ShrinkingSender{}
+	_ Unwrapper = &TextGeneratingSender{}
+)
+
+// drainingSender is a component with background work that takes drain to
+// finish.
+type drainingSender struct {
+	name     string
+	drain    time.Duration
+	shutdown *[]string
+}
+
+func (s *drainingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	return &SendResult{}, nil
+}
+
+func (s *drainingSender) Shutdown(ctx context.Context) error {
+	*s.shutdown = append(*s.shutdown, s.name)
+	select {
+	case <-time.After(s.drain):
+		return nil
+	case <-ctx.Done():
+		return errors.New(s.name + " abandoned its queue")
+	}
+}
+
+// blockingModerator ignores its context and returns once release is closed.
+func blockingModerator(release chan struct{}) Moderator {
+	return moderatorFunc(func(ctx context.Context, e Email) (Decision, error) {
+		<-release
+		return Decision{Action: MODERATION_ALLOW}, nil
+	})
+}
+
+// waitForGoroutines fails the test if the number of goroutines doesn't
+// return to n, i.e. if background work leaked.
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d goroutines, got %d", n, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShutdown_Chain(t *testing.T) {
+	var order []string
+	bottom := &drainingSender{name: "bottom", shutdown: &order}
+	chain := NewEventSender(NewTextGeneratingSender(AsSenderV2(AsSender(bottom))))
+
+	if err := Shutdown(context.Background(), chain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order) != 1 || order[0] != "bottom" {
+		t.Errorf("expected the wrapped component to be shut down, got %v", order)
+	}
+}
+
+func TestShutdown_DeadlineMidDrain(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	release := make(chan struct{})
+	var order []string
+	bottom := &drainingSender{name: "queue", drain: time.Hour, shutdown: &order}
+	moderated := NewModerationSender(bottom, blockingModerator(release), WithModerationTimeout(10*time.Millisecond, MODERATION_FAIL_OPEN))
+	chain := NewIndexingSender(moderated, NewSendIndex())
+
+	if err := chain.SendEmail(context.Background(), Email{Subject: "Hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := Shutdown(ctx, chain)
+
+	var emailErr *Error
+	if !errors.As(err, &emailErr) || !strings.Contains(emailErr.Message, "1 moderation calls still running") {
+		t.Errorf("expected the running moderation call to be reported, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "queue abandoned its queue") {
+		t.Errorf("expected the inner component to be shut down after the deadline too, got %v", err)
+	}
+	if len(order) != 1 {
+		t.Errorf("expected the inner component to be shut down once, got %v", order)
+	}
+
+	close(release)
+	if err := moderated.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error once the moderator returned: %v", err)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestModerationSender_ShutdownIdle(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	s := NewModerationSender(&recordingSenderV2{}, moderatorFunc(func(ctx context.Context, e Email) (Decision, error) {
+		return Decision{Action: MODERATION_ALLOW}, nil
+	}), WithModerationTimeout(time.Second, MODERATION_FAIL_CLOSED))
+
+	if err := s.SendEmail(context.Background(), Email{Subject: "Hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("expected an idle sender to shut down at once, got %v", err)
+	}
+	waitForGoroutines(t, baseline)
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+type LinkRule string
+
+const (
+	// The link points outside the allowed domains and prefixes.
+	LINK_NOT_ALLOWED LinkRule = "NOT_ALLOWED"
+	// The link uses plain http.
+	LINK_INSECURE LinkRule = "INSECURE"
+	// The link cannot be parsed, or its host is not a valid domain name.
+	LINK_MALFORMED LinkRule = "MALFORMED"
+)
+
+type LinkFinding struct {
+	Rule     LinkRule
+	Severity Severity
+	URL      string
+	Message  string
+}
+
+// LinkPolicy guards against emails linking to unexpected sites, such as a
+// lookalike domain slipped into a compromised template. Hosts are compared
+// after percent-decoding and conversion to punycode, so a name that only
+// looks like an allowed domain does not match it.
+type LinkPolicy struct {
+	// Domains links may point to, subdomains included, e.g. "icaa.org".
+	AllowedDomains []string
+	// URLs links may start with, e.g. "https://forms.example.com/icaa/".
+	// End them with a slash to match whole path segments only.
+	AllowedPrefixes []string
+	// Severity of links outside the allowlist. Defaults to SEVERITY_ERROR.
+	NotAllowedSeverity Severity
+	// Severity of http links. Defaults to SEVERITY_WARNING.
+	InsecureSeverity Severity
+}
+
+var textURLRegex = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// Check returns a finding for every offending link in the HTML body (href
+// targets) and the text body (bare URLs). mailto:, tel:, cid: and
+// in-document links are not checked.
+func (p LinkPolicy) Check(e Email) []LinkFinding {
+	var links []string
+	if e.HTMLBody != "" {
+		links = append(links, scanHTML(e.HTMLBody).links...)
+	}
+	for _, link := range textURLRegex.FindAllString(e.TextBody, -1) {
+		links = append(links, strings.TrimRight(link, ".,;:!?)]'"))
+	}
+
+	var findings []LinkFinding
+	add := func(rule LinkRule, severity Severity, link, format string, args ...any) {
+		findings = append(findings, LinkFinding{
+			Rule:     rule,
+			Severity: severity,
+			URL:      link,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	notAllowed := cmp.Or(p.NotAllowedSeverity, SEVERITY_ERROR)
+	insecure := cmp.Or(p.InsecureSeverity, SEVERITY_WARNING)
+
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if skipLink(link) {
+			continue
+		}
+
+		u, err := normalizeLink(link)
+		if err != nil {
+			add(LINK_MALFORMED, notAllowed, link, "link %q is malformed: %s", link, err)
+			continue
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			add(LINK_NOT_ALLOWED, notAllowed, link, "link %q uses the %s scheme", link, u.Scheme)
+			continue
+		}
+		if u.Scheme == "http" {
+			add(LINK_INSECURE, insecure, link, "link %q does not use https", link)
+		}
+
+		if !p.allows(u) {
+			host := u.Hostname()
+			if display, err := idna.Display.ToUnicode(host); err == nil && display != host {
+				host = fmt.Sprintf("%s (%s)", host, display)
+			}
+			add(LINK_NOT_ALLOWED, notAllowed, link, "link %q points to %s, which is not allowed", link, host)
+		}
+	}
+
+	return findings
+}
+
+// Validate fails with REASON_VALIDATION_ERROR listing every link Check
+// reports with SEVERITY_ERROR.
+func (p LinkPolicy) Validate(e Email) error {
+	var offending []string
+	for _, f := range p.Check(e) {
+		if f.Severity == SEVERITY_ERROR {
+			offending = append(offending, f.URL)
+		}
+	}
+	if len(offending) == 0 {
+		return nil
+	}
+	return NewValidationError(fmt.Sprintf("email links to disallowed URLs: %s", strings.Join(offending, ", ")), nil)
+}
+
+func (p LinkPolicy) allows(u *url.URL) bool {
+	host := u.Hostname()
+	for _, d := range p.AllowedDomains {
+		d, err := normalizeHost(d)
+		if err != nil || d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	s := u.String()
+	for _, prefix := range p.AllowedPrefixes {
+		pu, err := normalizeLink(prefix)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(s, pu.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipLink(link string) bool {
+	lower := strings.ToLower(link)
+	return link == "" || strings.HasPrefix(link, "#") ||
+		strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(lower, "tel:") || strings.HasPrefix(lower, "cid:")
+}
+
+// normalizeLink parses link with its host lower-cased and in punycode, and
+// without user info, which can be used to make a URL look like it points
+// elsewhere. Links without a scheme, such as //host/path, are taken as
+// https.
+func normalizeLink(link string) (*url.URL, error) {
+	link, err := decodeAuthority(link)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		if u.Host == "" {
+			return nil, fmt.Errorf("relative links cannot be checked")
+		}
+		u.Scheme = "https"
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return u, nil
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return nil, fmt.Errorf("no host")
+	}
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+
+	return &url.URL{Scheme: u.Scheme, Host: host, Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}, nil
+}
+
+// decodeAuthority percent-decodes the host part of link the way browsers do,
+// which url.Parse refuses to.
+func decodeAuthority(link string) (string, error) {
+	start := strings.Index(link, "//")
+	if start < 0 {
+		return link, nil
+	}
+	start += 2
+	end := len(link)
+	if i := strings.IndexAny(link[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	authority, err := url.PathUnescape(link[start:end])
+	if err != nil {
+		return "", err
+	}
+	return link[:start] + authority + link[end:], nil
+}
+
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	return idna.Lookup.ToASCII(host)
+}
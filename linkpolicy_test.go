@@ -0,0 +1,120 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLinkPolicy(t *testing.T) {
+	policy := LinkPolicy{
+		AllowedDomains:  []string{"icaa.org"},
+		AllowedPrefixes: []string{"https://forms.example.com/icaa/"},
+	}
+
+	legit := Email{
+		HTMLBody: `<p>Register at <a href="https://icaa.org/events/spring">icaa.org</a>,
+			sign the <a href="https://forms.example.com/icaa/waiver?id=1">waiver</a>,
+			see <a href="https://www.ICAA.org./rules">the rules</a> or
+			<a href="mailto:help@icaa.org">write to us</a>. <a href="#top">Top</a>
+			<img src="cid:logo@icaa.org"></p>`,
+		TextBody: "Register at https://icaa.org/events/spring.",
+	}
+
+	tests := []struct {
+		name  string
+		email Email
+		want  []LinkFinding
+	}{
+		{name: "legit template", email: legit},
+		{
+			name: "deceptive unicode domain",
+			// The "а" is Cyrillic.
+			email: Email{HTMLBody: `<a href="https://icаa.org/login">icaa.org</a>`},
+			want: []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "https://icаa.org/login",
+				Message: `link "https://icаa.org/login" points to xn--ica-7cd.org (icаa.org), which is not allowed`}},
+		},
+		{
+			name:  "percent-encoded lookalike",
+			email: Email{HTMLBody: `<a href="https://icaa.org%2eevil.example/">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "https://icaa.org%2eevil.example/"}},
+		},
+		{
+			name:  "user info",
+			email: Email{HTMLBody: `<a href="https://icaa.org@evil.example/">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "https://icaa.org@evil.example/"}},
+		},
+		{
+			name:  "suffix is not a subdomain",
+			email: Email{TextBody: "Log in at https://evilicaa.org/login."},
+			want:  []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "https://evilicaa.org/login"}},
+		},
+		{
+			name:  "prefix only covers its path",
+			email: Email{HTMLBody: `<a href="https://forms.example.com/other/">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "https://forms.example.com/other/"}},
+		},
+		{
+			name:  "insecure",
+			email: Email{HTMLBody: `<a href="http://icaa.org/">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_INSECURE, Severity: SEVERITY_WARNING, URL: "http://icaa.org/"}},
+		},
+		{
+			name:  "javascript",
+			email: Email{HTMLBody: `<a href="javascript:alert(1)">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_NOT_ALLOWED, Severity: SEVERITY_ERROR, URL: "javascript:alert(1)"}},
+		},
+		{
+			name:  "malformed",
+			email: Email{HTMLBody: `<a href="https://ic aa.org/">x</a>`},
+			want:  []LinkFinding{{Rule: LINK_MALFORMED, Severity: SEVERITY_ERROR, URL: "https://ic aa.org/"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Check(tt.email)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d findings, got %+v", len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if got[i].Rule != want.Rule || got[i].Severity != want.Severity || got[i].URL != want.URL {
+					t.Errorf("expected %+v, got %+v", want, got[i])
+				}
+				if want.Message != "" && got[i].Message != want.Message {
+					t.Errorf("expected message %q, got %q", want.Message, got[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkPolicy_Validate(t *testing.T) {
+	e := Email{
+		HTMLBody: `<a href="http://icaa.org/">ok</a> <a href="https://evil.example/a">a</a>`,
+		TextBody: "Or https://evil.example/b",
+	}
+
+	err := LinkPolicy{AllowedDomains: []string{"icaa.org"}}.Validate(e)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	for _, link := range []string{"https://evil.example/a", "https://evil.example/b"} {
+		if !strings.Contains(err.Error(), link) {
+			t.Errorf("expected %s to be listed, got %v", link, err)
+		}
+	}
+	if strings.Contains(err.Error(), "http://icaa.org/") {
+		t.Errorf("expected warnings not to be listed, got %v", err)
+	}
+
+	warnOnly := LinkPolicy{AllowedDomains: []string{"icaa.org"}, NotAllowedSeverity: SEVERITY_WARNING}
+	if err := warnOnly.Validate(e); err != nil {
+		t.Errorf("expected warnings only, got %v", err)
+	}
+
+	strict := LinkPolicy{AllowedDomains: []string{"icaa.org", "evil.example"}, InsecureSeverity: SEVERITY_ERROR}
+	if err := strict.Validate(e); err == nil || !strings.Contains(err.Error(), "http://icaa.org/") {
+		t.Errorf("expected insecure link to fail, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package email
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type Severity string
+
+const (
+	SEVERITY_WARNING Severity = "WARNING"
+	SEVERITY_ERROR   Severity = "ERROR"
+)
+
+type LintRule string
+
+const (
+	LINT_IMG_MISSING_ALT      LintRule = "IMG_MISSING_ALT"
+	LINT_HTML_MISSING_LANG    LintRule = "HTML_MISSING_LANG"
+	LINT_LAYOUT_TABLE_ROLE    LintRule = "LAYOUT_TABLE_ROLE"
+	LINT_FONT_SIZE_TOO_SMALL  LintRule = "FONT_SIZE_TOO_SMALL"
+	LINT_NON_DESCRIPTIVE_LINK LintRule = "NON_DESCRIPTIVE_LINK"
+)
+
+// MinAccessibleFontSizePx is the smallest inline font size, in pixels, that
+// LintHTML accepts.
+const MinAccessibleFontSizePx = 12
+
+type Finding struct {
+	Rule     LintRule
+	Severity Severity
+	Message  string
+}
+
+var (
+	fontSizeRegex = regexp.MustCompile(`(?i)font-size\s*:\s*([0-9]*\.?[0-9]+)\s*(px|pt)`)
+
+	nonDescriptiveLinkText = map[string]bool{
+		"click here": true,
+		"click":      true,
+		"here":       true,
+		"link":       true,
+		"this link":  true,
+	}
+)
+
+// LintHTML checks an HTML email body for common accessibility problems. It
+// never fails; markup it cannot make sense of is simply not reported on.
+// Tables with a <th> or <caption> hold data, so only the others need
+// role="presentation".
+func LintHTML(body string) []Finding {
+	var findings []Finding
+	add := func(rule LintRule, format string, args ...any) {
+		findings = append(findings, Finding{
+			Rule:     rule,
+			Severity: SEVERITY_WARNING,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Whether a table lays out the email or holds data is only known once
+	// its <th> or <caption> is seen, so the open tables are kept with where
+	// their finding goes, to report them in document order.
+	type openTable struct {
+		at           int
+		presentation bool
+		data         bool
+	}
+	var tables []openTable
+	closeTable := func() {
+		t := tables[len(tables)-1]
+		tables = tables[:len(tables)-1]
+		if t.presentation || t.data {
+			return
+		}
+		add(LINT_LAYOUT_TABLE_ROLE, "layout <table> has no role=\"presentation\"")
+		findings = slices.Insert(findings[:len(findings)-1], t.at, findings[len(findings)-1])
+	}
+
+	// Fragments, which most bodies are, have no <html> to carry a lang.
+	hasHTML, hasLang := false, false
+	inLink := false
+	var linkText strings.Builder
+
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if style, ok := attr(tok, "style"); ok {
+				for _, m := range fontSizeRegex.FindAllStringSubmatch(style, -1) {
+					size, err := strconv.ParseFloat(m[1], 64)
+					if err != nil {
+						continue
+					}
+					px := size
+					if strings.EqualFold(m[2], "pt") {
+						px = size * 4 / 3
+					}
+					if px < MinAccessibleFontSizePx {
+						add(LINT_FONT_SIZE_TOO_SMALL, "<%s> uses font-size %s%s, below %dpx", tok.Data, m[1], m[2], MinAccessibleFontSizePx)
+					}
+				}
+			}
+
+			switch tok.Data {
+			case "html":
+				hasHTML = true
+				if lang, ok := attr(tok, "lang"); ok && strings.TrimSpace(lang) != "" {
+					hasLang = true
+				}
+			case "img":
+				if _, ok := attr(tok, "alt"); !ok {
+					src, _ := attr(tok, "src")
+					add(LINT_IMG_MISSING_ALT, "<img src=%q> has no alt attribute", src)
+				}
+			case "table":
+				role, _ := attr(tok, "role")
+				tables = append(tables, openTable{at: len(findings), presentation: role == "presentation" || role == "none"})
+			case "th", "caption":
+				if len(tables) > 0 {
+					tables[len(tables)-1].data = true
+				}
+			case "a":
+				if tt == html.StartTagToken {
+					inLink = true
+					linkText.Reset()
+				}
+			}
+		case html.TextToken:
+			if inLink {
+				linkText.WriteString(tok.Data)
+			}
+		case html.EndTagToken:
+			if tok.Data == "table" && len(tables) > 0 {
+				closeTable()
+			}
+			if tok.Data == "a" && inLink {
+				inLink = false
+				text := strings.ToLower(strings.Join(strings.Fields(linkText.String()), " "))
+				text = strings.TrimRight(text, ".!:")
+				if nonDescriptiveLinkText[text] {
+					add(LINT_NON_DESCRIPTIVE_LINK, "link text %q does not describe its destination", text)
+				}
+			}
+		}
+	}
+
+	for len(tables) > 0 {
+		closeTable()
+	}
+	if hasHTML && !hasLang {
+		add(LINT_HTML_MISSING_LANG, "<html> has no lang attribute")
+	}
+
+	return findings
+}
+
+func attr(tok html.Token, key string) (string, bool) {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,118 @@
+package email
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLintHTML(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedRules []LintRule
+	}{
+		{
+			name: "accessible template",
+			body: readFixture(t, "testdata/lint/accessible.html"),
+		},
+		{
+			name: "inaccessible template",
+			body: readFixture(t, "testdata/lint/inaccessible.html"),
+			expectedRules: []LintRule{
+				LINT_LAYOUT_TABLE_ROLE,
+				LINT_IMG_MISSING_ALT,
+				LINT_NON_DESCRIPTIVE_LINK,
+				LINT_FONT_SIZE_TOO_SMALL,
+				LINT_HTML_MISSING_LANG,
+			},
+		},
+		{
+			name: "fragment without html element",
+			body: "<p>Hello</p>",
+		},
+		{
+			name:          "html element without lang",
+			body:          "<html><p>Hello</p></html>",
+			expectedRules: []LintRule{LINT_HTML_MISSING_LANG},
+		},
+		{
+			name:          "empty lang attribute",
+			body:          `<html lang=""><p>Hello</p></html>`,
+			expectedRules: []LintRule{LINT_HTML_MISSING_LANG},
+		},
+		{
+			name:          "self-closing img without alt",
+			body:          `<html lang="en"><img src="a.png"/></html>`,
+			expectedRules: []LintRule{LINT_IMG_MISSING_ALT},
+		},
+		{
+			name:          "small pixel font size",
+			body:          `<html lang="en"><span style="color:red;FONT-SIZE:10px">fine print</span></html>`,
+			expectedRules: []LintRule{LINT_FONT_SIZE_TOO_SMALL},
+		},
+		{
+			name: "font size at the threshold",
+			body: `<html lang="en"><span style="font-size: 12px">fine print</span><span style="font-size: 9pt">also fine</span></html>`,
+		},
+		{
+			name:          "link text with nested markup and whitespace",
+			body:          "<html lang=\"en\"><a href=\"https://icaa.org\"><b>Click</b>\n  HERE!</a></html>",
+			expectedRules: []LintRule{LINT_NON_DESCRIPTIVE_LINK},
+		},
+		{
+			name: "data tables",
+			body: `<html lang="en"><table><tr><th>Archer</th><th>Score</th></tr><tr><td>Ada</td><td>290</td></tr></table>` +
+				`<table><caption>Standings</caption><tr><td>Ada</td></tr></table></html>`,
+		},
+		{
+			name: "layout table with role none",
+			body: `<html lang="en"><table role="none"><tr><td>Hello</td></tr></table></html>`,
+		},
+		{
+			name:          "layout table around a data table",
+			body:          `<html lang="en"><table><tr><td><img src="logo.png"><table><tr><th>Score</th></tr></table></td></tr></table></html>`,
+			expectedRules: []LintRule{LINT_LAYOUT_TABLE_ROLE, LINT_IMG_MISSING_ALT},
+		},
+		{
+			name:          "unclosed layout table",
+			body:          `<html lang="en"><table><tr><td><a href="https://icaa.org">here</a>`,
+			expectedRules: []LintRule{LINT_LAYOUT_TABLE_ROLE, LINT_NON_DESCRIPTIVE_LINK},
+		},
+		{
+			name: "descriptive link text",
+			body: `<html lang="en"><a href="https://icaa.org">Here is the schedule</a></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := LintHTML(tt.body)
+
+			var rules []LintRule
+			for _, f := range findings {
+				if f.Severity != SEVERITY_WARNING {
+					t.Errorf("expected warning severity, got %s for %s", f.Severity, f.Rule)
+				}
+				if f.Message == "" {
+					t.Errorf("expected a message for %s", f.Rule)
+				}
+				rules = append(rules, f.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.expectedRules) {
+				t.Errorf("expected rules %v, got %v", tt.expectedRules, rules)
+			}
+		})
+	}
+}
+
+func readFixture(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", path, err)
+	}
+	return string(b)
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	ListIDHeader      = "List-Id"
+	ListArchiveHeader = "List-Archive"
+	ListPostHeader    = "List-Post"
+)
+
+// ListHeaders returns the List-Id, List-Archive and List-Post headers of
+// RFC 2919 and RFC 2369 for e, or nil if it has no ListID.
+func (e Email) ListHeaders() []Header {
+	if e.ListID == "" {
+		return nil
+	}
+
+	headers := []Header{{ListIDHeader, "<" + bareListID(e.ListID) + ">"}}
+	if e.ListArchiveURL != "" {
+		headers = append(headers, Header{ListArchiveHeader, "<" + e.ListArchiveURL + ">"})
+	}
+	if e.ListPostAddress != "" {
+		headers = append(headers, Header{ListPostHeader, "<" + mailtoURI(e.ListPostAddress) + ">"})
+	}
+	return headers
+}
+
+// ValidateList checks that e's ListID has the name.domain form, with or
+// without angle brackets, and that ListArchiveURL and ListPostAddress are
+// only set along with it.
+func ValidateList(e Email) error {
+	if e.ListID == "" {
+		if e.ListArchiveURL != "" || e.ListPostAddress != "" {
+			return NewValidationError("list archive and post headers require a ListID", nil)
+		}
+		return nil
+	}
+
+	id := bareListID(e.ListID)
+	labels := strings.Split(id, ".")
+	if len(id) > 255 || len(labels) < 2 || strings.ContainsFunc(id, func(r rune) bool { return r != '.' && !isAtext(r) }) {
+		return NewValidationError(fmt.Sprintf("list ID %q is not of the form name.domain", e.ListID), nil)
+	}
+	for _, label := range labels {
+		if label == "" {
+			return NewValidationError(fmt.Sprintf("list ID %q is not of the form name.domain", e.ListID), nil)
+		}
+	}
+
+	if e.ListArchiveURL != "" {
+		parsed, err := url.Parse(e.ListArchiveURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return NewValidationError(fmt.Sprintf("list archive URL %q is not an http or https URL", e.ListArchiveURL), err)
+		}
+		if strings.ContainsAny(e.ListArchiveURL, "<> \t\r\n") {
+			return NewValidationError(fmt.Sprintf("list archive URL %q must be percent-encoded", e.ListArchiveURL), nil)
+		}
+	}
+
+	if e.ListPostAddress != "" {
+		uri, err := url.Parse(mailtoURI(e.ListPostAddress))
+		if err != nil || uri.Opaque == "" || strings.ContainsAny(e.ListPostAddress, "<> \t\r\n") {
+			return NewValidationError(fmt.Sprintf("list post address %q is not an address or mailto URI", e.ListPostAddress), err)
+		}
+	}
+
+	return nil
+}
+
+func bareListID(id string) string {
+	if strings.HasPrefix(id, "<") && strings.HasSuffix(id, ">") {
+		return id[1 : len(id)-1]
+	}
+	return id
+}
+
+// isAtext reports whether r may appear in an RFC 5322 dot-atom.
+func isAtext(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    Email
+		expected []Header
+	}{
+		{"none", Email{}, nil},
+		{"brackets added", Email{ListID: "digest.icaa.example.com"}, []Header{{"List-Id", "<digest.icaa.example.com>"}}},
+		{"brackets kept", Email{ListID: "<digest.icaa.example.com>"}, []Header{{"List-Id", "<digest.icaa.example.com>"}}},
+		{"archive and post", Email{
+			ListID:          "digest.icaa.example.com",
+			ListArchiveURL:  "https://icaa.example.com/digest",
+			ListPostAddress: "digest@icaa.example.com",
+		}, []Header{
+			{"List-Id", "<digest.icaa.example.com>"},
+			{"List-Archive", "<https://icaa.example.com/digest>"},
+			{"List-Post", "<mailto:digest@icaa.example.com>"},
+		}},
+		{"post as mailto", Email{ListID: "digest.icaa.example.com", ListPostAddress: "mailto:digest@icaa.example.com"}, []Header{
+			{"List-Id", "<digest.icaa.example.com>"},
+			{"List-Post", "<mailto:digest@icaa.example.com>"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.email.ListHeaders(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateList(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   Email
+		wantErr bool
+	}{
+		{"none", Email{}, false},
+		{"bare", Email{ListID: "digest.icaa.example.com"}, false},
+		{"bracketed", Email{ListID: "<digest.icaa.example.com>"}, false},
+		{"atext", Email{ListID: "league_digest+weekly.icaa.example.com"}, false},
+		{"no namespace", Email{ListID: "digest"}, true},
+		{"empty label", Email{ListID: "digest..example.com"}, true},
+		{"leading dot", Email{ListID: ".digest.example.com"}, true},
+		{"space", Email{ListID: "league digest.example.com"}, true},
+		{"description", Email{ListID: "Weekly digest <digest.icaa.example.com>"}, true},
+		{"header injection", Email{ListID: "digest.example.com>\r\nBcc: x@example.com"}, true},
+		{"too long", Email{ListID: strings.Repeat("a", 250) + ".example.com"}, true},
+		{"archive without list", Email{ListArchiveURL: "https://icaa.example.com/digest"}, true},
+		{"post without list", Email{ListPostAddress: "digest@icaa.example.com"}, true},
+		{"archive not http", Email{ListID: "digest.example.com", ListArchiveURL: "ftp://example.com/digest"}, true},
+		{"archive not encoded", Email{ListID: "digest.example.com", ListArchiveURL: "https://example.com/league digest"}, true},
+		{"post not an address", Email{ListID: "digest.example.com", ListPostAddress: "digest@example.com>\r\nBcc: x@example.com"}, true},
+		{"everything", Email{ListID: "digest.example.com", ListArchiveURL: "https://example.com/digest", ListPostAddress: "digest@example.com"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateList(tt.email)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
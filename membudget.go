@@ -0,0 +1,164 @@
+package email
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// MemoryUsage is a snapshot of a MemoryBudget.
+type MemoryUsage struct {
+	Ceiling int64
+	InUse   int64
+	// Most bytes ever in use at once.
+	Peak int64
+	// Acquisitions blocked waiting for bytes to be released.
+	Waiting int
+}
+
+// MemoryBudget bounds the bytes buffered by concurrent sends, so many large
+// attachments in flight at once can't exhaust memory. Acquisitions are
+// granted in the order they were made: a large one at the front is not
+// starved by smaller ones behind it. It is safe for concurrent use and may
+// be shared by several senders.
+type MemoryBudget struct {
+	ceiling int64
+
+	mu      sync.Mutex
+	inUse   int64
+	peak    int64
+	waiters list.List
+}
+
+type budgetWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewMemoryBudget returns a MemoryBudget of ceiling bytes.
+func NewMemoryBudget(ceiling int64) *MemoryBudget {
+	return &MemoryBudget{ceiling: ceiling}
+}
+
+// Acquire blocks until n bytes are available or ctx is done, in which case
+// it returns the context's error and acquires nothing. Requests larger than
+// the ceiling acquire the whole budget, so they run alone instead of never.
+func (b *MemoryBudget) Acquire(ctx context.Context, n int64) error {
+	n = b.clamp(n)
+
+	b.mu.Lock()
+	if b.waiters.Len() == 0 && b.inUse+n <= b.ceiling {
+		b.take(n)
+		b.mu.Unlock()
+		return nil
+	}
+	w := &budgetWaiter{n: n, ready: make(chan struct{})}
+	elem := b.waiters.PushBack(w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted while giving up; hand the bytes on.
+			b.inUse -= n
+		default:
+			b.waiters.Remove(elem)
+		}
+		b.grant()
+		return ctx.Err()
+	}
+}
+
+// Release returns n bytes acquired with Acquire.
+func (b *MemoryBudget) Release(n int64) {
+	n = b.clamp(n)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inUse -= n
+	if b.inUse < 0 {
+		panic("email: MemoryBudget released more than was acquired")
+	}
+	b.grant()
+}
+
+func (b *MemoryBudget) Usage() MemoryUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return MemoryUsage{Ceiling: b.ceiling, InUse: b.inUse, Peak: b.peak, Waiting: b.waiters.Len()}
+}
+
+func (b *MemoryBudget) clamp(n int64) int64 {
+	return max(min(n, b.ceiling), 0)
+}
+
+func (b *MemoryBudget) take(n int64) {
+	b.inUse += n
+	b.peak = max(b.peak, b.inUse)
+}
+
+// grant wakes the waiters at the front that now fit, in order.
+func (b *MemoryBudget) grant() {
+	for {
+		front := b.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*budgetWaiter)
+		if b.inUse+w.n > b.ceiling {
+			return
+		}
+		b.take(w.n)
+		b.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+var _ Sender = &BudgetedSender{}
+var _ SenderV2 = &BudgetedSender{}
+
+// BudgetedSender decorates a SenderV2 to hold each email's estimated message
+// size, see EstimateSize, from a MemoryBudget while it is built and sent,
+// waiting for room when the budget is exhausted. Reader bodies are read
+// first, as their size is only known once read.
+type BudgetedSender struct {
+	inner  SenderV2
+	budget *MemoryBudget
+}
+
+func NewBudgetedSender(inner SenderV2, budget *MemoryBudget) *BudgetedSender {
+	return &BudgetedSender{inner: inner, budget: budget}
+}
+
+func (s *BudgetedSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *BudgetedSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *BudgetedSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	e, err = ReadBodies(e)
+	if err != nil {
+		return nil, err
+	}
+
+	n := int64(EstimateSize(e).Total)
+	if err := s.budget.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	defer s.budget.Release(n)
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
@@ -0,0 +1,203 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters polls until n acquisitions are blocked on b.
+func waitForWaiters(t *testing.T, b *MemoryBudget, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Usage().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, b.Usage().Waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryBudget_Order(t *testing.T) {
+	b := NewMemoryBudget(10)
+	ctx := context.Background()
+	if err := b.Acquire(ctx, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	granted := make(chan string, 2)
+	go func() {
+		if err := b.Acquire(ctx, 8); err == nil {
+			granted <- "large"
+		}
+	}()
+	waitForWaiters(t, b, 1)
+	go func() {
+		if err := b.Acquire(ctx, 2); err == nil {
+			granted <- "small"
+		}
+	}()
+	waitForWaiters(t, b, 2)
+
+	// Room for the small request, but not the large one ahead of it.
+	b.Release(4)
+	if usage := b.Usage(); usage.Waiting != 2 || usage.InUse != 6 {
+		t.Fatalf("expected both requests to keep waiting, got %+v", usage)
+	}
+
+	b.Release(4)
+	if first := <-granted; first != "large" {
+		t.Errorf("expected the large request first, got %s", first)
+	}
+	if usage := b.Usage(); usage.Waiting != 1 || usage.InUse != 10 {
+		t.Fatalf("expected the small request to wait for room, got %+v", usage)
+	}
+
+	b.Release(2)
+	if second := <-granted; second != "small" {
+		t.Errorf("expected the small request second, got %s", second)
+	}
+	if usage := b.Usage(); usage.InUse != 10 || usage.Peak != 10 || usage.Waiting != 0 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestMemoryBudget_Cancel(t *testing.T) {
+	b := NewMemoryBudget(10)
+	if err := b.Acquire(context.Background(), 6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() { canceled <- b.Acquire(ctx, 8) }()
+	waitForWaiters(t, b, 1)
+	granted := make(chan error)
+	go func() { granted <- b.Acquire(context.Background(), 4) }()
+	waitForWaiters(t, b, 2)
+
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	// The request behind the canceled one fits and goes ahead.
+	if err := <-granted; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if usage := b.Usage(); usage.InUse != 10 || usage.Waiting != 0 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestMemoryBudget_LargerThanCeiling(t *testing.T) {
+	b := NewMemoryBudget(10)
+
+	if err := b.Acquire(context.Background(), 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage := b.Usage(); usage.InUse != 10 {
+		t.Errorf("expected the whole budget to be acquired, got %+v", usage)
+	}
+	b.Release(100)
+	if usage := b.Usage(); usage.InUse != 0 {
+		t.Errorf("expected the budget to be released, got %+v", usage)
+	}
+}
+
+// budgetCheckingSender fails sends that find the budget over its ceiling.
+type budgetCheckingSender struct {
+	budget *MemoryBudget
+}
+
+func (s *budgetCheckingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	time.Sleep(time.Millisecond)
+	if usage := s.budget.Usage(); usage.InUse > usage.Ceiling {
+		return nil, NewUnknownError("budget exceeded", nil)
+	}
+	return &SendResult{}, nil
+}
+
+func TestBudgetedSender(t *testing.T) {
+	const ceiling = 3_000_000
+	budget := NewMemoryBudget(ceiling)
+	sender := NewBudgetedSender(&budgetCheckingSender{budget: budget}, budget)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- sender.SendEmail(context.Background(), Email{
+				FromAddress: "sender@example.com",
+				ToAddresses: []string{"recipient@example.com"},
+				Subject:     "Results",
+				TextBody:    "Attached",
+				Attachments: []Attachment{{
+					FileName:    "results.pdf",
+					Content:     bytes.Repeat([]byte{0xff}, 200_000*(i%5+1)),
+					ContentType: "application/pdf",
+				}},
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	usage := budget.Usage()
+	if usage.Peak > ceiling || usage.Peak == 0 {
+		t.Errorf("expected a peak within the ceiling, got %+v", usage)
+	}
+	if usage.InUse != 0 {
+		t.Errorf("expected every byte to be released, got %+v", usage)
+	}
+}
+
+// usageRecordingSender records the budget in use while it sends.
+type usageRecordingSender struct {
+	budget *MemoryBudget
+	inUse  int64
+	sent   Email
+}
+
+func (s *usageRecordingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	s.inUse = s.budget.Usage().InUse
+	s.sent = e
+	return &SendResult{}, nil
+}
+
+func TestBudgetedSender_ReaderBodies(t *testing.T) {
+	html := strings.Repeat("<p>Results</p>", 100_000)
+	budget := NewMemoryBudget(10_000_000)
+	inner := &usageRecordingSender{budget: budget}
+	sender := NewBudgetedSender(inner, budget)
+
+	err := sender.SendEmail(context.Background(), Email{
+		FromAddress:    "sender@example.com",
+		ToAddresses:    []string{"recipient@example.com"},
+		Subject:        "Results",
+		HTMLBodyReader: strings.NewReader(html),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if inner.inUse < int64(len(html)) {
+		t.Errorf("expected the streamed body to be held from the budget, got %d bytes for a %d byte body", inner.inUse, len(html))
+	}
+	if inner.sent.HTMLBody != html || inner.sent.HTMLBodyReader != nil {
+		t.Error("expected the inner sender to get the body that was read")
+	}
+	if usage := budget.Usage(); usage.InUse != 0 {
+		t.Errorf("expected every byte to be released, got %+v", usage)
+	}
+}
@@ -0,0 +1,59 @@
+package email
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Used by GenerateMessageID when there is no domain to generate for.
+const defaultMessageIDDomain = "localhost"
+
+// GenerateMessageID returns a new RFC 5322 message ID for domain in the form
+// <unique@domain>, with the unique part drawn from crypto/rand.
+// Internationalized domains are converted to their ASCII form.
+func GenerateMessageID(domain string) string {
+	domain, err := idna.Lookup.ToASCII(strings.TrimSpace(domain))
+	if err != nil || domain == "" {
+		domain = defaultMessageIDDomain
+	}
+	return "<" + strings.ToLower(rand.Text()) + "@" + domain + ">"
+}
+
+// EnsureMessageID returns e with a MessageID generated for the domain of its
+// From address, unless it already has one.
+func EnsureMessageID(e Email) Email {
+	if e.MessageID != "" {
+		return e
+	}
+	domain := ""
+	if from, err := mail.ParseAddress(e.FromAddress); err == nil {
+		domain = domainOf(from.Address)
+	}
+	e.MessageID = GenerateMessageID(domain)
+	return e
+}
+
+// ValidateMessageID checks that id is empty or an RFC 5322 msg-id: a
+// printable ASCII <left@right> without spaces.
+func ValidateMessageID(id string) error {
+	if id == "" {
+		return nil
+	}
+	if !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, ">") {
+		return NewValidationError(fmt.Sprintf("message ID %q must be enclosed in angle brackets", id), nil)
+	}
+	left, right, ok := strings.Cut(id[1:len(id)-1], "@")
+	if !ok || left == "" || right == "" || strings.Contains(right, "@") {
+		return NewValidationError(fmt.Sprintf("message ID %q must have the form <left@right>", id), nil)
+	}
+	for _, r := range id[1 : len(id)-1] {
+		if r <= ' ' || r > '~' || strings.ContainsRune(`<>()\",;:`, r) {
+			return NewValidationError(fmt.Sprintf("message ID %q contains an invalid character", id), nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMessageID(t *testing.T) {
+	tests := []struct {
+		domain string
+		suffix string
+	}{
+		{"icaa.example.com", "@icaa.example.com>"},
+		{" ICAA.example.com ", "@icaa.example.com>"},
+		{"münchen.de", "@xn--mnchen-3ya.de>"},
+		{"", "@localhost>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			id := GenerateMessageID(tt.domain)
+
+			if !strings.HasSuffix(id, tt.suffix) {
+				t.Errorf("expected a message ID ending in %s, got %s", tt.suffix, id)
+			}
+			if err := ValidateMessageID(id); err != nil {
+				t.Errorf("generated message ID is invalid: %v", err)
+			}
+		})
+	}
+
+	if GenerateMessageID("example.com") == GenerateMessageID("example.com") {
+		t.Error("expected unique message IDs")
+	}
+}
+
+func TestEnsureMessageID(t *testing.T) {
+	e := EnsureMessageID(Email{FromAddress: "Events <events@icaa.example.com>"})
+	if !strings.HasSuffix(e.MessageID, "@icaa.example.com>") {
+		t.Errorf("expected a message ID for the From domain, got %s", e.MessageID)
+	}
+
+	if again := EnsureMessageID(e); again.MessageID != e.MessageID {
+		t.Errorf("expected the message ID to be kept, got %s", again.MessageID)
+	}
+}
+
+func TestValidateMessageID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"", true},
+		{"<abc.123@icaa.example.com>", true},
+		{"<abc@[192.0.2.1]>", true},
+		{"abc@icaa.example.com", false},
+		{"<abc>", false},
+		{"<@icaa.example.com>", false},
+		{"<a@b@icaa.example.com>", false},
+		{"<a b@icaa.example.com>", false},
+		{"<abc@icaa.example.com>\r\nBcc: x@example.com", false},
+		{"<ä@icaa.example.com>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateMessageID(tt.id)
+			if tt.valid != (err == nil) {
+				t.Fatalf("expected valid=%v, got %v", tt.valid, err)
+			}
+			if err != nil && !errors.Is(err, ErrValidation) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
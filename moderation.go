@@ -0,0 +1,230 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type ModerationAction string
+
+const (
+	MODERATION_ALLOW  ModerationAction = "ALLOW"
+	MODERATION_REJECT ModerationAction = "REJECT"
+	// Send Decision.Email instead of the original.
+	MODERATION_MODIFY ModerationAction = "MODIFY"
+)
+
+type Decision struct {
+	Action ModerationAction
+	// Why the email was rejected, reported to the caller.
+	Reason string
+	// The email to send for MODERATION_MODIFY.
+	Email Email
+}
+
+// Moderator screens emails, e.g. ones containing user-generated content,
+// before they are sent.
+type Moderator interface {
+	Check(ctx context.Context, e Email) (Decision, error)
+}
+
+type ModerationTimeoutPolicy string
+
+const (
+	// Send the email unmoderated when the moderator times out.
+	MODERATION_FAIL_OPEN ModerationTimeoutPolicy = "FAIL_OPEN"
+	// Refuse to send the email when the moderator times out.
+	MODERATION_FAIL_CLOSED ModerationTimeoutPolicy = "FAIL_CLOSED"
+)
+
+// ModerationReasonMetadataKey is the Error.Metadata key holding the reason of
+// a MODERATION_REJECT decision.
+const ModerationReasonMetadataKey = "moderation_reason"
+
+var _ Sender = &ModerationSender{}
+var _ SenderV2 = &ModerationSender{}
+var _ Shutdowner = &ModerationSender{}
+
+// ModerationSender decorates a SenderV2 to have every email checked by a
+// Moderator first. Rejected emails fail with REASON_MESSAGE_REJECTED.
+// Modified emails are validated again by the wrapped sender, and may not
+// change the sender or recipients.
+type ModerationSender struct {
+	inner     SenderV2
+	moderator Moderator
+	timeout   time.Duration
+	onTimeout ModerationTimeoutPolicy
+
+	// Moderator calls still running, which outlive their send when the
+	// moderator ignores its context. idle is closed when the last returns.
+	mu      sync.Mutex
+	running int
+	idle    chan struct{}
+}
+
+type ModerationOption func(*ModerationSender)
+
+// WithModerationTimeout bounds how long the moderator may take, applying
+// policy when it runs out of time. Without it the moderator is only bound by
+// the context of the send.
+func WithModerationTimeout(timeout time.Duration, policy ModerationTimeoutPolicy) ModerationOption {
+	return func(s *ModerationSender) {
+		s.timeout = timeout
+		s.onTimeout = policy
+	}
+}
+
+func NewModerationSender(inner SenderV2, moderator Moderator, opts ...ModerationOption) *ModerationSender {
+	s := &ModerationSender{
+		inner:     inner,
+		moderator: moderator,
+		onTimeout: MODERATION_FAIL_CLOSED,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *ModerationSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *ModerationSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *ModerationSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	// Moderate the email as it will be sent, then hand that exact email on.
+	e, wrapped := opts.Forward(e)
+
+	decision, err := s.check(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch decision.Action {
+	case MODERATION_ALLOW:
+	case MODERATION_REJECT:
+		rejected := NewMessageRejectedError(fmt.Sprintf("rejected by moderation: %s", decision.Reason), nil)
+		rejected.Metadata = map[string]string{ModerationReasonMetadataKey: decision.Reason}
+		return nil, rejected
+	case MODERATION_MODIFY:
+		if err := checkModification(e, decision.Email); err != nil {
+			return nil, err
+		}
+		e = decision.Email
+	default:
+		return nil, NewUnknownError(fmt.Sprintf("unknown moderation action %q", decision.Action), nil)
+	}
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
+
+func (s *ModerationSender) check(ctx context.Context, e Email) (Decision, error) {
+	if s.timeout <= 0 {
+		decision, err := s.moderator.Check(ctx, e)
+		if err != nil {
+			return Decision{}, NewServiceError("moderation failed", err)
+		}
+		return decision, nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	type outcome struct {
+		decision Decision
+		err      error
+	}
+	done := make(chan outcome, 1)
+	s.started()
+	go func() {
+		decision, err := s.moderator.Check(checkCtx, e)
+		// Before replying, so a sender whose sends all returned is idle.
+		s.finished()
+		done <- outcome{decision, err}
+	}()
+
+	// Moderators that ignore their context still can't hold up the send.
+	var o outcome
+	select {
+	case o = <-done:
+	case <-checkCtx.Done():
+		o.err = checkCtx.Err()
+	}
+
+	timedOut := errors.Is(o.err, context.DeadlineExceeded) && ctx.Err() == nil
+	switch {
+	case timedOut && s.onTimeout == MODERATION_FAIL_OPEN:
+		return Decision{Action: MODERATION_ALLOW}, nil
+	case timedOut:
+		return Decision{}, NewServiceError(fmt.Sprintf("moderation timed out after %s", s.timeout), o.err)
+	case o.err != nil:
+		return Decision{}, NewServiceError("moderation failed", o.err)
+	}
+	return o.decision, nil
+}
+
+func (s *ModerationSender) started() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.running++
+}
+
+func (s *ModerationSender) finished() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	if s.running == 0 {
+		close(s.idle)
+	}
+}
+
+// Shutdown waits for moderator calls that outlived their send to return.
+func (s *ModerationSender) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	running, idle := s.running, s.idle
+	s.mu.Unlock()
+	if running == 0 {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return NewServiceError(fmt.Sprintf("%d moderation calls still running at shutdown", s.running), ctx.Err())
+	}
+}
+
+// checkModification makes sure a moderator only changed the content of an
+// email, not where it, its replies, bounces or receipts go.
+func checkModification(original, modified Email) error {
+	if modified.FromAddress != original.FromAddress ||
+		modified.SenderAddress != original.SenderAddress ||
+		!slices.Equal(modified.ToAddresses, original.ToAddresses) ||
+		!slices.Equal(modified.CCAddresses, original.CCAddresses) ||
+		!slices.Equal(modified.BCCAddresses, original.BCCAddresses) ||
+		!slices.Equal(modified.AlternateAddresses, original.AlternateAddresses) {
+		return NewValidationError("moderation may not change the sender or recipients", nil)
+	}
+	if !slices.Equal(modified.ReplyToAddresses, original.ReplyToAddresses) ||
+		modified.BounceAddress != original.BounceAddress ||
+		modified.ReadReceiptTo != original.ReadReceiptTo {
+		return NewValidationError("moderation may not change where replies, bounces or receipts go", nil)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type moderatorFunc func(ctx context.Context, e Email) (Decision, error)
+
+func (f moderatorFunc) Check(ctx context.Context, e Email) (Decision, error) {
+	return f(ctx, e)
+}
+
+func TestModerationSender_Decisions(t *testing.T) {
+	original := Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"team@example.com"},
+		Subject:     "New team",
+		TextBody:    "Team description: <user content>",
+	}
+	cleaned := original
+	cleaned.TextBody = "Team description: [removed]"
+	redirected := cleaned
+	redirected.ToAddresses = []string{"someone-else@example.com"}
+	modified := func(modify func(e *Email)) Decision {
+		e := cleaned
+		modify(&e)
+		return Decision{Action: MODERATION_MODIFY, Email: e}
+	}
+
+	tests := []struct {
+		name           string
+		decision       Decision
+		expectedReason ErrorReason
+		expectedSent   string
+	}{
+		{
+			name:         "allow",
+			decision:     Decision{Action: MODERATION_ALLOW},
+			expectedSent: original.TextBody,
+		},
+		{
+			name:           "reject",
+			decision:       Decision{Action: MODERATION_REJECT, Reason: "profanity"},
+			expectedReason: REASON_MESSAGE_REJECTED,
+		},
+		{
+			name:         "modify",
+			decision:     Decision{Action: MODERATION_MODIFY, Email: cleaned},
+			expectedSent: cleaned.TextBody,
+		},
+		{
+			name:           "modify recipients",
+			decision:       Decision{Action: MODERATION_MODIFY, Email: redirected},
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "modify reply-to",
+			decision:       modified(func(e *Email) { e.ReplyToAddresses = []string{"someone-else@example.com"} }),
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "modify bounce address",
+			decision:       modified(func(e *Email) { e.BounceAddress = "someone-else@example.com" }),
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "modify sender",
+			decision:       modified(func(e *Email) { e.SenderAddress = "someone-else@example.com" }),
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "modify read receipt address",
+			decision:       modified(func(e *Email) { e.ReadReceiptTo = "someone-else@example.com" }),
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "modify alternate addresses",
+			decision:       modified(func(e *Email) { e.AlternateAddresses = []string{"someone-else@example.com"} }),
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingSender{}
+			var checked Email
+			sender := NewModerationSender(AsSenderV2(inner), moderatorFunc(func(ctx context.Context, e Email) (Decision, error) {
+				checked = e
+				return tt.decision, nil
+			}))
+
+			_, err := sender.SendEmailV2(context.Background(), Email{}, &SendOptions{
+				Override: func(e *Email) { *e = original },
+			})
+
+			if checked.Subject != original.Subject {
+				t.Errorf("expected the moderator to see the overridden email, got %+v", checked)
+			}
+
+			if tt.expectedReason != "" {
+				var emailErr *Error
+				if !errors.As(err, &emailErr) || emailErr.Reason != tt.expectedReason {
+					t.Fatalf("expected %s, got %v", tt.expectedReason, err)
+				}
+				if tt.decision.Reason != "" && emailErr.Metadata[ModerationReasonMetadataKey] != tt.decision.Reason {
+					t.Errorf("expected the moderation reason in the metadata, got %v", emailErr.Metadata)
+				}
+				if len(inner.sent) != 0 {
+					t.Errorf("expected nothing to be sent, got %d emails", len(inner.sent))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(inner.sent) != 1 || inner.sent[0].TextBody != tt.expectedSent {
+				t.Errorf("expected %q to be sent, got %+v", tt.expectedSent, inner.sent)
+			}
+		})
+	}
+}
+
+func TestModerationSender_Timeout(t *testing.T) {
+	// Never answers, and ignores its context.
+	stuck := moderatorFunc(func(ctx context.Context, e Email) (Decision, error) {
+		select {}
+	})
+
+	tests := []struct {
+		name           string
+		policy         ModerationTimeoutPolicy
+		expectedReason ErrorReason
+	}{
+		{"fail open", MODERATION_FAIL_OPEN, ""},
+		{"fail closed", MODERATION_FAIL_CLOSED, REASON_SERVICE_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingSender{}
+			sender := NewModerationSender(AsSenderV2(inner), stuck, WithModerationTimeout(10*time.Millisecond, tt.policy))
+
+			err := sender.SendEmail(context.Background(), Email{Subject: "Hello"})
+
+			if tt.expectedReason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(inner.sent) != 1 {
+					t.Errorf("expected the email to be sent unmoderated, got %d emails", len(inner.sent))
+				}
+				return
+			}
+
+			var emailErr *Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != tt.expectedReason {
+				t.Fatalf("expected %s, got %v", tt.expectedReason, err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the timeout as cause, got %v", err)
+			}
+			if len(inner.sent) != 0 {
+				t.Errorf("expected nothing to be sent, got %d emails", len(inner.sent))
+			}
+		})
+	}
+}
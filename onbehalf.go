@@ -0,0 +1,81 @@
+package email
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// OnBehalfOf builds the From header value and Reply-To addresses for an
+// email a user sends through the system, e.g.
+// "Jane (via ICAA)" <noreply@icaa.org> with Reply-To Jane <jane@example.com>.
+// The From address is always the address of systemAddr, so the From domain
+// stays aligned with the domain the system authenticates for DMARC; only the
+// display name mentions the user. The system is named after the display name
+// of systemAddr, or its domain if it has none. Names are quoted or encoded as
+// RFC 5322 requires.
+//
+// Malformed addresses are used as given, to be reported by the provider's
+// validation.
+func OnBehalfOf(realName, realAddr, systemAddr string) (fromHeaderValue string, replyTo []string) {
+	system := parseOrRaw(systemAddr)
+	via := system.Name
+	if via == "" {
+		via = domainOf(system.Address)
+	}
+
+	realName = strings.TrimSpace(realName)
+	var real *mail.Address
+	if realAddr != "" {
+		real = parseOrRaw(realAddr)
+		if realName == "" {
+			realName = real.Name
+		}
+		if realName == "" {
+			realName, _, _ = strings.Cut(real.Address, "@")
+		}
+	}
+
+	name := realName
+	switch {
+	case name != "" && via != "":
+		name += " (via " + via + ")"
+	case name == "":
+		name = via
+	}
+
+	from := (&mail.Address{Name: name, Address: system.Address}).String()
+	if real == nil {
+		return from, nil
+	}
+	return from, []string{(&mail.Address{Name: realName, Address: real.Address}).String()}
+}
+
+// SetOnBehalfOf sets the From and Reply-To addresses of e as described by
+// OnBehalfOf, replacing any Reply-To addresses already set.
+func (e *Email) SetOnBehalfOf(realName, realAddr, systemAddr string) {
+	e.FromAddress, e.ReplyToAddresses = OnBehalfOf(realName, realAddr, systemAddr)
+}
+
+// SenderHeaderAddress returns the address of the Sender header of e:
+// SenderAddress, or empty if it is unset or the same mailbox as the From
+// address, which would make the header redundant.
+func (e Email) SenderHeaderAddress() string {
+	if e.SenderAddress == "" || indexAddress(e.SenderAddress) == indexAddress(e.FromAddress) {
+		return ""
+	}
+	return e.SenderAddress
+}
+
+func parseOrRaw(addr string) *mail.Address {
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		return parsed
+	}
+	return &mail.Address{Address: strings.TrimSpace(addr)}
+}
+
+func domainOf(addr string) string {
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package email
+
+import (
+	"net/mail"
+	"slices"
+	"testing"
+)
+
+func TestOnBehalfOf(t *testing.T) {
+	tests := []struct {
+		name        string
+		realName    string
+		realAddr    string
+		systemAddr  string
+		wantFrom    string
+		wantReplyTo []string
+	}{
+		{
+			name:        "plain name",
+			realName:    "Jane",
+			realAddr:    "jane@example.com",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    `"Jane (via ICAA)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"Jane" <jane@example.com>`},
+		},
+		{
+			name:        "comma",
+			realName:    "Doe, Jane",
+			realAddr:    "jane@example.com",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    `"Doe, Jane (via ICAA)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"Doe, Jane" <jane@example.com>`},
+		},
+		{
+			name:        "quotes",
+			realName:    `Jane "JJ" Doe`,
+			realAddr:    "jane@example.com",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    `"Jane \"JJ\" Doe (via ICAA)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"Jane \"JJ\" Doe" <jane@example.com>`},
+		},
+		{
+			name:        "non-ASCII",
+			realName:    "Zoë Ñúñez",
+			realAddr:    "zoe@example.com",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    "=?utf-8?b?Wm/DqyDDkcO6w7FleiAodmlhIElDQUEp?= <noreply@icaa.org>",
+			wantReplyTo: []string{"=?utf-8?q?Zo=C3=AB_=C3=91=C3=BA=C3=B1ez?= <zoe@example.com>"},
+		},
+		{
+			name:        "system without display name",
+			realName:    "Jane",
+			realAddr:    "jane@example.com",
+			systemAddr:  "noreply@icaa.org",
+			wantFrom:    `"Jane (via icaa.org)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"Jane" <jane@example.com>`},
+		},
+		{
+			name:        "name taken from real address",
+			realAddr:    "Jane Doe <jane@example.com>",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    `"Jane Doe (via ICAA)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"Jane Doe" <jane@example.com>`},
+		},
+		{
+			name:        "name falls back to local part",
+			realAddr:    "jane@example.com",
+			systemAddr:  "ICAA <noreply@icaa.org>",
+			wantFrom:    `"jane (via ICAA)" <noreply@icaa.org>`,
+			wantReplyTo: []string{`"jane" <jane@example.com>`},
+		},
+		{
+			name:       "no real address",
+			realName:   "Jane",
+			systemAddr: "ICAA <noreply@icaa.org>",
+			wantFrom:   `"Jane (via ICAA)" <noreply@icaa.org>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, replyTo := OnBehalfOf(tt.realName, tt.realAddr, tt.systemAddr)
+			if from != tt.wantFrom {
+				t.Errorf("expected From %s, got %s", tt.wantFrom, from)
+			}
+			if !slices.Equal(replyTo, tt.wantReplyTo) {
+				t.Errorf("expected Reply-To %v, got %v", tt.wantReplyTo, replyTo)
+			}
+		})
+	}
+}
+
+func TestOnBehalfOf_Alignment(t *testing.T) {
+	// Whatever the user is called or wherever they are, the From address is
+	// the system's and the name survives the round trip.
+	names := []string{"Jane", "Doe, Jane", `"Quoted"`, "Zoë <zoe@evil.example>", "a@b.c", "李雷", "Line\r\nBcc: x@evil.example", "(via Evil) "}
+	addrs := []string{"jane@example.com", "someone@evil.example", "not an address", ""}
+
+	for _, name := range names {
+		for _, addr := range addrs {
+			from, _ := OnBehalfOf(name, addr, "ICAA <noreply@icaa.org>")
+
+			parsed, err := mail.ParseAddress(from)
+			if err != nil {
+				t.Errorf("OnBehalfOf(%q, %q): From %q does not parse: %v", name, addr, from, err)
+				continue
+			}
+			if parsed.Address != "noreply@icaa.org" {
+				t.Errorf("OnBehalfOf(%q, %q): From address %q is not the system address", name, addr, parsed.Address)
+			}
+		}
+	}
+}
+
+func TestEmail_SetOnBehalfOf(t *testing.T) {
+	e := Email{ReplyToAddresses: []string{"old@example.com"}}
+	e.SetOnBehalfOf("Jane", "jane@example.com", "noreply@icaa.org")
+
+	if e.FromAddress != `"Jane (via icaa.org)" <noreply@icaa.org>` {
+		t.Errorf("unexpected From %s", e.FromAddress)
+	}
+	if !slices.Equal(e.ReplyToAddresses, []string{`"Jane" <jane@example.com>`}) {
+		t.Errorf("unexpected Reply-To %v", e.ReplyToAddresses)
+	}
+}
+
+func TestEmail_SenderHeaderAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		sender   string
+		expected string
+	}{
+		{name: "unset", from: "ICAA <info@icaa.org>", expected: ""},
+		{name: "distinct", from: "ICAA <info@icaa.org>", sender: "Jane <jane@icaa.org>", expected: "Jane <jane@icaa.org>"},
+		{name: "same as From", from: "ICAA <info@icaa.org>", sender: "info@icaa.org", expected: ""},
+		{name: "same as From in another case", from: "info@icaa.org", sender: "Info <INFO@ICAA.org>", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Email{FromAddress: tt.from, SenderAddress: tt.sender}
+			if got := e.SenderHeaderAddress(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
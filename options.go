@@ -0,0 +1,68 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OptionValue is one setting of a configured sender, named after the option
+// that sets it.
+type OptionValue struct {
+	Name  string
+	Value string
+}
+
+func (v OptionValue) String() string {
+	return v.Name + "=" + v.Value
+}
+
+// OptionDumper is implemented by senders that can describe their effective
+// configuration, defaults included, e.g. for logging it at startup. Secrets
+// are never included.
+type OptionDumper interface {
+	DumpOptions() []OptionValue
+}
+
+// OptionErrors collects the problems found in the options given to a
+// constructor, so they are reported together when the sender is created
+// instead of one at a time, or at send time. The zero value is ready to use.
+type OptionErrors struct {
+	errs  []error
+	given map[string]bool
+}
+
+// Once records that the option name was given, reporting a conflict if it
+// already was, since a second value would silently replace the first.
+func (o *OptionErrors) Once(name string) {
+	if o.given[name] {
+		o.Add("%s given more than once", name)
+		return
+	}
+	if o.given == nil {
+		o.given = map[string]bool{}
+	}
+	o.given[name] = true
+}
+
+// Given reports whether the option name was recorded with Once.
+func (o *OptionErrors) Given(name string) bool {
+	return o.given[name]
+}
+
+func (o *OptionErrors) Add(format string, args ...any) {
+	o.errs = append(o.errs, fmt.Errorf(format, args...))
+}
+
+// Err returns a REASON_VALIDATION_ERROR *Error listing every problem, or nil
+// if there are none.
+func (o *OptionErrors) Err() error {
+	if len(o.errs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(o.errs))
+	for i, err := range o.errs {
+		messages[i] = err.Error()
+	}
+	return NewValidationError("invalid options: "+strings.Join(messages, "; "), errors.Join(o.errs...))
+}
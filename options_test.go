@@ -0,0 +1,30 @@
+package email
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOptionErrors(t *testing.T) {
+	var o OptionErrors
+	if err := o.Err(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	o.Once("WithRate")
+	o.Once("WithBurst")
+	o.Once("WithRate")
+	o.Add("WithBurst: burst must be positive, got %d", 0)
+
+	err := o.Err()
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	want := "invalid options: WithRate given more than once; WithBurst: burst must be positive, got 0"
+	if got := err.(*Error).Message; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if !o.Given("WithBurst") || o.Given("WithJitter") {
+		t.Error("expected only the recorded options to be given")
+	}
+}
@@ -0,0 +1,48 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// Error.Metadata keys describing a panic recovered by RecoverSend.
+const (
+	PanicMetadataKey      = "panic"
+	PanicStackMetadataKey = "stack"
+)
+
+// Longest stack trace kept in a recovered panic error.
+const maxPanicStackBytes = 4096
+
+// RecoverSend turns a panic in a send into a REASON_UNKNOWN error carrying
+// the panic value and a trimmed stack trace, so one malformed email cannot
+// crash a whole batch. Senders defer it at the top of SendEmailV2, with err
+// being the named error result:
+//
+//	defer email.RecoverSend(ctx, e, opts, &err)
+//
+// SendHooks.OnPanic is called with the resulting error.
+func RecoverSend(ctx context.Context, e Email, opts *SendOptions, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	stack := debug.Stack()
+	if len(stack) > maxPanicStackBytes {
+		stack = stack[:maxPanicStackBytes]
+	}
+
+	cause, _ := v.(error)
+	panicErr := NewUnknownError(fmt.Sprintf("panic during send: %v", v), cause)
+	panicErr.Metadata = map[string]string{
+		PanicMetadataKey:      fmt.Sprint(v),
+		PanicStackMetadataKey: string(stack),
+	}
+
+	if opts != nil && opts.Hooks.OnPanic != nil {
+		opts.Hooks.OnPanic(ctx, e, panicErr)
+	}
+	*err = panicErr
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type panickingSender struct {
+	value any
+}
+
+func (s *panickingSender) SendEmail(ctx context.Context, e Email) error {
+	panic(s.value)
+}
+
+func TestRecoverSend(t *testing.T) {
+	cause := errors.New("nil template")
+
+	tests := []struct {
+		name      string
+		value     any
+		wantPanic string
+		wantCause error
+	}{
+		{name: "string", value: "index out of range", wantPanic: "index out of range"},
+		{name: "error", value: cause, wantPanic: "nil template", wantCause: cause},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hooked *Error
+			_, err := AsSenderV2(&panickingSender{value: tt.value}).SendEmailV2(context.Background(), Email{Subject: "Hi"}, &SendOptions{
+				Hooks: SendHooks{
+					OnPanic: func(ctx context.Context, e Email, err *Error) {
+						hooked = err
+					},
+				},
+			})
+
+			var emailErr *Error
+			if !errors.As(err, &emailErr) {
+				t.Fatalf("expected *Error, got %T: %v", err, err)
+			}
+			if emailErr.Reason != REASON_UNKNOWN {
+				t.Errorf("expected REASON_UNKNOWN, got %s", emailErr.Reason)
+			}
+			if got := emailErr.Metadata[PanicMetadataKey]; got != tt.wantPanic {
+				t.Errorf("expected panic value %q, got %q", tt.wantPanic, got)
+			}
+			stack := emailErr.Metadata[PanicStackMetadataKey]
+			if !strings.Contains(stack, "panickingSender") || len(stack) > maxPanicStackBytes {
+				t.Errorf("expected a trimmed stack through the sender, got %d bytes:\n%s", len(stack), stack)
+			}
+			if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Errorf("expected cause %v, got %v", tt.wantCause, err)
+			}
+			if hooked != emailErr {
+				t.Errorf("expected OnPanic to receive the returned error, got %v", hooked)
+			}
+		})
+	}
+}
+
+func TestRecoverSend_Decorators(t *testing.T) {
+	// Only the innermost guard sees the panic, so the hook runs once.
+	calls := 0
+	sender := NewEventSender(AsSenderV2(&panickingSender{value: "boom"}))
+
+	_, err := sender.SendEmailV2(context.Background(), Email{}, &SendOptions{
+		Hooks: SendHooks{
+			OnPanic: func(ctx context.Context, e Email, err *Error) { calls++ },
+		},
+	})
+
+	var emailErr *Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != REASON_UNKNOWN {
+		t.Errorf("expected REASON_UNKNOWN, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected OnPanic to be called once, got %d", calls)
+	}
+}
@@ -0,0 +1,208 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Recipient is one recipient of SendPersonalized, with the values of the
+// placeholders in the email sent to them.
+type Recipient struct {
+	Address string
+	Vars    map[string]string
+}
+
+// placeholderPattern matches {{name}} placeholders, spaces inside the braces
+// allowed.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Personalize returns a copy of base addressed to r alone, with the
+// {{name}} placeholders in its subject and bodies replaced by r's Vars.
+// Values are HTML-escaped in the HTML body. A placeholder without a value is
+// a validation error, so no one receives a literal "{{name}}". Reader bodies
+// must have been read with ReadBodies first.
+func Personalize(base Email, r Recipient) (Email, error) {
+	e := addressedTo(base, r.Address)
+
+	var err error
+	if e.Subject, err = expandPlaceholders(e.Subject, r.Vars, nil); err != nil {
+		return Email{}, err
+	}
+	if e.TextBody, err = expandPlaceholders(e.TextBody, r.Vars, nil); err != nil {
+		return Email{}, err
+	}
+	if e.HTMLBody, err = expandPlaceholders(e.HTMLBody, r.Vars, html.EscapeString); err != nil {
+		return Email{}, err
+	}
+	return e, nil
+}
+
+func addressedTo(e Email, addr string) Email {
+	e.ToAddresses = []string{addr}
+	e.CCAddresses = nil
+	e.BCCAddresses = nil
+	// Each copy is a message of its own, so it gets its own Message-ID.
+	if e.MessageID != "" {
+		e.MessageID = ""
+		e = EnsureMessageID(e)
+	}
+	return e
+}
+
+func expandPlaceholders(s string, vars map[string]string, escape func(string) string) (string, error) {
+	var missing []string
+	s = placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return placeholder
+		}
+		if escape != nil {
+			value = escape(value)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", NewValidationError(fmt.Sprintf("no value for placeholders %s", strings.Join(missing, ", ")), nil)
+	}
+	return s, nil
+}
+
+// RecipientError is the failure to send to one recipient of
+// SendPersonalized or SendWithVERP.
+type RecipientError struct {
+	Address string
+	Err     error
+}
+
+func (e *RecipientError) Error() string {
+	return e.Address + ": " + e.Err.Error()
+}
+
+func (e *RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// PersonalizedSendError is returned by SendPersonalized and SendWithVERP when
+// sending to some recipients failed. The others were sent to.
+type PersonalizedSendError struct {
+	// In recipient order.
+	Errors     []*RecipientError
+	Recipients int
+}
+
+func (e *PersonalizedSendError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to send to %d of %d recipients: %s", len(e.Errors), e.Recipients, strings.Join(messages, "; "))
+}
+
+func (e *PersonalizedSendError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+type PersonalizeOption func(*personalizeConfig)
+
+type personalizeConfig struct {
+	concurrency int
+}
+
+// WithPersonalizeConcurrency sends to up to n recipients at once. Recipients
+// are sent to one at a time by default.
+func WithPersonalizeConcurrency(n int) PersonalizeOption {
+	return func(c *personalizeConfig) {
+		c.concurrency = n
+	}
+}
+
+// SendPersonalized sends base to each recipient as a separate email,
+// rendered with Personalize, e.g. for a mail merge. The base's own recipients
+// are ignored. It returns one SendResult per recipient, in order, the zero
+// value for those that failed, and a *PersonalizedSendError if any did. Once
+// ctx is done the remaining recipients fail with its error.
+func SendPersonalized(ctx context.Context, sender Sender, base Email, recipients []Recipient, opts ...PersonalizeOption) ([]SendResult, error) {
+	cfg := personalizeConfig{concurrency: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.concurrency = max(cfg.concurrency, 1)
+
+	// Readers can only be read once, not once per recipient.
+	base, err := ReadBodies(base)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, len(recipients))
+	for i, r := range recipients {
+		addresses[i] = r.Address
+	}
+	return sendEach(ctx, AsSenderV2(sender), addresses, cfg.concurrency, func(i int) (Email, error) {
+		return Personalize(base, recipients[i])
+	})
+}
+
+// sendEach sends the email render returns for each recipient, up to
+// concurrency at once, for SendPersonalized and SendWithVERP.
+func sendEach(ctx context.Context, sender SenderV2, recipients []string, concurrency int, render func(i int) (Email, error)) ([]SendResult, error) {
+	results := make([]SendResult, len(recipients))
+	errs := make([]error, len(recipients))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range recipients {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			e, err := render(i)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			result, err := sender.SendEmailV2(ctx, e, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if result != nil {
+				results[i] = *result
+			}
+		}()
+	}
+	wg.Wait()
+
+	sendErr := &PersonalizedSendError{Recipients: len(recipients)}
+	for i, err := range errs {
+		if err != nil {
+			sendErr.Errors = append(sendErr.Errors, &RecipientError{Address: recipients[i], Err: err})
+		}
+	}
+	if len(sendErr.Errors) > 0 {
+		return results, sendErr
+	}
+	return results, nil
+}
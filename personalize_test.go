@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPersonalize(t *testing.T) {
+	base := Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  []string{"template@example.com"},
+		CCAddresses:  []string{"board@example.com"},
+		Subject:      "Welcome to {{team}}, {{ name }}",
+		TextBody:     "Hi {{name}}, you play for {{team}}.",
+		HTMLBody:     "<p>Hi {{name}}, you play for {{team}}.</p>",
+		BCCAddresses: []string{"archive@example.com"},
+	}
+
+	t.Run("replaces placeholders", func(t *testing.T) {
+		got, err := Personalize(base, Recipient{
+			Address: "ada@example.com",
+			Vars:    map[string]string{"name": "Ada", "team": "Arrows & Bows"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got.Subject != "Welcome to Arrows & Bows, Ada" {
+			t.Errorf("unexpected subject %q", got.Subject)
+		}
+		if got.TextBody != "Hi Ada, you play for Arrows & Bows." {
+			t.Errorf("unexpected text body %q", got.TextBody)
+		}
+		if got.HTMLBody != "<p>Hi Ada, you play for Arrows &amp; Bows.</p>" {
+			t.Errorf("unexpected HTML body %q", got.HTMLBody)
+		}
+		if len(got.ToAddresses) != 1 || got.ToAddresses[0] != "ada@example.com" || got.CCAddresses != nil || got.BCCAddresses != nil {
+			t.Errorf("expected the email to be addressed to the recipient alone, got %v %v %v", got.ToAddresses, got.CCAddresses, got.BCCAddresses)
+		}
+		if base.Subject != "Welcome to {{team}}, {{ name }}" {
+			t.Error("expected the base email to be unchanged")
+		}
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		_, err := Personalize(base, Recipient{Address: "ada@example.com", Vars: map[string]string{"name": "Ada"}})
+
+		var emailErr *Error
+		if !errors.As(err, &emailErr) || emailErr.Reason != REASON_VALIDATION_ERROR {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+	})
+}
+
+// concurrentSender records the most sends it saw in flight at once.
+type concurrentSender struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+
+	mu   sync.Mutex
+	sent map[string]Email
+}
+
+func (s *concurrentSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent[e.ToAddresses[0]] = e
+	if e.ToAddresses[0] == "rejected@example.com" {
+		return nil, NewInvalidEmailError("address rejected", nil)
+	}
+	return &SendResult{ProviderMessageID: "id-" + e.ToAddresses[0]}, nil
+}
+
+func TestSendPersonalized(t *testing.T) {
+	base := Email{FromAddress: "league@example.com", Subject: "Hi {{name}}", TextBody: "Hello {{name}}"}
+	recipients := []Recipient{
+		{Address: "ada@example.com", Vars: map[string]string{"name": "Ada"}},
+		{Address: "rejected@example.com", Vars: map[string]string{"name": "Rex"}},
+		{Address: "grace@example.com", Vars: map[string]string{"name": "Grace"}},
+		{Address: "nameless@example.com"},
+		{Address: "alan@example.com", Vars: map[string]string{"name": "Alan"}},
+	}
+
+	for _, concurrency := range []int{1, 2} {
+		sender := &concurrentSender{sent: map[string]Email{}}
+
+		results, err := SendPersonalized(context.Background(), AsSender(sender), base, recipients, WithPersonalizeConcurrency(concurrency))
+
+		var sendErr *PersonalizedSendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected a *PersonalizedSendError, got %v", err)
+		}
+		if len(sendErr.Errors) != 2 || sendErr.Errors[0].Address != "rejected@example.com" || sendErr.Errors[1].Address != "nameless@example.com" {
+			t.Errorf("unexpected recipient errors %v", sendErr.Errors)
+		}
+		var emailErr *Error
+		if !errors.As(err, &emailErr) || emailErr.Reason != REASON_INVALID_EMAIL {
+			t.Errorf("expected the first recipient's *Error to be reachable, got %v", emailErr)
+		}
+
+		for i, r := range recipients {
+			want := "id-" + r.Address
+			if r.Address == "rejected@example.com" || r.Address == "nameless@example.com" {
+				want = ""
+			}
+			if results[i].ProviderMessageID != want {
+				t.Errorf("result %d: expected message ID %q, got %q", i, want, results[i].ProviderMessageID)
+			}
+		}
+		if got := sender.sent["grace@example.com"].Subject; got != "Hi Grace" {
+			t.Errorf("unexpected subject %q", got)
+		}
+		if _, ok := sender.sent["nameless@example.com"]; ok {
+			t.Error("expected the recipient without values not to be sent to")
+		}
+		if peak := sender.peak.Load(); peak > int32(concurrency) {
+			t.Errorf("concurrency %d: %d sends in flight at once", concurrency, peak)
+		}
+	}
+}
+
+// messageIDSender reports the Message-ID of each email it is given, as
+// providers do.
+type messageIDSender struct{}
+
+func (messageIDSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	return &SendResult{MessageID: e.MessageID}, nil
+}
+
+func TestSendPersonalized_MessageID(t *testing.T) {
+	base := Email{FromAddress: "league@example.com", MessageID: "<base@example.com>", Subject: "Hi", TextBody: "Hello"}
+	recipients := []Recipient{{Address: "ada@example.com"}, {Address: "grace@example.com"}, {Address: "alan@example.com"}}
+
+	results, err := SendPersonalized(context.Background(), AsSender(messageIDSender{}), base, recipients)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := map[string]bool{base.MessageID: true}
+	for i, result := range results {
+		if result.MessageID == "" || seen[result.MessageID] {
+			t.Errorf("result %d: expected a Message-ID of its own, got %q", i, result.MessageID)
+		}
+		seen[result.MessageID] = true
+	}
+}
+
+func TestSendPersonalized_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := SendPersonalized(ctx, &recordingSender{}, Email{Subject: "Hi"}, []Recipient{{Address: "ada@example.com"}})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package email
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+type PreflightRule string
+
+const (
+	PREFLIGHT_NO_TEXT_BODY        PreflightRule = "NO_TEXT_BODY"
+	PREFLIGHT_TEXT_HTML_RATIO     PreflightRule = "TEXT_HTML_RATIO"
+	PREFLIGHT_IMAGE_HEAVY         PreflightRule = "IMAGE_HEAVY"
+	PREFLIGHT_MISSING_UNSUBSCRIBE PreflightRule = "MISSING_UNSUBSCRIBE"
+	PREFLIGHT_SUBJECT_ALL_CAPS    PreflightRule = "SUBJECT_ALL_CAPS"
+	PREFLIGHT_SUBJECT_PUNCTUATION PreflightRule = "SUBJECT_PUNCTUATION"
+	PREFLIGHT_SUBJECT_SPAM_PHRASE PreflightRule = "SUBJECT_SPAM_PHRASE"
+	PREFLIGHT_SUBJECT_TOO_LONG    PreflightRule = "SUBJECT_TOO_LONG"
+	PREFLIGHT_TOO_MANY_LINKS      PreflightRule = "TOO_MANY_LINKS"
+	PREFLIGHT_BARE_IP_LINK        PreflightRule = "BARE_IP_LINK"
+	PREFLIGHT_HTML_TOO_LARGE      PreflightRule = "HTML_TOO_LARGE"
+	PREFLIGHT_IMG_MISSING_ALT     PreflightRule = "IMG_MISSING_ALT"
+	PREFLIGHT_REPLY_TO_MISALIGNED PreflightRule = "REPLY_TO_MISALIGNED"
+)
+
+const (
+	// Gmail clips HTML bodies larger than this.
+	MaxPreflightHTMLBytes = 102 * 1024
+	MaxPreflightLinks     = 25
+	MaxPreflightSubject   = 78
+	// Fewer visible words than this per image reads as an image-only email.
+	MinPreflightWordsPerImage = 50
+)
+
+var subjectSpamPhrases = []string{
+	"100% free",
+	"act now",
+	"click here",
+	"free money",
+	"guaranteed",
+	"no cost",
+	"risk free",
+	"winner",
+}
+
+type PreflightFinding struct {
+	Rule     PreflightRule
+	Severity Severity
+	Message  string
+	// What to change to resolve the finding.
+	Advice string
+}
+
+// Report is the result of PreflightCheck. Score starts at 100 and loses 5
+// points per warning and 20 per error, down to 0.
+type Report struct {
+	Score    int
+	Findings []PreflightFinding
+}
+
+// PreflightCheck statically scores e for deliverability problems that
+// commonly land mail in spam, as a stand-in for running it through an
+// external spam checker. It never makes network calls.
+func PreflightCheck(e Email) Report {
+	var report Report
+	add := func(rule PreflightRule, severity Severity, advice, format string, args ...any) {
+		report.Findings = append(report.Findings, PreflightFinding{
+			Rule:     rule,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+			Advice:   advice,
+		})
+	}
+
+	checkSubject(e.Subject, add)
+
+	body := scanHTML(e.HTMLBody)
+
+	if e.HTMLBody != "" {
+		if e.TextBody == "" {
+			add(PREFLIGHT_NO_TEXT_BODY, SEVERITY_WARNING,
+				"Add a TextBody alongside the HTML.",
+				"email has an HTML body but no text alternative")
+		} else if words := len(strings.Fields(e.TextBody)); words*3 < body.words {
+			add(PREFLIGHT_TEXT_HTML_RATIO, SEVERITY_WARNING,
+				"Make the TextBody carry the same content as the HTML.",
+				"text body has %d words but the HTML shows %d", words, body.words)
+		}
+
+		if len(e.HTMLBody) > MaxPreflightHTMLBytes {
+			add(PREFLIGHT_HTML_TOO_LARGE, SEVERITY_WARNING,
+				"Trim inline styles and markup; Gmail clips larger bodies.",
+				"HTML body is %d bytes, over %d", len(e.HTMLBody), MaxPreflightHTMLBytes)
+		}
+
+		if body.images > 0 && body.words < body.images*MinPreflightWordsPerImage {
+			add(PREFLIGHT_IMAGE_HEAVY, SEVERITY_WARNING,
+				"Add more text or use fewer images.",
+				"%d images for %d words of text", body.images, body.words)
+		}
+
+		for _, f := range LintHTML(e.HTMLBody) {
+			if f.Rule == LINT_IMG_MISSING_ALT {
+				add(PREFLIGHT_IMG_MISSING_ALT, SEVERITY_WARNING,
+					"Describe every image with an alt attribute.",
+					"%s", f.Message)
+			}
+		}
+	}
+
+	links := body.links
+	for _, field := range strings.Fields(e.TextBody) {
+		if strings.HasPrefix(field, "http://") || strings.HasPrefix(field, "https://") {
+			links = append(links, field)
+		}
+	}
+
+	if len(links) > MaxPreflightLinks {
+		add(PREFLIGHT_TOO_MANY_LINKS, SEVERITY_WARNING,
+			"Link to a single landing page instead.",
+			"email has %d links, over %d", len(links), MaxPreflightLinks)
+	}
+
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		if net.ParseIP(u.Hostname()) != nil {
+			add(PREFLIGHT_BARE_IP_LINK, SEVERITY_ERROR,
+				"Link to a domain name instead of an IP address.",
+				"link %q points at a bare IP address", link)
+		}
+	}
+
+	if !body.unsubscribe && !strings.Contains(strings.ToLower(e.TextBody), "unsubscribe") && e.Unsubscribe.Headers() == nil {
+		add(PREFLIGHT_MISSING_UNSUBSCRIBE, SEVERITY_WARNING,
+			"Include an unsubscribe link for bulk mail.",
+			"email has no unsubscribe link")
+	}
+
+	fromDomain := addressDomain(e.FromAddress)
+	for _, addr := range e.ReplyToAddresses {
+		if domain := addressDomain(addr); domain != "" && fromDomain != "" && domain != fromDomain {
+			add(PREFLIGHT_REPLY_TO_MISALIGNED, SEVERITY_WARNING,
+				"Reply from the sending domain, or expect the mismatch to be scored as phishing.",
+				"Reply-To %s is not on the From domain %s", addr, fromDomain)
+		}
+	}
+
+	report.Score = 100
+	for _, f := range report.Findings {
+		if f.Severity == SEVERITY_ERROR {
+			report.Score -= 20
+		} else {
+			report.Score -= 5
+		}
+	}
+	report.Score = max(report.Score, 0)
+
+	return report
+}
+
+func checkSubject(subject string, add func(PreflightRule, Severity, string, string, ...any)) {
+	letters, upper := 0, 0
+	for _, r := range subject {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 4 && upper == letters {
+		add(PREFLIGHT_SUBJECT_ALL_CAPS, SEVERITY_WARNING,
+			"Use sentence case.",
+			"subject is written in capitals")
+	}
+
+	if strings.Contains(subject, "!!") || strings.Contains(subject, "??") || strings.Contains(subject, "$$") {
+		add(PREFLIGHT_SUBJECT_PUNCTUATION, SEVERITY_WARNING,
+			"Drop the repeated punctuation.",
+			"subject has repeated punctuation")
+	}
+
+	lower := strings.ToLower(subject)
+	for _, phrase := range subjectSpamPhrases {
+		if strings.Contains(lower, phrase) {
+			add(PREFLIGHT_SUBJECT_SPAM_PHRASE, SEVERITY_WARNING,
+				"Reword the subject.",
+				"subject contains %q", phrase)
+		}
+	}
+
+	if n := len([]rune(subject)); n > MaxPreflightSubject {
+		add(PREFLIGHT_SUBJECT_TOO_LONG, SEVERITY_WARNING,
+			"Shorten the subject; clients truncate it.",
+			"subject is %d characters, over %d", n, MaxPreflightSubject)
+	}
+}
+
+type htmlSummary struct {
+	words       int
+	images      int
+	links       []string
+	unsubscribe bool
+}
+
+func scanHTML(body string) htmlSummary {
+	var s htmlSummary
+	skip := 0
+
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tok.Data {
+			case "style", "script":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case "img":
+				s.images++
+			case "a":
+				if href, ok := attr(tok, "href"); ok {
+					s.links = append(s.links, href)
+					if strings.Contains(strings.ToLower(href), "unsubscribe") {
+						s.unsubscribe = true
+					}
+				}
+			}
+		case html.EndTagToken:
+			if (tok.Data == "style" || tok.Data == "script") && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				s.words += len(strings.Fields(tok.Data))
+				if strings.Contains(strings.ToLower(tok.Data), "unsubscribe") {
+					s.unsubscribe = true
+				}
+			}
+		}
+	}
+
+	return s
+}
+
+func addressDomain(addr string) string {
+	addr = strings.TrimSuffix(strings.TrimSpace(addr), ">")
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(addr[at+1:])
+}
@@ -0,0 +1,99 @@
+package email
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreflightCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         Email
+		expectedRules []PreflightRule
+		expectedScore int
+	}{
+		{
+			name: "known-good campaign",
+			email: Email{
+				FromAddress:      "events@icaa.org",
+				ReplyToAddresses: []string{"Tournament Desk <tournaments@icaa.org>"},
+				Subject:          "Spring tournament registration is open",
+				HTMLBody:         readFixture(t, "testdata/preflight/good.html"),
+				TextBody: "Registration for the spring tournament is now open to every member club. " +
+					"Club captains should submit their rosters before the end of the month. " +
+					"Register at https://icaa.org/register or unsubscribe at https://icaa.org/unsubscribe",
+			},
+			expectedScore: 100,
+		},
+		{
+			name: "known-bad campaign",
+			email: Email{
+				FromAddress:      "events@icaa.org",
+				ReplyToAddresses: []string{"claims@prize-desk.example"},
+				Subject:          "WINNER!! CLAIM NOW",
+				HTMLBody:         readFixture(t, "testdata/preflight/bad.html"),
+			},
+			expectedRules: []PreflightRule{
+				PREFLIGHT_SUBJECT_ALL_CAPS,
+				PREFLIGHT_SUBJECT_PUNCTUATION,
+				PREFLIGHT_SUBJECT_SPAM_PHRASE,
+				PREFLIGHT_NO_TEXT_BODY,
+				PREFLIGHT_IMAGE_HEAVY,
+				PREFLIGHT_IMG_MISSING_ALT,
+				PREFLIGHT_BARE_IP_LINK,
+				PREFLIGHT_MISSING_UNSUBSCRIBE,
+				PREFLIGHT_REPLY_TO_MISALIGNED,
+			},
+			expectedScore: 40,
+		},
+		{
+			name: "thin text alternative and oversized html",
+			email: Email{
+				FromAddress: "events@icaa.org",
+				Subject:     "Results",
+				HTMLBody:    "<p>" + strings.Repeat("result ", MaxPreflightHTMLBytes/7+1) + "unsubscribe</p>",
+				TextBody:    "See the website.",
+			},
+			expectedRules: []PreflightRule{
+				PREFLIGHT_TEXT_HTML_RATIO,
+				PREFLIGHT_HTML_TOO_LARGE,
+			},
+			expectedScore: 90,
+		},
+		{
+			name: "text only with too many links and long subject",
+			email: Email{
+				FromAddress: "events@icaa.org",
+				Subject:     strings.Repeat("a", MaxPreflightSubject+1),
+				TextBody:    strings.Repeat("https://icaa.org/x ", MaxPreflightLinks+1) + "unsubscribe",
+			},
+			expectedRules: []PreflightRule{
+				PREFLIGHT_SUBJECT_TOO_LONG,
+				PREFLIGHT_TOO_MANY_LINKS,
+			},
+			expectedScore: 90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := PreflightCheck(tt.email)
+
+			var rules []PreflightRule
+			for _, f := range report.Findings {
+				if f.Message == "" || f.Advice == "" {
+					t.Errorf("expected a message and advice for %s", f.Rule)
+				}
+				rules = append(rules, f.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.expectedRules) {
+				t.Errorf("expected rules %v, got %v", tt.expectedRules, rules)
+			}
+			if report.Score != tt.expectedScore {
+				t.Errorf("expected score %d, got %d", tt.expectedScore, report.Score)
+			}
+		})
+	}
+}
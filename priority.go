@@ -0,0 +1,32 @@
+package email
+
+import "fmt"
+
+// Priority marks how urgent an email is to clients such as Outlook and
+// Gmail. The zero value sends no priority headers.
+type Priority string
+
+const (
+	PRIORITY_HIGH   Priority = "HIGH"
+	PRIORITY_NORMAL Priority = "NORMAL"
+	PRIORITY_LOW    Priority = "LOW"
+)
+
+var priorityHeaders = map[Priority][]Header{
+	PRIORITY_HIGH:   {{"X-Priority", "1 (Highest)"}, {"X-MSMail-Priority", "High"}, {"Importance", "high"}},
+	PRIORITY_NORMAL: {{"X-Priority", "3 (Normal)"}, {"X-MSMail-Priority", "Normal"}, {"Importance", "normal"}},
+	PRIORITY_LOW:    {{"X-Priority", "5 (Lowest)"}, {"X-MSMail-Priority", "Low"}, {"Importance", "low"}},
+}
+
+// Headers returns the X-Priority, X-MSMail-Priority and Importance headers
+// for p, or nil if p is unset or unknown.
+func (p Priority) Headers() []Header {
+	return priorityHeaders[p]
+}
+
+func ValidatePriority(p Priority) error {
+	if _, ok := priorityHeaders[p]; p != "" && !ok {
+		return NewValidationError(fmt.Sprintf("unknown priority %q", p), nil)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package providersdk
+
+import (
+	"context"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// SendSplit sends e with send, split by email.SplitRecipients into messages
+// of at most maxPerMessage recipients, one after the other. An email that
+// fits is sent as is. The first message keeps e's Message-ID, and the others
+// each get a new one, reported in the results of SendResult.Chunks.
+//
+// If some messages fail it returns the result of those sent along with a
+// *email.ChunkedSendError; once ctx is done the remaining messages fail with
+// its error. If all fail only the error is returned.
+func SendSplit(ctx context.Context, e email.Email, maxPerMessage int, send func(ctx context.Context, e email.Email) (*email.SendResult, error)) (*email.SendResult, error) {
+	chunks := email.SplitRecipients(e, maxPerMessage)
+	if len(chunks) == 1 {
+		return send(ctx, chunks[0])
+	}
+
+	results := make([]email.SendResult, len(chunks))
+	sendErr := &email.ChunkedSendError{Chunks: len(chunks)}
+	first := -1
+	for i, chunk := range chunks {
+		if i > 0 {
+			// Message-IDs must be unique, and receivers dedupe on them.
+			chunk.MessageID = ""
+			chunk = email.EnsureMessageID(chunk)
+		}
+		err := ctx.Err()
+		if err == nil {
+			var result *email.SendResult
+			if result, err = send(ctx, chunk); err == nil && result != nil {
+				results[i] = *result
+			}
+		}
+		if err != nil {
+			sendErr.Errors = append(sendErr.Errors, email.NewChunkError(i, chunk, err))
+		} else if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return nil, sendErr
+	}
+
+	result := results[first]
+	result.Chunks = results
+	if len(sendErr.Errors) > 0 {
+		return &result, sendErr
+	}
+	return &result, nil
+}
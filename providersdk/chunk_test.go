@@ -0,0 +1,129 @@
+package providersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+func TestSendSplit(t *testing.T) {
+	league := make([]string, 5)
+	for i := range league {
+		league[i] = fmt.Sprintf("archer%d@example.com", i)
+	}
+	e := email.Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  []string{"captains@example.com"},
+		BCCAddresses: league,
+		Subject:      "Season opener",
+		TextBody:     "See you there",
+	}
+	rejected := email.NewMessageRejectedError("rejected", nil)
+
+	// send fails the chunks sent to the listed recipients.
+	send := func(sent *[]email.Email, failFor ...string) func(ctx context.Context, e email.Email) (*email.SendResult, error) {
+		return func(ctx context.Context, e email.Email) (*email.SendResult, error) {
+			*sent = append(*sent, e)
+			for _, addr := range append(e.ToAddresses, e.BCCAddresses...) {
+				for _, fail := range failFor {
+					if addr == fail {
+						return nil, rejected
+					}
+				}
+			}
+			return &email.SendResult{ProviderMessageID: fmt.Sprintf("id-%d", len(*sent))}, nil
+		}
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		var sent []email.Email
+		result, err := SendSplit(context.Background(), e, 6, send(&sent))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sent) != 1 || result.ProviderMessageID != "id-1" || result.Chunks != nil {
+			t.Errorf("expected a single send, got %d sends and %+v", len(sent), result)
+		}
+	})
+
+	t.Run("split", func(t *testing.T) {
+		var sent []email.Email
+		result, err := SendSplit(context.Background(), e, 2, send(&sent))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sent) != 3 {
+			t.Fatalf("expected 3 sends, got %d", len(sent))
+		}
+		if result.ProviderMessageID != "id-1" || len(result.Chunks) != 3 || result.Chunks[2].ProviderMessageID != "id-3" {
+			t.Errorf("expected the first result with every chunk's, got %+v", result)
+		}
+	})
+
+	t.Run("message IDs", func(t *testing.T) {
+		e := e
+		e.MessageID = "<opener@example.com>"
+		var sent []email.Email
+		if _, err := SendSplit(context.Background(), e, 2, send(&sent)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if sent[0].MessageID != e.MessageID {
+			t.Errorf("expected the first chunk to keep %s, got %s", e.MessageID, sent[0].MessageID)
+		}
+		seen := map[string]bool{}
+		for i, chunk := range sent {
+			if chunk.MessageID == "" || seen[chunk.MessageID] {
+				t.Errorf("chunk %d: expected a unique Message-ID, got %q", i, chunk.MessageID)
+			}
+			seen[chunk.MessageID] = true
+		}
+	})
+
+	t.Run("some chunks fail", func(t *testing.T) {
+		var sent []email.Email
+		result, err := SendSplit(context.Background(), e, 2, send(&sent, "captains@example.com", "archer4@example.com"))
+
+		var chunkedErr *email.ChunkedSendError
+		if !errors.As(err, &chunkedErr) || chunkedErr.Chunks != 3 || len(chunkedErr.Errors) != 2 {
+			t.Fatalf("expected 2 of 3 chunks to fail, got %v", err)
+		}
+		if chunkedErr.Errors[0].Index != 0 || chunkedErr.Errors[1].Index != 2 {
+			t.Errorf("expected chunks 0 and 2 to fail, got %v", chunkedErr)
+		}
+		if !errors.Is(err, rejected) {
+			t.Errorf("expected the chunk errors to be unwrapped, got %v", err)
+		}
+		if result == nil || result.ProviderMessageID != "id-2" || result.Chunks[0].ProviderMessageID != "" {
+			t.Errorf("expected the result of the chunk sent, got %+v", result)
+		}
+	})
+
+	t.Run("every chunk fails", func(t *testing.T) {
+		var sent []email.Email
+		result, err := SendSplit(context.Background(), e, 3, send(&sent, "captains@example.com", "archer4@example.com"))
+		if result != nil || !errors.Is(err, rejected) {
+			t.Errorf("expected only an error, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var sent []email.Email
+		_, err := SendSplit(ctx, e, 2, func(ctx context.Context, e email.Email) (*email.SendResult, error) {
+			cancel()
+			return send(&sent)(ctx, e)
+		})
+
+		var chunkedErr *email.ChunkedSendError
+		if !errors.As(err, &chunkedErr) || len(chunkedErr.Errors) != 2 || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the remaining chunks to fail with the context's error, got %v", err)
+		}
+		if len(sent) != 1 {
+			t.Errorf("expected 1 send, got %d", len(sent))
+		}
+	})
+}
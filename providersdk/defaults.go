@@ -0,0 +1,50 @@
+package providersdk
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// Defaults fill in fields an email leaves empty, so call sites don't all
+// repeat the same From and Reply-To addresses. Providers apply them before
+// validating the email, whose checks the defaults must pass on their own
+// when the provider is constructed.
+type Defaults struct {
+	From    string
+	ReplyTo []string
+	// Added unless the email has a header of the same name.
+	Headers       []email.Header
+	AutoSubmitted email.AutoSubmitted
+}
+
+// DefaultHeaders turns a map of header names to values into Defaults.Headers,
+// sorted by name so messages come out the same every time.
+func DefaultHeaders(headers map[string]string) []email.Header {
+	var out []email.Header
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		out = append(out, email.Header{Name: name, Value: headers[name]})
+	}
+	return out
+}
+
+// Apply returns e with the defaults filled in. Fields e sets always win.
+func (d Defaults) Apply(e email.Email) email.Email {
+	if e.FromAddress == "" {
+		e.FromAddress = d.From
+	}
+	if len(e.ReplyToAddresses) == 0 && len(d.ReplyTo) > 0 {
+		e.ReplyToAddresses = slices.Clone(d.ReplyTo)
+	}
+	for _, h := range d.Headers {
+		if !slices.ContainsFunc(e.Headers, func(set email.Header) bool { return strings.EqualFold(set.Name, h.Name) }) {
+			e.Headers = append(slices.Clip(e.Headers), h)
+		}
+	}
+	if e.AutoSubmitted == "" {
+		e.AutoSubmitted = d.AutoSubmitted
+	}
+	return e
+}
@@ -0,0 +1,90 @@
+package providersdk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+func TestDefaultHeaders(t *testing.T) {
+	got := DefaultHeaders(map[string]string{"X-Team": "events", "X-Build": "42"})
+	want := []email.Header{{Name: "X-Build", Value: "42"}, {Name: "X-Team", Value: "events"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := DefaultHeaders(nil); got != nil {
+		t.Errorf("expected no headers, got %v", got)
+	}
+}
+
+func TestDefaults_Apply(t *testing.T) {
+	d := Defaults{
+		From:          "events@icaa.example.com",
+		ReplyTo:       []string{"help@icaa.example.com"},
+		Headers:       DefaultHeaders(map[string]string{"X-Team": "events", "X-Build": "42"}),
+		AutoSubmitted: email.AUTO_SUBMITTED_GENERATED,
+	}
+
+	tests := []struct {
+		name     string
+		email    email.Email
+		expected email.Email
+	}{
+		{
+			name:  "empty fields are filled in",
+			email: email.Email{Subject: "Results"},
+			expected: email.Email{
+				FromAddress:      "events@icaa.example.com",
+				ReplyToAddresses: []string{"help@icaa.example.com"},
+				Subject:          "Results",
+				Headers:          []email.Header{{Name: "X-Build", Value: "42"}, {Name: "X-Team", Value: "events"}},
+				AutoSubmitted:    email.AUTO_SUBMITTED_GENERATED,
+			},
+		},
+		{
+			name: "explicit values win",
+			email: email.Email{
+				FromAddress:      "board@icaa.example.com",
+				ReplyToAddresses: []string{"chair@icaa.example.com"},
+				Headers:          []email.Header{{Name: "x-team", Value: "board"}},
+				AutoSubmitted:    email.AUTO_SUBMITTED_REPLIED,
+			},
+			expected: email.Email{
+				FromAddress:      "board@icaa.example.com",
+				ReplyToAddresses: []string{"chair@icaa.example.com"},
+				Headers:          []email.Header{{Name: "x-team", Value: "board"}, {Name: "X-Build", Value: "42"}},
+				AutoSubmitted:    email.AUTO_SUBMITTED_REPLIED,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Apply(tt.email); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("zero value changes nothing", func(t *testing.T) {
+		e := email.Email{Subject: "Results"}
+		if got := (Defaults{}).Apply(e); !reflect.DeepEqual(got, e) {
+			t.Errorf("expected %+v, got %+v", e, got)
+		}
+	})
+
+	t.Run("does not alias", func(t *testing.T) {
+		headers := make([]email.Header, 1, 4)
+		headers[0] = email.Header{Name: "X-Other", Value: "1"}
+		got := d.Apply(email.Email{Headers: headers})
+
+		got.ReplyToAddresses[0] = "changed@example.com"
+		if d.ReplyTo[0] != "help@icaa.example.com" {
+			t.Error("expected the default Reply-To not to share the email's slice")
+		}
+		if extended := headers[:2]; extended[1].Name != "" {
+			t.Errorf("expected the caller's header array untouched, got %v", extended)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package providersdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// MapHTTPStatus maps a failed HTTP response from a provider API to the
+// ErrorReason callers branch on. body is the response body or the error
+// message extracted from it, and is used to tell apart failures that share a
+// status code.
+func MapHTTPStatus(code int, body string) email.ErrorReason {
+	body = strings.ToLower(body)
+
+	switch {
+	case code == 400:
+		if strings.Contains(body, "invalid") &&
+			(strings.Contains(body, "recipient") || strings.Contains(body, "email") || strings.Contains(body, "address")) {
+			return email.REASON_INVALID_EMAIL
+		}
+		return email.REASON_VALIDATION_ERROR
+
+	case code == 401:
+		return email.REASON_VALIDATION_ERROR
+
+	case code == 413:
+		return email.REASON_MESSAGE_TOO_LARGE
+
+	case code == 403:
+		if strings.Contains(body, "blocked") &&
+			!strings.Contains(body, "scope") && !strings.Contains(body, "permission") && !strings.Contains(body, "domain") {
+			return email.REASON_MESSAGE_REJECTED
+		}
+		return email.REASON_UNVERIFIED_DOMAIN
+
+	case code == 429:
+		return email.REASON_RATE_LIMITED
+
+	default:
+		return email.REASON_SERVICE_ERROR
+	}
+}
+
+// MapTransportError maps an error that happened before a provider API
+// answered, such as a timeout or a dropped connection.
+func MapTransportError(err error) email.ErrorReason {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return email.REASON_SERVICE_ERROR
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return email.REASON_SERVICE_ERROR
+	}
+
+	msg := strings.ToLower(err.Error())
+	if (strings.Contains(msg, "context") && strings.Contains(msg, "deadline")) ||
+		strings.Contains(msg, "connection") || strings.Contains(msg, "network") {
+		return email.REASON_SERVICE_ERROR
+	}
+
+	return email.REASON_UNKNOWN
+}
+
+// ParseRetryAfter interprets a Retry-After header, given in seconds or as an
+// HTTP date, relative to now. It returns 0 when the header is absent or
+// invalid.
+func ParseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,82 @@
+package providersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+func TestMapHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code     int
+		body     string
+		expected email.ErrorReason
+	}{
+		{400, "Invalid recipient email address", email.REASON_INVALID_EMAIL},
+		{400, "Malformed message content", email.REASON_VALIDATION_ERROR},
+		{401, "Authentication failed", email.REASON_VALIDATION_ERROR},
+		{403, "Insufficient permissions to send email", email.REASON_UNVERIFIED_DOMAIN},
+		{403, "Sender blocked by recipient", email.REASON_MESSAGE_REJECTED},
+		{403, "Sender blocked by domain policy", email.REASON_UNVERIFIED_DOMAIN},
+		{413, "", email.REASON_MESSAGE_TOO_LARGE},
+		{429, "Quota exceeded", email.REASON_RATE_LIMITED},
+		{500, "", email.REASON_SERVICE_ERROR},
+		{503, "", email.REASON_SERVICE_ERROR},
+		{418, "I'm a teapot", email.REASON_SERVICE_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.code, tt.body), func(t *testing.T) {
+			if got := MapHTTPStatus(tt.code, tt.body); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMapTransportError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected email.ErrorReason
+	}{
+		{"deadline", fmt.Errorf("send: %w", context.DeadlineExceeded), email.REASON_SERVICE_ERROR},
+		{"network", errors.New("network connection failed"), email.REASON_SERVICE_ERROR},
+		{"other", errors.New("something odd"), email.REASON_UNKNOWN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapTransportError(tt.err); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"Sat, 01 Mar 2025 12:00:30 GMT", 30 * time.Second},
+		{"Sat, 01 Mar 2025 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseRetryAfter(tt.header, now); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package providersdk
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"mime"
+	"strings"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// BuildOptions tunes the message produced by BuildMessage.
+type BuildOptions struct {
+	// Force7Bit guarantees the message only contains 7-bit bytes, for relays
+	// that corrupt 8-bit content. Bodies are sent quoted-printable, display
+	// names and parameters are RFC 2047/2231 encoded and domains are
+	// converted to punycode. Other non-ASCII header values, such as URLs,
+	// are rejected.
+	Force7Bit bool
+	// Only used with Force7Bit. Defaults to UNREPRESENTABLE_REJECT.
+	Unrepresentable UnrepresentablePolicy
+	// OmitBcc leaves out the Bcc header, for providers whose API takes the
+	// BCC recipients in a separate envelope field. Providers that learn them
+	// from the message must remove the header before delivery instead.
+	OmitBcc bool
+}
+
+type builder struct {
+	opts    BuildOptions
+	charset email.Charset
+}
+
+// BuildMessage renders e as an RFC 5322 message, for providers whose API
+// accepts raw MIME. Reader bodies are consumed, see email.ReadBodies.
+func BuildMessage(e email.Email, opts BuildOptions) ([]byte, error) {
+	b := &builder{opts: opts}
+
+	e, err := email.ReadBodies(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, err = b.transcode(e); err != nil {
+		return nil, err
+	}
+
+	headers, err := b.headers(e)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := b.root(e)
+	if err != nil {
+		return nil, err
+	}
+
+	rootHeaders, body := root.render()
+	headers = append(headers, rootHeaders...)
+
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body), nil
+}
+
+// transcode converts the subject and bodies of e into its charset.
+func (b *builder) transcode(e email.Email) (email.Email, error) {
+	c, err := email.LookupCharset(e.Charset)
+	if err != nil {
+		return email.Email{}, err
+	}
+	b.charset = c
+
+	for _, s := range []*string{&e.Subject, &e.TextBody, &e.HTMLBody} {
+		if *s, err = c.Encode(*s); err != nil {
+			return email.Email{}, err
+		}
+	}
+	return e, nil
+}
+
+// encodeSubject RFC 2047 encodes a subject already transcoded into the
+// charset. Legacy charsets use the B encoding, as their readers expect for
+// e.g. ISO-2022-JP (RFC 1468).
+func (b *builder) encodeSubject(subject string) string {
+	if b.charset.IsUTF8() {
+		return mime.QEncoding.Encode(b.charset.Name, subject)
+	}
+	return mime.BEncoding.Encode(b.charset.Name, subject)
+}
+
+func (b *builder) headers(e email.Email) ([]string, error) {
+	from, err := b.formatAddressList([]string{e.FromAddress})
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := b.formatAddressList(e.ToAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{
+		fmt.Sprintf("From: %s", from),
+		fmt.Sprintf("To: %s", to),
+		fmt.Sprintf("Subject: %s", b.encodeSubject(e.Subject)),
+		"MIME-Version: 1.0",
+	}
+
+	bcc := e.BCCAddresses
+	if b.opts.OmitBcc {
+		bcc = nil
+	}
+	optionalAddressHeaders := []struct {
+		name  string
+		addrs []string
+	}{
+		{"Sender", nonEmpty(e.SenderHeaderAddress())},
+		{"Cc", e.CCAddresses},
+		{"Bcc", bcc},
+		{"Reply-To", e.ReplyToAddresses},
+		{email.DispositionNotificationToHeader, nonEmpty(e.ReadReceiptAddress())},
+		{email.ReturnReceiptToHeader, nonEmpty(e.ReadReceiptAddress())},
+	}
+	for _, h := range optionalAddressHeaders {
+		if len(h.addrs) == 0 {
+			continue
+		}
+		value, err := b.formatAddressList(h.addrs)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, fmt.Sprintf("%s: %s", h.name, value))
+	}
+
+	for _, h := range email.FieldHeaders(e) {
+		header, err := b.fieldHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+
+	for _, h := range e.Headers {
+		headers = append(headers, h.String())
+	}
+
+	return headers, nil
+}
+
+// root builds the MIME tree of e: its bodies, wrapped in multipart/mixed
+// together with the attachments if there are any. Inline attachments are
+// grouped with the HTML body in multipart/related.
+func (b *builder) root(e email.Email) (*entity, error) {
+	inline, regular := splitAttachments(e)
+
+	html := b.textEntity("text/html", e.HTMLBody)
+	if len(inline) > 0 {
+		parts := []*entity{html}
+		for _, a := range inline {
+			part, err := attachmentEntity(a, true)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		}
+		html = b.multipart("multipart/related", parts...)
+	}
+
+	var body *entity
+	switch {
+	case e.HTMLBody != "" && e.TextBody != "":
+		body = b.multipart("multipart/alternative", b.textEntity("text/plain", e.TextBody), html)
+	case e.HTMLBody != "":
+		body = html
+	default:
+		body = b.textEntity("text/plain", e.TextBody)
+	}
+
+	if len(regular) == 0 {
+		return body, nil
+	}
+
+	parts := []*entity{body}
+	for _, a := range regular {
+		part, err := attachmentEntity(a, false)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+
+	return b.multipart("multipart/mixed", parts...), nil
+}
+
+// splitAttachments separates the attachments shown inline in the HTML body
+// from regular ones. Without an HTML body there is nothing to show them in,
+// so they are sent as regular attachments.
+func splitAttachments(e email.Email) (inline, regular []email.Attachment) {
+	for _, a := range e.Attachments {
+		if a.ContentID != "" && e.HTMLBody != "" {
+			inline = append(inline, a)
+		} else {
+			regular = append(regular, a)
+		}
+	}
+	return inline, regular
+}
+
+func (b *builder) textEntity(mediaType, text string) *entity {
+	encoding, body := b.encodeBody(text)
+	return &entity{
+		mediaType: mediaType,
+		params:    map[string]string{"charset": b.charset.Name},
+		encoding:  encoding,
+		body:      body,
+	}
+}
+
+func (b *builder) multipart(mediaType string, parts ...*entity) *entity {
+	encoding := "8bit"
+	if b.opts.Force7Bit {
+		encoding = "7bit"
+	}
+	return &entity{mediaType: mediaType, encoding: encoding, parts: parts}
+}
+
+func attachmentEntity(a email.Attachment, inline bool) (*entity, error) {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, email.NewValidationError(fmt.Sprintf("invalid content type for attachment %s: %s", a.FileName, a.ContentType), err)
+	}
+	params["name"] = a.FileName
+
+	part := &entity{
+		mediaType:   mediaType,
+		params:      params,
+		encoding:    "base64",
+		disposition: mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}),
+		body:        wrapLines(base64.StdEncoding.EncodeToString(a.Content), 76),
+	}
+	if inline {
+		part.disposition = mime.FormatMediaType("inline", map[string]string{"filename": a.FileName})
+		part.contentID = a.ContentID
+	}
+	return part, nil
+}
+
+// entity is a node of a MIME tree. An entity renders its Content-Type header
+// and its body in one step, so the declared structure always matches the
+// parts that are actually written.
+type entity struct {
+	mediaType   string
+	params      map[string]string
+	encoding    string
+	disposition string
+	contentID   string
+	// Encoded content of a leaf entity.
+	body string
+	// Children of a multipart entity.
+	parts []*entity
+}
+
+func (e *entity) render() ([]string, string) {
+	params := maps.Clone(e.params)
+	body := e.body
+
+	if len(e.parts) > 0 {
+		rendered := make([]string, len(e.parts))
+		for i, p := range e.parts {
+			headers, body := p.render()
+			rendered[i] = strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+		}
+
+		boundary := boundaryFor(rendered)
+		if params == nil {
+			params = map[string]string{}
+		}
+		params["boundary"] = boundary
+
+		var sb strings.Builder
+		for _, r := range rendered {
+			sb.WriteString("--" + boundary + "\r\n")
+			sb.WriteString(r)
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("--" + boundary + "--")
+		body = sb.String()
+	}
+
+	headers := []string{fmt.Sprintf("Content-Type: %s", mime.FormatMediaType(e.mediaType, params))}
+	if e.encoding != "" {
+		headers = append(headers, fmt.Sprintf("Content-Transfer-Encoding: %s", e.encoding))
+	}
+	if e.disposition != "" {
+		headers = append(headers, fmt.Sprintf("Content-Disposition: %s", e.disposition))
+	}
+	if e.contentID != "" {
+		headers = append(headers, fmt.Sprintf("Content-ID: <%s>", e.contentID))
+	}
+
+	return headers, body
+}
+
+// boundaryFor derives a boundary from the parts it separates. It keeps output
+// deterministic while making a collision with the content itself
+// practically impossible.
+func boundaryFor(parts []string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return "b_" + hex.EncodeToString(h.Sum(nil))[:32]
+}
@@ -0,0 +1,370 @@
+package providersdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// TestBuildMessage_StructureMatchesContent generates random combinations of
+// bodies and attachments and checks that the structure every message declares
+// is the structure it actually has.
+func TestBuildMessage_StructureMatchesContent(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	samples := []string{
+		"Hello World",
+		"Grüße aus München",
+		"line one\nline two\n",
+		"--b_0123456789abcdef\r\nnot a boundary",
+		strings.Repeat("long line ", 200),
+	}
+	pick := func() string { return samples[rng.IntN(len(samples))] }
+
+	for i := range 500 {
+		e := email.Email{
+			FromAddress: "sender@example.com",
+			ToAddresses: []string{"recipient@example.com"},
+			Subject:     "Property",
+		}
+
+		switch rng.IntN(3) {
+		case 0:
+			e.TextBody = pick()
+		case 1:
+			e.HTMLBody = "<p>" + pick() + "</p>"
+		default:
+			e.TextBody = pick()
+			e.HTMLBody = "<p>" + pick() + "</p>"
+		}
+
+		for j := range rng.IntN(4) {
+			a := email.Attachment{
+				FileName:    fmt.Sprintf("file-%d.bin", j),
+				Content:     []byte(pick()),
+				ContentType: "application/octet-stream",
+			}
+			if rng.IntN(2) == 0 {
+				a.FileName = fmt.Sprintf("image-%d.png", j)
+				a.ContentType = "image/png"
+				a.ContentID = fmt.Sprintf("image-%d@example.com", j)
+			}
+			e.Attachments = append(e.Attachments, a)
+		}
+
+		opts := BuildOptions{Force7Bit: rng.IntN(2) == 0}
+
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			raw, err := BuildMessage(e, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := VerifyMessage(raw, e); err != nil {
+				t.Fatalf("message does not verify: %v", err)
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("headers do not parse: %v", err)
+			}
+
+			got := shape(t, msg.Header.Get("Content-Type"), msg.Body)
+			if want := expectedShape(e); got != want {
+				t.Errorf("declared structure %s, expected %s", got, want)
+			}
+		})
+	}
+}
+
+// shape describes the MIME tree of a part as declared by its headers, e.g.
+// "multipart/mixed[text/plain,application/octet-stream]".
+func shape(t *testing.T, contentType string, body io.Reader) string {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("invalid Content-Type %q: %v", contentType, err)
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return mediaType
+	}
+
+	var children []string
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid %s body: %v", mediaType, err)
+		}
+		children = append(children, shape(t, part.Header.Get("Content-Type"), part))
+	}
+
+	return mediaType + "[" + strings.Join(children, ",") + "]"
+}
+
+func expectedShape(e email.Email) string {
+	inline, regular := splitAttachments(e)
+
+	html := "text/html"
+	if len(inline) > 0 {
+		parts := []string{html}
+		for _, a := range inline {
+			parts = append(parts, a.ContentType)
+		}
+		html = "multipart/related[" + strings.Join(parts, ",") + "]"
+	}
+
+	body := "text/plain"
+	switch {
+	case e.TextBody != "" && e.HTMLBody != "":
+		body = "multipart/alternative[text/plain," + html + "]"
+	case e.HTMLBody != "":
+		body = html
+	}
+
+	if len(regular) == 0 {
+		return body
+	}
+
+	parts := []string{body}
+	for _, a := range regular {
+		parts = append(parts, a.ContentType)
+	}
+	return "multipart/mixed[" + strings.Join(parts, ",") + "]"
+}
+
+func TestEstimateSize_MatchesBuilder(t *testing.T) {
+	attachment := func(name string, size int) email.Attachment {
+		return email.Attachment{FileName: name, Content: bytes.Repeat([]byte{0xff}, size), ContentType: "application/pdf"}
+	}
+
+	tests := []struct {
+		name  string
+		email email.Email
+	}{
+		{"text", email.Email{TextBody: "Hello World"}},
+		{"html", email.Email{HTMLBody: "<p>Grüße</p>"}},
+		{"alternative", email.Email{TextBody: "Hello", HTMLBody: "<p>Hello</p>", CCAddresses: []string{"cc@example.com"}}},
+		{"attachments", email.Email{
+			TextBody:    "Hello",
+			HTMLBody:    "<p>Hello</p>",
+			Attachments: []email.Attachment{attachment("a.pdf", 0), attachment("b.pdf", 57), attachment("ä b.pdf", 100_000)},
+		}},
+		{"inline", email.Email{
+			TextBody: "Hello",
+			HTMLBody: `<p>Hello <img src="cid:logo@example.com"></p>`,
+			Attachments: []email.Attachment{
+				{FileName: "logo.png", Content: bytes.Repeat([]byte{0xff}, 5_000), ContentType: "image/png", ContentID: "logo@example.com"},
+				attachment("a.pdf", 1_000),
+			},
+		}},
+		{"campaign", email.Email{TextBody: "Hello", CampaignID: "spring", SequenceStep: 3, ReplyToAddresses: []string{"r@example.com"}}},
+		{"fallback", email.Email{TextBody: "Hello", FallbackFor: "old@example.com"}},
+		{"sender", email.Email{TextBody: "Hello", SenderAddress: "Turnierleitung Grüße <director@example.com>"}},
+		{"bounce", email.Email{TextBody: "Hello", BounceAddress: "Bounces <bounces@example.com>"}},
+		{"read receipt", email.Email{TextBody: "Hello", ReadReceiptTo: "Vorstand Grüße <board@example.com>"}},
+		{"message id", email.Email{TextBody: "Hello", MessageID: "<abc@example.com>"}},
+		{"date", email.Email{TextBody: "Hello", Date: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)}},
+		{"tags", email.Email{TextBody: "Hello", Tags: map[string]string{"env": "prod", "team": "events"}}},
+		{"priority", email.Email{TextBody: "Hello", Priority: email.PRIORITY_LOW}},
+		{"list", email.Email{TextBody: "Hello", ListID: "digest.example.com", ListArchiveURL: "https://example.com/digest", ListPostAddress: "digest@example.com"}},
+		{"auto-submitted", email.Email{TextBody: "Hello", AutoSubmitted: email.AUTO_SUBMITTED_REPLIED, SuppressAutoResponses: true}},
+		{"headers", email.Email{TextBody: "Hello", Headers: []email.Header{{Name: "X-Build", Value: strings.Repeat("Grüße ", 40)}}}},
+		{"unsubscribe", email.Email{TextBody: "Hello", Unsubscribe: email.Unsubscribe{URL: "https://example.com/u", Mailto: "u@example.com", OneClick: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.email
+			e.FromAddress = "Sender <sender@example.com>"
+			e.ToAddresses = []string{"a@example.com", "b@example.com"}
+			e.Subject = "Size estimate ✓"
+
+			raw, err := BuildMessage(e, BuildOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			estimate := email.EstimateSize(e)
+			if diff := estimate.Total - len(raw); diff < -len(raw)/100 || diff > len(raw)/100 {
+				t.Errorf("estimated %d bytes, built %d", estimate.Total, len(raw))
+			}
+		})
+	}
+}
+
+func TestBuildMessage_ReaderBodies(t *testing.T) {
+	html := "<p>" + strings.Repeat("Grüße aus München ", 10_000) + "</p>"
+	text := strings.Repeat("line one\nline two\n", 10_000)
+
+	strs := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Reader bodies",
+		HTMLBody:    html,
+		TextBody:    text,
+		Attachments: []email.Attachment{{FileName: "logo.png", Content: []byte("png"), ContentType: "image/png", ContentID: "logo@example.com"}},
+	}
+	readers := strs
+	readers.HTMLBody, readers.TextBody = "", ""
+
+	for _, opts := range []BuildOptions{{}, {Force7Bit: true}} {
+		want, err := BuildMessage(strs, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		e := readers
+		e.HTMLBodyReader = strings.NewReader(html)
+		e.TextBodyReader = iotest.OneByteReader(strings.NewReader(text))
+		got, err := BuildMessage(e, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Force7Bit=%v: reader bodies built a different message than string bodies", opts.Force7Bit)
+		}
+	}
+}
+
+func TestBuildMessage_ReaderError(t *testing.T) {
+	e := email.Email{
+		FromAddress:    "sender@example.com",
+		ToAddresses:    []string{"recipient@example.com"},
+		Subject:        "Reader bodies",
+		TextBodyReader: iotest.ErrReader(errors.New("disk gone")),
+	}
+
+	_, err := BuildMessage(e, BuildOptions{})
+
+	var emailErr *email.Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_VALIDATION_ERROR {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestBuildMessage_OmitBcc(t *testing.T) {
+	e := email.Email{
+		FromAddress:  "sender@example.com",
+		ToAddresses:  []string{"recipient@example.com"},
+		BCCAddresses: []string{"hidden@example.com"},
+		Subject:      "Hello",
+		TextBody:     "Hello",
+	}
+
+	for _, omit := range []bool{false, true} {
+		raw, err := BuildMessage(e, BuildOptions{OmitBcc: omit})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("failed to parse message: %v", err)
+		}
+		if got := msg.Header.Get("Bcc"); (got == "") != omit {
+			t.Errorf("OmitBcc=%v: unexpected Bcc header %q", omit, got)
+		}
+		if omit && bytes.Contains(raw, []byte("hidden@example.com")) {
+			t.Error("expected the BCC recipient to be left out of the message")
+		}
+	}
+}
+
+func TestBuildMessage_Charset(t *testing.T) {
+	e := email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.jp"},
+		Subject:     "大会のお知らせ",
+		TextBody:    "来週の大会について",
+		HTMLBody:    "<p>来週の大会について</p>",
+		Charset:     "iso-2022-jp",
+	}
+
+	raw, err := BuildMessage(e, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(raw, []byte("大会")) {
+		t.Error("expected the text to be transcoded out of UTF-8")
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	decoder := mime.WordDecoder{CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := ianaindex.MIME.Encoding(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}}
+	if subject, err := decoder.DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != e.Subject {
+		t.Errorf("expected subject %q, got %q (%v)", e.Subject, subject, err)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{e.TextBody, e.HTMLBody} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, partParams, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partParams["charset"] != "ISO-2022-JP" {
+			t.Errorf("expected charset ISO-2022-JP, got %q", partParams["charset"])
+		}
+		body, err := io.ReadAll(japanese.ISO2022JP.NewDecoder().Reader(part))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != want {
+			t.Errorf("expected %q, got %q", want, body)
+		}
+	}
+}
+
+func TestBuildMessage_CharsetErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		email email.Email
+	}{
+		{"unknown charset", email.Email{TextBody: "Hello", Charset: "klingon"}},
+		{"unrepresentable subject", email.Email{Subject: "Résultats 🏹", TextBody: "Hello", Charset: "iso-8859-1"}},
+		{"unrepresentable reader body", email.Email{TextBodyReader: strings.NewReader("大会"), Charset: "iso-8859-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.email
+			e.FromAddress = "sender@example.com"
+			e.ToAddresses = []string{"recipient@example.com"}
+
+			_, err := BuildMessage(e, BuildOptions{})
+			if !errors.Is(err, email.ErrValidation) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package providertest certifies that an email.Sender implementation follows
+// the semantics shared by every provider, so third-party provider packages
+// can run the same suite as the ones in this module.
+package providertest
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// Observation is the provider-neutral view of what a sender handed to its
+// backend for a single send.
+type Observation struct {
+	To      []string
+	CC      []string
+	BCC     []string
+	Subject string
+	HasHTML bool
+	HasText bool
+	// File names of the attachments carried by the request. Must be nil when
+	// the request carries no attachment structure at all.
+	Attachments []string
+	// Values of the campaign headers, empty when absent.
+	CampaignID   string
+	SequenceStep string
+	// Everything the request carries besides its envelope recipient fields,
+	// e.g. the raw message or the serialized content, for checking that BCC
+	// recipients stay hidden. Ignored when comparing observations.
+	Message string
+}
+
+// BCCHandling is email.BCCHandling, kept for harnesses written against this
+// package.
+type BCCHandling = email.BCCHandling
+
+const (
+	BCC_ENVELOPE        = email.BCC_ENVELOPE
+	BCC_STRIPPED_HEADER = email.BCC_STRIPPED_HEADER
+)
+
+// Harness wires a sender to a mocked provider backend.
+type Harness struct {
+	Sender email.Sender
+	// Taken from the sender's email.Capabilities if it reports them, which
+	// it must match; required otherwise.
+	BCC BCCHandling
+	// Observe returns what the sender passed to the backend on its most
+	// recent send, and false if nothing reached the backend.
+	Observe func() (Observation, bool)
+	// The message ID the mocked backend assigns to a successful send.
+	MessageID string
+}
+
+type testCase struct {
+	name     string
+	email    email.Email
+	expected Observation
+}
+
+func baseEmail() email.Email {
+	return email.Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Conformance",
+	}
+}
+
+func cases() []testCase {
+	withBodies := func(text, html string, attachments []email.Attachment) email.Email {
+		e := baseEmail()
+		e.TextBody = text
+		e.HTMLBody = html
+		e.Attachments = attachments
+		return e
+	}
+	observed := func(hasText, hasHTML bool, attachments []string) Observation {
+		return Observation{
+			To:          []string{"recipient@example.com"},
+			Subject:     "Conformance",
+			HasText:     hasText,
+			HasHTML:     hasHTML,
+			Attachments: attachments,
+		}
+	}
+	attachment := email.Attachment{
+		FileName:    "roster.csv",
+		Content:     []byte("name,team\n"),
+		ContentType: "text/csv",
+	}
+
+	return []testCase{
+		{
+			name:     "text body with nil attachments",
+			email:    withBodies("Hello", "", nil),
+			expected: observed(true, false, nil),
+		},
+		{
+			name:     "text body with empty attachments",
+			email:    withBodies("Hello", "", []email.Attachment{}),
+			expected: observed(true, false, nil),
+		},
+		{
+			name:     "html body with empty attachments",
+			email:    withBodies("", "<p>Hello</p>", []email.Attachment{}),
+			expected: observed(false, true, nil),
+		},
+		{
+			name:     "html and text bodies with empty attachments",
+			email:    withBodies("Hello", "<p>Hello</p>", []email.Attachment{}),
+			expected: observed(true, true, nil),
+		},
+		{
+			name:     "text body with one attachment",
+			email:    withBodies("Hello", "", []email.Attachment{attachment}),
+			expected: observed(true, false, []string{"roster.csv"}),
+		},
+	}
+}
+
+// RunSenderConformance exercises the sender built by newHarness against the
+// canonical emails and fails t on any divergence from the shared semantics.
+func RunSenderConformance(t *testing.T, newHarness func(t *testing.T) Harness) {
+	t.Helper()
+
+	for _, tc := range cases() {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+
+			if err := h.Sender.SendEmail(context.Background(), tc.email); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, ok := h.Observe()
+			if !ok {
+				t.Fatal("expected the send to reach the backend")
+			}
+
+			got.Message = ""
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("observed %#v, expected %#v", got, tc.expected)
+			}
+		})
+	}
+
+	runBCCPrivacy(t, newHarness)
+}
+
+// runBCCPrivacy checks that BCC recipients only reach the backend the way
+// the provider's BCCHandling allows, so To and CC recipients never see them.
+func runBCCPrivacy(t *testing.T, newHarness func(t *testing.T) Harness) {
+	t.Helper()
+
+	hidden := []string{"hidden-one@example.com", "hidden-two@example.com"}
+	plain := baseEmail()
+	plain.TextBody = "Hello"
+	plain.CCAddresses = []string{"cc@example.com"}
+	plain.BCCAddresses = hidden
+
+	// A header too long for some providers' structured requests, which
+	// makes them fall back to a raw message.
+	longHeader := plain
+	longHeader.Headers = []email.Header{{Name: "X-Notes", Value: strings.Repeat("n", 900)}}
+
+	for name, e := range map[string]email.Email{"bcc recipients stay hidden": plain, "bcc recipients stay hidden in raw fallbacks": longHeader} {
+		t.Run(name, func(t *testing.T) {
+			h := newHarness(t)
+			if c, ok := email.CapabilitiesOf(h.Sender); ok {
+				if h.BCC != "" && h.BCC != c.BCC {
+					t.Fatalf("Harness.BCC is %q but the sender reports %q", h.BCC, c.BCC)
+				}
+				h.BCC = c.BCC
+			}
+			if h.BCC != BCC_ENVELOPE && h.BCC != BCC_STRIPPED_HEADER {
+				t.Fatalf("Harness.BCC must be BCC_ENVELOPE or BCC_STRIPPED_HEADER, got %q", h.BCC)
+			}
+
+			if err := h.Sender.SendEmail(context.Background(), e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, ok := h.Observe()
+			if !ok {
+				t.Fatal("expected the send to reach the backend")
+			}
+
+			if !reflect.DeepEqual(got.BCC, hidden) {
+				t.Errorf("expected BCC recipients %v, got %v", hidden, got.BCC)
+			}
+			for _, addr := range hidden {
+				if slices.Contains(got.To, addr) || slices.Contains(got.CC, addr) {
+					t.Errorf("BCC recipient %s is a To or CC recipient", addr)
+				}
+			}
+
+			visible := got.Message
+			if visible == "" {
+				t.Fatal("expected the observation to carry the message")
+			}
+			if h.BCC == BCC_STRIPPED_HEADER {
+				var removed int
+				visible, removed = stripBccHeaders(visible)
+				if removed != 1 {
+					t.Errorf("expected one top-level Bcc header, got %d", removed)
+				}
+			}
+			for _, addr := range hidden {
+				if strings.Contains(strings.ToLower(visible), addr) {
+					t.Errorf("BCC recipient %s is visible to the other recipients", addr)
+				}
+			}
+		})
+	}
+}
+
+// RunSenderV2Conformance checks the SendEmailV2 contract of the sender built
+// by newHarness.
+func RunSenderV2Conformance(t *testing.T, newHarness func(t *testing.T) Harness) {
+	t.Helper()
+
+	e := baseEmail()
+	e.TextBody = "Hello"
+
+	t.Run("dry run does not reach the backend", func(t *testing.T) {
+		h := newHarness(t)
+
+		result, err := senderV2(t, h).SendEmailV2(context.Background(), e, &email.SendOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := h.Observe(); ok {
+			t.Error("dry run reached the backend")
+		}
+		if !result.DryRun || result.Provider == "" || result.ProviderMessageID != "" {
+			t.Errorf("unexpected dry run result %#v", result)
+		}
+	})
+
+	t.Run("result carries the provider message id", func(t *testing.T) {
+		h := newHarness(t)
+
+		result, err := senderV2(t, h).SendEmailV2(context.Background(), e, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.DryRun || result.Provider == "" || result.ProviderMessageID != h.MessageID {
+			t.Errorf("unexpected result %#v, expected message id %q", result, h.MessageID)
+		}
+	})
+
+	t.Run("campaign is surfaced in headers and result", func(t *testing.T) {
+		h := newHarness(t)
+
+		campaign := e
+		campaign.CampaignID = "registration-2030"
+		campaign.SequenceStep = 2
+
+		result, err := senderV2(t, h).SendEmailV2(context.Background(), campaign, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.CampaignID != "registration-2030" || result.SequenceStep != 2 {
+			t.Errorf("expected campaign in result, got %#v", result)
+		}
+		got, _ := h.Observe()
+		if got.CampaignID != "registration-2030" || got.SequenceStep != "2" {
+			t.Errorf("expected campaign headers, got %q step %q", got.CampaignID, got.SequenceStep)
+		}
+	})
+
+	t.Run("override and hooks", func(t *testing.T) {
+		h := newHarness(t)
+
+		var before, after bool
+		opts := &email.SendOptions{
+			Override: func(e *email.Email) {
+				e.Subject = "Overridden"
+			},
+			Hooks: email.SendHooks{
+				BeforeSend: func(ctx context.Context, e email.Email) error {
+					before = true
+					if e.Subject != "Overridden" {
+						t.Errorf("BeforeSend saw subject %q", e.Subject)
+					}
+					return nil
+				},
+				AfterSend: func(ctx context.Context, e email.Email, result *email.SendResult, err error) {
+					after = true
+					if err != nil || result == nil {
+						t.Errorf("AfterSend got result %#v, err %v", result, err)
+					}
+				},
+			},
+		}
+
+		if _, err := senderV2(t, h).SendEmailV2(context.Background(), e, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !before || !after {
+			t.Errorf("expected both hooks to run, BeforeSend %v, AfterSend %v", before, after)
+		}
+		if got, _ := h.Observe(); got.Subject != "Overridden" {
+			t.Errorf("expected the override to reach the backend, got subject %q", got.Subject)
+		}
+		if e.Subject != "Conformance" {
+			t.Error("override modified the caller's email")
+		}
+	})
+}
+
+func senderV2(t *testing.T, h Harness) email.SenderV2 {
+	t.Helper()
+
+	s, ok := h.Sender.(email.SenderV2)
+	if !ok {
+		t.Fatalf("%T does not implement email.SenderV2", h.Sender)
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package providertest
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// ObserveRawMessage builds the Observation of a raw RFC 5322 message, for
+// providers that hand their backend the message itself. The recipients are
+// read from its headers.
+func ObserveRawMessage(t *testing.T, raw string) Observation {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse raw message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+
+	obs := Observation{
+		To:      headerAddresses(msg.Header, "To"),
+		CC:      headerAddresses(msg.Header, "Cc"),
+		BCC:     headerAddresses(msg.Header, "Bcc"),
+		Subject: subject,
+
+		CampaignID:   msg.Header.Get(email.CampaignIDHeader),
+		SequenceStep: msg.Header.Get(email.SequenceStepHeader),
+		Message:      raw,
+	}
+	observePart(t, &obs, msg.Header.Get("Content-Type"), "", msg.Body)
+
+	return obs
+}
+
+func headerAddresses(h mail.Header, key string) []string {
+	list, err := h.AddressList(key)
+	if err != nil {
+		return nil
+	}
+
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		addrs[i] = a.Address
+	}
+	return addrs
+}
+
+func observePart(t *testing.T, obs *Observation, contentType, disposition string, body io.Reader) {
+	t.Helper()
+
+	if disposition != "" {
+		dispType, params, err := mime.ParseMediaType(disposition)
+		if err != nil {
+			t.Fatalf("invalid Content-Disposition %q: %v", disposition, err)
+		}
+		if dispType == "attachment" {
+			obs.Attachments = append(obs.Attachments, params["filename"])
+			return
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("invalid Content-Type %q: %v", contentType, err)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read multipart body: %v", err)
+			}
+			observePart(t, obs, part.Header.Get("Content-Type"), part.Header.Get("Content-Disposition"), part)
+		}
+	case mediaType == "text/plain":
+		obs.HasText = true
+	case mediaType == "text/html":
+		obs.HasHTML = true
+	}
+}
+
+// stripBccHeaders removes the Bcc header fields, continuation lines
+// included, from the top-level header of a raw message, the way providers
+// with BCC_STRIPPED_HEADER do before delivery. It returns how many it
+// removed.
+func stripBccHeaders(raw string) (string, int) {
+	header, body, found := strings.Cut(raw, "\r\n\r\n")
+	if !found {
+		return raw, 0
+	}
+
+	var kept []string
+	removed := 0
+	inBcc := false
+	for _, line := range strings.Split(header, "\r\n") {
+		if inBcc && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		inBcc = strings.EqualFold(strings.TrimSpace(name), "Bcc")
+		if inBcc {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\r\n") + "\r\n\r\n" + body, removed
+}
@@ -0,0 +1,157 @@
+package providersdk
+
+import (
+	"bytes"
+	"fmt"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// UnrepresentablePolicy decides what a 7-bit build does with content that has
+// no 7-bit representation, which in practice means non-ASCII local parts of
+// addresses.
+type UnrepresentablePolicy string
+
+const (
+	UNREPRESENTABLE_REJECT UnrepresentablePolicy = "REJECT"
+	// Strips diacritics (josé -> jose) and rejects whatever is still not ASCII.
+	UNREPRESENTABLE_TRANSLITERATE UnrepresentablePolicy = "TRANSLITERATE"
+)
+
+// encodeBody returns the transfer encoding and encoded form of a text body.
+func (b *builder) encodeBody(body string) (string, string) {
+	if !b.opts.Force7Bit {
+		return "8bit", body
+	}
+
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	// Writes to a bytes.Buffer can't fail.
+	_, _ = w.Write([]byte(body))
+	_ = w.Close()
+
+	return "quoted-printable", buf.String()
+}
+
+// formatAddressList renders addresses for an address header.
+func (b *builder) formatAddressList(addrs []string) (string, error) {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		var a string
+		var err error
+		if b.opts.Force7Bit {
+			a, err = b.sevenBitAddress(addr)
+		} else {
+			a, err = FormatAddress(addr)
+		}
+		if err != nil {
+			return "", err
+		}
+		formatted[i] = a
+	}
+
+	return strings.Join(formatted, ", "), nil
+}
+
+// fieldHeader renders a header of email.FieldHeaders. With Force7Bit,
+// non-ASCII addresses are converted like those of the address headers.
+// Other values, such as URLs, have no 7-bit form and are rejected; campaign
+// IDs and tags are validated to be ASCII already.
+func (b *builder) fieldHeader(h email.Header) (string, error) {
+	if !b.opts.Force7Bit || isASCII(h.Value) {
+		return fmt.Sprintf("%s: %s", h.Name, h.Value), nil
+	}
+
+	switch {
+	case h.Name == "Return-Path":
+		addr, err := b.sevenBitAddress(strings.TrimSuffix(strings.TrimPrefix(h.Value, "<"), ">"))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s: %s", h.Name, email.ReturnPath(addr)), nil
+	case h.Name == email.DeliveryFallbackHeader:
+		addr, err := b.sevenBitAddress(h.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s: %s", h.Name, addr), nil
+	}
+	return "", email.NewValidationError(fmt.Sprintf("%s header cannot be represented in 7-bit: %s", h.Name, h.Value), nil)
+}
+
+// FormatAddress normalizes a bare or formatted address for a header, RFC 2047
+// encoding a non-ASCII display name.
+func FormatAddress(addr string) (string, error) {
+	a, err := email.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+func (b *builder) sevenBitAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", email.NewInvalidEmailError(fmt.Sprintf("Invalid address format: %s", addr), err)
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", email.NewInvalidEmailError(fmt.Sprintf("Domain cannot be converted to punycode: %s", domain), err)
+	}
+
+	if !isASCII(local) {
+		if b.opts.Unrepresentable != UNREPRESENTABLE_TRANSLITERATE {
+			return "", email.NewInvalidEmailError(fmt.Sprintf("Address cannot be represented in 7-bit: %s", addr), nil)
+		}
+		local = transliterate(local)
+		if !isASCII(local) {
+			return "", email.NewInvalidEmailError(fmt.Sprintf("Address cannot be transliterated to 7-bit: %s", addr), nil)
+		}
+	}
+
+	parsed.Address = local + "@" + asciiDomain
+	// mail.Address.String RFC 2047 encodes non-ASCII display names.
+	return parsed.String(), nil
+}
+
+// transliterate strips combining marks after canonical decomposition.
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func wrapLines(s string, width int) string {
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString("\r\n")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
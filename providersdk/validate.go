@@ -0,0 +1,224 @@
+// Package providersdk holds the pieces every email provider needs, so a new
+// provider package only has to translate an email.Email into its backend's
+// request. The providers in this module are built on it; providertest holds
+// the conformance suite that certifies a provider against the shared
+// semantics.
+package providersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// Validate checks that e is complete and well-formed before a provider tries
+// to send it.
+func Validate(e email.Email) error {
+	if e.FromAddress == "" {
+		return email.NewValidationError("from address is required", nil)
+	}
+
+	if _, err := mail.ParseAddress(e.FromAddress); err != nil {
+		return email.NewInvalidEmailError("invalid from address format", err)
+	}
+
+	if e.SenderAddress != "" {
+		if _, err := mail.ParseAddress(e.SenderAddress); err != nil {
+			return email.NewInvalidEmailError(fmt.Sprintf("invalid sender address: %s", e.SenderAddress), err)
+		}
+	}
+
+	if len(e.ToAddresses)+len(e.CCAddresses)+len(e.BCCAddresses) == 0 {
+		return email.NewValidationError("at least one recipient is required", nil)
+	}
+
+	for _, addrs := range [][]string{e.ToAddresses, e.CCAddresses, e.BCCAddresses} {
+		for _, addr := range addrs {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return email.NewInvalidEmailError(fmt.Sprintf("invalid recipient address: %s", addr), err)
+			}
+		}
+	}
+
+	for _, addr := range e.ReplyToAddresses {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return email.NewInvalidEmailError(fmt.Sprintf("invalid reply-to address: %s", addr), err)
+		}
+	}
+
+	if e.BounceAddress != "" {
+		if _, err := mail.ParseAddress(e.BounceAddress); err != nil {
+			return email.NewInvalidEmailError(fmt.Sprintf("invalid bounce address: %s", e.BounceAddress), err)
+		}
+	}
+
+	if e.ReadReceiptTo != "" {
+		if _, err := mail.ParseAddress(e.ReadReceiptTo); err != nil {
+			return email.NewInvalidEmailError(fmt.Sprintf("invalid read receipt address: %s", e.ReadReceiptTo), err)
+		}
+	}
+
+	if e.FallbackFor != "" {
+		if _, err := mail.ParseAddress(e.FallbackFor); err != nil {
+			return email.NewInvalidEmailError(fmt.Sprintf("invalid fallback address: %s", e.FallbackFor), err)
+		}
+	}
+
+	// Internationalized domains must convert to punycode for sending.
+	if _, err := email.NormalizeAddresses(e); err != nil {
+		return err
+	}
+
+	if e.Subject == "" {
+		return email.NewValidationError("subject is required", nil)
+	}
+
+	if e.HTMLBody != "" && e.HTMLBodyReader != nil {
+		return email.NewValidationError("HTMLBody and HTMLBodyReader are mutually exclusive", nil)
+	}
+
+	if e.TextBody != "" && e.TextBodyReader != nil {
+		return email.NewValidationError("TextBody and TextBodyReader are mutually exclusive", nil)
+	}
+
+	if !e.HasBody() {
+		return email.NewValidationError("email body is required (HTML or text)", nil)
+	}
+
+	if err := email.ValidateCharset(e); err != nil {
+		return err
+	}
+
+	if !e.Expires.IsZero() && !e.AllowPastExpiry && e.Expires.Before(time.Now()) {
+		return email.NewValidationError("expiry date is in the past", nil)
+	}
+
+	if !e.Date.IsZero() && (e.Date.Year() < 1900 || e.Date.Year() > 9999) {
+		return email.NewValidationError(fmt.Sprintf("date %s is out of range", e.Date), nil)
+	}
+
+	for _, a := range e.Attachments {
+		if a.Ref != "" && a.Content == nil {
+			return email.NewValidationError(fmt.Sprintf("attachment %s references %s but was not resolved", a.FileName, a.Ref), nil)
+		}
+	}
+
+	if err := email.ValidateCampaign(e); err != nil {
+		return err
+	}
+
+	if err := email.ValidateUnsubscribe(e.Unsubscribe); err != nil {
+		return err
+	}
+
+	if err := email.ValidateList(e); err != nil {
+		return err
+	}
+
+	if err := email.ValidateMessageID(e.MessageID); err != nil {
+		return err
+	}
+
+	if err := email.ValidatePriority(e.Priority); err != nil {
+		return err
+	}
+
+	if err := email.ValidateAutoSubmitted(e.AutoSubmitted); err != nil {
+		return err
+	}
+
+	if err := email.ValidateTags(e.Tags); err != nil {
+		return err
+	}
+
+	if err := email.ValidateHeaders(e.Headers); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validator is Validate extended with caller-defined address rules. A nil
+// *Validator only runs Validate.
+type Validator struct {
+	rules []email.AddressRule
+}
+
+func NewValidator(rules ...email.AddressRule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// Validate runs Validate, then the rules against every From, To, CC, BCC,
+// Reply-To, bounce and read receipt address in that order. For each address
+// the rules run in the order they were given, and the first veto is
+// returned. Vetoes become REASON_INVALID_EMAIL errors, unless the rule
+// returned a REASON_VALIDATION_ERROR *email.Error, with the address and its
+// field in the metadata.
+func (v *Validator) Validate(ctx context.Context, e email.Email) error {
+	if err := Validate(e); err != nil {
+		return err
+	}
+	if v == nil || len(v.rules) == 0 {
+		return nil
+	}
+
+	fields := []struct {
+		field email.AddressField
+		addrs []string
+	}{
+		{email.ADDRESS_FROM, []string{e.FromAddress}},
+		{email.ADDRESS_SENDER, nonEmpty(e.SenderAddress)},
+		{email.ADDRESS_TO, e.ToAddresses},
+		{email.ADDRESS_CC, e.CCAddresses},
+		{email.ADDRESS_BCC, e.BCCAddresses},
+		{email.ADDRESS_REPLY_TO, e.ReplyToAddresses},
+		{email.ADDRESS_BOUNCE, nonEmpty(e.BounceAddress)},
+		{email.ADDRESS_READ_RECEIPT, nonEmpty(e.ReadReceiptTo)},
+	}
+	for _, f := range fields {
+		for _, addr := range f.addrs {
+			// Validate made sure every address parses.
+			parsed, _ := mail.ParseAddress(addr)
+			for _, rule := range v.rules {
+				if err := rule(ctx, parsed.Address, f.field); err != nil {
+					return ruleError(err, parsed.Address, f.field)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func nonEmpty(addr string) []string {
+	if addr == "" {
+		return nil
+	}
+	return []string{addr}
+}
+
+func ruleError(cause error, addr string, field email.AddressField) error {
+	if errors.Is(cause, context.Canceled) || errors.Is(cause, context.DeadlineExceeded) {
+		return email.NewServiceError(fmt.Sprintf("checking %s address %s did not finish", field, addr), cause)
+	}
+
+	message := fmt.Sprintf("%s address %s is not allowed: %s", field, addr, cause)
+	var err *email.Error
+	var ruleErr *email.Error
+	if errors.As(cause, &ruleErr) {
+		message = fmt.Sprintf("%s address %s is not allowed: %s", field, addr, ruleErr.Message)
+	}
+	if ruleErr != nil && ruleErr.Reason == email.REASON_VALIDATION_ERROR {
+		err = email.NewValidationError(message, cause)
+	} else {
+		err = email.NewInvalidEmailError(message, cause)
+	}
+	err.Metadata = map[string]string{
+		email.AddressFieldMetadataKey: string(field),
+		email.AddressMetadataKey:      addr,
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+package providersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() email.Email {
+		return email.Email{
+			FromAddress: "sender@example.com",
+			ToAddresses: []string{"recipient@example.com"},
+			Subject:     "Subject",
+			TextBody:    "Body",
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(e *email.Email)
+		expected email.ErrorReason
+	}{
+		{"valid", func(e *email.Email) {}, ""},
+		{"display name", func(e *email.Email) { e.FromAddress = "Sender <sender@example.com>" }, ""},
+		{"bcc only", func(e *email.Email) { e.ToAddresses = nil; e.BCCAddresses = []string{"b@example.com"} }, ""},
+		{"missing from", func(e *email.Email) { e.FromAddress = "" }, email.REASON_VALIDATION_ERROR},
+		{"invalid from", func(e *email.Email) { e.FromAddress = "invalid-email" }, email.REASON_INVALID_EMAIL},
+		{"no recipients", func(e *email.Email) { e.ToAddresses = nil }, email.REASON_VALIDATION_ERROR},
+		{"invalid cc", func(e *email.Email) { e.CCAddresses = []string{"nope"} }, email.REASON_INVALID_EMAIL},
+		{"missing subject", func(e *email.Email) { e.Subject = "" }, email.REASON_VALIDATION_ERROR},
+		{"missing body", func(e *email.Email) { e.TextBody = "" }, email.REASON_VALIDATION_ERROR},
+		{"text body reader", func(e *email.Email) { e.TextBody = ""; e.TextBodyReader = strings.NewReader("Body") }, ""},
+		{"html body reader", func(e *email.Email) { e.HTMLBodyReader = strings.NewReader("<p>Body</p>") }, ""},
+		{"text body and reader", func(e *email.Email) { e.TextBodyReader = strings.NewReader("Body") }, email.REASON_VALIDATION_ERROR},
+		{"html body and reader", func(e *email.Email) {
+			e.HTMLBody = "<p>Body</p>"
+			e.HTMLBodyReader = strings.NewReader("<p>Body</p>")
+		}, email.REASON_VALIDATION_ERROR},
+		{"past expiry", func(e *email.Email) { e.Expires = time.Now().Add(-time.Hour) }, email.REASON_VALIDATION_ERROR},
+		{"date", func(e *email.Email) { e.Date = time.Date(2023, 11, 4, 18, 15, 0, 0, time.UTC) }, ""},
+		{"date out of range", func(e *email.Email) { e.Date = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC) }, email.REASON_VALIDATION_ERROR},
+		{"invalid campaign", func(e *email.Email) { e.CampaignID = "spring sale" }, email.REASON_VALIDATION_ERROR},
+		{"priority", func(e *email.Email) { e.Priority = email.PRIORITY_HIGH }, ""},
+		{"one-click unsubscribe", func(e *email.Email) { e.Unsubscribe = email.OneClickUnsubscribe("https://icaa.example.com/u/1") }, ""},
+		{"one-click over http", func(e *email.Email) { e.Unsubscribe = email.OneClickUnsubscribe("http://icaa.example.com/u/1") }, email.REASON_VALIDATION_ERROR},
+		{"unsubscribe header injection", func(e *email.Email) {
+			e.Unsubscribe = email.Unsubscribe{Mailto: "u@example.com>\r\nBcc: x@example.com"}
+		}, email.REASON_VALIDATION_ERROR},
+		{"custom header", func(e *email.Email) { e.Headers = []email.Header{{Name: "X-Team", Value: "north"}} }, ""},
+		{"reserved header", func(e *email.Email) { e.Headers = []email.Header{{Name: "Bcc", Value: "x@example.com"}} }, email.REASON_VALIDATION_ERROR},
+		{"message id", func(e *email.Email) { e.MessageID = "<abc@example.com>" }, ""},
+		{"invalid message id", func(e *email.Email) { e.MessageID = "abc@example.com" }, email.REASON_VALIDATION_ERROR},
+		{"tags", func(e *email.Email) { e.Tags = map[string]string{"env": "prod"} }, ""},
+		{"invalid tag", func(e *email.Email) { e.Tags = map[string]string{"env": "prod\r\nBcc: x@example.com"} }, email.REASON_VALIDATION_ERROR},
+		{"unknown priority", func(e *email.Email) { e.Priority = "URGENT" }, email.REASON_VALIDATION_ERROR},
+		{"auto-submitted", func(e *email.Email) { e.AutoSubmitted = email.AUTO_SUBMITTED_GENERATED }, ""},
+		{"unknown auto-submitted", func(e *email.Email) { e.AutoSubmitted = "yes" }, email.REASON_VALIDATION_ERROR},
+		{"list", func(e *email.Email) { e.ListID = "digest.icaa.example.com" }, ""},
+		{"invalid list ID", func(e *email.Email) { e.ListID = "digest" }, email.REASON_VALIDATION_ERROR},
+		{"internationalized domain", func(e *email.Email) { e.CCAddresses = []string{"jose@münchen.de"} }, ""},
+		{"invalid internationalized domain", func(e *email.Email) { e.CCAddresses = []string{"jose@mün_chen.de"} }, email.REASON_INVALID_EMAIL},
+		{"sender", func(e *email.Email) { e.SenderAddress = "Director <director@example.com>" }, ""},
+		{"invalid sender", func(e *email.Email) { e.SenderAddress = "director" }, email.REASON_INVALID_EMAIL},
+		{"invalid reply-to", func(e *email.Email) { e.ReplyToAddresses = []string{"help"} }, email.REASON_INVALID_EMAIL},
+		{"bounce address", func(e *email.Email) { e.BounceAddress = "Bounces <bounces@example.com>" }, ""},
+		{"invalid bounce address", func(e *email.Email) { e.BounceAddress = "bounces" }, email.REASON_INVALID_EMAIL},
+		{"read receipt", func(e *email.Email) { e.RequestReadReceipt = true }, ""},
+		{"read receipt address", func(e *email.Email) { e.ReadReceiptTo = "Board <board@example.com>" }, ""},
+		{"invalid read receipt address", func(e *email.Email) { e.ReadReceiptTo = "board" }, email.REASON_INVALID_EMAIL},
+		{"read receipt header", func(e *email.Email) {
+			e.Headers = []email.Header{{Name: "Disposition-Notification-To", Value: "x@example.com"}}
+		}, email.REASON_VALIDATION_ERROR},
+		{"invalid fallback", func(e *email.Email) { e.FallbackFor = "ada@example.com\r\nBcc: x@example.com" }, email.REASON_INVALID_EMAIL},
+		{"charset", func(e *email.Email) { e.Charset = "iso-2022-jp"; e.Subject = "大会のお知らせ" }, ""},
+		{"unknown charset", func(e *email.Email) { e.Charset = "klingon" }, email.REASON_VALIDATION_ERROR},
+		{"utf-16 charset", func(e *email.Email) { e.Charset = "utf-16" }, email.REASON_VALIDATION_ERROR},
+		{"unrepresentable body", func(e *email.Email) { e.Charset = "iso-8859-1"; e.HTMLBody = "<p>大会</p>" }, email.REASON_VALIDATION_ERROR},
+		{"unresolved attachment", func(e *email.Email) {
+			e.Attachments = []email.Attachment{{FileName: "roster.csv", Ref: "s3://rosters/2026.csv"}}
+		}, email.REASON_VALIDATION_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid()
+			tt.modify(&e)
+
+			err := Validate(e)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var emailErr *email.Error
+			if !errors.As(err, &emailErr) {
+				t.Fatalf("expected email.Error, got %v", err)
+			}
+			if emailErr.Reason != tt.expected {
+				t.Errorf("expected error reason %s, got %s", tt.expected, emailErr.Reason)
+			}
+		})
+	}
+}
+
+type ctxKey struct{}
+
+func TestValidator(t *testing.T) {
+	e := email.Email{
+		FromAddress:      "Events <events@icaa.example.com>",
+		ToAddresses:      []string{"ada@example.com"},
+		CCAddresses:      []string{"admin@example.com"},
+		ReplyToAddresses: []string{"help@icaa.example.com"},
+		Subject:          "Subject",
+		TextBody:         "Body",
+	}
+
+	var calls []string
+	noRoleAccounts := func(ctx context.Context, addr string, field email.AddressField) error {
+		calls = append(calls, fmt.Sprintf("role %s %s", field, addr))
+		if strings.HasPrefix(addr, "admin@") {
+			return errors.New("role accounts are not allowed")
+		}
+		return nil
+	}
+	onFile := func(ctx context.Context, addr string, field email.AddressField) error {
+		calls = append(calls, fmt.Sprintf("file %s %s", field, addr))
+		if ctx.Value(ctxKey{}) != "members" {
+			t.Errorf("expected the context of the send, got %v", ctx.Value(ctxKey{}))
+		}
+		if addr == "bo@example.com" {
+			return email.NewValidationError("not a member", nil)
+		}
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "members")
+
+	t.Run("rules run in order per address", func(t *testing.T) {
+		calls = nil
+		err := NewValidator(noRoleAccounts, onFile).Validate(ctx, e)
+
+		want := []string{
+			"role From events@icaa.example.com", "file From events@icaa.example.com",
+			"role To ada@example.com", "file To ada@example.com",
+			"role CC admin@example.com",
+		}
+		if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+			t.Errorf("expected calls %v, got %v", want, calls)
+		}
+
+		var emailErr *email.Error
+		if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_INVALID_EMAIL {
+			t.Fatalf("expected invalid email error, got %v", err)
+		}
+		if emailErr.Metadata[email.AddressFieldMetadataKey] != "CC" || emailErr.Metadata[email.AddressMetadataKey] != "admin@example.com" {
+			t.Errorf("unexpected metadata %v", emailErr.Metadata)
+		}
+		if !strings.Contains(emailErr.Message, "role accounts are not allowed") {
+			t.Errorf("expected the rule's message, got %q", emailErr.Message)
+		}
+	})
+
+	t.Run("validation error reason is kept", func(t *testing.T) {
+		withBo := e
+		withBo.CCAddresses = nil
+		withBo.BCCAddresses = []string{"Bo <bo@example.com>"}
+
+		err := NewValidator(noRoleAccounts, onFile).Validate(ctx, withBo)
+
+		var emailErr *email.Error
+		if !errors.As(err, &emailErr) || emailErr.Reason != email.REASON_VALIDATION_ERROR {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+		if emailErr.Metadata[email.AddressFieldMetadataKey] != "BCC" || emailErr.Metadata[email.AddressMetadataKey] != "bo@example.com" {
+			t.Errorf("unexpected metadata %v", emailErr.Metadata)
+		}
+	})
+
+	t.Run("reply-to is checked", func(t *testing.T) {
+		calls = nil
+		ok := e
+		ok.CCAddresses = nil
+
+		if err := NewValidator(onFile).Validate(ctx, ok); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls[len(calls)-1] != "file Reply-To help@icaa.example.com" {
+			t.Errorf("expected Reply-To to be checked last, got %v", calls)
+		}
+	})
+
+	t.Run("bounce address is checked", func(t *testing.T) {
+		calls = nil
+		withBounce := e
+		withBounce.CCAddresses = nil
+		withBounce.BounceAddress = "Bounces <bounces@icaa.example.com>"
+
+		if err := NewValidator(onFile).Validate(ctx, withBounce); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls[len(calls)-1] != "file Return-Path bounces@icaa.example.com" {
+			t.Errorf("expected the bounce address to be checked last, got %v", calls)
+		}
+	})
+
+	t.Run("read receipt address is checked", func(t *testing.T) {
+		calls = nil
+		withReceipt := e
+		withReceipt.CCAddresses = nil
+		withReceipt.ReadReceiptTo = "Board <board@icaa.example.com>"
+
+		if err := NewValidator(onFile).Validate(ctx, withReceipt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls[len(calls)-1] != "file Disposition-Notification-To board@icaa.example.com" {
+			t.Errorf("expected the read receipt address to be checked last, got %v", calls)
+		}
+	})
+
+	t.Run("sender address is checked", func(t *testing.T) {
+		calls = nil
+		withSender := e
+		withSender.CCAddresses = nil
+		withSender.SenderAddress = "Director <director@icaa.example.com>"
+
+		if err := NewValidator(onFile).Validate(ctx, withSender); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls[1] != "file Sender director@icaa.example.com" {
+			t.Errorf("expected the sender address to be checked after From, got %v", calls)
+		}
+	})
+
+	t.Run("rules run after syntax checks", func(t *testing.T) {
+		calls = nil
+		bad := e
+		bad.ToAddresses = []string{"nope"}
+
+		err := NewValidator(onFile).Validate(ctx, bad)
+
+		if !errors.Is(err, email.ErrInvalidEmail) || len(calls) != 0 {
+			t.Errorf("expected a syntax error before any rule ran, got %v after %v", err, calls)
+		}
+	})
+
+	t.Run("deadline is a service error", func(t *testing.T) {
+		slow := func(ctx context.Context, addr string, field email.AddressField) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		expired, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := NewValidator(slow).Validate(expired, e)
+
+		if !errors.Is(err, email.ErrServiceError) || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected service error wrapping the context error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package providersdk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+)
+
+// messagePart is a node of a parsed MIME message.
+type messagePart struct {
+	mediaType string
+	// Set for parts with an attachment Content-Disposition, and for inline
+	// parts that have a Content-ID.
+	fileName  string
+	contentID string
+	inline    bool
+	// The charset parameter of a text part.
+	charset string
+	// Decoded content of a leaf part.
+	content []byte
+	// Children of a multipart part.
+	parts []messagePart
+}
+
+// VerifyMessage re-parses a generated message and checks that it is
+// well-formed and carries exactly the content of e, in the structure its
+// headers declare.
+func VerifyMessage(raw []byte, e email.Email) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("headers do not parse: %w", err)
+	}
+
+	if _, err := mail.ParseAddress(msg.Header.Get("From")); err != nil {
+		return fmt.Errorf("invalid From header: %w", err)
+	}
+
+	for _, key := range []string{"To", "Cc", "Bcc", "Reply-To"} {
+		if msg.Header.Get(key) == "" {
+			continue
+		}
+		if _, err := msg.Header.AddressList(key); err != nil {
+			return fmt.Errorf("invalid %s header: %w", key, err)
+		}
+	}
+
+	decoder := &mime.WordDecoder{CharsetReader: charsetReader}
+	if _, err := decoder.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		return fmt.Errorf("undecodable Subject header: %w", err)
+	}
+
+	root, err := readPart(msg.Header.Get, msg.Body)
+	if err != nil {
+		return err
+	}
+
+	inline, regular := splitAttachments(e)
+	if len(regular) == 0 {
+		return verifyBody(root, e, inline)
+	}
+
+	if root.mediaType != "multipart/mixed" {
+		return fmt.Errorf("message with attachments is %s, expected multipart/mixed", root.mediaType)
+	}
+
+	if err := verifyAttachments(root.parts[1:], regular, false); err != nil {
+		return err
+	}
+
+	return verifyBody(root.parts[0], e, inline)
+}
+
+func verifyAttachments(parts []messagePart, attachments []email.Attachment, inline bool) error {
+	kind := "attachments"
+	if inline {
+		kind = "inline attachments"
+	}
+
+	found := 0
+	for _, a := range parts {
+		if a.fileName != "" && a.inline == inline {
+			found++
+		}
+	}
+	if found != len(attachments) || len(parts) != len(attachments) {
+		return fmt.Errorf("expected %d %s, found %d", len(attachments), kind, found)
+	}
+
+	for i, a := range attachments {
+		if parts[i].fileName != a.FileName {
+			return fmt.Errorf("attachment %d is named %q, expected %q", i, parts[i].fileName, a.FileName)
+		}
+		if inline && parts[i].contentID != a.ContentID {
+			return fmt.Errorf("inline attachment %q has Content-ID %q, expected %q", a.FileName, parts[i].contentID, a.ContentID)
+		}
+		if !bytes.Equal(parts[i].content, a.Content) {
+			return fmt.Errorf("attachment %q content does not round-trip", a.FileName)
+		}
+	}
+
+	return nil
+}
+
+func verifyBody(p messagePart, e email.Email, inline []email.Attachment) error {
+	if p.fileName != "" {
+		return fmt.Errorf("expected the body, found attachment %q", p.fileName)
+	}
+
+	if e.TextBody != "" && e.HTMLBody != "" {
+		if p.mediaType != "multipart/alternative" {
+			return fmt.Errorf("message with text and HTML bodies is %s, expected multipart/alternative", p.mediaType)
+		}
+		if len(p.parts) != 2 {
+			return fmt.Errorf("expected 2 body parts, found %d", len(p.parts))
+		}
+		if err := verifyText(p.parts[0], "text/plain", e.TextBody); err != nil {
+			return err
+		}
+		return verifyHTML(p.parts[1], e.HTMLBody, inline)
+	}
+
+	if e.HTMLBody != "" {
+		return verifyHTML(p, e.HTMLBody, inline)
+	}
+	return verifyText(p, "text/plain", e.TextBody)
+}
+
+// verifyHTML checks the HTML body, which is wrapped in multipart/related
+// together with the inline attachments it references.
+func verifyHTML(p messagePart, html string, inline []email.Attachment) error {
+	if len(inline) == 0 {
+		return verifyText(p, "text/html", html)
+	}
+
+	if p.mediaType != "multipart/related" {
+		return fmt.Errorf("HTML body with inline attachments is %s, expected multipart/related", p.mediaType)
+	}
+	if err := verifyText(p.parts[0], "text/html", html); err != nil {
+		return err
+	}
+	return verifyAttachments(p.parts[1:], inline, true)
+}
+
+func verifyText(p messagePart, mediaType, text string) error {
+	if p.mediaType != mediaType || p.fileName != "" {
+		return fmt.Errorf("body part is %s, expected %s", p.mediaType, mediaType)
+	}
+
+	c, err := email.LookupCharset(p.charset)
+	if err != nil {
+		return fmt.Errorf("%s body has unknown charset %q", mediaType, p.charset)
+	}
+	content, err := c.Decode(string(p.content))
+	if err != nil {
+		return fmt.Errorf("%s body does not decode from %s", mediaType, c.Name)
+	}
+
+	// Line endings are normalized by quoted-printable encoding and by relays.
+	normalize := func(s string) string { return strings.ReplaceAll(s, "\r\n", "\n") }
+	if normalize(content) != normalize(text) {
+		return fmt.Errorf("%s body does not round-trip", mediaType)
+	}
+
+	return nil
+}
+
+// readPart parses the MIME tree rooted at a part, decoding its leaves.
+func readPart(header func(string) string, body io.Reader) (messagePart, error) {
+	contentType := header("Content-Type")
+	disposition := header("Content-Disposition")
+	encoding := header("Content-Transfer-Encoding")
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return messagePart{}, fmt.Errorf("invalid Content-Type %q: %w", contentType, err)
+	}
+
+	p := messagePart{mediaType: mediaType, charset: params["charset"]}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return messagePart{}, fmt.Errorf("%s has no boundary", mediaType)
+		}
+
+		mr := multipart.NewReader(body, boundary)
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return messagePart{}, fmt.Errorf("invalid %s structure: %w", mediaType, err)
+			}
+
+			child, err := readPart(part.Header.Get, part)
+			if err != nil {
+				return messagePart{}, err
+			}
+			p.parts = append(p.parts, child)
+		}
+
+		if len(p.parts) == 0 {
+			return messagePart{}, fmt.Errorf("%s has no parts", mediaType)
+		}
+		return p, nil
+	}
+
+	if disposition != "" {
+		dispType, dispParams, err := mime.ParseMediaType(disposition)
+		if err != nil {
+			return messagePart{}, fmt.Errorf("invalid Content-Disposition %q: %w", disposition, err)
+		}
+		switch {
+		case dispType == "attachment":
+			p.fileName = dispParams["filename"]
+			if p.fileName == "" {
+				return messagePart{}, errors.New("attachment part has no filename")
+			}
+		case dispType == "inline" && header("Content-ID") != "":
+			p.fileName = dispParams["filename"]
+			p.contentID = strings.Trim(header("Content-ID"), "<>")
+			p.inline = true
+		}
+	}
+
+	switch strings.ToLower(encoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		// multipart.Reader decodes quoted-printable parts itself and drops
+		// the header, so this is only reached for a top-level body.
+		body = quotedprintable.NewReader(body)
+	case "", "7bit", "8bit", "binary":
+	default:
+		return messagePart{}, fmt.Errorf("unsupported Content-Transfer-Encoding %q", encoding)
+	}
+
+	p.content, err = io.ReadAll(body)
+	if err != nil {
+		return messagePart{}, fmt.Errorf("%s part does not decode: %w", mediaType, err)
+	}
+
+	return p, nil
+}
+
+// charsetReader decodes RFC 2047 encoded-words in any charset LookupCharset
+// knows.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	c, err := email.LookupCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := c.Decode(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decoded), nil
+}
+
+// newlineStripper drops line breaks so wrapped base64 can be decoded.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	for {
+		read, err := n.r.Read(p)
+		kept := 0
+		for _, b := range p[:read] {
+			if b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package email
+
+// Headers asking the recipient's client to send a read receipt.
+// Return-Receipt-To is only understood by older clients.
+const (
+	DispositionNotificationToHeader = "Disposition-Notification-To"
+	ReturnReceiptToHeader           = "Return-Receipt-To"
+)
+
+// ReadReceiptAddress returns where read receipts for e go: ReadReceiptTo, or
+// the From address when only RequestReadReceipt is set. Empty means no
+// receipt is requested.
+func (e Email) ReadReceiptAddress() string {
+	if e.ReadReceiptTo != "" {
+		return e.ReadReceiptTo
+	}
+	if e.RequestReadReceipt {
+		return e.FromAddress
+	}
+	return ""
+}
+
+// ReadReceiptHeaders returns the Disposition-Notification-To and
+// Return-Receipt-To headers for e, or nil if it requests no receipt.
+func ReadReceiptHeaders(e Email) []Header {
+	addr := e.ReadReceiptAddress()
+	if addr == "" {
+		return nil
+	}
+	value := headerAddresses([]string{addr})
+	return []Header{
+		{Name: DispositionNotificationToHeader, Value: value},
+		{Name: ReturnReceiptToHeader, Value: value},
+	}
+}
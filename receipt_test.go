@@ -0,0 +1,38 @@
+package email
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReadReceiptHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    Email
+		expected string
+	}{
+		{"not requested", Email{FromAddress: "events@icaa.example.com"}, ""},
+		{"defaults to from", Email{FromAddress: "Events <events@icaa.example.com>", RequestReadReceipt: true}, `"Events" <events@icaa.example.com>`},
+		{"address", Email{FromAddress: "events@icaa.example.com", ReadReceiptTo: "board@icaa.example.com"}, "board@icaa.example.com"},
+		{"address wins over from", Email{FromAddress: "events@icaa.example.com", RequestReadReceipt: true, ReadReceiptTo: "board@icaa.example.com"}, "board@icaa.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReadReceiptHeaders(tt.email)
+			if tt.expected == "" {
+				if got != nil {
+					t.Errorf("expected no headers, got %v", got)
+				}
+				return
+			}
+			want := []Header{
+				{Name: DispositionNotificationToHeader, Value: tt.expected},
+				{Name: ReturnReceiptToHeader, Value: tt.expected},
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
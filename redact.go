@@ -0,0 +1,129 @@
+package email
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultRedactedBodyLength is how many characters of each body Redacted
+// keeps unless WithRedactedBodyLength is given.
+const DefaultRedactedBodyLength = 80
+
+type redactConfig struct {
+	bodyLength int
+}
+
+type RedactOption func(*redactConfig)
+
+// WithRedactedBodyLength replaces DefaultRedactedBodyLength. Zero drops the
+// bodies entirely.
+func WithRedactedBodyLength(n int) RedactOption {
+	return func(c *redactConfig) {
+		c.bodyLength = n
+	}
+}
+
+// Redacted returns a copy of e that is safe to log: the local parts and
+// display names of recipient addresses are masked, e.g. "j***@example.com",
+// bodies are truncated and attachment content is replaced with a
+// "<N bytes>" placeholder, keeping file names and counts. Body readers are
+// dropped, as they can only be read once. The sender side, such as From
+// and the bounce address, is kept.
+func (e Email) Redacted(opts ...RedactOption) Email {
+	c := redactConfig{bodyLength: DefaultRedactedBodyLength}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	e.ToAddresses = redactAddresses(e.ToAddresses)
+	e.CCAddresses = redactAddresses(e.CCAddresses)
+	e.BCCAddresses = redactAddresses(e.BCCAddresses)
+	e.ReplyToAddresses = redactAddresses(e.ReplyToAddresses)
+	e.AlternateAddresses = redactAddresses(e.AlternateAddresses)
+	if e.FallbackFor != "" {
+		e.FallbackFor = RedactAddress(e.FallbackFor)
+	}
+
+	e.TextBody = truncateDisplay(e.TextBody, c.bodyLength)
+	e.HTMLBody = truncateDisplay(e.HTMLBody, c.bodyLength)
+	e.TextBodyReader, e.HTMLBodyReader = nil, nil
+
+	if e.Attachments != nil {
+		attachments := make([]Attachment, len(e.Attachments))
+		for i, a := range e.Attachments {
+			if a.Content != nil {
+				a.Content = []byte(fmt.Sprintf("<%d bytes>", len(a.Content)))
+			}
+			attachments[i] = a
+		}
+		e.Attachments = attachments
+	}
+	return e
+}
+
+// RedactAddress masks all but the first character of the local part of
+// addr and drops its display name, e.g. "Jane <jane@example.com>" becomes
+// "j***@example.com". Unparseable addresses are masked entirely.
+func RedactAddress(addr string) string {
+	parsed, err := ParseAddress(addr)
+	if err != nil {
+		return "***"
+	}
+
+	local, domain, _ := strings.Cut(parsed.Address, "@")
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + domain
+}
+
+func redactAddresses(addrs []string) []string {
+	if addrs == nil {
+		return nil
+	}
+	redacted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		redacted[i] = RedactAddress(addr)
+	}
+	return redacted
+}
+
+var _ slog.LogValuer = Email{}
+
+// LogValue logs the Redacted email, so passing an Email to slog never
+// leaks recipients or content.
+func (e Email) LogValue() slog.Value {
+	r := e.Redacted()
+
+	attrs := []slog.Attr{
+		slog.String("from", r.FromAddress),
+		slog.Any("to", r.ToAddresses),
+	}
+	if len(r.CCAddresses) > 0 {
+		attrs = append(attrs, slog.Any("cc", r.CCAddresses))
+	}
+	if len(r.BCCAddresses) > 0 {
+		attrs = append(attrs, slog.Any("bcc", r.BCCAddresses))
+	}
+	attrs = append(attrs, slog.String("subject", r.Subject))
+	if r.TextBody != "" {
+		attrs = append(attrs, slog.String("text_body", r.TextBody))
+	}
+	if r.HTMLBody != "" {
+		attrs = append(attrs, slog.String("html_body", r.HTMLBody))
+	}
+	if len(r.Attachments) > 0 {
+		attachments := make([]string, len(r.Attachments))
+		for i, a := range r.Attachments {
+			attachments[i] = fmt.Sprintf("%s %s", a.FileName, a.Content)
+		}
+		attrs = append(attrs, slog.Any("attachments", attachments))
+	}
+	if r.MessageID != "" {
+		attrs = append(attrs, slog.String("message_id", r.MessageID))
+	}
+	if r.CampaignID != "" {
+		attrs = append(attrs, slog.String("campaign_id", r.CampaignID), slog.Int("sequence_step", r.SequenceStep))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "jane@example.com", expected: "j***@example.com"},
+		{addr: "Jane Doe <jane.doe@example.com>", expected: "j***@example.com"},
+		{addr: "élodie@example.fr", expected: "é***@example.fr"},
+		{addr: "not an address", expected: "***"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := RedactAddress(tc.addr); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	e := Email{
+		FromAddress:  "events@icaa.org",
+		ToAddresses:  []string{"Jane Doe <jane@example.com>"},
+		BCCAddresses: []string{"coach@example.com"},
+		Subject:      "Registration",
+		TextBody:     strings.Repeat("private ", 20),
+		HTMLBody:     "<p>Hi Jane</p>",
+		Attachments: []Attachment{
+			{FileName: "waiver.pdf", Content: []byte("%PDF-1.7 signed")},
+			{FileName: "logo.png", Ref: "s3://bucket/logo.png"},
+		},
+		FallbackFor: "jane@old.example.com",
+	}
+
+	r := e.Redacted(WithRedactedBodyLength(10))
+
+	if r.ToAddresses[0] != "j***@example.com" || r.BCCAddresses[0] != "c***@example.com" || r.FallbackFor != "j***@old.example.com" {
+		t.Errorf("expected recipients to be masked, got %v %v %q", r.ToAddresses, r.BCCAddresses, r.FallbackFor)
+	}
+	if r.FromAddress != e.FromAddress || r.Subject != e.Subject {
+		t.Error("expected the sender and subject to be kept")
+	}
+	if r.TextBody != "private p…" || r.HTMLBody != "<p>Hi Jan…" {
+		t.Errorf("expected bodies truncated to 10 characters, got %q and %q", r.TextBody, r.HTMLBody)
+	}
+	if len(r.Attachments) != 2 || r.Attachments[0].FileName != "waiver.pdf" || string(r.Attachments[0].Content) != "<15 bytes>" {
+		t.Errorf("expected attachment content replaced by its size, got %+v", r.Attachments)
+	}
+	if r.Attachments[1].Content != nil || r.Attachments[1].Ref != "s3://bucket/logo.png" {
+		t.Errorf("expected the unresolved attachment to be kept, got %+v", r.Attachments[1])
+	}
+
+	if e.ToAddresses[0] != "Jane Doe <jane@example.com>" || string(e.Attachments[0].Content) != "%PDF-1.7 signed" {
+		t.Error("expected the original email to be left alone")
+	}
+}
+
+func TestEmail_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logger.Info("send failed", "email", Email{
+		FromAddress: "events@icaa.org",
+		ToAddresses: []string{"jane@example.com"},
+		Subject:     "Registration",
+		TextBody:    "Your card ending 4242 " + strings.Repeat("was charged. ", 20),
+		Attachments: []Attachment{{FileName: "receipt.pdf", Content: []byte("secret receipt")}},
+	})
+
+	out := buf.String()
+	for _, leaked := range []string{"jane@", "secret receipt", strings.Repeat("was charged. ", 10)} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q not to be logged, got %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{`"to":["j***@example.com"]`, `"subject":"Registration"`, "receipt.pdf <14 bytes>"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %s to be logged, got %s", kept, out)
+		}
+	}
+}
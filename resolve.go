@@ -0,0 +1,279 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxResolvedAttachmentBytes is the largest attachment ResolvingSender
+// fetches unless WithMaxResolvedAttachmentBytes is given.
+const DefaultMaxResolvedAttachmentBytes = 10 * 1024 * 1024
+
+// AttachmentResolver fetches the content an Attachment.Ref points to.
+type AttachmentResolver interface {
+	Open(ctx context.Context, ref *url.URL) (io.ReadCloser, error)
+}
+
+// FSResolver resolves references against fsys, reading <scheme>://dir/file
+// as dir/file.
+func FSResolver(fsys fs.FS) AttachmentResolver {
+	return fsResolver{fsys}
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+func (r fsResolver) Open(ctx context.Context, ref *url.URL) (io.ReadCloser, error) {
+	return r.fsys.Open(strings.TrimPrefix(ref.Host+ref.Path, "/"))
+}
+
+type HTTPResolverOption func(*httpResolver)
+
+func WithResolverHTTPClient(client *http.Client) HTTPResolverOption {
+	return func(r *httpResolver) {
+		r.client = client
+	}
+}
+
+// WithAllowedContentTypes only accepts responses of the given media types,
+// e.g. "application/pdf", or of any subtype with "image/*". Any type is
+// accepted by default.
+func WithAllowedContentTypes(types ...string) HTTPResolverOption {
+	return func(r *httpResolver) {
+		r.contentTypes = types
+	}
+}
+
+// HTTPResolver resolves http and https references with a GET request, using
+// http.DefaultClient unless WithResolverHTTPClient is given. Like
+// ImageInliner, it only fetches from allowedHosts, so queued emails can't
+// make the sender reach arbitrary URLs. Register it for each scheme with
+// WithAttachmentResolver.
+func HTTPResolver(allowedHosts []string, opts ...HTTPResolverOption) AttachmentResolver {
+	r := &httpResolver{client: http.DefaultClient}
+	for _, h := range allowedHosts {
+		r.allowedHosts = append(r.allowedHosts, strings.ToLower(h))
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// Every redirect is checked against allowedHosts too, on a copy so the
+	// caller's client is left alone.
+	client := *r.client
+	next := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !r.allowed(req.URL) {
+			return fmt.Errorf("redirect to host %s is not allowed", req.URL.Hostname())
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	r.client = &client
+	return r
+}
+
+type httpResolver struct {
+	client       *http.Client
+	allowedHosts []string
+	contentTypes []string
+}
+
+func (r *httpResolver) Open(ctx context.Context, ref *url.URL) (io.ReadCloser, error) {
+	if !r.allowed(ref) {
+		return nil, fmt.Errorf("host %s is not allowed", ref.Hostname())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if len(r.contentTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if !contentTypeAllowed(mediaType, r.contentTypes) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("content type %q is not allowed", resp.Header.Get("Content-Type"))
+		}
+	}
+
+	return resp.Body, nil
+}
+
+func (r *httpResolver) allowed(u *url.URL) bool {
+	return slices.Contains(r.allowedHosts, strings.ToLower(u.Hostname()))
+}
+
+func contentTypeAllowed(mediaType string, allowed []string) bool {
+	for _, t := range allowed {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+type ResolveOption func(*ResolvingSender)
+
+// WithAttachmentResolver resolves references with the given URL scheme, such
+// as "s3", using r.
+func WithAttachmentResolver(scheme string, r AttachmentResolver) ResolveOption {
+	return func(s *ResolvingSender) {
+		s.resolvers[scheme] = r
+	}
+}
+
+// WithMaxResolvedAttachmentBytes replaces DefaultMaxResolvedAttachmentBytes.
+// Either is lowered to the inner sender's MaxMessageSize, if it reports a
+// smaller one.
+func WithMaxResolvedAttachmentBytes(n int64) ResolveOption {
+	return func(s *ResolvingSender) {
+		s.maxBytes = n
+	}
+}
+
+var _ Sender = &ResolvingSender{}
+var _ SenderV2 = &ResolvingSender{}
+
+// ResolvingSender decorates a SenderV2 to fetch the content of attachments
+// that only carry a Ref, so queued emails can reference large files instead
+// of embedding them. Downloads stop as soon as they exceed the size limit,
+// and failures to fetch are validation errors naming the reference, as
+// retrying the send won't make a bad reference work.
+type ResolvingSender struct {
+	inner     SenderV2
+	resolvers map[string]AttachmentResolver
+	maxBytes  int64
+}
+
+func NewResolvingSender(inner SenderV2, opts ...ResolveOption) *ResolvingSender {
+	s := &ResolvingSender{
+		inner:     inner,
+		resolvers: map[string]AttachmentResolver{},
+		maxBytes:  DefaultMaxResolvedAttachmentBytes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// Content the provider can't send anyway isn't worth downloading.
+	if caps, ok := CapabilitiesOf(AsSender(inner)); ok && caps.MaxMessageSize > 0 && int64(caps.MaxMessageSize) < s.maxBytes {
+		s.maxBytes = int64(caps.MaxMessageSize)
+	}
+	return s
+}
+
+func (s *ResolvingSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *ResolvingSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *ResolvingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	e, err = s.ResolveAttachments(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
+
+// ResolveAttachments returns a copy of e with the content of every
+// attachment that has a Ref and no Content fetched.
+func (s *ResolvingSender) ResolveAttachments(ctx context.Context, e Email) (Email, error) {
+	var resolved []Attachment
+	for i, a := range e.Attachments {
+		if a.Ref == "" || a.Content != nil {
+			continue
+		}
+
+		content, err := s.resolve(ctx, a)
+		if err != nil {
+			return Email{}, err
+		}
+
+		if resolved == nil {
+			resolved = make([]Attachment, len(e.Attachments))
+			copy(resolved, e.Attachments)
+		}
+		resolved[i].Content = content
+	}
+
+	if resolved != nil {
+		e.Attachments = resolved
+	}
+	return e, nil
+}
+
+func (s *ResolvingSender) resolve(ctx context.Context, a Attachment) ([]byte, error) {
+	ref, err := url.Parse(a.Ref)
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid reference %s for attachment %s", a.Ref, a.FileName), err)
+	}
+
+	resolver, ok := s.resolvers[ref.Scheme]
+	if !ok {
+		return nil, NewValidationError(fmt.Sprintf("no resolver for %s references (attachment %s)", ref.Scheme, a.FileName), nil)
+	}
+
+	rc, err := resolver.Open(ctx, ref)
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("failed to fetch attachment %s from %s", a.FileName, a.Ref), err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, s.maxBytes+1))
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("failed to fetch attachment %s from %s", a.FileName, a.Ref), err)
+	}
+	if int64(len(content)) > s.maxBytes {
+		return nil, NewValidationError(fmt.Sprintf("attachment %s from %s is larger than %d bytes", a.FileName, a.Ref, s.maxBytes), nil)
+	}
+
+	if a.SHA256 != "" {
+		want, err := hex.DecodeString(a.SHA256)
+		if err != nil {
+			return nil, NewValidationError(fmt.Sprintf("invalid SHA256 for attachment %s", a.FileName), err)
+		}
+		if sum := sha256.Sum256(content); !bytes.Equal(sum[:], want) {
+			return nil, NewValidationError(fmt.Sprintf("attachment %s from %s does not match its SHA256", a.FileName, a.Ref), nil)
+		}
+	}
+
+	return content, nil
+}
@@ -0,0 +1,190 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolvingSender(t *testing.T) {
+	roster := []byte("name,club\nAda,North\n")
+	sum := sha256.Sum256(roster)
+
+	fsys := fstest.MapFS{
+		"rosters/2026.csv": {Data: roster},
+		"large.bin":        {Data: make([]byte, 2048)},
+	}
+
+	tests := []struct {
+		name           string
+		attachment     Attachment
+		expectedReason ErrorReason
+	}{
+		{
+			name:       "resolved",
+			attachment: Attachment{FileName: "roster.csv", Ref: "test://rosters/2026.csv"},
+		},
+		{
+			name:       "checksum verified",
+			attachment: Attachment{FileName: "roster.csv", Ref: "test://rosters/2026.csv", SHA256: hex.EncodeToString(sum[:])},
+		},
+		{
+			name:           "checksum mismatch",
+			attachment:     Attachment{FileName: "roster.csv", Ref: "test://rosters/2026.csv", SHA256: hex.EncodeToString(make([]byte, 32))},
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "too large",
+			attachment:     Attachment{FileName: "large.bin", Ref: "test://large.bin"},
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "missing object",
+			attachment:     Attachment{FileName: "roster.csv", Ref: "test://rosters/2025.csv"},
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+		{
+			name:           "unknown scheme",
+			attachment:     Attachment{FileName: "roster.csv", Ref: "gs://rosters/2026.csv"},
+			expectedReason: REASON_VALIDATION_ERROR,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingSender{}
+			sender := NewResolvingSender(AsSenderV2(inner),
+				WithAttachmentResolver("test", FSResolver(fsys)),
+				WithMaxResolvedAttachmentBytes(1024),
+			)
+
+			e := Email{Subject: "Rosters", Attachments: []Attachment{tt.attachment}}
+			err := sender.SendEmail(context.Background(), e)
+
+			if tt.expectedReason != "" {
+				var emailErr *Error
+				if !errors.As(err, &emailErr) || emailErr.Reason != tt.expectedReason {
+					t.Fatalf("expected %s, got %v", tt.expectedReason, err)
+				}
+				if len(inner.sent) != 0 {
+					t.Errorf("expected nothing to be sent, got %d emails", len(inner.sent))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(inner.sent) != 1 || string(inner.sent[0].Attachments[0].Content) != string(roster) {
+				t.Fatalf("expected the resolved roster to be sent, got %+v", inner.sent)
+			}
+			if e.Attachments[0].Content != nil {
+				t.Error("original email was modified")
+			}
+		})
+	}
+}
+
+func TestResolvingSender_HTTP(t *testing.T) {
+	report := []byte("%PDF-1.7 report")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(report)
+	})
+	mux.HandleFunc("/large.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(bytes.Repeat([]byte{'x'}, 4096))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<p>Sign in</p>"))
+	})
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(report)
+	}))
+	t.Cleanup(internal.Close)
+	internalURL, _ := url.Parse(internal.URL)
+	// Same listener, but a host name that isn't allowed.
+	internalURL.Host = "localhost:" + internalURL.Port()
+	mux.HandleFunc("/moved.pdf", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/report.pdf", http.StatusFound)
+	})
+	mux.HandleFunc("/internal.pdf", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internalURL.String()+"/secret.pdf", http.StatusFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+
+	client := srv.Client()
+	resolver := HTTPResolver([]string{u.Hostname()},
+		WithResolverHTTPClient(client),
+		WithAllowedContentTypes("application/pdf", "image/*"),
+	)
+
+	tests := []struct {
+		name           string
+		ref            string
+		expectedReason ErrorReason
+	}{
+		{name: "fetched", ref: srv.URL + "/report.pdf"},
+		{name: "redirect to allowed host", ref: srv.URL + "/moved.pdf"},
+		{name: "redirect to disallowed host", ref: srv.URL + "/internal.pdf", expectedReason: REASON_VALIDATION_ERROR},
+		{name: "not found", ref: srv.URL + "/missing.pdf", expectedReason: REASON_VALIDATION_ERROR},
+		{name: "content type not allowed", ref: srv.URL + "/login", expectedReason: REASON_VALIDATION_ERROR},
+		{name: "larger than provider limit", ref: srv.URL + "/large.pdf", expectedReason: REASON_VALIDATION_ERROR},
+		{name: "host not allowed", ref: "http://reports.example.com/report.pdf", expectedReason: REASON_VALIDATION_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &capableSender{capabilities: Capabilities{MaxMessageSize: 1024}}
+			sender := NewResolvingSender(AsSenderV2(provider), WithAttachmentResolver("http", resolver))
+
+			e := Email{Subject: "Report", Attachments: []Attachment{{FileName: "report.pdf", Ref: tt.ref}}}
+			err := sender.SendEmail(context.Background(), e)
+
+			if tt.expectedReason != "" {
+				var emailErr *Error
+				if !errors.As(err, &emailErr) || emailErr.Reason != tt.expectedReason {
+					t.Fatalf("expected %s, got %v", tt.expectedReason, err)
+				}
+				if !strings.Contains(err.Error(), tt.ref) {
+					t.Errorf("expected the error to name %s, got %v", tt.ref, err)
+				}
+				if len(provider.sent) != 0 {
+					t.Errorf("expected nothing to be sent, got %d emails", len(provider.sent))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(provider.sent) != 1 || !bytes.Equal(provider.sent[0].Attachments[0].Content, report) {
+				t.Fatalf("expected the report to be sent, got %+v", provider.sent)
+			}
+		})
+	}
+
+	if internalHits.Load() != 0 {
+		t.Errorf("expected the disallowed host to never be reached, got %d requests", internalHits.Load())
+	}
+	if client.CheckRedirect != nil {
+		t.Error("expected the caller's client to be left unmodified")
+	}
+}
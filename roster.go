@@ -0,0 +1,197 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// DefaultMaxGroupExpansion is the most addresses RosterSender expands the
+// groups of one email to unless WithMaxGroupExpansion is given.
+const DefaultMaxGroupExpansion = 500
+
+// GroupResolver maps a symbolic group, such as "role:captains" or "team:42",
+// to its members. Members may be addresses or other groups.
+type GroupResolver interface {
+	Members(ctx context.Context, group string) ([]string, error)
+}
+
+// IsGroup reports whether recipient names a group rather than an address:
+// a "kind:name" pair without an @.
+func IsGroup(recipient string) bool {
+	kind, name, ok := strings.Cut(recipient, ":")
+	return ok && kind != "" && name != "" && !strings.Contains(recipient, "@")
+}
+
+var _ GroupResolver = Roster{}
+
+// Roster is an in-memory GroupResolver.
+type Roster map[string][]string
+
+func (r Roster) Members(ctx context.Context, group string) ([]string, error) {
+	members, ok := r[group]
+	if !ok {
+		return nil, NewValidationError(fmt.Sprintf("unknown group %s", group), nil)
+	}
+	return members, nil
+}
+
+type RosterOption func(*RosterSender)
+
+// WithMaxGroupExpansion replaces DefaultMaxGroupExpansion.
+func WithMaxGroupExpansion(n int) RosterOption {
+	return func(s *RosterSender) {
+		s.maxExpansion = n
+	}
+}
+
+var _ Sender = &RosterSender{}
+var _ SenderV2 = &RosterSender{}
+
+// RosterSender decorates a SenderV2 to replace groups among the recipients
+// with their members, so call sites can address "role:board" instead of
+// passing address lists around. Nested groups are expanded too. An address
+// reached more than once is only sent to once, in the first of To, CC and
+// BCC it appears in.
+type RosterSender struct {
+	inner        SenderV2
+	resolver     GroupResolver
+	maxExpansion int
+}
+
+func NewRosterSender(inner SenderV2, resolver GroupResolver, opts ...RosterOption) *RosterSender {
+	s := &RosterSender{
+		inner:        inner,
+		resolver:     resolver,
+		maxExpansion: DefaultMaxGroupExpansion,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RosterSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *RosterSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *RosterSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	e, err = s.ExpandRecipients(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inner.SendEmailV2(ctx, e, wrapped)
+}
+
+// ExpandRecipients returns a copy of e with every group in To, CC and BCC
+// replaced by its members.
+func (s *RosterSender) ExpandRecipients(ctx context.Context, e Email) (Email, error) {
+	x := expansion{sender: s, seen: map[string]bool{}}
+
+	var err error
+	if e.ToAddresses, err = x.list(ctx, e.ToAddresses); err != nil {
+		return Email{}, err
+	}
+	if e.CCAddresses, err = x.list(ctx, e.CCAddresses); err != nil {
+		return Email{}, err
+	}
+	if e.BCCAddresses, err = x.list(ctx, e.BCCAddresses); err != nil {
+		return Email{}, err
+	}
+	return e, nil
+}
+
+type expansion struct {
+	sender *RosterSender
+	// Addresses already added, lower-cased, mapped to whether a group added
+	// them.
+	seen map[string]bool
+	// Addresses added from groups.
+	expanded int
+}
+
+func (x *expansion) list(ctx context.Context, recipients []string) ([]string, error) {
+	var out []string
+	for _, r := range recipients {
+		if !IsGroup(r) {
+			// Explicit addresses are kept as given, even if repeated, for
+			// validation to judge, unless an earlier group already added
+			// them.
+			key := addressKey(r)
+			fromGroup, ok := x.seen[key]
+			if fromGroup {
+				continue
+			}
+			if !ok {
+				x.seen[key] = false
+			}
+			out = append(out, r)
+			continue
+		}
+
+		var err error
+		if out, err = x.group(ctx, r, nil, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (x *expansion) group(ctx context.Context, group string, path []string, out []string) ([]string, error) {
+	for i, g := range path {
+		if g == group {
+			return nil, NewValidationError(fmt.Sprintf("group %s contains itself: %s", group, strings.Join(path[i:], " -> ")+" -> "+group), nil)
+		}
+	}
+	path = append(path, group)
+
+	members, err := x.sender.resolver.Members(ctx, group)
+	if err != nil {
+		var emailErr *Error
+		if errors.As(err, &emailErr) {
+			return nil, err
+		}
+		return nil, NewServiceError(fmt.Sprintf("failed to resolve group %s", group), err)
+	}
+
+	for _, m := range members {
+		if IsGroup(m) {
+			if out, err = x.group(ctx, m, path, out); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key := addressKey(m)
+		if _, ok := x.seen[key]; ok {
+			continue
+		}
+		x.seen[key] = true
+
+		x.expanded++
+		if x.expanded > x.sender.maxExpansion {
+			return nil, NewValidationError(fmt.Sprintf("groups expand to more than %d recipients", x.sender.maxExpansion), nil)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func addressKey(recipient string) string {
+	if a, err := mail.ParseAddress(recipient); err == nil {
+		return strings.ToLower(a.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(recipient))
+}
@@ -0,0 +1,165 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsGroup(t *testing.T) {
+	tests := []struct {
+		recipient string
+		want      bool
+	}{
+		{"role:captains", true},
+		{"team:42", true},
+		{"ada@example.com", false},
+		{"Ada <ada@example.com>", false},
+		{`"Team: North" <north@example.com>`, false},
+		{"role:", false},
+		{":captains", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsGroup(tt.recipient); got != tt.want {
+			t.Errorf("IsGroup(%q) = %v, want %v", tt.recipient, got, tt.want)
+		}
+	}
+}
+
+func TestRosterSender(t *testing.T) {
+	roster := Roster{
+		"role:board":    {"ada@example.com", "bo@example.com"},
+		"role:captains": {"Cy <cy@example.com>", "team:42"},
+		"team:42":       {"dee@example.com", "ADA@example.com"},
+		"role:cycle":    {"x@example.com", "role:cycle2"},
+		"role:cycle2":   {"role:cycle"},
+		"role:self":     {"role:self"},
+	}
+
+	tests := []struct {
+		name      string
+		email     Email
+		max       int
+		wantTo    []string
+		wantCC    []string
+		wantBCC   []string
+		wantError string
+	}{
+		{
+			name:   "plain addresses are untouched",
+			email:  Email{ToAddresses: []string{"ada@example.com", "ada@example.com"}},
+			wantTo: []string{"ada@example.com", "ada@example.com"},
+		},
+		{
+			name:   "group",
+			email:  Email{ToAddresses: []string{"role:board"}},
+			wantTo: []string{"ada@example.com", "bo@example.com"},
+		},
+		{
+			name:   "nested group",
+			email:  Email{ToAddresses: []string{"role:captains"}},
+			wantTo: []string{"Cy <cy@example.com>", "dee@example.com", "ADA@example.com"},
+		},
+		{
+			name: "duplicates across lists go to the first",
+			email: Email{
+				ToAddresses:  []string{"role:board"},
+				CCAddresses:  []string{"role:captains"},
+				BCCAddresses: []string{"team:42"},
+			},
+			wantTo: []string{"ada@example.com", "bo@example.com"},
+			wantCC: []string{"Cy <cy@example.com>", "dee@example.com"},
+		},
+		{
+			name:   "explicit address suppresses group member",
+			email:  Email{ToAddresses: []string{"Bo <bo@example.com>", "role:board"}},
+			wantTo: []string{"Bo <bo@example.com>", "ada@example.com"},
+		},
+		{
+			name:   "group member suppresses later explicit address",
+			email:  Email{ToAddresses: []string{"role:board", "Bo <bo@example.com>"}, BCCAddresses: []string{"ADA@example.com"}},
+			wantTo: []string{"ada@example.com", "bo@example.com"},
+		},
+		{
+			name:      "cycle",
+			email:     Email{ToAddresses: []string{"role:cycle"}},
+			wantError: "role:cycle -> role:cycle2 -> role:cycle",
+		},
+		{
+			name:      "self reference",
+			email:     Email{ToAddresses: []string{"role:self"}},
+			wantError: "role:self -> role:self",
+		},
+		{
+			name:      "unknown group",
+			email:     Email{ToAddresses: []string{"role:nobody"}},
+			wantError: "unknown group role:nobody",
+		},
+		{
+			name:      "cap",
+			email:     Email{ToAddresses: []string{"role:board", "role:captains"}},
+			max:       3,
+			wantError: "more than 3 recipients",
+		},
+		{
+			name:   "duplicates do not count towards the cap",
+			email:  Email{ToAddresses: []string{"role:board", "role:board", "team:42"}},
+			max:    3,
+			wantTo: []string{"ada@example.com", "bo@example.com", "dee@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []RosterOption
+			if tt.max > 0 {
+				opts = append(opts, WithMaxGroupExpansion(tt.max))
+			}
+			inner := &recordingSender{}
+			sender := NewRosterSender(AsSenderV2(inner), roster, opts...)
+
+			err := sender.SendEmail(context.Background(), tt.email)
+
+			if tt.wantError != "" {
+				var emailErr *Error
+				if !errors.As(err, &emailErr) || emailErr.Reason != REASON_VALIDATION_ERROR || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("expected validation error containing %q, got %v", tt.wantError, err)
+				}
+				if len(inner.sent) != 0 {
+					t.Errorf("expected no sends, got %d", len(inner.sent))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sent := inner.sent[0]
+			if !slices.Equal(sent.ToAddresses, tt.wantTo) || !slices.Equal(sent.CCAddresses, tt.wantCC) || !slices.Equal(sent.BCCAddresses, tt.wantBCC) {
+				t.Errorf("expected To %v CC %v BCC %v, got To %v CC %v BCC %v",
+					tt.wantTo, tt.wantCC, tt.wantBCC, sent.ToAddresses, sent.CCAddresses, sent.BCCAddresses)
+			}
+		})
+	}
+}
+
+type failingResolver struct{}
+
+func (failingResolver) Members(ctx context.Context, group string) ([]string, error) {
+	return nil, fmt.Errorf("directory unavailable")
+}
+
+func TestRosterSender_ResolverError(t *testing.T) {
+	sender := NewRosterSender(AsSenderV2(&recordingSender{}), failingResolver{})
+
+	err := sender.SendEmail(context.Background(), Email{ToAddresses: []string{"role:board"}})
+
+	var emailErr *Error
+	if !errors.As(err, &emailErr) || emailErr.Reason != REASON_SERVICE_ERROR {
+		t.Errorf("expected service error, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSendIndexMaxEntries is how many sends a SendIndex keeps unless
+// WithIndexMaxEntries is given.
+const DefaultSendIndexMaxEntries = 10_000
+
+// IndexedSend is one send recorded by a SendIndex.
+type IndexedSend struct {
+	SentAt time.Time
+	// Bare, lower-case addresses of the To, CC and BCC recipients.
+	Recipients   []string
+	Subject      string
+	CampaignID   string
+	SequenceStep int
+	// Nil if the send failed.
+	Result *SendResult `json:",omitempty"`
+	// Empty if the send succeeded.
+	Reason ErrorReason `json:",omitempty"`
+	Error  string      `json:",omitempty"`
+}
+
+// size approximates the memory held by s, for WithIndexMaxBytes.
+func (s IndexedSend) size() int {
+	n := len(s.Subject) + len(s.CampaignID) + len(s.Reason) + len(s.Error)
+	for _, r := range s.Recipients {
+		n += len(r)
+	}
+	if s.Result != nil {
+		n += len(s.Result.Provider) + len(s.Result.ProviderMessageID) + len(s.Result.ProviderRequestID) +
+			len(s.Result.ThreadID) + len(s.Result.MessageID) + len(s.Result.ContentMode)
+	}
+	return n
+}
+
+type SendIndexOption func(*SendIndex)
+
+// WithIndexMaxEntries replaces DefaultSendIndexMaxEntries. Zero means
+// unbounded.
+func WithIndexMaxEntries(n int) SendIndexOption {
+	return func(s *SendIndex) {
+		s.maxEntries = n
+	}
+}
+
+// WithIndexMaxBytes bounds the approximate size of the recorded sends.
+// Unbounded by default.
+func WithIndexMaxBytes(n int) SendIndexOption {
+	return func(s *SendIndex) {
+		s.maxBytes = n
+	}
+}
+
+// WithIndexTTL forgets sends older than ttl. Sends are kept until evicted by
+// count or size by default.
+func WithIndexTTL(ttl time.Duration) SendIndexOption {
+	return func(s *SendIndex) {
+		s.ttl = ttl
+	}
+}
+
+// WithIndexClock sets the Clock used to timestamp sends and expire them.
+func WithIndexClock(c Clock) SendIndexOption {
+	return func(s *SendIndex) {
+		s.clock = c
+	}
+}
+
+// SendIndex keeps recent sends in memory so they can be looked up by
+// recipient, campaign or failure reason from the running process, e.g.
+// during an incident. It is filled by IndexingSender. When a bound is
+// exceeded the oldest sends are evicted first. It is safe for concurrent use.
+type SendIndex struct {
+	maxEntries int
+	maxBytes   int
+	ttl        time.Duration
+	clock      Clock
+
+	mu      sync.RWMutex
+	entries []IndexedSend
+	bytes   int
+}
+
+func NewSendIndex(opts ...SendIndexOption) *SendIndex {
+	s := &SendIndex{
+		maxEntries: DefaultSendIndexMaxEntries,
+		clock:      SystemClock(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Record adds the outcome of sending e.
+func (s *SendIndex) Record(e Email, result *SendResult, err error) {
+	entry := IndexedSend{
+		SentAt:       s.clock.Now(),
+		Subject:      e.Subject,
+		CampaignID:   e.CampaignID,
+		SequenceStep: e.SequenceStep,
+	}
+	if result != nil {
+		copied := *result
+		entry.Result = &copied
+	}
+	for _, addrs := range [][]string{e.ToAddresses, e.CCAddresses, e.BCCAddresses} {
+		for _, addr := range addrs {
+			entry.Recipients = append(entry.Recipients, indexAddress(addr))
+		}
+	}
+	if err != nil {
+		emailErr := asError(err)
+		entry.Result = nil
+		entry.Reason = emailErr.Reason
+		entry.Error = emailErr.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	s.bytes += entry.size()
+	s.evict(entry.SentAt)
+}
+
+// evict drops expired sends, then the oldest ones until the index is within
+// its bounds again.
+func (s *SendIndex) evict(now time.Time) {
+	n := 0
+	for n < len(s.entries) {
+		over := (s.maxEntries > 0 && len(s.entries)-n > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+		if !over && !s.expired(s.entries[n], now) {
+			break
+		}
+		s.bytes -= s.entries[n].size()
+		s.entries[n] = IndexedSend{}
+		n++
+	}
+	s.entries = s.entries[n:]
+}
+
+func (s *SendIndex) expired(entry IndexedSend, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(entry.SentAt) > s.ttl
+}
+
+// ByRecipient returns the sends to addr, as To, CC or BCC recipient, oldest
+// first. Addresses are compared case-insensitively, ignoring display names.
+func (s *SendIndex) ByRecipient(addr string) []IndexedSend {
+	addr = indexAddress(addr)
+	return s.query(func(entry IndexedSend) bool {
+		return slices.Contains(entry.Recipients, addr)
+	})
+}
+
+// ByCampaign returns the sends of the campaign, oldest first.
+func (s *SendIndex) ByCampaign(campaignID string) []IndexedSend {
+	return s.query(func(entry IndexedSend) bool {
+		return entry.CampaignID == campaignID
+	})
+}
+
+// ByReason returns the sends that failed with reason, oldest first.
+func (s *SendIndex) ByReason(reason ErrorReason) []IndexedSend {
+	return s.query(func(entry IndexedSend) bool {
+		return entry.Reason == reason
+	})
+}
+
+// Len returns the number of sends in the index.
+func (s *SendIndex) Len() int {
+	return len(s.query(func(IndexedSend) bool { return true }))
+}
+
+// WriteJSON writes every send in the index to w as a JSON array, oldest
+// first.
+func (s *SendIndex) WriteJSON(w io.Writer) error {
+	entries := s.query(func(IndexedSend) bool { return true })
+	if entries == nil {
+		entries = []IndexedSend{}
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// query returns the unexpired sends that match. Expired sends are only
+// dropped by Record, so queries never need the write lock.
+func (s *SendIndex) query(match func(IndexedSend) bool) []IndexedSend {
+	now := s.clock.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []IndexedSend
+	for _, entry := range s.entries {
+		if !s.expired(entry, now) && match(entry) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+func indexAddress(addr string) string {
+	if a, err := ParseAddress(addr); err == nil {
+		addr = a.Address
+	}
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+var _ Sender = &IndexingSender{}
+var _ SenderV2 = &IndexingSender{}
+
+// IndexingSender decorates a SenderV2 to record every send, successful or
+// not, in a SendIndex.
+type IndexingSender struct {
+	inner SenderV2
+	index *SendIndex
+}
+
+func NewIndexingSender(inner SenderV2, index *SendIndex) *IndexingSender {
+	return &IndexingSender{inner: inner, index: index}
+}
+
+func (s *IndexingSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *IndexingSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *IndexingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	result, err := s.inner.SendEmailV2(ctx, e, wrapped)
+	s.index.Record(e, result, err)
+	return result, err
+}
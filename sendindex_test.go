@@ -0,0 +1,176 @@
+package email_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/International-Combat-Archery-Alliance/email"
+	"github.com/International-Combat-Archery-Alliance/email/emailtest"
+)
+
+// reasonSenderV2 fails sends whose subject is a known error reason.
+type reasonSenderV2 struct{}
+
+func (reasonSenderV2) SendEmailV2(ctx context.Context, e email.Email, opts *email.SendOptions) (*email.SendResult, error) {
+	switch email.ErrorReason(e.Subject) {
+	case email.REASON_INVALID_EMAIL:
+		return nil, email.NewInvalidEmailError("mailbox does not exist", nil)
+	case email.REASON_RATE_LIMITED:
+		return nil, email.NewRateLimitedError("slow down", nil)
+	}
+	return &email.SendResult{Provider: "test", MessageID: "<" + e.Subject + "@example.com>"}, nil
+}
+
+func indexSubjects(sends []email.IndexedSend) []string {
+	subjects := make([]string, len(sends))
+	for i, s := range sends {
+		subjects[i] = s.Subject
+	}
+	return subjects
+}
+
+func TestSendIndex_Queries(t *testing.T) {
+	ctx := context.Background()
+	index := email.NewSendIndex()
+	sender := email.NewIndexingSender(reasonSenderV2{}, index)
+
+	emails := []email.Email{
+		{ToAddresses: []string{"Ada <Ada@Example.com>"}, Subject: "welcome", CampaignID: "spring", SequenceStep: 1},
+		{ToAddresses: []string{"bo@example.com"}, CCAddresses: []string{"ada@example.com"}, Subject: "reminder", CampaignID: "spring", SequenceStep: 2},
+		{BCCAddresses: []string{"ada@example.com"}, Subject: string(email.REASON_INVALID_EMAIL)},
+		{ToAddresses: []string{"bo@example.com"}, Subject: string(email.REASON_RATE_LIMITED)},
+	}
+	for _, e := range emails {
+		sender.SendEmailV2(ctx, e, nil)
+	}
+
+	tests := []struct {
+		name     string
+		got      []email.IndexedSend
+		expected []string
+	}{
+		{"recipient", index.ByRecipient("ADA@example.COM"), []string{"welcome", "reminder", "INVALID_EMAIL"}},
+		{"recipient with name", index.ByRecipient("Bo <bo@example.com>"), []string{"reminder", "RATE_LIMITED"}},
+		{"unknown recipient", index.ByRecipient("cy@example.com"), nil},
+		{"campaign", index.ByCampaign("spring"), []string{"welcome", "reminder"}},
+		{"reason", index.ByReason(email.REASON_RATE_LIMITED), []string{"RATE_LIMITED"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexSubjects(tt.got); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	failed := index.ByReason(email.REASON_INVALID_EMAIL)[0]
+	if failed.Result != nil || failed.Error == "" {
+		t.Errorf("expected a failed send without result, got %+v", failed)
+	}
+	if sent := index.ByCampaign("spring")[1]; sent.Result == nil || sent.Result.MessageID != "<reminder@example.com>" || sent.SequenceStep != 2 {
+		t.Errorf("expected the send result to be recorded, got %+v", sent)
+	}
+}
+
+func TestSendIndex_Eviction(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	record := func(index *email.SendIndex, clock *emailtest.FakeClock, subjects ...string) {
+		for _, s := range subjects {
+			index.Record(email.Email{ToAddresses: []string{"ada@example.com"}, Subject: s}, &email.SendResult{}, nil)
+			clock.Advance(time.Minute)
+		}
+	}
+
+	t.Run("count", func(t *testing.T) {
+		clock := emailtest.NewFakeClock(start)
+		index := email.NewSendIndex(email.WithIndexMaxEntries(3), email.WithIndexClock(clock))
+		record(index, clock, "1", "2", "3", "4", "5")
+
+		if got := indexSubjects(index.ByRecipient("ada@example.com")); fmt.Sprint(got) != "[3 4 5]" {
+			t.Errorf("expected the oldest sends to be evicted, got %v", got)
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		clock := emailtest.NewFakeClock(start)
+		// Each send holds a 1 byte subject and a 15 byte address.
+		index := email.NewSendIndex(email.WithIndexMaxBytes(40), email.WithIndexClock(clock))
+		record(index, clock, "1", "2", "3", "4")
+
+		if got := indexSubjects(index.ByRecipient("ada@example.com")); fmt.Sprint(got) != "[3 4]" {
+			t.Errorf("expected the oldest sends to be evicted, got %v", got)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		clock := emailtest.NewFakeClock(start)
+		index := email.NewSendIndex(email.WithIndexTTL(2*time.Minute), email.WithIndexClock(clock))
+		record(index, clock, "1", "2", "3")
+
+		// 3 minutes after the first send, 1 minute after the last.
+		if got := indexSubjects(index.ByRecipient("ada@example.com")); fmt.Sprint(got) != "[2 3]" {
+			t.Errorf("expected expired sends to be hidden, got %v", got)
+		}
+		clock.Advance(time.Hour)
+		if n := index.Len(); n != 0 {
+			t.Errorf("expected every send to have expired, got %d", n)
+		}
+	})
+}
+
+func TestSendIndex_WriteJSON(t *testing.T) {
+	index := email.NewSendIndex(email.WithIndexClock(emailtest.NewFakeClock(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))))
+
+	var empty bytes.Buffer
+	if err := index.WriteJSON(&empty); err != nil || empty.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %q (%v)", empty.String(), err)
+	}
+
+	index.Record(email.Email{ToAddresses: []string{"ada@example.com"}, Subject: "welcome"}, nil, email.NewInvalidEmailError("no such mailbox", nil))
+
+	var buf bytes.Buffer
+	if err := index.WriteJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []email.IndexedSend
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("snapshot does not decode: %v", err)
+	}
+	if len(got) != 1 || got[0].Reason != email.REASON_INVALID_EMAIL || got[0].Recipients[0] != "ada@example.com" {
+		t.Errorf("unexpected snapshot %+v", got)
+	}
+}
+
+func TestSendIndex_ConcurrentQueries(t *testing.T) {
+	ctx := context.Background()
+	index := email.NewSendIndex(email.WithIndexMaxEntries(50))
+	sender := email.NewIndexingSender(reasonSenderV2{}, index)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				sender.SendEmailV2(ctx, email.Email{ToAddresses: []string{fmt.Sprintf("r%d@example.com", j%3)}, Subject: fmt.Sprintf("%d-%d", i, j)}, nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				index.ByRecipient("r1@example.com")
+				index.WriteJSON(&bytes.Buffer{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := index.Len(); n != 50 {
+		t.Errorf("expected the index to be full, got %d sends", n)
+	}
+}
@@ -0,0 +1,191 @@
+package email
+
+import "context"
+
+// SenderV2 is the extensible successor to Sender. New per-send behavior is
+// added to SendOptions and SendResult instead of growing the interface.
+type SenderV2 interface {
+	SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error)
+}
+
+// SendOptions tunes a single SendEmailV2 call. A nil *SendOptions is valid and
+// means the defaults.
+type SendOptions struct {
+	// Validate and build the message without handing it to the provider.
+	DryRun bool
+	// Identifies logically identical sends so decorators can suppress
+	// duplicates. Providers without native support ignore it.
+	IdempotencyKey string
+	// Applied to a copy of the email before it is validated.
+	Override func(e *Email)
+	Hooks    SendHooks
+}
+
+// SendHooks are called around the provider call of a SendEmailV2.
+type SendHooks struct {
+	// Called after validation, right before the provider call. Returning an
+	// error aborts the send with that error.
+	BeforeSend func(ctx context.Context, e Email) error
+	// Called once the send has finished, successfully or not.
+	AfterSend func(ctx context.Context, e Email, result *SendResult, err error)
+	// Called when the send panicked, with the error it was turned into. See
+	// RecoverSend.
+	OnPanic func(ctx context.Context, e Email, err *Error)
+}
+
+type SendResult struct {
+	// Name of the provider that handled the send, e.g. "ses" or "gmail".
+	Provider string
+	// ID the provider assigned to the message. Empty for dry runs.
+	ProviderMessageID string
+	// ID the provider assigned to the send request, for correlating with its
+	// logs and archives. Empty if the provider does not report one.
+	ProviderRequestID string
+	// Conversation the provider filed the message under. Only Gmail reports
+	// one.
+	ThreadID string
+	// The Message-ID header the message was sent with.
+	MessageID string
+	// How the message was handed to the provider, e.g. "simple" or "raw"
+	// for SES. Empty for providers with a single way.
+	ContentMode string
+	DryRun      bool
+	// Copied from the sent Email.
+	CampaignID   string
+	SequenceStep int
+	// Recipients rewritten by AutoCorrectSender before sending.
+	Corrections []AddressCorrection
+	// Size of the raw message, and of the request it was encoded into for
+	// the provider. Zero for providers that do not build the raw message.
+	MessageBytes int
+	EncodedBytes int
+	// Recipients FallbackSender replaced with an alternate address after
+	// they were rejected.
+	Fallbacks []AddressCorrection
+	// Inline images ShrinkingSender recompressed or turned into attachments.
+	ImageActions []ImageAction
+	// Results of each email when the recipients were split across several,
+	// in order, the zero value for those that failed. The other fields are
+	// those of the first sent.
+	Chunks []SendResult
+}
+
+// Apply returns e with the Override applied. The Override works on a deep
+// copy, see Email.Clone, so the caller's email is never modified, even by
+// changes to its slices in place.
+func (o *SendOptions) Apply(e Email) Email {
+	if o == nil || o.Override == nil {
+		return e
+	}
+
+	e = e.Clone()
+	o.Override(&e)
+	return e
+}
+
+// Forward returns e with the Override applied, and the options a decorator
+// passes on to the sender it wraps: o without the Override, which must not
+// be applied twice. The returned options are a copy, safe to modify.
+func (o *SendOptions) Forward(e Email) (Email, *SendOptions) {
+	var forwarded SendOptions
+	if o != nil {
+		forwarded = *o
+	}
+	forwarded.Override = nil
+	return o.Apply(e), &forwarded
+}
+
+func (o *SendOptions) IsDryRun() bool {
+	return o != nil && o.DryRun
+}
+
+func (o *SendOptions) RunBeforeSend(ctx context.Context, e Email) error {
+	if o == nil || o.Hooks.BeforeSend == nil {
+		return nil
+	}
+
+	return o.Hooks.BeforeSend(ctx, e)
+}
+
+func (o *SendOptions) RunAfterSend(ctx context.Context, e Email, result *SendResult, err error) {
+	if o == nil || o.Hooks.AfterSend == nil {
+		return
+	}
+
+	o.Hooks.AfterSend(ctx, e, result, err)
+}
+
+// AsSenderV2 returns s as a SenderV2. Senders that already implement SenderV2
+// are returned as is; others are wrapped, in which case a dry run skips the
+// send entirely since a Sender cannot validate without sending.
+func AsSenderV2(s Sender) SenderV2 {
+	if v2, ok := s.(SenderV2); ok {
+		return v2
+	}
+
+	if a, ok := s.(*v2Adapter); ok {
+		return a.sender
+	}
+
+	return &v1Adapter{sender: s}
+}
+
+// AsSender returns s as a Sender that sends with default options.
+func AsSender(s SenderV2) Sender {
+	if v1, ok := s.(Sender); ok {
+		return v1
+	}
+
+	if a, ok := s.(*v1Adapter); ok {
+		return a.sender
+	}
+
+	return &v2Adapter{sender: s}
+}
+
+type v1Adapter struct {
+	sender Sender
+}
+
+func (a *v1Adapter) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e = opts.Apply(e)
+
+	if err := opts.RunBeforeSend(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if opts.IsDryRun() {
+		result := &SendResult{DryRun: true}
+		opts.RunAfterSend(ctx, e, result, nil)
+		return result, nil
+	}
+
+	err = a.sender.SendEmail(ctx, e)
+	if err != nil {
+		opts.RunAfterSend(ctx, e, nil, err)
+		return nil, err
+	}
+
+	result := &SendResult{}
+	opts.RunAfterSend(ctx, e, result, nil)
+	return result, nil
+}
+
+// Shutdown shuts the wrapped Sender down if it owns background work.
+func (a *v1Adapter) Shutdown(ctx context.Context) error {
+	if sd, ok := a.sender.(Shutdowner); ok {
+		return sd.Shutdown(ctx)
+	}
+	return nil
+}
+
+type v2Adapter struct {
+	sender SenderV2
+}
+
+func (a *v2Adapter) SendEmail(ctx context.Context, e Email) error {
+	_, err := a.sender.SendEmailV2(ctx, e, nil)
+	return err
+}
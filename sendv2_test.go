@@ -0,0 +1,180 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingSender struct {
+	sent []Email
+	err  error
+}
+
+func (s *recordingSender) SendEmail(ctx context.Context, e Email) error {
+	s.sent = append(s.sent, e)
+	return s.err
+}
+
+type recordingSenderV2 struct {
+	opts []*SendOptions
+}
+
+func (s *recordingSenderV2) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	s.opts = append(s.opts, opts)
+	return &SendResult{Provider: "recording"}, nil
+}
+
+func TestAsSenderV2(t *testing.T) {
+	t.Run("applies override and hooks", func(t *testing.T) {
+		inner := &recordingSender{}
+		var afterResult *SendResult
+
+		_, err := AsSenderV2(inner).SendEmailV2(context.Background(), Email{Subject: "Original"}, &SendOptions{
+			Override: func(e *Email) { e.Subject = "Overridden" },
+			Hooks: SendHooks{
+				AfterSend: func(ctx context.Context, e Email, result *SendResult, err error) {
+					afterResult = result
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(inner.sent) != 1 || inner.sent[0].Subject != "Overridden" {
+			t.Errorf("expected one overridden send, got %+v", inner.sent)
+		}
+		if afterResult == nil {
+			t.Error("expected AfterSend to receive the result")
+		}
+	})
+
+	t.Run("dry run skips the send", func(t *testing.T) {
+		inner := &recordingSender{}
+
+		result, err := AsSenderV2(inner).SendEmailV2(context.Background(), Email{}, &SendOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(inner.sent) != 0 {
+			t.Errorf("expected no sends, got %d", len(inner.sent))
+		}
+		if !result.DryRun {
+			t.Error("expected a dry run result")
+		}
+	})
+
+	t.Run("BeforeSend error aborts", func(t *testing.T) {
+		inner := &recordingSender{}
+		hookErr := errors.New("blocked")
+
+		_, err := AsSenderV2(inner).SendEmailV2(context.Background(), Email{}, &SendOptions{
+			Hooks: SendHooks{
+				BeforeSend: func(ctx context.Context, e Email) error { return hookErr },
+			},
+		})
+
+		if !errors.Is(err, hookErr) {
+			t.Errorf("expected hook error, got %v", err)
+		}
+		if len(inner.sent) != 0 {
+			t.Errorf("expected no sends, got %d", len(inner.sent))
+		}
+	})
+
+	t.Run("send error is passed to AfterSend", func(t *testing.T) {
+		sendErr := NewServiceError("down", nil)
+		inner := &recordingSender{err: sendErr}
+		var afterErr error
+
+		_, err := AsSenderV2(inner).SendEmailV2(context.Background(), Email{}, &SendOptions{
+			Hooks: SendHooks{
+				AfterSend: func(ctx context.Context, e Email, result *SendResult, err error) {
+					afterErr = err
+				},
+			},
+		})
+
+		if err != sendErr || afterErr != sendErr {
+			t.Errorf("expected send error to be returned and passed to AfterSend, got %v and %v", err, afterErr)
+		}
+	})
+
+	t.Run("native V2 sender is returned as is", func(t *testing.T) {
+		inner := &recordingSenderV2{}
+
+		if AsSenderV2(AsSender(inner)) != SenderV2(inner) {
+			t.Error("expected round trip to unwrap to the original sender")
+		}
+	})
+}
+
+func TestAsSender(t *testing.T) {
+	inner := &recordingSenderV2{}
+
+	if err := AsSender(inner).SendEmail(context.Background(), Email{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inner.opts) != 1 || inner.opts[0] != nil {
+		t.Errorf("expected one send with default options, got %+v", inner.opts)
+	}
+}
+
+func TestSendOptions_Forward(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		var opts *SendOptions
+		e, forwarded := opts.Forward(Email{Subject: "Hello"})
+		if e.Subject != "Hello" || forwarded == nil || forwarded.Override != nil {
+			t.Errorf("expected the email as is and default options, got %+v and %+v", e, forwarded)
+		}
+	})
+
+	t.Run("override applied once", func(t *testing.T) {
+		opts := &SendOptions{
+			IdempotencyKey: "key-1",
+			Override:       func(e *Email) { e.Subject += "!" },
+		}
+		e, forwarded := opts.Forward(Email{Subject: "Hello"})
+
+		if e.Subject != "Hello!" {
+			t.Errorf("expected the override to be applied, got %q", e.Subject)
+		}
+		if forwarded.Override != nil || forwarded.IdempotencyKey != "key-1" {
+			t.Errorf("expected the options without the override, got %+v", forwarded)
+		}
+		forwarded.IdempotencyKey = "key-2"
+		if opts.Override == nil || opts.IdempotencyKey != "key-1" {
+			t.Error("expected the caller's options to be left alone")
+		}
+	})
+}
+
+func TestSendOptions_Apply(t *testing.T) {
+	original := Email{
+		ToAddresses: make([]string, 1, 4),
+		Headers:     []Header{{Name: "X-Team", Value: "north"}},
+		Tags:        map[string]string{"env": "prod"},
+		Attachments: []Attachment{{FileName: "roster.csv", Content: []byte("name")}},
+	}
+	original.ToAddresses[0] = "ada@example.com"
+
+	opts := &SendOptions{Override: func(e *Email) {
+		e.ToAddresses = append(e.ToAddresses, "bo@example.com")
+		e.ToAddresses[0] = "cy@example.com"
+		e.Headers[0].Value = "south"
+		e.Tags["env"] = "test"
+		e.Attachments[0].Content[0] = 'N'
+	}}
+	applied := opts.Apply(original)
+
+	if applied.ToAddresses[0] != "cy@example.com" || len(applied.ToAddresses) != 2 || applied.Tags["env"] != "test" {
+		t.Errorf("expected the override to be applied, got %+v", applied)
+	}
+	if original.ToAddresses[0] != "ada@example.com" || original.ToAddresses[:2][1] != "" ||
+		original.Headers[0].Value != "north" || original.Tags["env"] != "prod" || string(original.Attachments[0].Content) != "name" {
+		t.Errorf("expected the caller's email to be left alone, got %+v", original)
+	}
+}
@@ -0,0 +1,232 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// DefaultShrinkJPEGQuality is the quality ImageShrinker recompresses images
+// at unless WithJPEGQuality is given.
+const DefaultShrinkJPEGQuality = 75
+
+// ImageActionKind is what ImageShrinker did to an oversized image.
+type ImageActionKind string
+
+const (
+	// The image was recompressed and is now within the limit.
+	IMAGE_RECOMPRESSED ImageActionKind = "RECOMPRESSED"
+	// The image was still too large, so it was sent as a regular attachment
+	// and replaced with a placeholder in the HTML body.
+	IMAGE_DETACHED ImageActionKind = "DETACHED"
+)
+
+// ImageAction reports what ImageShrinker did to one inline image.
+type ImageAction struct {
+	Kind      ImageActionKind
+	ContentID string
+	// File name after the action, e.g. with a .jpg extension once a PNG was
+	// recompressed as JPEG.
+	FileName      string
+	OriginalBytes int
+	Bytes         int
+}
+
+type ShrinkOption func(*ImageShrinker)
+
+// WithJPEGQuality replaces DefaultShrinkJPEGQuality. It ranges from 1 to
+// 100.
+func WithJPEGQuality(quality int) ShrinkOption {
+	return func(s *ImageShrinker) {
+		s.quality = quality
+	}
+}
+
+// ImageShrinker keeps inline images, e.g. pasted screenshots, from pushing
+// messages past provider size limits. It is safe for concurrent use.
+type ImageShrinker struct {
+	maxBytes int
+	quality  int
+}
+
+// NewImageShrinker returns an ImageShrinker for inline images larger than
+// maxBytes.
+func NewImageShrinker(maxBytes int, opts ...ShrinkOption) *ImageShrinker {
+	s := &ImageShrinker{maxBytes: maxBytes, quality: DefaultShrinkJPEGQuality}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Shrink returns a copy of e whose inline images larger than the limit are
+// recompressed: JPEGs at the configured quality, opaque PNGs as JPEG and
+// other PNGs with the best compression. Images still over the limit, or in
+// formats that can't be recompressed, become regular attachments and their
+// <img> tags are replaced with a note pointing at the attachment.
+func (s *ImageShrinker) Shrink(e Email) (Email, []ImageAction, error) {
+	if e.HTMLBody == "" {
+		return e, nil, nil
+	}
+
+	var actions []ImageAction
+	detached := map[string]string{}
+	var attachments []Attachment
+	for _, a := range e.Attachments {
+		if a.ContentID == "" || len(a.Content) <= s.maxBytes {
+			attachments = append(attachments, a)
+			continue
+		}
+
+		original := len(a.Content)
+		if shrunk, ok := s.recompress(a); ok && len(shrunk.Content) < original {
+			a = shrunk
+		}
+		if len(a.Content) <= s.maxBytes {
+			actions = append(actions, ImageAction{Kind: IMAGE_RECOMPRESSED, ContentID: a.ContentID, FileName: a.FileName, OriginalBytes: original, Bytes: len(a.Content)})
+			attachments = append(attachments, a)
+			continue
+		}
+
+		actions = append(actions, ImageAction{Kind: IMAGE_DETACHED, ContentID: a.ContentID, FileName: a.FileName, OriginalBytes: original, Bytes: len(a.Content)})
+		detached[a.ContentID] = a.FileName
+		a.ContentID = ""
+		attachments = append(attachments, a)
+	}
+
+	if len(actions) == 0 {
+		return e, nil, nil
+	}
+
+	if len(detached) > 0 {
+		body, err := replaceDetachedImages(e.HTMLBody, detached)
+		if err != nil {
+			return Email{}, nil, err
+		}
+		e.HTMLBody = body
+	}
+	e.Attachments = attachments
+	return e, actions, nil
+}
+
+func (s *ImageShrinker) recompress(a Attachment) (Attachment, bool) {
+	img, format, err := image.Decode(bytes.NewReader(a.Content))
+	if err != nil {
+		return a, false
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case format == "jpeg" || format == "png" && isOpaque(img):
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: s.quality}); err != nil {
+			return a, false
+		}
+		if format == "png" {
+			a.FileName = strings.TrimSuffix(a.FileName, path.Ext(a.FileName)) + ".jpg"
+			a.ContentType = "image/jpeg"
+		}
+	case format == "png":
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(&buf, img); err != nil {
+			return a, false
+		}
+	default:
+		return a, false
+	}
+
+	a.Content = buf.Bytes()
+	return a, true
+}
+
+func isOpaque(img image.Image) bool {
+	o, ok := img.(interface{ Opaque() bool })
+	return ok && o.Opaque()
+}
+
+// replaceDetachedImages replaces the <img> tags referencing the detached
+// content IDs with a note naming the attachment.
+func replaceDetachedImages(body string, detached map[string]string) (string, error) {
+	var sb strings.Builder
+	z := xhtml.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			if z.Err() != io.EOF {
+				return "", NewValidationError("failed to parse HTML body", z.Err())
+			}
+			break
+		}
+
+		// Token lowercases the raw tag name in place, so copy it first.
+		raw := string(z.Raw())
+		if tt != xhtml.StartTagToken && tt != xhtml.SelfClosingTagToken {
+			sb.WriteString(raw)
+			continue
+		}
+
+		tok := z.Token()
+		src := slices.IndexFunc(tok.Attr, func(a xhtml.Attribute) bool { return a.Key == "src" })
+		if tok.Data != "img" || src < 0 {
+			sb.WriteString(raw)
+			continue
+		}
+		contentID, isCID := strings.CutPrefix(tok.Attr[src].Val, "cid:")
+		name, ok := detached[contentID]
+		if !isCID || !ok {
+			sb.WriteString(raw)
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("<em>[Image attached: %s]</em>", html.EscapeString(name)))
+	}
+	return sb.String(), nil
+}
+
+var _ Sender = &ShrinkingSender{}
+var _ SenderV2 = &ShrinkingSender{}
+
+// ShrinkingSender decorates a SenderV2 to run every email through an
+// ImageShrinker, reporting what it did in SendResult.ImageActions.
+type ShrinkingSender struct {
+	inner    SenderV2
+	shrinker *ImageShrinker
+}
+
+func NewShrinkingSender(inner SenderV2, shrinker *ImageShrinker) *ShrinkingSender {
+	return &ShrinkingSender{inner: inner, shrinker: shrinker}
+}
+
+func (s *ShrinkingSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *ShrinkingSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *ShrinkingSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	e, actions, err := s.shrinker.Shrink(e)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.inner.SendEmailV2(ctx, e, wrapped)
+	if result != nil && len(actions) > 0 {
+		result.ImageActions = append(result.ImageActions, actions...)
+	}
+	return result, err
+}
@@ -0,0 +1,145 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// noiseImage returns a width×height image of random pixels, which compresses
+// poorly as PNG.
+func noiseImage(width, height int, opaque bool) image.Image {
+	rng := rand.New(rand.NewPCG(1, 2))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			alpha := uint8(255)
+			if !opaque {
+				alpha = uint8(rng.IntN(256))
+			}
+			img.SetNRGBA(x, y, color.NRGBA{uint8(rng.IntN(256)), uint8(rng.IntN(256)), uint8(rng.IntN(256)), alpha})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageShrinker_Recompresses(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, noiseImage(400, 400, true), &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		attachment  Attachment
+		maxRatio    float64
+		fileName    string
+		contentType string
+	}{
+		{"opaque png becomes jpeg", Attachment{FileName: "shot.png", Content: encodePNG(t, noiseImage(400, 400, true)), ContentType: "image/png"}, 0.5, "shot.jpg", "image/jpeg"},
+		{"jpeg at lower quality", Attachment{FileName: "photo.jpg", Content: jpegBuf.Bytes(), ContentType: "image/jpeg"}, 0.8, "photo.jpg", "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.attachment
+			a.ContentID = "img@example.com"
+			original := len(a.Content)
+			e := Email{HTMLBody: `<p><img src="cid:img@example.com"></p>`, Attachments: []Attachment{a}}
+
+			got, actions, err := NewImageShrinker(original / 2).Shrink(e)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(actions) != 1 || actions[0].Kind != IMAGE_RECOMPRESSED {
+				t.Fatalf("expected the image to be recompressed, got %+v", actions)
+			}
+			shrunk := got.Attachments[0]
+			if ratio := float64(len(shrunk.Content)) / float64(original); ratio > tt.maxRatio {
+				t.Errorf("expected a ratio of at most %.2f, got %.2f", tt.maxRatio, ratio)
+			}
+			if actions[0].OriginalBytes != original || actions[0].Bytes != len(shrunk.Content) {
+				t.Errorf("unexpected sizes in %+v", actions[0])
+			}
+			if shrunk.FileName != tt.fileName || shrunk.ContentType != tt.contentType || shrunk.ContentID != "img@example.com" {
+				t.Errorf("unexpected attachment %s %s %s", shrunk.FileName, shrunk.ContentType, shrunk.ContentID)
+			}
+			if got.HTMLBody != e.HTMLBody {
+				t.Errorf("expected the HTML body to be unchanged, got %s", got.HTMLBody)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(shrunk.Content)); err != nil {
+				t.Errorf("recompressed image does not decode: %v", err)
+			}
+		})
+	}
+}
+
+func TestImageShrinker_Detaches(t *testing.T) {
+	transparent := encodePNG(t, noiseImage(200, 200, false))
+	e := Email{
+		HTMLBody: `<p>Results</p><IMG SRC="cid:chart@example.com" alt="Chart"><img src="cid:small@example.com">`,
+		Attachments: []Attachment{
+			{FileName: "chart.png", Content: transparent, ContentType: "image/png", ContentID: "chart@example.com"},
+			{FileName: "small.png", Content: []byte("tiny"), ContentType: "image/png", ContentID: "small@example.com"},
+			{FileName: "report.pdf", Content: bytes.Repeat([]byte("%PDF"), 1000), ContentType: "application/pdf"},
+		},
+	}
+
+	got, actions, err := NewImageShrinker(1000).Shrink(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(actions) != 1 || actions[0].Kind != IMAGE_DETACHED || actions[0].ContentID != "chart@example.com" {
+		t.Fatalf("expected the chart to be detached, got %+v", actions)
+	}
+	if want := `<p>Results</p><em>[Image attached: chart.png]</em><img src="cid:small@example.com">`; got.HTMLBody != want {
+		t.Errorf("expected %s, got %s", want, got.HTMLBody)
+	}
+	if chart := got.Attachments[0]; chart.ContentID != "" || chart.FileName != "chart.png" {
+		t.Errorf("expected the chart to be a regular attachment, got %+v", chart)
+	}
+	if got.Attachments[1].ContentID != "small@example.com" || got.Attachments[2].FileName != "report.pdf" {
+		t.Errorf("expected the other attachments to be kept, got %+v", got.Attachments[1:])
+	}
+	if e.Attachments[0].ContentID != "chart@example.com" {
+		t.Error("expected the caller's email to be unchanged")
+	}
+}
+
+func TestShrinkingSender_ReportsActions(t *testing.T) {
+	inner := &rejectingSender{}
+	sender := NewShrinkingSender(inner, NewImageShrinker(1000))
+
+	result, err := sender.SendEmailV2(context.Background(), Email{
+		ToAddresses: []string{"ada@example.com"},
+		HTMLBody:    `<img src="cid:chart@example.com">`,
+		Attachments: []Attachment{{FileName: "chart.gif", Content: bytes.Repeat([]byte("GIF"), 1000), ContentType: "image/gif", ContentID: "chart@example.com"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.ImageActions) != 1 || result.ImageActions[0].Kind != IMAGE_DETACHED {
+		t.Errorf("expected the undecodable image to be detached, got %+v", result.ImageActions)
+	}
+	if !strings.Contains(inner.sent[0].HTMLBody, "[Image attached: chart.gif]") {
+		t.Errorf("expected the shrunk email to be sent, got %s", inner.sent[0].HTMLBody)
+	}
+}
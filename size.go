@@ -0,0 +1,243 @@
+package email
+
+import (
+	"mime"
+	"strings"
+)
+
+// Length of the boundaries generated by providersdk.BuildMessage.
+const estimatedBoundaryLength = 34
+
+type AttachmentSize struct {
+	FileName string
+	// Size of the base64 encoded content, line breaks included.
+	Size int
+}
+
+// SizeEstimate breaks down the encoded size in bytes of the message built
+// for an email.
+type SizeEstimate struct {
+	// Top-level message headers.
+	Headers int
+	Text    int
+	HTML    int
+	// In the same order as Email.Attachments.
+	Attachments []AttachmentSize
+	// Boundaries and headers of the MIME parts.
+	MultipartOverhead int
+	Total             int
+	// Set when e has reader bodies, which can't be sized without reading
+	// them. They count as empty, so Total is only a lower bound; call
+	// ReadBodies first for a full estimate.
+	UnreadBodies bool
+}
+
+// EstimateSize is a shorthand for EstimateSize(e).
+func (e Email) EstimateSize() SizeEstimate {
+	return EstimateSize(e)
+}
+
+// FitsIn reports whether the estimated message is no larger than limit bytes.
+// It is false for estimates with UnreadBodies, whose size isn't known.
+func (s SizeEstimate) FitsIn(limit int) bool {
+	return !s.UnreadBodies && s.Total <= limit
+}
+
+// EstimateSize predicts the size of the raw message providersdk.BuildMessage
+// produces for e with default options, without building it. It is meant for
+// warning users while they compose, e.g. that attachments are too large.
+func EstimateSize(e Email) SizeEstimate {
+	var s SizeEstimate
+
+	headers := []string{
+		"From: " + headerAddresses([]string{e.FromAddress}),
+		"To: " + headerAddresses(e.ToAddresses),
+		"Subject: " + mime.QEncoding.Encode("utf-8", e.Subject),
+		"MIME-Version: 1.0",
+	}
+	if sender := e.SenderHeaderAddress(); sender != "" {
+		headers = append(headers, "Sender: "+headerAddresses([]string{sender}))
+	}
+	for name, addrs := range map[string][]string{"Cc": e.CCAddresses, "Bcc": e.BCCAddresses, "Reply-To": e.ReplyToAddresses} {
+		if len(addrs) > 0 {
+			headers = append(headers, name+": "+headerAddresses(addrs))
+		}
+	}
+	for _, h := range ReadReceiptHeaders(e) {
+		headers = append(headers, h.Name+": "+h.Value)
+	}
+	for _, h := range FieldHeaders(e) {
+		headers = append(headers, h.Name+": "+h.Value)
+	}
+	for _, h := range e.Headers {
+		headers = append(headers, h.String())
+	}
+
+	// Readers replace the string bodies, see ReadBodies, so only the MIME
+	// structure is known for them.
+	hasText, hasHTML := e.TextBody != "", e.HTMLBody != ""
+	if e.TextBodyReader != nil {
+		hasText, s.UnreadBodies = true, true
+	} else {
+		s.Text = len(e.TextBody)
+	}
+	if e.HTMLBodyReader != nil {
+		hasHTML, s.UnreadBodies = true, true
+	} else {
+		s.HTML = len(e.HTMLBody)
+	}
+
+	// Transcoded bodies are estimated at their UTF-8 size.
+	charset := DefaultCharset
+	if c, err := LookupCharset(e.Charset); err == nil {
+		charset = c.Name
+	}
+
+	textPart := func(mediaType string, size int) int {
+		return partSize([]string{
+			"Content-Type: " + mediaType + "; charset=" + charset,
+			"Content-Transfer-Encoding: 8bit",
+		}, size)
+	}
+
+	// Inline attachments only render as such next to an HTML body, in
+	// multipart/related; otherwise they are regular attachments.
+	attachments := make([]int, len(e.Attachments))
+	for i, a := range e.Attachments {
+		attachments[i] = base64Size(len(a.Content))
+		s.Attachments = append(s.Attachments, AttachmentSize{FileName: a.FileName, Size: attachments[i]})
+	}
+	isInline := func(a Attachment) bool { return a.ContentID != "" && hasHTML }
+
+	htmlHeaders := []string{"Content-Type: text/html; charset=" + charset, "Content-Transfer-Encoding: 8bit"}
+	htmlSize := s.HTML
+	var related []int
+	for i, a := range e.Attachments {
+		if isInline(a) {
+			related = append(related, partSize(attachmentHeaders(a, true), attachments[i]))
+		}
+	}
+	if len(related) > 0 {
+		htmlSize = multipartSize(append([]int{partSize(htmlHeaders, htmlSize)}, related...))
+		htmlHeaders = multipartHeaders("multipart/related")
+	}
+
+	var bodyHeaders []string
+	var bodySize int
+	switch {
+	case hasText && hasHTML:
+		bodyHeaders = multipartHeaders("multipart/alternative")
+		bodySize = multipartSize([]int{textPart("text/plain", s.Text), partSize(htmlHeaders, htmlSize)})
+	case hasHTML:
+		bodyHeaders, bodySize = htmlHeaders, htmlSize
+	default:
+		bodyHeaders = []string{"Content-Type: text/plain; charset=" + charset, "Content-Transfer-Encoding: 8bit"}
+		bodySize = s.Text
+	}
+
+	rootHeaders, rootSize := bodyHeaders, bodySize
+	parts := []int{partSize(bodyHeaders, bodySize)}
+	for i, a := range e.Attachments {
+		if !isInline(a) {
+			parts = append(parts, partSize(attachmentHeaders(a, false), attachments[i]))
+		}
+	}
+	if len(parts) > 1 {
+		rootHeaders = multipartHeaders("multipart/mixed")
+		rootSize = multipartSize(parts)
+	}
+
+	headers = append(headers, rootHeaders...)
+	s.Headers = joinedSize(headers) + len("\r\n\r\n")
+
+	s.Total = s.Headers + rootSize
+	s.MultipartOverhead = rootSize - s.Text - s.HTML
+	for _, a := range s.Attachments {
+		s.MultipartOverhead -= a.Size
+	}
+
+	return s
+}
+
+func attachmentHeaders(a Attachment, inline bool) []string {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+		params["name"] = a.FileName
+		contentType = mime.FormatMediaType(mediaType, params)
+	}
+
+	if !inline {
+		return []string{
+			"Content-Type: " + contentType,
+			"Content-Transfer-Encoding: base64",
+			"Content-Disposition: " + mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}),
+		}
+	}
+	return []string{
+		"Content-Type: " + contentType,
+		"Content-Transfer-Encoding: base64",
+		"Content-Disposition: " + mime.FormatMediaType("inline", map[string]string{"filename": a.FileName}),
+		"Content-ID: <" + a.ContentID + ">",
+	}
+}
+
+func multipartHeaders(mediaType string) []string {
+	return []string{
+		"Content-Type: " + mediaType + "; boundary=" + strings.Repeat("x", estimatedBoundaryLength),
+		"Content-Transfer-Encoding: 8bit",
+	}
+}
+
+// headerAddresses formats addrs the way providersdk.BuildMessage does.
+func headerAddresses(addrs []string) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = addr
+		if a, err := ParseAddress(addr); err == nil {
+			formatted[i] = a.String()
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// partSize is the size of a rendered MIME part: its headers, a blank line and
+// its body.
+func partSize(headers []string, body int) int {
+	return joinedSize(headers) + len("\r\n\r\n") + body
+}
+
+// multipartSize is the size of a multipart body holding parts of the given
+// sizes, delimiters included.
+func multipartSize(parts []int) int {
+	delimiter := len("--") + estimatedBoundaryLength
+	size := delimiter + len("--")
+	for _, p := range parts {
+		size += delimiter + len("\r\n") + p + len("\r\n")
+	}
+	return size
+}
+
+func joinedSize(lines []string) int {
+	size := 0
+	for _, l := range lines {
+		size += len(l)
+	}
+	if len(lines) > 1 {
+		size += len("\r\n") * (len(lines) - 1)
+	}
+	return size
+}
+
+// base64Size is the size of n bytes base64 encoded and wrapped at 76
+// characters per line.
+func base64Size(n int) int {
+	encoded := (n + 2) / 3 * 4
+	if encoded == 0 {
+		return 0
+	}
+	lines := (encoded + 75) / 76
+	return encoded + len("\r\n")*(lines-1)
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	e := Email{
+		FromAddress: "sender@example.com",
+		ToAddresses: []string{"recipient@example.com"},
+		Subject:     "Roster",
+		TextBody:    "See attached.",
+		HTMLBody:    "<p>See attached.</p>",
+		Attachments: []Attachment{
+			{FileName: "roster.csv", Content: bytes.Repeat([]byte("x"), 57), ContentType: "text/csv"},
+			{FileName: "scan.pdf", Content: bytes.Repeat([]byte("x"), 1000), ContentType: "application/pdf"},
+		},
+	}
+
+	s := EstimateSize(e)
+
+	if s.Text != len(e.TextBody) || s.HTML != len(e.HTMLBody) {
+		t.Errorf("expected body sizes %d and %d, got %d and %d", len(e.TextBody), len(e.HTMLBody), s.Text, s.HTML)
+	}
+
+	// 57 bytes encode to exactly one 76 character line; 1000 bytes to 1336
+	// characters over 18 lines.
+	expected := []AttachmentSize{{"roster.csv", 76}, {"scan.pdf", 1336 + 17*2}}
+	if len(s.Attachments) != len(expected) {
+		t.Fatalf("expected %d attachment sizes, got %d", len(expected), len(s.Attachments))
+	}
+	for i, a := range expected {
+		if s.Attachments[i] != a {
+			t.Errorf("expected attachment size %+v, got %+v", a, s.Attachments[i])
+		}
+	}
+
+	sum := s.Headers + s.Text + s.HTML + s.MultipartOverhead
+	for _, a := range s.Attachments {
+		sum += a.Size
+	}
+	if sum != s.Total {
+		t.Errorf("expected components to add up to the total %d, got %d", s.Total, sum)
+	}
+
+	if !s.FitsIn(s.Total) || s.FitsIn(s.Total-1) {
+		t.Errorf("expected FitsIn to compare against the total %d", s.Total)
+	}
+}
+
+func TestEmail_EstimateSize(t *testing.T) {
+	e := Email{FromAddress: "sender@example.com", ToAddresses: []string{"a@example.com"}, Subject: "Hi", TextBody: "Hello"}
+
+	if e.EstimateSize().Total != EstimateSize(e).Total {
+		t.Errorf("expected the method to match EstimateSize")
+	}
+}
+
+func TestEstimateSize_ReaderBodies(t *testing.T) {
+	html := "<p>" + strings.Repeat("Roster attached. ", 1000) + `<img src="cid:logo@example.com"></p>`
+	e := Email{
+		FromAddress:    "sender@example.com",
+		ToAddresses:    []string{"recipient@example.com"},
+		Subject:        "Roster",
+		TextBody:       "See attached.",
+		HTMLBodyReader: strings.NewReader(html),
+		Attachments:    []Attachment{{FileName: "logo.png", Content: []byte("png"), ContentType: "image/png", ContentID: "logo@example.com"}},
+	}
+
+	s := EstimateSize(e)
+	if !s.UnreadBodies {
+		t.Error("expected the reader body to be reported as unread")
+	}
+	if s.FitsIn(1 << 30) {
+		t.Error("expected an estimate with unread bodies not to fit any limit")
+	}
+
+	read, err := ReadBodies(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	full := EstimateSize(read)
+	if full.UnreadBodies {
+		t.Error("expected no unread bodies after ReadBodies")
+	}
+	// Same MIME structure, only missing the HTML body itself.
+	if full.Total-s.Total != len(html) || full.MultipartOverhead != s.MultipartOverhead {
+		t.Errorf("expected the estimates to differ by the HTML body, got %+v and %+v", s, full)
+	}
+}
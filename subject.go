@@ -0,0 +1,126 @@
+package email
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Reply and forward markers, e.g. "Re:", "RE[2]:", German "AW:" and "WG:",
+// Scandinavian "SV:", Dutch "Antw:", Finnish "VS:", French "TR:" and Spanish
+// "RV:".
+var (
+	replyMarker   = regexp.MustCompile(`^(?i)(re|aw|sv|antw|vs)\s*(\[\d+\]|\(\d+\))?\s*:`)
+	forwardMarker = regexp.MustCompile(`^(?i)(fwd|fw|wg|tr|rv)\s*(\[\d+\]|\(\d+\))?\s*:`)
+)
+
+// subjectParts is a subject split into the reply or forward marker that
+// comes first, the bracketed tags and the rest.
+type subjectParts struct {
+	marker string
+	tags   []string
+	base   string
+}
+
+func parseSubject(s string) subjectParts {
+	var p subjectParts
+	rest := strings.TrimSpace(s)
+	for {
+		if m := replyMarker.FindString(rest); m != "" {
+			if p.marker == "" {
+				p.marker = "Re:"
+			}
+			rest = strings.TrimSpace(rest[len(m):])
+			continue
+		}
+		if m := forwardMarker.FindString(rest); m != "" {
+			if p.marker == "" {
+				p.marker = "Fwd:"
+			}
+			rest = strings.TrimSpace(rest[len(m):])
+			continue
+		}
+		if tag, after, ok := cutTag(rest); ok {
+			if !slices.ContainsFunc(p.tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+				p.tags = append(p.tags, tag)
+			}
+			rest = strings.TrimSpace(after)
+			continue
+		}
+		break
+	}
+	p.base = rest
+	return p
+}
+
+// cutTag cuts a leading "[tag]" off s.
+func cutTag(s string) (tag, after string, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s, false
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 || strings.Contains(s[1:end], "[") {
+		return "", s, false
+	}
+	tag = strings.TrimSpace(s[1:end])
+	if tag == "" {
+		return "", s, false
+	}
+	return tag, s[end+1:], true
+}
+
+func (p subjectParts) String() string {
+	var parts []string
+	if p.marker != "" {
+		parts = append(parts, p.marker)
+	}
+	for _, tag := range p.tags {
+		parts = append(parts, "["+tag+"]")
+	}
+	if p.base != "" {
+		parts = append(parts, p.base)
+	}
+	return strings.Join(parts, " ")
+}
+
+// NormalizeSubject tidies the prefixes replies and forwards pile up, e.g.
+// "RE: AW: [ICAA] Fwd: Re: Results" becomes "Re: [ICAA] Results". The
+// reply or forward marker that comes first is kept once, as "Re:" or
+// "Fwd:", followed by the bracketed tags without duplicates.
+func NormalizeSubject(s string) string {
+	return parseSubject(s).String()
+}
+
+// PrefixSubject tags a subject with "[tag]" exactly once, after any reply
+// or forward marker and ahead of other tags. The tag may be given with or
+// without brackets. The result is normalized like NormalizeSubject.
+func PrefixSubject(tag, s string) string {
+	tag = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(tag), "["), "]"))
+	p := parseSubject(s)
+	if tag == "" {
+		return p.String()
+	}
+
+	p.tags = slices.DeleteFunc(p.tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	p.tags = append([]string{tag}, p.tags...)
+	return p.String()
+}
+
+// TruncateSubject shortens s to at most n characters for display, ending
+// it with "…" when anything was cut. It never splits a character.
+func TruncateSubject(s string, n int) string {
+	return truncateDisplay(s, n)
+}
+
+func truncateDisplay(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)[:n-1]
+	return strings.TrimRight(string(runes), " \t") + "…"
+}
@@ -0,0 +1,108 @@
+package email
+
+import "testing"
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		expected string
+	}{
+		{name: "plain", subject: "Spring tournament", expected: "Spring tournament"},
+		{name: "surrounding space", subject: "  Spring tournament ", expected: "Spring tournament"},
+		{name: "empty", subject: "", expected: ""},
+		{name: "single reply", subject: "Re: Results", expected: "Re: Results"},
+		{name: "repeated replies", subject: "Re: Re: RE: Results", expected: "Re: Results"},
+		{name: "no space after colon", subject: "Re:Re:Results", expected: "Re: Results"},
+		{name: "space before colon", subject: "Re : Results", expected: "Re: Results"},
+		{name: "counted reply", subject: "Re[2]: Re(3): Results", expected: "Re: Results"},
+		{name: "forward", subject: "FW: Fwd: Results", expected: "Fwd: Results"},
+		{name: "reply to a forward", subject: "Re: Fwd: Results", expected: "Re: Results"},
+		{name: "forward of a reply", subject: "Fwd: Re: Results", expected: "Fwd: Results"},
+		{name: "german", subject: "AW: WG: Ergebnisse", expected: "Re: Ergebnisse"},
+		{name: "swedish", subject: "SV: Sv: Resultat", expected: "Re: Resultat"},
+		{name: "dutch", subject: "Antw: Uitslag", expected: "Re: Uitslag"},
+		{name: "finnish", subject: "VS: Tulokset", expected: "Re: Tulokset"},
+		{name: "french forward", subject: "TR: Résultats", expected: "Fwd: Résultats"},
+		{name: "spanish forward", subject: "RV: Resultados", expected: "Fwd: Resultados"},
+		{name: "mixed languages", subject: "RE: AW: [ICAA] Fwd: Re: Results", expected: "Re: [ICAA] Results"},
+		{name: "tag first", subject: "[ICAA] Re: Results", expected: "Re: [ICAA] Results"},
+		{name: "repeated tag", subject: "Re: [ICAA] Re: [icaa] Results", expected: "Re: [ICAA] Results"},
+		{name: "several tags", subject: "[ICAA] [Juniors] Re: [ICAA] Results", expected: "Re: [ICAA] [Juniors] Results"},
+		{name: "marker words in the subject", subject: "Results for Fwd: line", expected: "Results for Fwd: line"},
+		{name: "word starting like a marker", subject: "Review: scoring", expected: "Review: scoring"},
+		{name: "unclosed bracket", subject: "[ICAA Results", expected: "[ICAA Results"},
+		{name: "empty brackets", subject: "[] Results", expected: "[] Results"},
+		{name: "only markers", subject: "Re: Fwd:", expected: "Re:"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NormalizeSubject(tc.subject); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			if got := NormalizeSubject(tc.expected); got != tc.expected {
+				t.Errorf("expected normalizing to be idempotent, got %q", got)
+			}
+		})
+	}
+}
+
+func TestPrefixSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		subject  string
+		expected string
+	}{
+		{name: "untagged", tag: "ICAA", subject: "Results", expected: "[ICAA] Results"},
+		{name: "bracketed tag", tag: "[ICAA]", subject: "Results", expected: "[ICAA] Results"},
+		{name: "already tagged", tag: "ICAA", subject: "[ICAA] Results", expected: "[ICAA] Results"},
+		{name: "already tagged in another case", tag: "ICAA", subject: "[icaa] Results", expected: "[ICAA] Results"},
+		{name: "after the reply marker", tag: "ICAA", subject: "Re: Results", expected: "Re: [ICAA] Results"},
+		{name: "tagged reply", tag: "ICAA", subject: "[ICAA] Re: Re: [ICAA] Results", expected: "Re: [ICAA] Results"},
+		{name: "ahead of other tags", tag: "ICAA", subject: "[Juniors] Results", expected: "[ICAA] [Juniors] Results"},
+		{name: "moved ahead of other tags", tag: "ICAA", subject: "[Juniors] [ICAA] Results", expected: "[ICAA] [Juniors] Results"},
+		{name: "empty tag", tag: " ", subject: "Re: Re: Results", expected: "Re: Results"},
+		{name: "empty subject", tag: "ICAA", subject: "", expected: "[ICAA]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := PrefixSubject(tc.tag, tc.subject)
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			if again := PrefixSubject(tc.tag, got); again != got {
+				t.Errorf("expected tagging twice to change nothing, got %q", again)
+			}
+		})
+	}
+}
+
+func TestTruncateSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		n        int
+		expected string
+	}{
+		{name: "short", subject: "Results", n: 10, expected: "Results"},
+		{name: "exact", subject: "Results", n: 7, expected: "Results"},
+		{name: "cut", subject: "Spring tournament results", n: 10, expected: "Spring to…"},
+		{name: "cut at a space", subject: "Spring tournament", n: 8, expected: "Spring…"},
+		{name: "multibyte", subject: "Résultats été", n: 5, expected: "Résu…"},
+		{name: "emoji", subject: "🏹🏹🏹🏹", n: 3, expected: "🏹🏹…"},
+		{name: "one", subject: "Results", n: 1, expected: "…"},
+		{name: "zero", subject: "Results", n: 0, expected: ""},
+		{name: "negative", subject: "Results", n: -1, expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := TruncateSubject(tc.subject, tc.n); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
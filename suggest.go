@@ -0,0 +1,236 @@
+package email
+
+import (
+	"context"
+	"net/mail"
+	"slices"
+	"strings"
+)
+
+type Confidence string
+
+const (
+	// The domain is a known typo; safe to correct automatically.
+	CONFIDENCE_HIGH Confidence = "HIGH"
+	// The domain is a few edits away from a popular one; ask a human.
+	CONFIDENCE_LOW Confidence = "LOW"
+)
+
+// DefaultMaxSuggestionDistance is the largest edit distance between a domain
+// and a popular domain for SuggestCorrection to propose it.
+const DefaultMaxSuggestionDistance = 2
+
+// DefaultTypoDomains maps common misspellings to the domain that was meant.
+var DefaultTypoDomains = map[string]string{
+	"gamil.com":   "gmail.com",
+	"gmial.com":   "gmail.com",
+	"gmai.com":    "gmail.com",
+	"gmal.com":    "gmail.com",
+	"gmail.co":    "gmail.com",
+	"gmail.con":   "gmail.com",
+	"gnail.com":   "gmail.com",
+	"hotmial.com": "hotmail.com",
+	"hotmal.com":  "hotmail.com",
+	"hotmail.co":  "hotmail.com",
+	"hotmail.con": "hotmail.com",
+	"yaho.com":    "yahoo.com",
+	"yahooo.com":  "yahoo.com",
+	"yahoo.con":   "yahoo.com",
+	"outlok.com":  "outlook.com",
+	"outlook.con": "outlook.com",
+	"iclould.com": "icloud.com",
+	"icloud.con":  "icloud.com",
+}
+
+// DefaultPopularDomains are the domains SuggestCorrection matches misspelled
+// domains against.
+var DefaultPopularDomains = []string{
+	"gmail.com",
+	"googlemail.com",
+	"yahoo.com",
+	"hotmail.com",
+	"outlook.com",
+	"live.com",
+	"icloud.com",
+	"aol.com",
+	"protonmail.com",
+}
+
+type Suggestion struct {
+	Address    string
+	Confidence Confidence
+}
+
+type suggestConfig struct {
+	typos       map[string]string
+	popular     []string
+	maxDistance int
+}
+
+type SuggestOption func(*suggestConfig)
+
+// WithTypoDomains replaces DefaultTypoDomains.
+func WithTypoDomains(typos map[string]string) SuggestOption {
+	return func(c *suggestConfig) {
+		c.typos = typos
+	}
+}
+
+// WithPopularDomains replaces DefaultPopularDomains.
+func WithPopularDomains(domains []string) SuggestOption {
+	return func(c *suggestConfig) {
+		c.popular = domains
+	}
+}
+
+// WithMaxSuggestionDistance replaces DefaultMaxSuggestionDistance. Zero
+// disables suggestions based on edit distance.
+func WithMaxSuggestionDistance(distance int) SuggestOption {
+	return func(c *suggestConfig) {
+		c.maxDistance = distance
+	}
+}
+
+// SuggestCorrection proposes corrected versions of addr whose domain looks
+// like a typo, most likely first. Only the domain is ever changed. It returns
+// nil for addresses that look fine or cannot be parsed.
+func SuggestCorrection(addr string, opts ...SuggestOption) []Suggestion {
+	cfg := suggestConfig{
+		typos:       DefaultTypoDomains,
+		popular:     DefaultPopularDomains,
+		maxDistance: DefaultMaxSuggestionDistance,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return nil
+	}
+	at := strings.LastIndex(parsed.Address, "@")
+	domain := strings.ToLower(parsed.Address[at+1:])
+
+	if slices.Contains(cfg.popular, domain) {
+		return nil
+	}
+
+	var suggestions []Suggestion
+	seen := map[string]bool{}
+	if fixed, ok := cfg.typos[domain]; ok {
+		suggestions = append(suggestions, Suggestion{Address: replaceDomain(addr, domain, fixed), Confidence: CONFIDENCE_HIGH})
+		seen[fixed] = true
+	}
+
+	type candidate struct {
+		domain   string
+		distance int
+	}
+	var candidates []candidate
+	for _, p := range cfg.popular {
+		if d := levenshtein(domain, p); d > 0 && d <= cfg.maxDistance && !seen[p] {
+			candidates = append(candidates, candidate{p, d})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.distance - b.distance })
+
+	for _, c := range candidates {
+		suggestions = append(suggestions, Suggestion{Address: replaceDomain(addr, domain, c.domain), Confidence: CONFIDENCE_LOW})
+	}
+
+	return suggestions
+}
+
+// replaceDomain swaps the domain of addr, keeping any display name and the
+// local part as written.
+func replaceDomain(addr, domain, replacement string) string {
+	i := strings.LastIndex(strings.ToLower(addr), "@"+domain)
+	return addr[:i+1] + replacement + addr[i+1+len(domain):]
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// AddressCorrection records a recipient address rewritten by
+// AutoCorrectSender or FallbackSender.
+type AddressCorrection struct {
+	Original  string
+	Corrected string
+}
+
+var _ Sender = &AutoCorrectSender{}
+var _ SenderV2 = &AutoCorrectSender{}
+
+// AutoCorrectSender decorates a SenderV2 to fix recipient domains that are
+// known typos before sending. Only CONFIDENCE_HIGH suggestions are applied;
+// the corrections are reported in SendResult.Corrections.
+type AutoCorrectSender struct {
+	inner SenderV2
+	opts  []SuggestOption
+}
+
+func NewAutoCorrectSender(inner SenderV2, opts ...SuggestOption) *AutoCorrectSender {
+	return &AutoCorrectSender{inner: inner, opts: opts}
+}
+
+func (s *AutoCorrectSender) Unwrap() SenderV2 {
+	return s.inner
+}
+
+func (s *AutoCorrectSender) SendEmail(ctx context.Context, e Email) error {
+	_, err := s.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (s *AutoCorrectSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	var corrections []AddressCorrection
+	correct := func(addrs []string) []string {
+		var out []string
+		for i, addr := range addrs {
+			suggestions := SuggestCorrection(addr, s.opts...)
+			if len(suggestions) == 0 || suggestions[0].Confidence != CONFIDENCE_HIGH {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(addrs)
+			}
+			out[i] = suggestions[0].Address
+			corrections = append(corrections, AddressCorrection{Original: addr, Corrected: out[i]})
+		}
+		if out == nil {
+			return addrs
+		}
+		return out
+	}
+
+	e.ToAddresses = correct(e.ToAddresses)
+	e.CCAddresses = correct(e.CCAddresses)
+	e.BCCAddresses = correct(e.BCCAddresses)
+
+	result, err := s.inner.SendEmailV2(ctx, e, opts)
+	if result != nil && len(corrections) > 0 {
+		result.Corrections = append(result.Corrections, corrections...)
+	}
+	return result, err
+}
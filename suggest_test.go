@@ -0,0 +1,112 @@
+package email
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSuggestCorrection(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		opts     []SuggestOption
+		expected []Suggestion
+	}{
+		{
+			name:     "known typo",
+			addr:     "ada@gamil.com",
+			expected: []Suggestion{{"ada@gmail.com", CONFIDENCE_HIGH}},
+		},
+		{
+			name:     "known typo keeps display name and local part",
+			addr:     "Ada Lovelace <Ada.L@GMIAL.com>",
+			expected: []Suggestion{{"Ada Lovelace <Ada.L@gmail.com>", CONFIDENCE_HIGH}},
+		},
+		{
+			name:     "typo of a popular domain",
+			addr:     "ada@hotmaill.com",
+			expected: []Suggestion{{"ada@hotmail.com", CONFIDENCE_LOW}},
+		},
+		{
+			name: "nearest candidates first",
+			addr: "ada@icaa.orm",
+			opts: []SuggestOption{WithPopularDomains([]string{"icaa.com", "icaa.org"})},
+			expected: []Suggestion{
+				{"ada@icaa.org", CONFIDENCE_LOW},
+				{"ada@icaa.com", CONFIDENCE_LOW},
+			},
+		},
+		{
+			name: "popular domain",
+			addr: "ada@gmail.com",
+		},
+		{
+			name: "unrelated domain",
+			addr: "ada@icaa.org",
+		},
+		{
+			name: "beyond the distance threshold",
+			addr: "ada@hotmaill.com",
+			opts: []SuggestOption{WithMaxSuggestionDistance(0)},
+		},
+		{
+			name:     "custom typo map",
+			addr:     "captain@icca.org",
+			opts:     []SuggestOption{WithTypoDomains(map[string]string{"icca.org": "icaa.org"})},
+			expected: []Suggestion{{"captain@icaa.org", CONFIDENCE_HIGH}},
+		},
+		{
+			name: "unparseable address",
+			addr: "not an address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuggestCorrection(tt.addr, tt.opts...)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutoCorrectSender(t *testing.T) {
+	inner := &recordingSender{}
+	sender := NewAutoCorrectSender(AsSenderV2(inner))
+
+	e := Email{
+		ToAddresses:  []string{"ada@gamil.com", "grace@example.com"},
+		CCAddresses:  []string{"alan@hotmaill.com"},
+		BCCAddresses: []string{"Ed <ed@yahooo.com>"},
+	}
+
+	result, err := sender.SendEmailV2(context.Background(), e, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := inner.sent[0]
+	if !reflect.DeepEqual(sent.ToAddresses, []string{"ada@gmail.com", "grace@example.com"}) {
+		t.Errorf("expected the known typo to be corrected, got %v", sent.ToAddresses)
+	}
+	if !reflect.DeepEqual(sent.CCAddresses, e.CCAddresses) {
+		t.Errorf("expected low confidence suggestions to be left alone, got %v", sent.CCAddresses)
+	}
+	if !reflect.DeepEqual(sent.BCCAddresses, []string{"Ed <ed@yahoo.com>"}) {
+		t.Errorf("expected BCC to be corrected, got %v", sent.BCCAddresses)
+	}
+
+	expected := []AddressCorrection{
+		{Original: "ada@gamil.com", Corrected: "ada@gmail.com"},
+		{Original: "Ed <ed@yahooo.com>", Corrected: "Ed <ed@yahoo.com>"},
+	}
+	if !reflect.DeepEqual(result.Corrections, expected) {
+		t.Errorf("expected corrections %v, got %v", expected, result.Corrections)
+	}
+
+	if e.ToAddresses[0] != "ada@gamil.com" {
+		t.Error("expected the caller's email to be left untouched")
+	}
+}
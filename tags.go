@@ -0,0 +1,51 @@
+package email
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+const (
+	// Prefix of the headers Email.Tags are sent as by providers without
+	// native tagging, followed by the tag name.
+	TagHeaderPrefix = "X-Tag-"
+
+	maxTagLength = 256
+)
+
+// ValidateTags checks that tag names and values are 1 to 256 ASCII letters,
+// digits, underscores and dashes, the format SES message tags require.
+func ValidateTags(tags map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(tags)) {
+		if err := validateTagPart("name", name, name); err != nil {
+			return err
+		}
+		if err := validateTagPart("value", name, tags[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTagPart(part, name, s string) error {
+	if s == "" || len(s) > maxTagLength {
+		return NewValidationError(fmt.Sprintf("tag %s for %q must be 1 to %d characters", part, name, maxTagLength), nil)
+	}
+	for _, r := range s {
+		if !isTagRune(r) {
+			return NewValidationError(fmt.Sprintf("tag %s %q contains %q; only letters, digits, '_' and '-' are allowed", part, s, r), nil)
+		}
+	}
+	return nil
+}
+
+// TagHeaders returns a header per tag, named TagHeaderPrefix followed by the
+// tag name, sorted by name.
+func TagHeaders(tags map[string]string) []Header {
+	var headers []Header
+	for _, name := range slices.Sorted(maps.Keys(tags)) {
+		headers = append(headers, Header{Name: TagHeaderPrefix + name, Value: tags[name]})
+	}
+	return headers
+}
@@ -0,0 +1,47 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  map[string]string
+		valid bool
+	}{
+		{"none", nil, true},
+		{"valid", map[string]string{"env": "prod", "team_id": "north-1"}, true},
+		{"empty name", map[string]string{"": "prod"}, false},
+		{"empty value", map[string]string{"env": ""}, false},
+		{"space", map[string]string{"env": "prod eu"}, false},
+		{"dot in name", map[string]string{"app.env": "prod"}, false},
+		{"non-ASCII", map[string]string{"city": "München"}, false},
+		{"header injection", map[string]string{"env": "prod\r\nBcc: x@example.com"}, false},
+		{"too long", map[string]string{"env": strings.Repeat("a", 257)}, false},
+		{"longest", map[string]string{strings.Repeat("a", 256): strings.Repeat("b", 256)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTags(tt.tags)
+			if tt.valid != (err == nil) {
+				t.Fatalf("expected valid=%v, got %v", tt.valid, err)
+			}
+			if err != nil && !errors.Is(err, ErrValidation) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTagHeaders(t *testing.T) {
+	headers := TagHeaders(map[string]string{"team": "events", "env": "prod"})
+
+	want := []Header{{"X-Tag-env", "prod"}, {"X-Tag-team", "events"}}
+	if len(headers) != len(want) || headers[0] != want[0] || headers[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, headers)
+	}
+}
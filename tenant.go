@@ -0,0 +1,218 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TenantMetadataKey is the Error.Metadata key naming the tenant a
+// TenantRegistry refused a send for.
+const TenantMetadataKey = "tenant"
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose sends TenantRegistry routes to the
+// tenant id, e.g. one league of a deployment hosting several.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the tenant set with WithTenant, e.g. for
+// labeling metrics in SendHooks.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// TenantBuilder builds the sender chain of a tenant, with its own provider
+// configuration, e.g. SES configuration set, and decorators.
+type TenantBuilder func(ctx context.Context) (SenderV2, error)
+
+type TenantOption func(*tenant)
+
+// WithTenantDomains restricts the From, Sender and bounce addresses of the
+// tenant's emails to the given domains, so one tenant can't send as
+// another. Subdomains must be listed separately.
+func WithTenantDomains(domains ...string) TenantOption {
+	return func(t *tenant) {
+		for _, d := range domains {
+			t.domains = append(t.domains, strings.ToLower(strings.TrimSpace(d)))
+		}
+	}
+}
+
+type tenant struct {
+	id      string
+	build   TenantBuilder
+	domains []string
+
+	mu sync.Mutex
+	// Guarded by mu. Nil until the first send.
+	sender SenderV2
+}
+
+var _ Sender = &TenantRegistry{}
+var _ SenderV2 = &TenantRegistry{}
+
+// TenantRegistry routes each send to the sender chain of the tenant in its
+// context, building the chain on the tenant's first send and reusing it
+// after. Sends without a tenant, or for one that isn't registered, fail.
+type TenantRegistry struct {
+	mu sync.RWMutex
+	// Guarded by mu.
+	tenants map[string]*tenant
+}
+
+func NewTenantRegistry() *TenantRegistry {
+	return &TenantRegistry{tenants: map[string]*tenant{}}
+}
+
+// Register adds a tenant whose sender chain build returns.
+func (r *TenantRegistry) Register(id string, build TenantBuilder, opts ...TenantOption) error {
+	if id == "" {
+		return NewValidationError("tenant ID is required", nil)
+	}
+
+	t := &tenant{id: id, build: build}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[id]; ok {
+		return NewValidationError(fmt.Sprintf("tenant %s is already registered", id), nil)
+	}
+	r.tenants[id] = t
+	return nil
+}
+
+// Sender returns the sender chain of the tenant id, building it if this is
+// its first use. A failed build is retried on the next call.
+func (r *TenantRegistry) Sender(ctx context.Context, id string) (SenderV2, error) {
+	t, err := r.tenant(id)
+	if err != nil {
+		return nil, err
+	}
+	return t.get(ctx)
+}
+
+func (r *TenantRegistry) tenant(id string) (*tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		err := NewValidationError(fmt.Sprintf("unknown tenant %s", id), nil)
+		err.Metadata = map[string]string{TenantMetadataKey: id}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *tenant) get(ctx context.Context) (SenderV2, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sender != nil {
+		return t.sender, nil
+	}
+	s, err := t.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.sender = s
+	return s, nil
+}
+
+// checkDomains refuses e if it is sent from a domain the tenant doesn't
+// own.
+func (t *tenant) checkDomains(e Email) error {
+	if len(t.domains) == 0 {
+		return nil
+	}
+
+	for _, f := range []struct {
+		field AddressField
+		addr  string
+	}{
+		{ADDRESS_FROM, e.FromAddress},
+		{ADDRESS_SENDER, e.SenderAddress},
+		{ADDRESS_BOUNCE, e.BounceAddress},
+	} {
+		if f.addr == "" {
+			continue
+		}
+		addr := indexAddress(f.addr)
+		domain := domainOf(addr)
+		if slices.Contains(t.domains, domain) {
+			continue
+		}
+		err := NewValidationError(fmt.Sprintf("%s domain %s is not allowed for tenant %s", f.field, domain, t.id), nil)
+		err.Metadata = map[string]string{
+			TenantMetadataKey:       t.id,
+			AddressFieldMetadataKey: string(f.field),
+			AddressMetadataKey:      addr,
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *TenantRegistry) SendEmail(ctx context.Context, e Email) error {
+	_, err := r.SendEmailV2(ctx, e, nil)
+	return err
+}
+
+func (r *TenantRegistry) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (_ *SendResult, err error) {
+	defer RecoverSend(ctx, e, opts, &err)
+
+	e, wrapped := opts.Forward(e)
+
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, NewValidationError("no tenant in context", nil)
+	}
+	t, err := r.tenant(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkDomains(e); err != nil {
+		return nil, err
+	}
+
+	s, err := t.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.SendEmailV2(ctx, e, wrapped)
+}
+
+// Shutdown shuts down the sender chains built so far, see Shutdown.
+func (r *TenantRegistry) Shutdown(ctx context.Context) error {
+	r.mu.RLock()
+	tenants := make([]*tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	r.mu.RUnlock()
+
+	var errs []error
+	for _, t := range tenants {
+		t.mu.Lock()
+		s := t.sender
+		t.mu.Unlock()
+		if s == nil {
+			continue
+		}
+		if err := Shutdown(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", t.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,242 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// tenantSender records the From address of its sends. It is safe for
+// concurrent use.
+type tenantSender struct {
+	mu   sync.Mutex
+	from []string
+}
+
+func (s *tenantSender) SendEmailV2(ctx context.Context, e Email, opts *SendOptions) (*SendResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.from = append(s.from, e.FromAddress)
+	return &SendResult{Provider: "tenant"}, nil
+}
+
+func (s *tenantSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.from...)
+}
+
+// tenantFixture registers the tenants "archers" and "bowmen", each
+// sending through its own tenantSender and counting its builds.
+func tenantFixture(t *testing.T) (*TenantRegistry, map[string]*tenantSender, map[string]*atomic.Int32) {
+	t.Helper()
+
+	r := NewTenantRegistry()
+	senders := map[string]*tenantSender{}
+	builds := map[string]*atomic.Int32{}
+	for id, domain := range map[string]string{"archers": "archers.org", "bowmen": "Bowmen.org"} {
+		senders[id] = &tenantSender{}
+		builds[id] = &atomic.Int32{}
+		err := r.Register(id, func(ctx context.Context) (SenderV2, error) {
+			builds[id].Add(1)
+			return senders[id], nil
+		}, WithTenantDomains(domain))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return r, senders, builds
+}
+
+func TestTenantRegistry_Isolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		tenant string
+		email  Email
+		field  string
+	}{
+		{
+			name:   "from another tenant's domain",
+			tenant: "archers",
+			email:  Email{FromAddress: "results@bowmen.org"},
+			field:  "From",
+		},
+		{
+			name:   "sender on another tenant's domain",
+			tenant: "bowmen",
+			email:  Email{FromAddress: "results@bowmen.org", SenderAddress: "Director <director@archers.org>"},
+			field:  "Sender",
+		},
+		{
+			name:   "bounces to another tenant's domain",
+			tenant: "archers",
+			email:  Email{FromAddress: "results@archers.org", BounceAddress: "bounces@bowmen.org"},
+			field:  "Return-Path",
+		},
+		{
+			name:   "subdomain",
+			tenant: "archers",
+			email:  Email{FromAddress: "results@mail.archers.org"},
+			field:  "From",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, senders, builds := tenantFixture(t)
+
+			_, err := r.SendEmailV2(WithTenant(context.Background(), tc.tenant), tc.email, nil)
+			var emailErr *Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != REASON_VALIDATION_ERROR {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if emailErr.Metadata[TenantMetadataKey] != tc.tenant || emailErr.Metadata[AddressFieldMetadataKey] != tc.field {
+				t.Errorf("unexpected metadata %v", emailErr.Metadata)
+			}
+			for id := range senders {
+				if len(senders[id].sent()) != 0 || builds[id].Load() != 0 {
+					t.Errorf("expected tenant %s not to be used", id)
+				}
+			}
+		})
+	}
+}
+
+func TestTenantRegistry_Routing(t *testing.T) {
+	r, senders, _ := tenantFixture(t)
+
+	for _, tc := range []struct{ tenant, from string }{
+		{"archers", "results@archers.org"},
+		{"bowmen", "Results <results@BOWMEN.org>"},
+	} {
+		if _, err := r.SendEmailV2(WithTenant(context.Background(), tc.tenant), Email{FromAddress: tc.from}, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sent := senders[tc.tenant].sent(); len(sent) != 1 || sent[0] != tc.from {
+			t.Errorf("expected tenant %s to send from %s, got %v", tc.tenant, tc.from, sent)
+		}
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"no tenant":      context.Background(),
+		"unknown tenant": WithTenant(context.Background(), "crossbows"),
+	} {
+		_, err := r.SendEmailV2(ctx, Email{FromAddress: "results@archers.org"}, nil)
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+}
+
+func TestTenantRegistry_Register(t *testing.T) {
+	r := NewTenantRegistry()
+	build := func(ctx context.Context) (SenderV2, error) { return &tenantSender{}, nil }
+
+	if err := r.Register("archers", build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Register("archers", build); err == nil {
+		t.Error("expected registering a tenant twice to fail")
+	}
+	if err := r.Register("", build); err == nil {
+		t.Error("expected a tenant without an ID to fail")
+	}
+}
+
+func TestTenantRegistry_LazyBuild(t *testing.T) {
+	r := NewTenantRegistry()
+	var builds atomic.Int32
+	fail := true
+	err := r.Register("archers", func(ctx context.Context) (SenderV2, error) {
+		builds.Add(1)
+		if fail {
+			return nil, errors.New("no credentials yet")
+		}
+		return &tenantSender{}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if builds.Load() != 0 {
+		t.Fatal("expected the chain not to be built at registration")
+	}
+
+	ctx := WithTenant(context.Background(), "archers")
+	if _, err := r.SendEmailV2(ctx, Email{}, nil); err == nil {
+		t.Fatal("expected the failed build to fail the send")
+	}
+
+	fail = false
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.SendEmailV2(ctx, Email{}, nil); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := builds.Load(); got != 2 {
+		t.Errorf("expected the failed build to be retried once and then cached, got %d builds", got)
+	}
+}
+
+func TestTenantRegistry_ConcurrentTenants(t *testing.T) {
+	r, senders, builds := tenantFixture(t)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		tenant, from := "archers", "results@archers.org"
+		if i%2 == 1 {
+			tenant, from = "bowmen", "results@bowmen.org"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.SendEmailV2(WithTenant(context.Background(), tenant), Email{FromAddress: from}, nil); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id, want := range map[string]string{"archers": "results@archers.org", "bowmen": "results@bowmen.org"} {
+		sent := senders[id].sent()
+		if len(sent) != 50 || builds[id].Load() != 1 {
+			t.Errorf("expected tenant %s to send 50 emails with one build, got %d and %d", id, len(sent), builds[id].Load())
+		}
+		for _, from := range sent {
+			if from != want {
+				t.Errorf("tenant %s sent an email from %s", id, from)
+			}
+		}
+	}
+}
+
+func TestTenantRegistry_Shutdown(t *testing.T) {
+	var shutdown []string
+	r := NewTenantRegistry()
+	for _, id := range []string{"archers", "bowmen"} {
+		err := r.Register(id, func(ctx context.Context) (SenderV2, error) {
+			return &drainingSender{name: id, shutdown: &shutdown}, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := r.SendEmailV2(WithTenant(context.Background(), "bowmen"), Email{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Shutdown(context.Background(), r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shutdown) != 1 || shutdown[0] != "bowmen" {
+		t.Errorf("expected only the built chain to be shut down, got %v", shutdown)
+	}
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	ListUnsubscribeHeader     = "List-Unsubscribe"
+	ListUnsubscribePostHeader = "List-Unsubscribe-Post"
+)
+
+// Unsubscribe describes how recipients of bulk mail unsubscribe, sent as the
+// List-Unsubscribe headers of RFC 2369 and RFC 8058. The zero value sends no
+// headers.
+type Unsubscribe struct {
+	// An http or https URL.
+	URL string
+	// An address, or a mailto: URI, that unsubscribes when mailed.
+	Mailto string
+	// Lets clients unsubscribe with a single POST to URL, as Gmail and Yahoo
+	// require of bulk senders. Requires an https URL.
+	OneClick bool
+}
+
+// OneClickUnsubscribe returns an RFC 8058 one-click Unsubscribe for url.
+func OneClickUnsubscribe(url string) Unsubscribe {
+	return Unsubscribe{URL: url, OneClick: true}
+}
+
+// Headers returns the List-Unsubscribe and List-Unsubscribe-Post headers for
+// u, or nil if u is empty.
+func (u Unsubscribe) Headers() []Header {
+	var targets []string
+	if u.URL != "" {
+		targets = append(targets, "<"+u.URL+">")
+	}
+	if u.Mailto != "" {
+		targets = append(targets, "<"+mailtoURI(u.Mailto)+">")
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	headers := []Header{{ListUnsubscribeHeader, strings.Join(targets, ", ")}}
+	if u.OneClick {
+		headers = append(headers, Header{ListUnsubscribePostHeader, "List-Unsubscribe=One-Click"})
+	}
+	return headers
+}
+
+func ValidateUnsubscribe(u Unsubscribe) error {
+	if u.URL != "" {
+		parsed, err := url.Parse(u.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return NewValidationError(fmt.Sprintf("unsubscribe URL %q is not an http or https URL", u.URL), err)
+		}
+		if u.OneClick && parsed.Scheme != "https" {
+			return NewValidationError("one-click unsubscribe requires an https URL", nil)
+		}
+		if strings.ContainsAny(u.URL, "<> \t\r\n") {
+			return NewValidationError(fmt.Sprintf("unsubscribe URL %q must be percent-encoded", u.URL), nil)
+		}
+	} else if u.OneClick {
+		return NewValidationError("one-click unsubscribe requires a URL", nil)
+	}
+
+	if u.Mailto != "" {
+		uri, err := url.Parse(mailtoURI(u.Mailto))
+		if err != nil || uri.Opaque == "" || strings.ContainsAny(u.Mailto, "<> \t\r\n") {
+			return NewValidationError(fmt.Sprintf("unsubscribe mailto %q is not a mailto URI", u.Mailto), err)
+		}
+	}
+
+	return nil
+}
+
+func mailtoURI(s string) string {
+	if strings.HasPrefix(strings.ToLower(s), "mailto:") {
+		return s
+	}
+	return "mailto:" + s
+}
@@ -0,0 +1,68 @@
+package email
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnsubscribe_Headers(t *testing.T) {
+	tests := []struct {
+		name        string
+		unsubscribe Unsubscribe
+		want        []Header
+	}{
+		{name: "none"},
+		{
+			name:        "one-click",
+			unsubscribe: OneClickUnsubscribe("https://icaa.example.com/u/1"),
+			want: []Header{
+				{"List-Unsubscribe", "<https://icaa.example.com/u/1>"},
+				{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+			},
+		},
+		{
+			name:        "url and mailto",
+			unsubscribe: Unsubscribe{URL: "https://icaa.example.com/u/1", Mailto: "unsubscribe@icaa.example.com"},
+			want:        []Header{{"List-Unsubscribe", "<https://icaa.example.com/u/1>, <mailto:unsubscribe@icaa.example.com>"}},
+		},
+		{
+			name:        "mailto URI",
+			unsubscribe: Unsubscribe{Mailto: "mailto:unsubscribe@icaa.example.com?subject=unsubscribe"},
+			want:        []Header{{"List-Unsubscribe", "<mailto:unsubscribe@icaa.example.com?subject=unsubscribe>"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.unsubscribe.Headers(); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if err := ValidateUnsubscribe(tt.unsubscribe); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateUnsubscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		unsubscribe Unsubscribe
+	}{
+		{"not a URL", Unsubscribe{URL: "icaa.example.com/u/1"}},
+		{"ftp", Unsubscribe{URL: "ftp://icaa.example.com/u/1"}},
+		{"one-click over http", OneClickUnsubscribe("http://icaa.example.com/u/1")},
+		{"one-click without URL", Unsubscribe{Mailto: "u@icaa.example.com", OneClick: true}},
+		{"angle bracket in URL", Unsubscribe{URL: "https://icaa.example.com/u>"}},
+		{"empty mailto", Unsubscribe{Mailto: "mailto:"}},
+		{"line break in mailto", Unsubscribe{Mailto: "u@icaa.example.com\r\nBcc: x@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateUnsubscribe(tt.unsubscribe); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
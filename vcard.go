@@ -0,0 +1,77 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/International-Combat-Archery-Alliance/email/internal/vcard"
+)
+
+// VCard is a contact card, e.g. of an event's organizer, for
+// VCardAttachment.
+type VCard struct {
+	// Full name. Required.
+	Name  string
+	Org   string
+	Email string
+	Phone string
+	URL   string
+}
+
+// VCardAttachment returns contact as a vCard 3.0 attachment, a .vcf file
+// named after the contact that address books import.
+func VCardAttachment(contact VCard) (Attachment, error) {
+	name := strings.TrimSpace(contact.Name)
+	if name == "" {
+		return Attachment{}, NewValidationError("vCard name is required", nil)
+	}
+
+	props := []vcard.Property{
+		{Name: "FN", Values: []string{name}},
+		{Name: "N", Values: structuredName(name)},
+	}
+	if contact.Org != "" {
+		props = append(props, vcard.Property{Name: "ORG", Values: []string{contact.Org}})
+	}
+	if contact.Email != "" {
+		addr, err := ParseAddress(contact.Email)
+		if err != nil {
+			return Attachment{}, NewInvalidEmailError(fmt.Sprintf("invalid vCard email address: %s", contact.Email), err)
+		}
+		props = append(props, vcard.Property{Name: "EMAIL", Params: []string{"TYPE=INTERNET"}, Values: []string{addr.Address}})
+	}
+	if contact.Phone != "" {
+		props = append(props, vcard.Property{Name: "TEL", Params: []string{"TYPE=VOICE"}, Values: []string{contact.Phone}})
+	}
+	if contact.URL != "" {
+		props = append(props, vcard.Property{Name: "URL", Values: []string{contact.URL}})
+	}
+
+	return Attachment{
+		FileName:    vcardFileName(name),
+		Content:     vcard.Encode(props),
+		ContentType: "text/vcard",
+	}, nil
+}
+
+// structuredName splits a full name into the family and given names of
+// the N property, taking the last word as the family name.
+func structuredName(name string) []string {
+	given, family := "", name
+	if i := strings.LastIndexFunc(name, unicode.IsSpace); i >= 0 {
+		given, family = strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return []string{family, given, "", "", ""}
+}
+
+// vcardFileName returns name with characters unsafe in file names replaced.
+func vcardFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -_.", r) {
+			return r
+		}
+		return '_'
+	}, name)
+	return safe + ".vcf"
+}
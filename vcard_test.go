@@ -0,0 +1,114 @@
+package email
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/International-Combat-Archery-Alliance/email/internal/vcard"
+)
+
+func TestVCardAttachment(t *testing.T) {
+	tests := []struct {
+		name     string
+		contact  VCard
+		fileName string
+		expected []vcard.Property
+	}{
+		{
+			name:     "name only",
+			contact:  VCard{Name: "Ada"},
+			fileName: "Ada.vcf",
+			expected: []vcard.Property{
+				{Name: "FN", Values: []string{"Ada"}},
+				{Name: "N", Values: []string{"Ada", "", "", "", ""}},
+			},
+		},
+		{
+			name: "every field",
+			contact: VCard{
+				Name:  "Ada King Lovelace",
+				Org:   "Arrows; Bows, and Co.",
+				Email: "Ada <ada@icaa.example.com>",
+				Phone: "+44 20 7946 0000",
+				URL:   "https://icaa.example.com/organizers?id=1,2",
+			},
+			fileName: "Ada King Lovelace.vcf",
+			expected: []vcard.Property{
+				{Name: "FN", Values: []string{"Ada King Lovelace"}},
+				{Name: "N", Values: []string{"Lovelace", "Ada King", "", "", ""}},
+				{Name: "ORG", Values: []string{"Arrows; Bows, and Co."}},
+				{Name: "EMAIL", Params: []string{"TYPE=INTERNET"}, Values: []string{"ada@icaa.example.com"}},
+				{Name: "TEL", Params: []string{"TYPE=VOICE"}, Values: []string{"+44 20 7946 0000"}},
+				{Name: "URL", Values: []string{"https://icaa.example.com/organizers?id=1,2"}},
+			},
+		},
+		{
+			name:     "unsafe file name",
+			contact:  VCard{Name: "José / Turnierleitung\nMünchen"},
+			fileName: "José _ Turnierleitung_München.vcf",
+			expected: []vcard.Property{
+				{Name: "FN", Values: []string{"José / Turnierleitung\nMünchen"}},
+				{Name: "N", Values: []string{"München", "José / Turnierleitung", "", "", ""}},
+			},
+		},
+		{
+			name:     "long organization",
+			contact:  VCard{Name: "Ada", Org: strings.Repeat("International Combat Archery Alliance, ", 5)},
+			fileName: "Ada.vcf",
+			expected: []vcard.Property{
+				{Name: "FN", Values: []string{"Ada"}},
+				{Name: "N", Values: []string{"Ada", "", "", "", ""}},
+				{Name: "ORG", Values: []string{strings.Repeat("International Combat Archery Alliance, ", 5)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := VCardAttachment(tt.contact)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a.FileName != tt.fileName || a.ContentType != "text/vcard" {
+				t.Errorf("expected %s as text/vcard, got %s as %s", tt.fileName, a.FileName, a.ContentType)
+			}
+			for _, line := range strings.SplitAfter(string(a.Content), "\r\n") {
+				if len(line) > 77 {
+					t.Errorf("line longer than 75 octets: %q", line)
+				}
+			}
+
+			got, err := vcard.Decode(a.Content)
+			if err != nil {
+				t.Fatalf("failed to parse the card: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestVCardAttachment_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contact  VCard
+		expected ErrorReason
+	}{
+		{"no name", VCard{Email: "ada@example.com"}, REASON_VALIDATION_ERROR},
+		{"blank name", VCard{Name: "  "}, REASON_VALIDATION_ERROR},
+		{"invalid email", VCard{Name: "Ada", Email: "ada"}, REASON_INVALID_EMAIL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VCardAttachment(tt.contact)
+			var emailErr *Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// DefaultVERPSeparator separates the parts of the local part of VERP
+// addresses unless WithVERPSeparator is given.
+const DefaultVERPSeparator = "+"
+
+// verpSignatureBytes is how much of the HMAC is kept, enough to make
+// guessing impractical while keeping addresses short.
+const verpSignatureBytes = 8
+
+// maxLocalPartLength is the longest local part RFC 5321 allows.
+const maxLocalPartLength = 64
+
+var verpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type VERPOption func(*VERP)
+
+// WithVERPSeparator replaces DefaultVERPSeparator. It must be "+", "-" or
+// "_", whichever the bounce domain's mail server delivers to the base
+// mailbox.
+func WithVERPSeparator(sep string) VERPOption {
+	return func(v *VERP) {
+		v.sep = sep
+	}
+}
+
+// VERP generates and parses variable envelope return path addresses, which
+// encode the recipient a message was sent to so a bounce can be attributed
+// without provider notifications. With the base address
+// bounce@bounces.example.org, mail to ada@example.com bounces to
+// bounce+ada=example.com+<signature>@bounces.example.org. The signature is an
+// HMAC of the recipient, so bounces can't be forged to suppress arbitrary
+// addresses. It is safe for concurrent use.
+type VERP struct {
+	prefix string
+	domain string
+	sep    string
+	key    []byte
+}
+
+// NewVERP returns a VERP for the base bounce address, whose local part
+// becomes the prefix of every VERP address, signing with key.
+func NewVERP(bounceAddress string, key []byte, opts ...VERPOption) (*VERP, error) {
+	addr, err := ParseAddress(bounceAddress)
+	if err != nil {
+		return nil, NewInvalidEmailError(fmt.Sprintf("invalid bounce address: %s", bounceAddress), err)
+	}
+	prefix, domain, _ := strings.Cut(addr.Address, "@")
+
+	v := &VERP{prefix: prefix, domain: domain, sep: DefaultVERPSeparator, key: key}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if len(key) == 0 {
+		return nil, NewValidationError("VERP signing key is empty", nil)
+	}
+	if v.sep != "+" && v.sep != "-" && v.sep != "_" {
+		return nil, NewValidationError(fmt.Sprintf("VERP separator %q is not one of +, - or _", v.sep), nil)
+	}
+	return v, nil
+}
+
+// Encode returns the VERP address for recipient. Recipients are compared
+// case-insensitively, since mail servers may change the case of the local
+// part.
+func (v *VERP) Encode(recipient string) (string, error) {
+	addr, err := ParseAddress(recipient)
+	if err != nil {
+		return "", NewInvalidEmailError(fmt.Sprintf("invalid recipient: %s", recipient), err)
+	}
+	bare := strings.ToLower(addr.Address)
+	local, domain, _ := strings.Cut(bare, "@")
+
+	verpLocal := v.prefix + v.sep + local + "=" + domain + v.sep + v.sign(bare)
+	if len(verpLocal) > maxLocalPartLength {
+		return "", NewValidationError(fmt.Sprintf("VERP address for %s is longer than %d characters", recipient, maxLocalPartLength), nil)
+	}
+	return verpLocal + "@" + v.domain, nil
+}
+
+// Decode returns the recipient encoded in a VERP address, e.g. the Return-Path
+// or recipient of a bounce. Addresses that aren't VERP addresses of v, or
+// whose signature doesn't match, are validation errors.
+func (v *VERP) Decode(verpAddress string) (string, error) {
+	addr, err := ParseAddress(verpAddress)
+	if err != nil {
+		return "", NewInvalidEmailError(fmt.Sprintf("invalid VERP address: %s", verpAddress), err)
+	}
+	local, domain, _ := strings.Cut(strings.ToLower(addr.Address), "@")
+
+	rest, ok := strings.CutPrefix(local, strings.ToLower(v.prefix)+v.sep)
+	if !ok || domain != strings.ToLower(v.domain) {
+		return "", NewValidationError(fmt.Sprintf("%s is not a VERP address", verpAddress), nil)
+	}
+
+	i := strings.LastIndex(rest, v.sep)
+	if i < 0 {
+		return "", NewValidationError(fmt.Sprintf("%s has no VERP signature", verpAddress), nil)
+	}
+	payload, signature := rest[:i], rest[i+len(v.sep):]
+	j := strings.LastIndex(payload, "=")
+	if j <= 0 || j == len(payload)-1 {
+		return "", NewValidationError(fmt.Sprintf("%s does not encode a recipient", verpAddress), nil)
+	}
+
+	recipient := payload[:j] + "@" + payload[j+1:]
+	if !hmac.Equal([]byte(signature), []byte(v.sign(recipient))) {
+		return "", NewValidationError(fmt.Sprintf("VERP signature of %s does not match", verpAddress), nil)
+	}
+	return recipient, nil
+}
+
+func (v *VERP) sign(recipient string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(recipient))
+	return strings.ToLower(verpEncoding.EncodeToString(mac.Sum(nil)[:verpSignatureBytes]))
+}
+
+// SendWithVERP sends e to each of its To, CC and BCC recipients as a
+// separate email addressed to them alone, with the recipient's VERP address
+// as BounceAddress. An address listed more than once is sent to once, and
+// each email gets a Message-ID of its own. Results and errors are as for
+// SendPersonalized, which options it shares.
+//
+// VERP only works where bounces reach BounceAddress, see
+// Capabilities.Bounces: with BOUNCE_ENVELOPE_SENDER every bounce does, and
+// with BOUNCE_FORWARDED, as for SES, the bounces the provider receives are
+// forwarded to it. With BOUNCE_HEADER_ONLY, as for Gmail, receiving servers
+// usually replace the Return-Path header, so bounces go to the provider's
+// envelope sender instead and do not name the recipient.
+func SendWithVERP(ctx context.Context, sender Sender, e Email, v *VERP, opts ...PersonalizeOption) ([]SendResult, error) {
+	cfg := personalizeConfig{concurrency: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.concurrency = max(cfg.concurrency, 1)
+
+	e, err := ReadBodies(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []string
+	seen := map[string]bool{}
+	for _, addrs := range [][]string{e.ToAddresses, e.CCAddresses, e.BCCAddresses} {
+		for _, addr := range addrs {
+			key := indexAddress(addr)
+			if !seen[key] {
+				seen[key] = true
+				recipients = append(recipients, addr)
+			}
+		}
+	}
+
+	return sendEach(ctx, AsSenderV2(sender), recipients, cfg.concurrency, func(i int) (Email, error) {
+		bounce, err := v.Encode(recipients[i])
+		if err != nil {
+			return Email{}, err
+		}
+		single := addressedTo(e, recipients[i])
+		single.BounceAddress = bounce
+		return single, nil
+	})
+}
@@ -0,0 +1,196 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVERP(t *testing.T) {
+	key := []byte("secret")
+
+	tests := []struct {
+		name      string
+		sep       string
+		recipient string
+		expected  string
+	}{
+		{name: "plain", recipient: "ada@example.com", expected: "bounce+ada=example.com+"},
+		{name: "display name and case", recipient: "Ada <Ada@Example.com>", expected: "bounce+ada=example.com+"},
+		{name: "separator in the local part", recipient: "ada+league@example.com", expected: "bounce+ada+league=example.com+"},
+		{name: "dash separator", sep: "-", recipient: "mary-jane@example.com", expected: "bounce-mary-jane=example.com-"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []VERPOption
+			if tc.sep != "" {
+				opts = append(opts, WithVERPSeparator(tc.sep))
+			}
+			v, err := NewVERP("Bounces <bounce@bounces.example.org>", key, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			addr, err := v.Encode(tc.recipient)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(addr, tc.expected) || !strings.HasSuffix(addr, "@bounces.example.org") {
+				t.Errorf("expected %s<signature>@bounces.example.org, got %s", tc.expected, addr)
+			}
+
+			for _, received := range []string{addr, "<" + addr + ">", strings.ToUpper(addr)} {
+				got, err := v.Decode(received)
+				if err != nil {
+					t.Fatalf("decoding %s: unexpected error: %v", received, err)
+				}
+				if want := indexAddress(tc.recipient); got != want {
+					t.Errorf("decoding %s: expected %s, got %s", received, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestVERP_Rejects(t *testing.T) {
+	v, err := NewVERP("bounce@bounces.example.org", []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := NewVERP("bounce@bounces.example.org", []byte("other secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	genuine, _ := v.Encode("ada@example.com")
+	forged, _ := other.Encode("ada@example.com")
+	signature := genuine[strings.LastIndex(genuine, "+"):strings.Index(genuine, "@")]
+
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{name: "signed with another key", address: forged},
+		{name: "recipient swapped", address: "bounce+grace=example.com" + signature + "@bounces.example.org"},
+		{name: "no signature", address: "bounce+ada=example.com@bounces.example.org"},
+		{name: "no recipient", address: "bounce+ada" + signature + "@bounces.example.org"},
+		{name: "other prefix", address: strings.Replace(genuine, "bounce+", "noreply+", 1)},
+		{name: "other domain", address: strings.Replace(genuine, "@bounces.example.org", "@example.org", 1)},
+		{name: "base address", address: "bounce@bounces.example.org"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.Decode(tc.address)
+
+			var emailErr *Error
+			if !errors.As(err, &emailErr) || emailErr.Reason != REASON_VALIDATION_ERROR {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewVERP_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		key     []byte
+		opts    []VERPOption
+	}{
+		{name: "invalid address", address: "bounce", key: []byte("secret")},
+		{name: "empty key", address: "bounce@bounces.example.org"},
+		{name: "invalid separator", address: "bounce@bounces.example.org", key: []byte("secret"), opts: []VERPOption{WithVERPSeparator("=")}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewVERP(tc.address, tc.key, tc.opts...); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestVERP_EncodeTooLong(t *testing.T) {
+	v, err := NewVERP("bounce@bounces.example.org", []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := v.Encode(strings.Repeat("a", 40) + "@example.com"); err == nil {
+		t.Fatal("expected an error for a local part over 64 characters")
+	}
+}
+
+func TestSendWithVERP(t *testing.T) {
+	v, err := NewVERP("bounce@bounces.example.org", []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inner := &rejectingSender{rejected: []string{"board@example.com"}}
+	e := Email{
+		FromAddress:  "league@example.com",
+		ToAddresses:  []string{"ada@example.com", "Grace <grace@example.com>"},
+		CCAddresses:  []string{"board@example.com", "ADA@example.com"},
+		BCCAddresses: []string{"archive@example.com"},
+		Subject:      "Results",
+		TextBody:     "Results",
+	}
+
+	results, err := SendWithVERP(context.Background(), AsSender(inner), e, v)
+
+	var sendErr *PersonalizedSendError
+	if !errors.As(err, &sendErr) || len(sendErr.Errors) != 1 || sendErr.Errors[0].Address != "board@example.com" {
+		t.Fatalf("expected board@example.com to fail alone, got %v", err)
+	}
+	if len(results) != 4 || results[0].Provider != "rejecting" || results[2].Provider != "" {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	expected := []string{"ada@example.com", "Grace <grace@example.com>", "board@example.com", "archive@example.com"}
+	if len(inner.sent) != len(expected) {
+		t.Fatalf("expected %d sends, got %d", len(expected), len(inner.sent))
+	}
+	for i, sent := range inner.sent {
+		if len(sent.ToAddresses) != 1 || sent.ToAddresses[0] != expected[i] || sent.CCAddresses != nil || sent.BCCAddresses != nil {
+			t.Errorf("send %d: expected to be addressed to %s alone, got %v %v %v", i, expected[i], sent.ToAddresses, sent.CCAddresses, sent.BCCAddresses)
+		}
+		got, err := v.Decode(sent.BounceAddress)
+		if err != nil {
+			t.Fatalf("send %d: unexpected error: %v", i, err)
+		}
+		if want := indexAddress(expected[i]); got != want {
+			t.Errorf("send %d: bounce address decodes to %s, expected %s", i, got, want)
+		}
+	}
+}
+
+func TestSendWithVERP_MessageID(t *testing.T) {
+	v, err := NewVERP("bounce@bounces.example.org", []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := Email{
+		FromAddress: "league@example.com",
+		ToAddresses: []string{"ada@example.com", "grace@example.com"},
+		CCAddresses: []string{"board@example.com"},
+		MessageID:   "<results@example.com>",
+		Subject:     "Results",
+		TextBody:    "Results",
+	}
+
+	results, err := SendWithVERP(context.Background(), AsSender(messageIDSender{}), e, v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := map[string]bool{e.MessageID: true}
+	for i, result := range results {
+		if result.MessageID == "" || seen[result.MessageID] {
+			t.Errorf("result %d: expected a Message-ID of its own, got %q", i, result.MessageID)
+		}
+		seen[result.MessageID] = true
+	}
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+type zipConfig struct {
+	maxBytes             int64
+	compressionThreshold int64
+}
+
+type ZipOption func(*zipConfig)
+
+// WithMaxArchiveBytes caps the size of the archive ZipAttachments produces,
+// DefaultMaxAttachmentBytes by default.
+func WithMaxArchiveBytes(n int64) ZipOption {
+	return func(c *zipConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithCompressionThreshold only compresses the archive when the attachments
+// add up to more than n bytes, storing them as they are otherwise, e.g. to
+// spare small archives of already compressed PDFs the effort. Archives are
+// always compressed by default.
+func WithCompressionThreshold(n int64) ZipOption {
+	return func(c *zipConfig) {
+		c.compressionThreshold = n
+	}
+}
+
+// ZipAttachments bundles attachments into a single zip archive called name,
+// e.g. to send many files to providers that limit the number of
+// attachments. Entries are named after the attachments, with numeric
+// suffixes added to names that collide, ignoring case.
+func ZipAttachments(name string, attachments []Attachment, opts ...ZipOption) (Attachment, error) {
+	cfg := zipConfig{maxBytes: DefaultMaxAttachmentBytes}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if name == "" {
+		return Attachment{}, NewValidationError("archive name is required", nil)
+	}
+	if len(attachments) == 0 {
+		return Attachment{}, NewValidationError("no attachments to archive", nil)
+	}
+
+	var total int64
+	for _, a := range attachments {
+		total += int64(len(a.Content))
+	}
+	method := zip.Deflate
+	if total <= cfg.compressionThreshold {
+		method = zip.Store
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	taken := map[string]bool{}
+	for _, a := range attachments {
+		entry := zipEntryName(a.FileName, taken)
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: entry, Method: method})
+		if err != nil {
+			return Attachment{}, NewUnknownError(fmt.Sprintf("failed to archive attachment %s", a.FileName), err)
+		}
+		if _, err := w.Write(a.Content); err != nil {
+			return Attachment{}, NewUnknownError(fmt.Sprintf("failed to archive attachment %s", a.FileName), err)
+		}
+		// Checked as it grows, so an oversized archive is never built in
+		// full.
+		if int64(buf.Len()) > cfg.maxBytes {
+			return Attachment{}, NewValidationError(fmt.Sprintf("archive %s is larger than %d bytes", name, cfg.maxBytes), nil)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return Attachment{}, NewUnknownError(fmt.Sprintf("failed to write archive %s", name), err)
+	}
+	if int64(buf.Len()) > cfg.maxBytes {
+		return Attachment{}, NewValidationError(fmt.Sprintf("archive %s is larger than %d bytes", name, cfg.maxBytes), nil)
+	}
+
+	return Attachment{
+		FileName:    name,
+		Content:     buf.Bytes(),
+		ContentType: "application/zip",
+	}, nil
+}
+
+// zipEntryName returns the base of fileName, suffixed with " (2)", " (3)"
+// and so on before the extension if it is already taken, and marks it
+// taken.
+func zipEntryName(fileName string, taken map[string]bool) string {
+	base := path.Base(strings.ReplaceAll(fileName, `\`, "/"))
+	if base == "." || base == "/" || base == ".." {
+		base = "attachment"
+	}
+
+	entry := base
+	ext := path.Ext(base)
+	for n := 2; taken[strings.ToLower(entry)]; n++ {
+		entry = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), n, ext)
+	}
+	taken[strings.ToLower(entry)] = true
+	return entry
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func readZip(t *testing.T, a Attachment) (names []string, contents map[string][]byte, methods map[string]uint16) {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(a.Content), int64(len(a.Content)))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+	contents = map[string][]byte{}
+	methods = map[string]uint16{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		names = append(names, f.Name)
+		contents[f.Name] = content
+		methods[f.Name] = f.Method
+	}
+	return names, contents, methods
+}
+
+func TestZipAttachments(t *testing.T) {
+	scorecard := bytes.Repeat([]byte("round 1: 10 9 9 8\n"), 100)
+	attachments := []Attachment{
+		{FileName: "scorecard.pdf", Content: scorecard, ContentType: "application/pdf"},
+		{FileName: "scorecard.pdf", Content: []byte("second"), ContentType: "application/pdf"},
+		{FileName: "Scorecard.PDF", Content: []byte("third"), ContentType: "application/pdf"},
+		{FileName: "scorecard (2).pdf", Content: []byte("taken"), ContentType: "application/pdf"},
+		{FileName: "../../etc/passwd", Content: []byte("root"), ContentType: "text/plain"},
+		{FileName: `C:\scores\summary.csv`, Content: []byte("name,score\n"), ContentType: "text/csv"},
+		{FileName: "", Content: []byte{}, ContentType: "application/octet-stream"},
+	}
+
+	a, err := ZipAttachments("scorecards.zip", attachments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.FileName != "scorecards.zip" || a.ContentType != "application/zip" {
+		t.Errorf("expected scorecards.zip as application/zip, got %s as %s", a.FileName, a.ContentType)
+	}
+
+	names, contents, methods := readZip(t, a)
+	want := []string{"scorecard.pdf", "scorecard (2).pdf", "Scorecard (3).PDF", "scorecard (2) (2).pdf", "passwd", "summary.csv", "attachment"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected entries %q, got %q", want, names)
+	}
+	if !bytes.Equal(contents["scorecard.pdf"], scorecard) || string(contents["scorecard (2).pdf"]) != "second" {
+		t.Error("expected the entries to keep their content")
+	}
+	if methods["scorecard.pdf"] != zip.Deflate {
+		t.Errorf("expected entries to be compressed by default, got method %d", methods["scorecard.pdf"])
+	}
+}
+
+func TestZipAttachments_CompressionThreshold(t *testing.T) {
+	attachments := []Attachment{{FileName: "a.txt", Content: bytes.Repeat([]byte("a"), 1000)}}
+
+	tests := []struct {
+		name      string
+		threshold int64
+		expected  uint16
+	}{
+		{"below", 1000, zip.Store},
+		{"above", 999, zip.Deflate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ZipAttachments("a.zip", attachments, WithCompressionThreshold(tt.threshold))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, _, methods := readZip(t, a); methods["a.txt"] != tt.expected {
+				t.Errorf("expected method %d, got %d", tt.expected, methods["a.txt"])
+			}
+		})
+	}
+}
+
+func TestZipAttachments_Errors(t *testing.T) {
+	large := []Attachment{{FileName: "a.bin", Content: bytes.Repeat([]byte{0x5a}, 10_000)}}
+
+	tests := []struct {
+		name        string
+		archive     string
+		attachments []Attachment
+		opts        []ZipOption
+	}{
+		{"no name", "", large, nil},
+		{"no attachments", "a.zip", nil, nil},
+		{"over the cap", "a.zip", large, []ZipOption{WithMaxArchiveBytes(5_000), WithCompressionThreshold(1 << 20)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ZipAttachments(tt.archive, tt.attachments, tt.opts...)
+			if !errors.Is(err, ErrValidation) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+
+	// Compressed, the same content fits.
+	if _, err := ZipAttachments("a.zip", large, WithMaxArchiveBytes(5_000)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}